@@ -0,0 +1,281 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/funks"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+	tlmanager "github.com/uol/timelinemanager"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/metadata"
+)
+
+// Stores the metric catalog of each keyset: the unit, description, owner, type and free-form
+// attributes of the metrics. The entries of a keyset are cached and read again after the cache
+// duration, so the changes made in other nodes are seen after it
+
+// Configuration - the metric catalog settings
+type Configuration struct {
+	// CacheDuration - the time the catalog of a keyset stays cached
+	CacheDuration funks.Duration
+}
+
+const (
+	// TypeGauge - the metric points are the measured values
+	TypeGauge string = "gauge"
+
+	// TypeCounter - the metric points are an increasing count, they are queried by their rate
+	TypeCounter string = "counter"
+)
+
+// Entry - the catalog entry of a metric
+type Entry struct {
+	Metric      string            `json:"metric"`
+	Unit        string            `json:"unit,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	UpdateDate  time.Time         `json:"updateDate"`
+}
+
+// Validate - validates the entry type
+func (e Entry) Validate() gobol.Error {
+
+	if e.Type != constants.StringsEmpty && e.Type != TypeGauge && e.Type != TypeCounter {
+		return errBadRequest("Validate", fmt.Sprintf("the metric type must be %s or %s", TypeGauge, TypeCounter))
+	}
+
+	return nil
+}
+
+const (
+	formatCreateCatalogTable string = `CREATE TABLE IF NOT EXISTS %s.ts_metric_catalog (keyset text, metric text, unit text, description text, owner text, type text, attributes map<text, text>, update_date timestamp, PRIMARY KEY (keyset, metric))`
+	formatListEntries        string = `SELECT metric, unit, description, owner, type, attributes, update_date FROM %s.ts_metric_catalog WHERE keyset = ?`
+	formatPutEntry           string = `INSERT INTO %s.ts_metric_catalog (keyset, metric, unit, description, owner, type, attributes, update_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	formatDeleteEntry        string = `DELETE FROM %s.ts_metric_catalog WHERE keyset = ? AND metric = ?`
+)
+
+type cachedEntries struct {
+	entries map[string]Entry
+	expires time.Time
+}
+
+// Catalog - the metric catalog
+type Catalog struct {
+	session         *gocql.Session
+	ksAdmin         string
+	metaStorage     *metadata.Storage
+	timelineManager *tlmanager.Instance
+	logger          *logh.ContextualLogger
+	cacheDuration   time.Duration
+	mutex           sync.RWMutex
+	cache           map[string]*cachedEntries
+}
+
+// New - creates the metric catalog and its table
+func New(conf Configuration, ksAdmin string, session *gocql.Session, metaStorage *metadata.Storage, timelineManager *tlmanager.Instance) (*Catalog, error) {
+
+	if conf.CacheDuration.Duration < 0 {
+		return nil, fmt.Errorf("the metric catalog cache duration can not be negative")
+	}
+
+	err := session.Query(fmt.Sprintf(formatCreateCatalogTable, ksAdmin)).Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Catalog{
+		session:         session,
+		ksAdmin:         ksAdmin,
+		metaStorage:     metaStorage,
+		timelineManager: timelineManager,
+		logger:          logh.CreateContextualLogger(constants.StringsPKG, "catalog"),
+		cacheDuration:   conf.CacheDuration.Duration,
+		cache:           map[string]*cachedEntries{},
+	}, nil
+}
+
+const funcLoad string = "load"
+
+// load - reads all entries of the keyset
+func (c *Catalog) load(keyset string) (map[string]Entry, gobol.Error) {
+
+	start := time.Now()
+
+	iter := c.session.Query(fmt.Sprintf(formatListEntries, c.ksAdmin), keyset).Iter()
+
+	entries := map[string]Entry{}
+	entry := Entry{}
+
+	for iter.Scan(&entry.Metric, &entry.Unit, &entry.Description, &entry.Owner, &entry.Type, &entry.Attributes, &entry.UpdateDate) {
+		entries[entry.Metric] = entry
+		entry = Entry{}
+	}
+
+	if err := iter.Close(); err != nil {
+		c.statsQueryError(funcLoad, keyset, constants.CRUDOperationSelect)
+		return nil, errPersist(funcLoad, err)
+	}
+
+	c.statsQuery(funcLoad, keyset, constants.CRUDOperationSelect, time.Since(start))
+
+	return entries, nil
+}
+
+// entries - returns the cached entries of the keyset, reading them when expired
+func (c *Catalog) entries(keyset string) (map[string]Entry, gobol.Error) {
+
+	c.mutex.RLock()
+	cached, ok := c.cache[keyset]
+	c.mutex.RUnlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.entries, nil
+	}
+
+	entries, gerr := c.load(keyset)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	c.mutex.Lock()
+	c.cache[keyset] = &cachedEntries{entries: entries, expires: time.Now().Add(c.cacheDuration)}
+	c.mutex.Unlock()
+
+	return entries, nil
+}
+
+// invalidate - removes the cached entries of the keyset
+func (c *Catalog) invalidate(keyset string) {
+
+	c.mutex.Lock()
+	delete(c.cache, keyset)
+	c.mutex.Unlock()
+}
+
+// List - returns all entries of the keyset sorted by metric
+func (c *Catalog) List(keyset string) ([]Entry, gobol.Error) {
+
+	entries, gerr := c.entries(keyset)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	list := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Metric < list[j].Metric
+	})
+
+	return list, nil
+}
+
+// Get - returns the entry of the metric
+func (c *Catalog) Get(keyset, metric string) (Entry, bool, gobol.Error) {
+
+	entries, gerr := c.entries(keyset)
+	if gerr != nil {
+		return Entry{}, false, gerr
+	}
+
+	entry, ok := entries[metric]
+
+	return entry, ok, nil
+}
+
+const funcLookup string = "Lookup"
+
+// Lookup - returns the entries of the metrics found in the catalog, the errors are only logged
+// because the catalog complements the responses
+func (c *Catalog) Lookup(keyset string, metrics ...string) map[string]Entry {
+
+	if c == nil {
+		return nil
+	}
+
+	entries, gerr := c.entries(keyset)
+	if gerr != nil {
+		if logh.ErrorEnabled {
+			c.logger.Error().Str(constants.StringsFunc, funcLookup).Str(constants.StringsKeyset, keyset).Err(gerr).Msg("error reading the metric catalog")
+		}
+		return nil
+	}
+
+	found := map[string]Entry{}
+
+	for _, metric := range metrics {
+		if entry, ok := entries[metric]; ok {
+			found[metric] = entry
+		}
+	}
+
+	return found
+}
+
+// IsCounter - checks if the metric is a counter
+func (c *Catalog) IsCounter(keyset, metric string) bool {
+
+	return c.Lookup(keyset, metric)[metric].Type == TypeCounter
+}
+
+const funcPut string = "Put"
+
+// Put - creates or replaces the entry of the metric
+func (c *Catalog) Put(keyset string, entry *Entry) gobol.Error {
+
+	start := time.Now()
+
+	entry.UpdateDate = start.UTC().Truncate(time.Millisecond)
+
+	err := c.session.Query(
+		fmt.Sprintf(formatPutEntry, c.ksAdmin),
+		keyset,
+		entry.Metric,
+		entry.Unit,
+		entry.Description,
+		entry.Owner,
+		entry.Type,
+		entry.Attributes,
+		entry.UpdateDate,
+	).Exec()
+	if err != nil {
+		c.statsQueryError(funcPut, keyset, constants.CRUDOperationInsert)
+		return errPersist(funcPut, err)
+	}
+
+	c.statsQuery(funcPut, keyset, constants.CRUDOperationInsert, time.Since(start))
+
+	c.invalidate(keyset)
+
+	return nil
+}
+
+const funcDelete string = "Delete"
+
+// Delete - removes the entry of the metric
+func (c *Catalog) Delete(keyset, metric string) gobol.Error {
+
+	start := time.Now()
+
+	err := c.session.Query(fmt.Sprintf(formatDeleteEntry, c.ksAdmin), keyset, metric).Exec()
+	if err != nil {
+		c.statsQueryError(funcDelete, keyset, constants.CRUDOperationDelete)
+		return errPersist(funcDelete, err)
+	}
+
+	c.statsQuery(funcDelete, keyset, constants.CRUDOperationDelete, time.Since(start))
+
+	c.invalidate(keyset)
+
+	return nil
+}
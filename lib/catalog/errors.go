@@ -0,0 +1,43 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/tserr"
+)
+
+const (
+	cPackage string = "catalog"
+)
+
+func errBasic(function, message string, code int, e error) gobol.Error {
+	if e != nil {
+		return tserr.New(
+			e,
+			message,
+			cPackage,
+			function,
+			code,
+		)
+	}
+	return nil
+}
+
+func errBadRequest(function, message string) gobol.Error {
+	return errBasic(function, message, http.StatusBadRequest, errors.New(message))
+}
+
+func errPersist(function string, e error) gobol.Error {
+	return errBasic(function, e.Error(), http.StatusInternalServerError, e)
+}
+
+func errNotFound(function string) gobol.Error {
+	return errBasic(function, "metric not found in the catalog", http.StatusNotFound, errors.New("metric not found in the catalog"))
+}
+
+func errKeysetNotFound(function string) gobol.Error {
+	return errBasic(function, "keyset not found", http.StatusNotFound, errors.New("keyset not found"))
+}
@@ -0,0 +1,151 @@
+package catalog
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// getParameters - returns the keyset and the metric, the metric is a catch all parameter because it can have slashes
+func (c *Catalog) getParameters(ps httprouter.Params, function string, metricRequired bool) (string, string, gobol.Error) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		return constants.StringsEmpty, constants.StringsEmpty, errKeysetNotFound(function)
+	}
+
+	if !c.metaStorage.CheckKeyset(keyset) {
+		return constants.StringsEmpty, constants.StringsEmpty, errKeysetNotFound(function)
+	}
+
+	metric := strings.TrimPrefix(ps.ByName("metric"), "/")
+	if metricRequired && metric == constants.StringsEmpty {
+		return constants.StringsEmpty, constants.StringsEmpty, errBadRequest(function, "parameter 'metric' cannot be empty")
+	}
+
+	return keyset, metric, nil
+}
+
+// ListEntries - returns all catalog entries of the keyset
+func (c *Catalog) ListEntries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, _, gerr := c.getParameters(ps, "ListEntries", false)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	entries, gerr := c.List(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if len(entries) == 0 {
+		rip.Success(w, http.StatusNoContent, nil)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, entries)
+}
+
+// GetEntry - returns the catalog entry of the metric
+func (c *Catalog) GetEntry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, metric, gerr := c.getParameters(ps, "GetEntry", true)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	entry, found, gerr := c.Get(keyset, metric)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if !found {
+		rip.Fail(w, errNotFound("GetEntry"))
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, entry)
+}
+
+// PutEntry - creates or replaces the catalog entry of the metric
+func (c *Catalog) PutEntry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, metric, gerr := c.getParameters(ps, "PutEntry", true)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	entry := Entry{}
+
+	gerr = rip.FromJSON(r, &entry)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if entry.Metric != constants.StringsEmpty && entry.Metric != metric {
+		rip.Fail(w, errBadRequest("PutEntry", "the metric of the entry is different from the one in the path"))
+		return
+	}
+
+	entry.Metric = metric
+
+	_, found, gerr := c.Get(keyset, metric)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	gerr = c.Put(keyset, &entry)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if found {
+		rip.SuccessJSON(w, http.StatusOK, entry)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusCreated, entry)
+}
+
+// DeleteEntry - removes the catalog entry of the metric
+func (c *Catalog) DeleteEntry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, metric, gerr := c.getParameters(ps, "DeleteEntry", true)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	_, found, gerr := c.Get(keyset, metric)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if !found {
+		rip.Fail(w, errNotFound("DeleteEntry"))
+		return
+	}
+
+	gerr = c.Delete(keyset, metric)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.Success(w, http.StatusOK, nil)
+}
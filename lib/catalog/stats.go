@@ -0,0 +1,38 @@
+package catalog
+
+import (
+	"time"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+func (c *Catalog) statsQuery(function, keyset string, operation constants.CRUDOperation, d time.Duration) {
+
+	c.timelineManager.FlattenMaxN(
+		function,
+		float64(d.Nanoseconds())/float64(time.Millisecond),
+		constants.StringsMetricScyllaQueryDuration,
+		constants.StringsKeyspace, c.ksAdmin,
+		constants.StringsKeyset, keyset,
+		constants.StringsOperation, operation,
+	)
+
+	c.timelineManager.FlattenCountIncN(
+		function,
+		constants.StringsMetricScyllaQuery,
+		constants.StringsKeyspace, c.ksAdmin,
+		constants.StringsKeyset, keyset,
+		constants.StringsOperation, operation,
+	)
+}
+
+func (c *Catalog) statsQueryError(function, keyset string, operation constants.CRUDOperation) {
+
+	c.timelineManager.FlattenCountIncN(
+		function,
+		constants.StringsMetricScyllaQueryError,
+		constants.StringsKeyspace, c.ksAdmin,
+		constants.StringsKeyset, keyset,
+		constants.StringsOperation, operation,
+	)
+}
@@ -0,0 +1,229 @@
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/gorilla"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+const (
+	fmtInsertBlockQuery string = `INSERT INTO %v.ts_number_block (id, window, block, points) VALUES (?, ?, ?, ?)`
+	numBlockShards      int    = 64
+)
+
+type blockKey struct {
+	keyspace string
+	tsid     string
+	window   int64
+}
+
+// openBlock - a block still receiving points, it is written again on every flush while dirty
+type openBlock struct {
+	id      gocql.UUID
+	encoder *gorilla.Encoder
+	dirty   bool
+}
+
+type blockShard struct {
+	sync.Mutex
+	blocks map[blockKey][]*openBlock
+}
+
+type pendingBlock struct {
+	key     blockKey
+	block   *openBlock
+	points  []byte
+	closing bool
+}
+
+// blockWriter - buffers the points of the keyspaces with the block layout in gorilla compressed
+// blocks, one per timeseries and time window, a point older than the last one of the open blocks
+// opens a new block of the same window
+type blockWriter struct {
+	cassandra     *gocql.Session
+	shards        []*blockShard
+	window        int64
+	closeDelay    int64
+	flushInterval time.Duration
+	stop          chan struct{}
+	done          chan struct{}
+	logger        *logh.ContextualLogger
+}
+
+func newBlockWriter(cassandra *gocql.Session, conf *structs.NumberBlocksConfiguration) (*blockWriter, error) {
+
+	if conf.Window.Duration < time.Second || conf.FlushInterval.Duration <= 0 {
+		return nil, fmt.Errorf("the number blocks window needs to be at least one second and the flush interval bigger than zero")
+	}
+
+	shards := make([]*blockShard, numBlockShards)
+
+	for i := range shards {
+		shards[i] = &blockShard{
+			blocks: map[blockKey][]*openBlock{},
+		}
+	}
+
+	return &blockWriter{
+		cassandra:     cassandra,
+		shards:        shards,
+		window:        int64(conf.Window.Duration / time.Millisecond),
+		closeDelay:    int64(conf.CloseDelay.Duration / time.Millisecond),
+		flushInterval: conf.FlushInterval.Duration,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		logger:        logh.CreateContextualLogger(constants.StringsPKG, "collector/blocks"),
+	}, nil
+}
+
+func (writer *blockWriter) start() {
+
+	go func() {
+
+		ticker := time.NewTicker(writer.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				writer.flush(false)
+			case <-writer.stop:
+				writer.flush(true)
+				close(writer.done)
+				return
+			}
+		}
+	}()
+}
+
+// shutdown - writes all the open blocks and stops the flush loop
+func (writer *blockWriter) shutdown() {
+
+	close(writer.stop)
+	<-writer.done
+}
+
+func (writer *blockWriter) shard(tsid string) *blockShard {
+
+	hash := fnv.New32a()
+	hash.Write([]byte(tsid))
+
+	return writer.shards[hash.Sum32()%uint32(len(writer.shards))]
+}
+
+// add - appends the point to an open block of its window
+func (writer *blockWriter) add(keyspace, tsid string, date int64, value float64) {
+
+	key := blockKey{
+		keyspace: keyspace,
+		tsid:     tsid,
+		window:   date - date%writer.window,
+	}
+
+	shard := writer.shard(tsid)
+
+	shard.Lock()
+	defer shard.Unlock()
+
+	for _, block := range shard.blocks[key] {
+		if date >= block.encoder.LastDate() {
+			block.encoder.Append(date, value)
+			block.dirty = true
+			return
+		}
+	}
+
+	block := &openBlock{
+		id:      gocql.TimeUUID(),
+		encoder: gorilla.NewEncoder(),
+		dirty:   true,
+	}
+
+	block.encoder.Append(date, value)
+
+	shard.blocks[key] = append(shard.blocks[key], block)
+}
+
+// flush - writes the dirty blocks and forgets the ones whose window is closed
+func (writer *blockWriter) flush(all bool) {
+
+	closeLimit := time.Now().UnixNano()/int64(time.Millisecond) - writer.window - writer.closeDelay
+
+	for _, shard := range writer.shards {
+
+		pending := []pendingBlock{}
+
+		shard.Lock()
+
+		for key, blocks := range shard.blocks {
+
+			closing := all || key.window <= closeLimit
+
+			for _, block := range blocks {
+				if block.dirty {
+					pending = append(pending, pendingBlock{
+						key:     key,
+						block:   block,
+						points:  block.encoder.Bytes(),
+						closing: closing,
+					})
+					block.dirty = false
+				}
+			}
+
+			if closing {
+				delete(shard.blocks, key)
+			}
+		}
+
+		shard.Unlock()
+
+		for _, p := range pending {
+
+			if err := writer.write(p); err != nil {
+
+				if all {
+					continue
+				}
+
+				shard.Lock()
+				p.block.dirty = true
+				if p.closing {
+					shard.blocks[p.key] = append(shard.blocks[p.key], p.block)
+				}
+				shard.Unlock()
+			}
+		}
+	}
+}
+
+func (writer *blockWriter) write(p pendingBlock) error {
+
+	start := time.Now()
+
+	if err := writer.cassandra.Query(
+		fmt.Sprintf(fmtInsertBlockQuery, p.key.keyspace),
+		p.key.tsid,
+		p.key.window,
+		p.block.id,
+		p.points,
+	).Exec(); err != nil {
+		statsInsertQueryError(p.key.keyspace)
+		if logh.ErrorEnabled {
+			writer.logger.Error().Err(err).Str(constants.StringsFunc, "write").Str("tsid", p.key.tsid).Int64("window", p.key.window).Str("ksid", p.key.keyspace).Send()
+		}
+		return err
+	}
+
+	statsInsertQuery(p.key.keyspace, time.Since(start))
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/uol/mycenae/lib/structs"
@@ -17,6 +18,8 @@ import (
 	"github.com/uol/logh"
 	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/persistence"
+	"github.com/uol/mycenae/lib/rollup"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
@@ -25,8 +28,6 @@ var (
 )
 
 const (
-	cNumber              string = "number"
-	cText                string = "text"
 	cFuncHandleJSONBytes string = "HandleJSONBytes"
 )
 
@@ -36,8 +37,10 @@ func New(
 	cass *gocql.Session,
 	metaStorage *metadata.Storage,
 	set *structs.Settings,
-	keyspaceTTLMap map[int]string,
+	keyspaceTTLMap *persistence.KeyspaceTTLMap,
+	keyspaceLayouts *persistence.KeyspaceLayouts,
 	validation *validation.Service,
+	rollups *rollup.Compactor,
 ) (*Collector, error) {
 
 	timelineManager = tm
@@ -50,6 +53,26 @@ func New(
 		keyspaceTTLMap: keyspaceTTLMap,
 		logger:         logh.CreateContextualLogger(constants.StringsPKG, "collector"),
 		validation:     validation,
+		rollups:        rollups,
+		layouts:        keyspaceLayouts,
+	}
+
+	// the block writer is created even without block keyspaces, the layouts are refreshed later,
+	// it is only required to be configured when there is a block keyspace
+	blocks, err := newBlockWriter(cass, &set.NumberBlocks)
+	if err != nil {
+		for _, layout := range keyspaceLayouts.Map() {
+			if layout == constants.KeyspaceLayoutBlock {
+				return nil, err
+			}
+		}
+
+		if logh.WarnEnabled {
+			collect.logger.Warn().Str(constants.StringsFunc, "New").Err(err).Msg("the number blocks are disabled")
+		}
+	} else {
+		blocks.start()
+		collect.blocks = blocks
 	}
 
 	for i := 0; i < set.MaxConcurrentPoints; i++ {
@@ -68,9 +91,12 @@ type Collector struct {
 
 	shutdown       bool
 	jobChannel     chan workerData
-	keyspaceTTLMap map[int]string
+	keyspaceTTLMap *persistence.KeyspaceTTLMap
+	layouts        *persistence.KeyspaceLayouts
+	blocks         *blockWriter
 
 	validation *validation.Service
+	rollups    *rollup.Compactor
 	logger     *logh.ContextualLogger
 }
 
@@ -79,32 +105,34 @@ type workerData struct {
 	source         *constants.SourceType
 }
 
-func (collect *Collector) getType(number bool) string {
-	if number {
-		return cNumber
-	}
-	return cText
-}
-
 func (collect *Collector) worker(id int, jobChannel <-chan workerData) {
 
 	for j := range jobChannel {
 
 		err := collect.processPacket(j.validatedPoint)
 		if err != nil {
-			statsPointsError(j.validatedPoint.Message.Keyset, collect.getType(j.validatedPoint.Number), j.source, j.validatedPoint.Message.TTL)
+			statsPointsError(j.validatedPoint.Message.Keyset, string(j.validatedPoint.Type), j.source, j.validatedPoint.Message.TTL)
 			if logh.ErrorEnabled {
 				collect.logger.Error().Str(constants.StringsFunc, "worker").Err(err).Send()
 			}
 		} else {
-			statsPoints(j.validatedPoint.Message.Keyset, collect.getType(j.validatedPoint.Number), j.source, j.validatedPoint.Message.TTL)
+			statsPoints(j.validatedPoint.Message.Keyset, string(j.validatedPoint.Type), j.source, j.validatedPoint.Message.TTL)
 		}
 	}
 }
 
-// Stop - stops the UDP collector
+// BlocksEnabled - checks if the number blocks are configured, the keyspaces with the block layout need them
+func (collect *Collector) BlocksEnabled() bool {
+	return collect.blocks != nil
+}
+
+// Stop - stops the UDP collector and writes the open number blocks
 func (collect *Collector) Stop() {
 	collect.shutdown = true
+
+	if collect.blocks != nil {
+		collect.blocks.shutdown()
+	}
 }
 
 func (collect *Collector) processPacket(point *Point) gobol.Error {
@@ -117,11 +145,24 @@ func (collect *Collector) processPacket(point *Point) gobol.Error {
 		statsDelayedMetrics(point.Message.Keyset, pastTime)
 	}
 
+	metaType := metaTypeOf(point.Type)
+
+	// the point is saved even when the metadata can not be checked, only the new series count in the quota
+	found, metaErr := collect.CheckMetadata(point.Message.Keyset, metaType, point.ID, point.HashID)
+	if metaErr == nil && !found {
+		if gerr := collect.validation.CheckSerieQuota(point.Message.Keyset); gerr != nil {
+			return gerr
+		}
+	}
+
 	var gerr gobol.Error
 
-	if point.Number {
+	switch point.Type {
+	case constants.PointTypeNumber:
 		gerr = collect.saveValue(point)
-	} else {
+	case constants.PointTypeHistogram:
+		gerr = collect.saveHistogram(point)
+	default:
 		gerr = collect.saveText(point)
 	}
 
@@ -129,7 +170,12 @@ func (collect *Collector) processPacket(point *Point) gobol.Error {
 		return gerr
 	}
 
-	gerr = collect.saveMeta(point)
+	if metaErr != nil {
+		statsLostMeta(point.Message.Keyset)
+		return metaErr
+	}
+
+	gerr = collect.saveMeta(point, metaType, found)
 	if gerr != nil {
 		return gerr
 	}
@@ -140,12 +186,12 @@ func (collect *Collector) processPacket(point *Point) gobol.Error {
 }
 
 // HandleJSONBytes - handles a point in byte format
-func (collect *Collector) HandleJSONBytes(data []byte, sourceType *constants.SourceType, ip string, isNumber bool) (int, gobol.Error) {
+func (collect *Collector) HandleJSONBytes(data []byte, sourceType *constants.SourceType, ip string, pointType constants.PointType) (int, gobol.Error) {
 
 	points := structs.TSDBpoints{}
 	gerrs := []gobol.Error{}
 
-	keyset := collect.ParsePoints(cFuncHandleJSONBytes, isNumber, data, &points, &gerrs)
+	keyset := collect.ParsePoints(cFuncHandleJSONBytes, pointType, data, &points, &gerrs)
 	numErrs := len(gerrs)
 	if numErrs > 0 {
 		for _, gerr := range gerrs {
@@ -165,26 +211,37 @@ func (collect *Collector) HandleJSONBytes(data []byte, sourceType *constants.Sou
 
 	for _, p := range points {
 
-		vp, err := collect.MakePacket(p, isNumber)
+		if p.TTLCoerced {
+			collect.validation.StatsValidationError(cFuncHandleJSONBytes, p.Keyset, ip, sourceType, validation.ErrTTLCoerced)
+		}
+
+		vp, err := collect.MakePacket(p, pointType)
 		if err != nil {
 			return 0, err
 		}
 
-		collect.HandlePacket(vp, sourceType)
+		err = collect.HandlePacket(vp, sourceType)
+		if err != nil {
+			collect.validation.StatsValidationError(cFuncHandleJSONBytes, p.Keyset, ip, sourceType, err)
+			return 0, err
+		}
 	}
 
 	return len(points), nil
 }
 
-const cTextTSIDFormat string = "T%v"
+const (
+	cTextTSIDFormat      string = "T%v"
+	cHistogramTSIDFormat string = "H%v"
+)
 
 // MakePacket - validates a point and fills the packet
-func (collect *Collector) MakePacket(rcvMsg *structs.TSDBpoint, number bool) (*Point, gobol.Error) {
+func (collect *Collector) MakePacket(rcvMsg *structs.TSDBpoint, pointType constants.PointType) (*Point, gobol.Error) {
 
 	packet := &Point{}
 
 	var err error
-	packet.Number = number
+	packet.Type = pointType
 	packet.Message = rcvMsg
 	packet.ID, packet.HashID, err = collect.GenerateID(rcvMsg)
 
@@ -192,20 +249,73 @@ func (collect *Collector) MakePacket(rcvMsg *structs.TSDBpoint, number bool) (*P
 		return nil, errInternalServerError("makePacket", "error creating the tsid hash", err)
 	}
 
-	if !number {
+	switch pointType {
+	case constants.PointTypeText:
 		packet.ID = fmt.Sprintf(cTextTSIDFormat, packet.ID)
+	case constants.PointTypeHistogram:
+		packet.ID = fmt.Sprintf(cHistogramTSIDFormat, packet.ID)
 	}
 
 	return packet, nil
 }
 
-// HandlePacket - handles a point in struct format
-func (collect *Collector) HandlePacket(vp *Point, source *constants.SourceType) {
+// HandlePacket - handles a point in struct format, the points exceeding the keyset points per second are rejected
+func (collect *Collector) HandlePacket(vp *Point, source *constants.SourceType) gobol.Error {
+
+	if gerr := collect.validation.CheckPointQuota(vp.Message.Keyset); gerr != nil {
+		return gerr
+	}
 
 	collect.jobChannel <- workerData{
 		validatedPoint: vp,
 		source:         source,
 	}
+
+	if target, ok := collect.validation.MirrorKeyset(vp.Message.Keyset); ok {
+		collect.mirrorPacket(vp, target, source)
+	}
+
+	return nil
+}
+
+// mirrorPacket - writes a copy of the point in the keyset replacing the renamed one, the TTL
+// of the point is requested again following the policy of the keyset
+func (collect *Collector) mirrorPacket(vp *Point, keyset string, source *constants.SourceType) {
+
+	msg := *vp.Message
+	msg.Keyset = keyset
+	msg.Tags = make([]structs.TSDBTag, 0, len(vp.Message.Tags))
+
+	for _, tag := range vp.Message.Tags {
+		switch tag.Name {
+		case constants.StringsTTL:
+			continue
+		case constants.StringsKSID:
+			tag.Value = keyset
+		}
+		msg.Tags = append(msg.Tags, tag)
+	}
+
+	_, gerr := collect.validation.ApplyTTLPolicy(&msg, strconv.Itoa(vp.Message.TTL))
+	if gerr == nil {
+		msg.Timestamp, gerr = collect.validation.ValidateTimestamp(msg.Timestamp, keyset)
+	}
+
+	if gerr == nil {
+		var mirror *Point
+		mirror, gerr = collect.MakePacket(&msg, vp.Type)
+		if gerr == nil {
+			collect.jobChannel <- workerData{
+				validatedPoint: mirror,
+				source:         source,
+			}
+			return
+		}
+	}
+
+	if logh.ErrorEnabled {
+		collect.logger.Error().Str(constants.StringsFunc, "mirrorPacket").Str(constants.StringsKeyset, keyset).Err(gerr).Msg("error writing the point in the renamed keyset")
+	}
 }
 
 // GenerateID - generates the unique ID from a point
@@ -7,56 +7,67 @@ import (
 )
 
 const (
-	cMetaTypeNumber string = "meta"
-	cMetaTypeText   string = "metatext"
+	cMetaTypeNumber    string = "meta"
+	cMetaTypeText      string = "metatext"
+	cMetaTypeHistogram string = "metahistogram"
 )
 
-func (collect *Collector) saveMeta(packet *Point) gobol.Error {
+// metaTypeOf - returns the metadata type of the point type
+func metaTypeOf(pointType constants.PointType) string {
 
-	found := false
-
-	var gerr gobol.Error
-
-	if packet.Number {
-		found, gerr = collect.CheckMetadata(packet.Message.Keyset, cMetaTypeNumber, packet.ID, packet.HashID)
-	} else {
-		found, gerr = collect.CheckMetadata(packet.Message.Keyset, cMetaTypeText, packet.ID, packet.HashID)
+	switch pointType {
+	case constants.PointTypeNumber:
+		return cMetaTypeNumber
+	case constants.PointTypeHistogram:
+		return cMetaTypeHistogram
+	default:
+		return cMetaTypeText
 	}
+}
 
-	if gerr != nil {
-		statsLostMeta(packet.Message.Keyset)
-		return gerr
+// saveMeta - indexes the metadata of the new series and touches the existing ones
+func (collect *Collector) saveMeta(packet *Point, metaType string, found bool) gobol.Error {
+
+	var tagKeys, tagValues []string
+	for _, tag := range packet.Message.Tags {
+		if tag.Name != constants.StringsKSID {
+			tagKeys = append(tagKeys, tag.Name)
+			tagValues = append(tagValues, tag.Value)
+		}
 	}
 
-	var metaType string
-	if packet.Number {
-		metaType = cMetaTypeNumber
-	} else {
-		metaType = cMetaTypeText
+	metadata := &metadata.Metadata{
+		ID:       packet.ID,
+		Metric:   packet.Message.Metric,
+		MetaType: metaType,
+		TagKey:   tagKeys,
+		TagValue: tagValues,
 	}
 
 	if !found {
 		statsCountNewTimeseries(packet.Message.Keyset, metaType, packet.Message.TTL)
 
-		var tagKeys, tagValues []string
-		for _, tag := range packet.Message.Tags {
-			if tag.Name != constants.StringsKSID {
-				tagKeys = append(tagKeys, tag.Name)
-				tagValues = append(tagValues, tag.Value)
-			}
+		gerr := collect.IndexMetadata(packet.Message.Keyset, metadata)
+		if gerr != nil {
+			statsLostMeta(packet.Message.Keyset)
+			return gerr
 		}
+	} else {
+		statsCountOldTimeseries(packet.Message.Keyset, metaType, packet.Message.TTL)
 
-		metadata := &metadata.Metadata{
-			ID:       packet.ID,
-			Metric:   packet.Message.Metric,
-			MetaType: metaType,
-			TagKey:   tagKeys,
-			TagValue: tagValues,
+		touched, gerr := collect.TouchMetadata(packet.Message.Keyset, metadata)
+		if gerr != nil {
+			return gerr
 		}
 
-		collect.AddMetadata(packet.Message.Keyset, metadata)
-	} else {
-		statsCountOldTimeseries(packet.Message.Keyset, metaType, packet.Message.TTL)
+		if !touched {
+			return nil
+		}
+	}
+
+	// the series row expires with the points, it is written again every time the serie is touched
+	if ksid, ok := collect.keyspaceTTLMap.Get(packet.Message.TTL); ok {
+		collect.InsertSeries(ksid, packet.Message.Keyset, metadata)
 	}
 
 	return nil
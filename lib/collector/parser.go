@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/buger/jsonparser"
 	"github.com/uol/gobol"
 	"github.com/uol/logh"
 	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/sketch"
 	"github.com/uol/mycenae/lib/structs"
 	"github.com/uol/mycenae/lib/validation"
 )
@@ -21,7 +23,7 @@ const (
 )
 
 // ParsePoints - parses an array of points
-func (collect *Collector) ParsePoints(function string, isNumber bool, data []byte, outPoints *structs.TSDBpoints, outErrs *[]gobol.Error) string {
+func (collect *Collector) ParsePoints(function string, pointType constants.PointType, data []byte, outPoints *structs.TSDBpoints, outErrs *[]gobol.Error) string {
 
 	defer func() {
 		if err := recover(); err != nil {
@@ -40,12 +42,12 @@ func (collect *Collector) ParsePoints(function string, isNumber bool, data []byt
 	var keyset string
 
 	if dtype == jsonparser.Array {
-		keyset = collect.ParsePointArray(function, isNumber, data, outPoints, outErrs)
+		keyset = collect.ParsePointArray(function, pointType, data, outPoints, outErrs)
 	} else {
 		var gerr gobol.Error
 		var point *structs.TSDBpoint
 
-		point, keyset, gerr = collect.ParsePoint(function, isNumber, data)
+		point, keyset, gerr = collect.ParsePoint(function, pointType, data)
 		if gerr != nil {
 			(*outErrs) = append((*outErrs), gerr)
 		}
@@ -57,7 +59,7 @@ func (collect *Collector) ParsePoints(function string, isNumber bool, data []byt
 }
 
 // ParsePointArray - parses an array of points
-func (collect *Collector) ParsePointArray(function string, isNumber bool, data []byte, outPoints *structs.TSDBpoints, outErrs *[]gobol.Error) (keyset string) {
+func (collect *Collector) ParsePointArray(function string, pointType constants.PointType, data []byte, outPoints *structs.TSDBpoints, outErrs *[]gobol.Error) (keyset string) {
 
 	_, err := jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, inErr error) {
 
@@ -68,7 +70,7 @@ func (collect *Collector) ParsePointArray(function string, isNumber bool, data [
 
 		var point *structs.TSDBpoint
 		var gerr gobol.Error
-		point, keyset, gerr = collect.ParsePoint(function, isNumber, value)
+		point, keyset, gerr = collect.ParsePoint(function, pointType, value)
 		if gerr != nil {
 			(*outErrs) = append((*outErrs), gerr)
 			if gerr == validation.ErrInvalidTTLValue || gerr == validation.ErrInexistentKeyset || gerr == validation.ErrInvalidKeysetFormat {
@@ -89,14 +91,14 @@ func (collect *Collector) ParsePointArray(function string, isNumber bool, data [
 }
 
 // ParsePoint - parses the json bytes to the object fields (seconds return is the keyset)
-func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte) (*structs.TSDBpoint, string, gobol.Error) {
+func (collect *Collector) ParsePoint(function string, pointType constants.PointType, data []byte) (*structs.TSDBpoint, string, gobol.Error) {
 
 	var err error
 	var gerr gobol.Error
 
 	p := structs.TSDBpoint{}
 	p.Tags = []structs.TSDBTag{}
-	ttlFound := false
+	ttlValue := constants.StringsEmpty
 	ksidFound := false
 
 	var tagsError gobol.Error
@@ -119,16 +121,11 @@ func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte
 
 		switch tag.Name {
 		case constants.StringsTTL:
-			ttl, ttlStr, gerr := collect.validation.ParseTTL(tag.Value)
-			if gerr != nil {
-				tagsError = gerr
-				return nil
-			}
-			p.TTL = ttl
-			tag.Value = ttlStr
-			ttlFound = true
+			// the ttl tag is added when the keyset policy is applied
+			ttlValue = tag.Value
+			return nil
 		case constants.StringsKSID:
-			gerr = collect.validation.ValidateKeyset(tag.Value)
+			tag.Value, gerr = collect.validation.ResolveKeyset(tag.Value)
 			if gerr != nil {
 				tagsError = gerr
 				return nil
@@ -163,16 +160,15 @@ func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte
 		return nil, p.Keyset, tagsError
 	}
 
-	if !ttlFound {
-		defaultTTLTag, defaultTTL := collect.validation.GetDefaultTTLTag()
-		p.Tags = append(p.Tags, *defaultTTLTag)
-		p.TTL = defaultTTL
-	}
-
 	if !ksidFound {
 		return nil, p.Keyset, validation.ErrNoKeysetTag
 	}
 
+	p.TTLCoerced, gerr = collect.validation.ApplyTTLPolicy(&p, ttlValue)
+	if gerr != nil {
+		return nil, p.Keyset, gerr
+	}
+
 	gerr = collect.validation.ValidateTags(&p)
 	if gerr != nil {
 		return nil, p.Keyset, gerr
@@ -191,12 +187,13 @@ func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte
 		return nil, p.Keyset, validation.ErrParsingTimestamp
 	}
 
-	p.Timestamp, gerr = collect.validation.ValidateTimestamp(p.Timestamp)
+	p.Timestamp, gerr = collect.validation.ValidateTimestamp(p.Timestamp, p.Keyset)
 	if gerr != nil {
 		return nil, p.Keyset, gerr
 	}
 
-	if isNumber {
+	switch pointType {
+	case constants.PointTypeNumber:
 		dataIn, tdata, _, err := jsonparser.Get(data, constants.StringsValue)
 		if err != nil && err != jsonparser.KeyPathNotFoundError {
 			return nil, p.Keyset, validation.ErrParsingValue
@@ -216,7 +213,11 @@ func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte
 		default:
 			return nil, p.Keyset, validation.ErrParsingValue
 		}
-	} else {
+	case constants.PointTypeHistogram:
+		if p.Histogram, gerr = parseHistogram(data); gerr != nil {
+			return nil, p.Keyset, gerr
+		}
+	default:
 		if p.Text, err = jsonparser.GetString(data, constants.StringsText); err != nil && err != jsonparser.KeyPathNotFoundError {
 			return nil, p.Keyset, validation.ErrParsingText
 		}
@@ -224,10 +225,52 @@ func (collect *Collector) ParsePoint(function string, isNumber bool, data []byte
 		p.Text = strings.TrimSpace(p.Text)
 	}
 
-	gerr = collect.validation.ValidateType(&p, isNumber)
+	gerr = collect.validation.ValidateType(&p, pointType)
 	if gerr != nil {
 		return nil, p.Keyset, gerr
 	}
 
 	return &p, p.Keyset, nil
 }
+
+// parseHistogram - parses the histogram field, a map of values to their counts
+func parseHistogram(data []byte) (*sketch.Sketch, gobol.Error) {
+
+	dataIn, tdata, _, err := jsonparser.Get(data, constants.StringsHistogram)
+	if err == jsonparser.KeyPathNotFoundError {
+		return nil, nil
+	}
+
+	if err != nil || tdata != jsonparser.Object {
+		return nil, validation.ErrParsingHistogram
+	}
+
+	histogram := sketch.New()
+
+	err = jsonparser.ObjectEach(dataIn, func(key, value []byte, dataType jsonparser.ValueType, offset int) error {
+
+		v, err := strconv.ParseFloat(string(key), 64)
+		if err != nil {
+			return err
+		}
+
+		n, err := jsonparser.ParseInt(value)
+		if err != nil {
+			return err
+		}
+
+		if n < 1 {
+			return validation.ErrParsingHistogram
+		}
+
+		histogram.Add(v, uint64(n))
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, validation.ErrParsingHistogram
+	}
+
+	return histogram, nil
+}
@@ -9,13 +9,16 @@ import (
 	"github.com/uol/gobol"
 	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/sketch"
 )
 
 const (
-	fmtInsertNumberQuery string = `INSERT INTO %v.ts_number_stamp (id, date, value) VALUES (?, ?, ?)`
-	fmtInsertTextQuery   string = `INSERT INTO %v.ts_text_stamp (id, date , value) VALUES (?, ?, ?)`
-	tableNumberStamp     string = "ts_number_stamp"
-	tableTextStamp       string = "ts_text_stamp"
+	fmtInsertNumberQuery    string = `INSERT INTO %v.ts_number_stamp (id, date, value) VALUES (?, ?, ?)`
+	fmtInsertTextQuery      string = `INSERT INTO %v.ts_text_stamp (id, date , value) VALUES (?, ?, ?)`
+	fmtInsertHistogramQuery string = `INSERT INTO %v.ts_histogram_stamp (id, date, value) VALUES (?, ?, ?)`
+	fmtInsertSeriesQuery    string = `INSERT INTO %v.ts_series (id, type, keyset, metric, tag_keys, tag_values) VALUES (?, ?, ?, ?, ?, ?)`
+	tableNumberStamp        string = "ts_number_stamp"
+	tableTextStamp          string = "ts_text_stamp"
 )
 
 func (collect *Collector) InsertPoint(ksid, tsid string, timestamp int64, value float64) gobol.Error {
@@ -67,6 +70,30 @@ func (collect *Collector) InsertText(ksid, tsid string, timestamp int64, text st
 	return nil
 }
 
+func (collect *Collector) InsertHistogram(ksid, tsid string, timestamp int64, histogram *sketch.Sketch) gobol.Error {
+
+	start := time.Now()
+
+	var err error
+	if err = collect.cassandra.Query(
+		fmt.Sprintf(fmtInsertHistogramQuery, ksid),
+		tsid,
+		timestamp,
+		histogram.Bytes(),
+	).Exec(); err != nil {
+		statsInsertQueryError(ksid)
+		if logh.ErrorEnabled {
+			collect.logger.Error().Err(err).Str(constants.StringsFunc, "InsertHistogram").Str("tsid", tsid).Int64("timestamp", timestamp).Uint64("count", histogram.Count()).Str("ksid", ksid).Send()
+		}
+		statsInsertRollback(ksid)
+		return errPersist("InsertHistogram", err)
+	}
+
+	statsInsertQuery(ksid, time.Since(start))
+
+	return nil
+}
+
 const funcCheckMetadata string = "CheckMetadata"
 
 // CheckMetadata - checks for the metadata existence
@@ -92,3 +119,57 @@ func (collect *Collector) AddMetadata(collection string, m *metadata.Metadata) g
 
 	return nil
 }
+
+const funcIndexMetadata string = "IndexMetadata"
+
+// IndexMetadata - queues a new document metadata to be indexed
+func (collect *Collector) IndexMetadata(collection string, m *metadata.Metadata) gobol.Error {
+
+	err := collect.metaStorage.IndexDocument(collection, m)
+	if err != nil {
+		return errPersist(funcIndexMetadata, err)
+	}
+
+	return nil
+}
+
+const funcTouchMetadata string = "TouchMetadata"
+
+// TouchMetadata - updates the last seen timestamp of the document metadata, true when it was updated
+func (collect *Collector) TouchMetadata(collection string, m *metadata.Metadata) (bool, gobol.Error) {
+
+	touched, err := collect.metaStorage.TouchDocument(collection, m)
+	if err != nil {
+		return false, errPersist(funcTouchMetadata, err)
+	}
+
+	return touched, nil
+}
+
+const funcInsertSeries string = "InsertSeries"
+
+// InsertSeries - stores the metric and the tags of the serie in the keyspace, the reindex
+// job rebuilds the lost metadata documents from them, a failure does not stop the point
+func (collect *Collector) InsertSeries(ksid, collection string, m *metadata.Metadata) {
+
+	start := time.Now()
+
+	err := collect.cassandra.Query(
+		fmt.Sprintf(fmtInsertSeriesQuery, ksid),
+		m.ID,
+		m.MetaType,
+		collection,
+		m.Metric,
+		m.TagKey,
+		m.TagValue,
+	).Exec()
+	if err != nil {
+		statsInsertQueryError(ksid)
+		if logh.ErrorEnabled {
+			collect.logger.Error().Err(err).Str(constants.StringsFunc, funcInsertSeries).Str("tsid", m.ID).Str("ksid", ksid).Send()
+		}
+		return
+	}
+
+	statsInsertQuery(ksid, time.Since(start))
+}
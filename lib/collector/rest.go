@@ -14,7 +14,7 @@ import (
 	"github.com/uol/gobol/rip"
 )
 
-func (collect *Collector) handle(w http.ResponseWriter, r *http.Request, ip string, number bool) {
+func (collect *Collector) handle(w http.ResponseWriter, r *http.Request, ip string, pointType constants.PointType) {
 
 	var bytes []byte
 	var err error
@@ -40,7 +40,7 @@ func (collect *Collector) handle(w http.ResponseWriter, r *http.Request, ip stri
 		return
 	}
 
-	_, gerr := collect.HandleJSONBytes(bytes, constants.SourceTypeHTTP, ip, number)
+	_, gerr := collect.HandleJSONBytes(bytes, constants.SourceTypeHTTP, ip, pointType)
 	if gerr != nil {
 		rip.Fail(w, gerr)
 		return
@@ -61,14 +61,21 @@ func (collect *Collector) handle(w http.ResponseWriter, r *http.Request, ip stri
 func (collect *Collector) HandleNumber(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	ip := collect.sendIPStats(r)
-	collect.handle(w, r, ip, true)
+	collect.handle(w, r, ip, constants.PointTypeNumber)
 }
 
 // HandleText - handles the point in text format
 func (collect *Collector) HandleText(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	ip := collect.sendIPStats(r)
-	collect.handle(w, r, ip, false)
+	collect.handle(w, r, ip, constants.PointTypeText)
+}
+
+// HandleHistogram - handles the point in histogram format
+func (collect *Collector) HandleHistogram(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	ip := collect.sendIPStats(r)
+	collect.handle(w, r, ip, constants.PointTypeHistogram)
 }
 
 const (
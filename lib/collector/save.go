@@ -1,21 +1,60 @@
 package collector
 
 import (
+	"fmt"
+
 	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
 )
 
+// keyspaceOf - returns the keyspace of the point TTL, the keyspace may have changed its TTL after the point was validated
+func (collector *Collector) keyspaceOf(function string, packet *Point) (string, gobol.Error) {
+	ksid, ok := collector.keyspaceTTLMap.Get(packet.Message.TTL)
+	if !ok {
+		return constants.StringsEmpty, errInternalServerError(function, "the point TTL has no keyspace", fmt.Errorf("no keyspace with the TTL %d", packet.Message.TTL))
+	}
+
+	return ksid, nil
+}
+
 func (collector *Collector) saveValue(packet *Point) gobol.Error {
-	ksid := collector.keyspaceTTLMap[packet.Message.TTL]
-	return collector.InsertPoint(
+	ksid, gerr := collector.keyspaceOf("saveValue", packet)
+	if gerr != nil {
+		return gerr
+	}
+
+	if collector.layouts.Get(ksid) == constants.KeyspaceLayoutBlock {
+		if collector.blocks == nil {
+			return errInternalServerError("saveValue", "the number blocks are not configured", fmt.Errorf("no block writer for the keyspace %s", ksid))
+		}
+		collector.blocks.add(ksid, packet.ID, packet.Message.Timestamp, *(packet.Message.Value))
+		return nil
+	}
+
+	gerr = collector.InsertPoint(
 		ksid,
 		packet.ID,
 		packet.Message.Timestamp,
 		*(packet.Message.Value),
 	)
+	if gerr != nil {
+		return gerr
+	}
+
+	if collector.rollups != nil {
+		collector.rollups.Mark(ksid, packet.ID, packet.Message.Timestamp)
+	}
+
+	return nil
 }
 
 func (collector *Collector) saveText(packet *Point) gobol.Error {
-	ksid := collector.keyspaceTTLMap[packet.Message.TTL]
+	ksid, gerr := collector.keyspaceOf("saveText", packet)
+	if gerr != nil {
+		return gerr
+	}
+
 	return collector.InsertText(
 		ksid,
 		packet.ID,
@@ -23,3 +62,17 @@ func (collector *Collector) saveText(packet *Point) gobol.Error {
 		packet.Message.Text,
 	)
 }
+
+func (collector *Collector) saveHistogram(packet *Point) gobol.Error {
+	ksid, gerr := collector.keyspaceOf("saveHistogram", packet)
+	if gerr != nil {
+		return gerr
+	}
+
+	return collector.InsertHistogram(
+		ksid,
+		packet.ID,
+		packet.Message.Timestamp,
+		packet.Message.Histogram,
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/structs"
 
 	"github.com/uol/gobol"
@@ -39,7 +40,7 @@ type Point struct {
 	Message *structs.TSDBpoint
 	ID      string
 	HashID  []byte
-	Number  bool
+	Type    constants.PointType
 }
 
 type StructV2Error struct {
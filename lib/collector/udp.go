@@ -11,7 +11,7 @@ func (collector *Collector) HandleUDPpacket(buf []byte, addr string) {
 
 	statsNetworkIP(addr, constants.StringsUDP)
 
-	_, gerr := collector.HandleJSONBytes(buf, constants.SourceTypeUDP, addr, true)
+	_, gerr := collector.HandleJSONBytes(buf, constants.SourceTypeUDP, addr, constants.PointTypeNumber)
 	if gerr != nil {
 		collector.fail(gerr, addr)
 	}
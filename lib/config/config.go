@@ -12,6 +12,9 @@ func GetAggregators() []string {
 		"min",
 		"max",
 		"sum",
+		"zimsum",
+		"mimmin",
+		"mimmax",
 	}
 }
 
@@ -61,5 +64,9 @@ func GetFillers() []string {
 		"nan",
 		"null",
 		"zero",
+		"previous",
+		"last",
+		"linear",
+		"value",
 	}
 }
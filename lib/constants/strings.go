@@ -31,6 +31,9 @@ const (
 	// StringsText - text word
 	StringsText string = "text"
 
+	// StringsHistogram - histogram word
+	StringsHistogram string = "histogram"
+
 	// StringsKeyset - keyset word
 	StringsKeyset string = "keyset"
 
@@ -131,3 +134,39 @@ const (
 	// ClusteringOrderDESC - clustering order
 	ClusteringOrderDESC ClusteringOrder = "DESC"
 )
+
+// KeyspaceLayout - defines how the number points of a keyspace are stored
+type KeyspaceLayout string
+
+const (
+	// KeyspaceLayoutRow - one row per point (ts_number_stamp)
+	KeyspaceLayoutRow KeyspaceLayout = "row"
+
+	// KeyspaceLayoutBlock - gorilla compressed blocks per time window (ts_number_block)
+	KeyspaceLayoutBlock KeyspaceLayout = "block"
+)
+
+// TimestampPrecision - defines the timestamp precision of the points of a keyset
+type TimestampPrecision string
+
+const (
+	// TimestampPrecisionSecond - timestamps are truncated to seconds (default)
+	TimestampPrecisionSecond TimestampPrecision = "s"
+
+	// TimestampPrecisionMillisecond - timestamps keep their milliseconds
+	TimestampPrecisionMillisecond TimestampPrecision = "ms"
+)
+
+// PointType - the type of the value of a point
+type PointType string
+
+const (
+	// PointTypeNumber - a float64 value
+	PointTypeNumber PointType = "number"
+
+	// PointTypeText - a text value
+	PointTypeText PointType = "text"
+
+	// PointTypeHistogram - a distribution of values (sketch)
+	PointTypeHistogram PointType = "histogram"
+)
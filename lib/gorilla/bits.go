@@ -0,0 +1,79 @@
+package gorilla
+
+import "errors"
+
+// ErrShortBlock - the block ended before all its points were read
+var ErrShortBlock = errors.New("gorilla block is shorter than its header says")
+
+type bitWriter struct {
+	bytes []byte
+	free  uint8
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+
+	if w.free == 0 {
+		w.bytes = append(w.bytes, 0)
+		w.free = 8
+	}
+
+	w.free--
+
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 1 << w.free
+	}
+}
+
+// writeBits - writes the n least significant bits of value, the most significant first
+func (w *bitWriter) writeBits(value uint64, n uint8) {
+
+	for n > 0 {
+		n--
+		w.writeBit((value>>n)&1 == 1)
+	}
+}
+
+type bitReader struct {
+	bytes []byte
+	index int
+	used  uint8
+}
+
+func (r *bitReader) readBit() (bool, error) {
+
+	if r.index >= len(r.bytes) {
+		return false, ErrShortBlock
+	}
+
+	bit := (r.bytes[r.index]>>(7-r.used))&1 == 1
+
+	r.used++
+
+	if r.used == 8 {
+		r.used = 0
+		r.index++
+	}
+
+	return bit, nil
+}
+
+func (r *bitReader) readBits(n uint8) (uint64, error) {
+
+	var value uint64
+
+	for ; n > 0; n-- {
+
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		value <<= 1
+
+		if bit {
+			value |= 1
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,185 @@
+package gorilla
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Decoder - iterates over the points of a block
+type Decoder struct {
+	reader       bitReader
+	count        uint32
+	read         uint32
+	date         int64
+	delta        int64
+	value        uint64
+	lastLeading  uint8
+	lastTrailing uint8
+	err          error
+}
+
+// NewDecoder - creates a decoder for a block built by an Encoder
+func NewDecoder(block []byte) *Decoder {
+
+	if len(block) < headerSize {
+		return &Decoder{err: ErrShortBlock}
+	}
+
+	return &Decoder{
+		reader: bitReader{bytes: block[headerSize:]},
+		count:  binary.BigEndian.Uint32(block),
+	}
+}
+
+// Next - moves to the next point, returns false at the end of the block or on errors
+func (d *Decoder) Next() bool {
+
+	if d.err != nil || d.read == d.count {
+		return false
+	}
+
+	if d.read == 0 {
+		d.err = d.readFirst()
+	} else {
+		d.err = d.readNext()
+	}
+
+	if d.err != nil {
+		return false
+	}
+
+	d.read++
+
+	return true
+}
+
+// At - returns the current point
+func (d *Decoder) At() (int64, float64) {
+
+	return d.date, math.Float64frombits(d.value)
+}
+
+// Err - returns the error that stopped the iteration, if any
+func (d *Decoder) Err() error {
+
+	return d.err
+}
+
+func (d *Decoder) readFirst() error {
+
+	date, err := d.reader.readBits(64)
+	if err != nil {
+		return err
+	}
+
+	value, err := d.reader.readBits(64)
+	if err != nil {
+		return err
+	}
+
+	d.date = int64(date)
+	d.value = value
+
+	return nil
+}
+
+func (d *Decoder) readNext() error {
+
+	dod, err := d.readDod()
+	if err != nil {
+		return err
+	}
+
+	d.delta += dod
+	d.date += d.delta
+
+	return d.readXor()
+}
+
+func (d *Decoder) readDod() (int64, error) {
+
+	// counts the control bits set before the first unset one, up to 4
+	var ones int
+
+	for ones < int(dodFallbackBits) {
+
+		bit, err := d.reader.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		if !bit {
+			break
+		}
+
+		ones++
+	}
+
+	if ones == 0 {
+		return 0, nil
+	}
+
+	var valueBits uint8 = 64
+
+	if ones <= len(dodRanges) {
+		valueBits = dodRanges[ones-1].valueBits
+	}
+
+	raw, err := d.reader.readBits(valueBits)
+	if err != nil {
+		return 0, err
+	}
+
+	// sign extension of the two's complement value
+	shift := 64 - valueBits
+
+	return int64(raw<<shift) >> shift, nil
+}
+
+func (d *Decoder) readXor() error {
+
+	changed, err := d.reader.readBit()
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	newWindow, err := d.reader.readBit()
+	if err != nil {
+		return err
+	}
+
+	if newWindow {
+
+		leading, err := d.reader.readBits(leadingBits)
+		if err != nil {
+			return err
+		}
+
+		meaningful, err := d.reader.readBits(meaningfulBits)
+		if err != nil {
+			return err
+		}
+
+		if meaningful == 0 {
+			meaningful = 64
+		}
+
+		d.lastLeading = uint8(leading)
+		d.lastTrailing = uint8(64 - leading - meaningful)
+	}
+
+	meaningful := 64 - d.lastLeading - d.lastTrailing
+
+	xor, err := d.reader.readBits(meaningful)
+	if err != nil {
+		return err
+	}
+
+	d.value ^= xor << d.lastTrailing
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package gorilla
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// Implements the Gorilla compression (Pelkonen et al., 2015) for millisecond timestamps:
+// the timestamps are stored as delta of deltas and the values as the XOR with the previous one
+
+// ranges of the delta of deltas, each one with its control bits
+var dodRanges = []struct {
+	control     uint64
+	controlBits uint8
+	valueBits   uint8
+}{
+	{control: 0x2, controlBits: 2, valueBits: 7},
+	{control: 0x6, controlBits: 3, valueBits: 9},
+	{control: 0xe, controlBits: 4, valueBits: 12},
+}
+
+const (
+	headerSize       int    = 4
+	dodFallback      uint64 = 0xf
+	dodFallbackBits  uint8  = 4
+	leadingBits      uint8  = 5
+	meaningfulBits   uint8  = 6
+	maxLeadingZeroes int    = 31
+)
+
+// Encoder - appends points to a block, the points must come in date order
+type Encoder struct {
+	writer       bitWriter
+	count        uint32
+	lastDate     int64
+	lastDelta    int64
+	lastValue    uint64
+	lastLeading  int
+	lastTrailing int
+}
+
+// NewEncoder - creates an empty block
+func NewEncoder() *Encoder {
+
+	return &Encoder{
+		lastLeading: -1,
+	}
+}
+
+// Count - returns the number of points in the block
+func (e *Encoder) Count() int {
+
+	return int(e.count)
+}
+
+// LastDate - returns the date of the last point appended
+func (e *Encoder) LastDate() int64 {
+
+	return e.lastDate
+}
+
+// Append - adds a point to the block, its date can not be before the last one
+func (e *Encoder) Append(date int64, value float64) {
+
+	valueBits := math.Float64bits(value)
+
+	if e.count == 0 {
+		e.writer.writeBits(uint64(date), 64)
+		e.writer.writeBits(valueBits, 64)
+	} else {
+		delta := date - e.lastDate
+		e.writeDod(delta - e.lastDelta)
+		e.writeXor(valueBits ^ e.lastValue)
+		e.lastDelta = delta
+	}
+
+	e.lastDate = date
+	e.lastValue = valueBits
+	e.count++
+}
+
+func (e *Encoder) writeDod(dod int64) {
+
+	if dod == 0 {
+		e.writer.writeBit(false)
+		return
+	}
+
+	for _, r := range dodRanges {
+
+		limit := int64(1) << (r.valueBits - 1)
+
+		if dod >= -limit && dod < limit {
+			e.writer.writeBits(r.control, r.controlBits)
+			e.writer.writeBits(uint64(dod), r.valueBits)
+			return
+		}
+	}
+
+	e.writer.writeBits(dodFallback, dodFallbackBits)
+	e.writer.writeBits(uint64(dod), 64)
+}
+
+func (e *Encoder) writeXor(xor uint64) {
+
+	if xor == 0 {
+		e.writer.writeBit(false)
+		return
+	}
+
+	e.writer.writeBit(true)
+
+	leading := bits.LeadingZeros64(xor)
+	trailing := bits.TrailingZeros64(xor)
+
+	if leading > maxLeadingZeroes {
+		leading = maxLeadingZeroes
+	}
+
+	if e.lastLeading >= 0 && leading >= e.lastLeading && trailing >= e.lastTrailing {
+		e.writer.writeBit(false)
+		e.writer.writeBits(xor>>uint(e.lastTrailing), uint8(64-e.lastLeading-e.lastTrailing))
+		return
+	}
+
+	meaningful := 64 - leading - trailing
+
+	e.writer.writeBit(true)
+	e.writer.writeBits(uint64(leading), leadingBits)
+	// 64 meaningful bits do not fit in 6 bits, they are written as 0
+	e.writer.writeBits(uint64(meaningful&0x3f), meaningfulBits)
+	e.writer.writeBits(xor>>uint(trailing), uint8(meaningful))
+
+	e.lastLeading = leading
+	e.lastTrailing = trailing
+}
+
+// Bytes - returns the block, the number of points followed by the compressed stream
+func (e *Encoder) Bytes() []byte {
+
+	block := make([]byte, headerSize+len(e.writer.bytes))
+
+	binary.BigEndian.PutUint32(block, e.count)
+
+	copy(block[headerSize:], e.writer.bytes)
+
+	return block
+}
@@ -0,0 +1,133 @@
+package gorilla
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+type point struct {
+	date  int64
+	value float64
+}
+
+func roundTrip(t *testing.T, points []point) {
+
+	encoder := NewEncoder()
+
+	for _, p := range points {
+		encoder.Append(p.date, p.value)
+	}
+
+	if encoder.Count() != len(points) {
+		t.Fatalf("expected %d points in the encoder, found %d", len(points), encoder.Count())
+	}
+
+	decoder := NewDecoder(encoder.Bytes())
+
+	i := 0
+
+	for decoder.Next() {
+
+		date, value := decoder.At()
+
+		if date != points[i].date || math.Float64bits(value) != math.Float64bits(points[i].value) {
+			t.Fatalf("point %d: expected %d %v, found %d %v", i, points[i].date, points[i].value, date, value)
+		}
+
+		i++
+	}
+
+	if decoder.Err() != nil {
+		t.Fatal(decoder.Err())
+	}
+
+	if i != len(points) {
+		t.Fatalf("expected %d decoded points, found %d", len(points), i)
+	}
+}
+
+func TestRegularSerie(t *testing.T) {
+
+	points := []point{}
+
+	for i := 0; i < 1000; i++ {
+		points = append(points, point{date: 1448452800000 + int64(i)*10000, value: float64(i % 17)})
+	}
+
+	roundTrip(t, points)
+}
+
+func TestIrregularSerie(t *testing.T) {
+
+	random := rand.New(rand.NewSource(42))
+
+	points := []point{}
+	date := int64(1448452800000)
+
+	for i := 0; i < 5000; i++ {
+
+		switch i % 4 {
+		case 0:
+			date += random.Int63n(100)
+		case 1:
+			date += random.Int63n(5000)
+		case 2:
+			date += random.Int63n(1 << 40)
+		}
+
+		points = append(points, point{date: date, value: random.NormFloat64() * 1e6})
+	}
+
+	roundTrip(t, points)
+}
+
+func TestSpecialValues(t *testing.T) {
+
+	roundTrip(t, []point{
+		{1, 0},
+		{2, math.Inf(1)},
+		{3, math.Inf(-1)},
+		{4, math.NaN()},
+		{5, -0.0},
+		{6, math.MaxFloat64},
+		{7, math.SmallestNonzeroFloat64},
+		{8, 1},
+		{9, 1},
+	})
+}
+
+func TestEmptyAndShortBlocks(t *testing.T) {
+
+	roundTrip(t, []point{})
+
+	roundTrip(t, []point{{1448452800000, 3.14}})
+
+	encoder := NewEncoder()
+	encoder.Append(1, 1)
+	encoder.Append(2, 2)
+
+	block := encoder.Bytes()
+
+	decoder := NewDecoder(block[:len(block)-2])
+
+	for decoder.Next() {
+	}
+
+	if decoder.Err() != ErrShortBlock {
+		t.Fatalf("expected a short block error, found %v", decoder.Err())
+	}
+}
+
+func TestCompression(t *testing.T) {
+
+	encoder := NewEncoder()
+
+	for i := 0; i < 720; i++ {
+		encoder.Append(1448452800000+int64(i)*10000, 50+float64(i%3))
+	}
+
+	if size := len(encoder.Bytes()); size > 720*4 {
+		t.Fatalf("expected less than 4 bytes per point, found %d bytes", size)
+	}
+}
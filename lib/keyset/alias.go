@@ -0,0 +1,250 @@
+package keyset
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// An alias maps a keyset name to another keyset, the writes and reads addressed to the alias go to the
+// target keyset. A keyset is renamed with an alias over its own name: until the dual write date the
+// writes go to both keysets and the reads stay in the old one, after it everything goes to the new one
+
+const (
+	formatCreateAliasTable string = `CREATE TABLE IF NOT EXISTS %s.ts_keyset_alias (alias text PRIMARY KEY, keyset text, dual_write_until timestamp, creation_date timestamp)`
+	formatListAliases      string = `SELECT alias, keyset, dual_write_until, creation_date FROM %s.ts_keyset_alias`
+	formatCreateAlias      string = `INSERT INTO %s.ts_keyset_alias (alias, keyset, dual_write_until, creation_date) VALUES (?, ?, ?, ?)`
+	formatDeleteAlias      string = `DELETE FROM %s.ts_keyset_alias WHERE alias = ?`
+
+	keysetsPath string = "/keysets/"
+	restorePath string = "/restore"
+	paramAlias  string = "alias"
+)
+
+// Alias - the keyset alias
+type Alias struct {
+	Alias          string     `json:"alias"`
+	Keyset         string     `json:"keyset"`
+	DualWriteUntil *time.Time `json:"dualWriteUntil,omitempty"`
+	CreationDate   *time.Time `json:"creationDate,omitempty"`
+}
+
+// Validate - validates the alias fields sent in the requests
+func (a Alias) Validate() gobol.Error {
+
+	if a.Keyset == constants.StringsEmpty {
+		return errBadRequest("Validate", "keyset cannot be empty")
+	}
+
+	return nil
+}
+
+// dualWriting - checks if the writes are still sent to the alias keyset and its target
+func (a *Alias) dualWriting(now time.Time) bool {
+
+	return a.DualWriteUntil != nil && now.Before(*a.DualWriteUntil)
+}
+
+// refreshAliases - reads all aliases
+func (ks *Manager) refreshAliases() error {
+
+	iter := ks.session.Query(fmt.Sprintf(formatListAliases, ks.ksAdmin)).Iter()
+
+	aliases := map[string]Alias{}
+
+	var alias Alias
+	var dualWriteUntil, creationDate time.Time
+
+	for iter.Scan(&alias.Alias, &alias.Keyset, &dualWriteUntil, &creationDate) {
+
+		if !dualWriteUntil.IsZero() {
+			date := dualWriteUntil
+			alias.DualWriteUntil = &date
+		}
+
+		if !creationDate.IsZero() {
+			date := creationDate
+			alias.CreationDate = &date
+		}
+
+		aliases[alias.Alias] = alias
+		alias = Alias{}
+		dualWriteUntil, creationDate = time.Time{}, time.Time{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	ks.mutex.Lock()
+	ks.aliases = aliases
+	ks.mutex.Unlock()
+
+	return nil
+}
+
+// Alias - returns the alias
+func (ks *Manager) Alias(name string) (Alias, bool) {
+
+	ks.mutex.RLock()
+	alias, ok := ks.aliases[name]
+	ks.mutex.RUnlock()
+
+	return alias, ok
+}
+
+// ListAliases - returns the aliases sorted by name
+func (ks *Manager) ListAliases() []Alias {
+
+	ks.mutex.RLock()
+	list := make([]Alias, 0, len(ks.aliases))
+	for _, alias := range ks.aliases {
+		list = append(list, alias)
+	}
+	ks.mutex.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Alias < list[j].Alias
+	})
+
+	return list
+}
+
+// Resolve - returns the target keyset of the alias, the keyset itself when it is not an alias or it is in dual write
+func (ks *Manager) Resolve(keyset string) string {
+
+	alias, ok := ks.Alias(keyset)
+	if ok && !alias.dualWriting(time.Now()) {
+		return alias.Keyset
+	}
+
+	return keyset
+}
+
+// MirrorTarget - returns the keyset also receiving the writes of the keyset in dual write
+func (ks *Manager) MirrorTarget(keyset string) (string, bool) {
+
+	alias, ok := ks.Alias(keyset)
+	if ok && alias.dualWriting(time.Now()) {
+		return alias.Keyset, true
+	}
+
+	return constants.StringsEmpty, false
+}
+
+// SetAlias - creates or replaces the alias
+func (ks *Manager) SetAlias(alias *Alias) gobol.Error {
+
+	if !ks.IsKeysetNameValid(alias.Alias) || !ks.IsKeysetNameValid(alias.Keyset) {
+		return errBadRequest("SetAlias", "invalid keyset name format")
+	}
+
+	if alias.Alias == alias.Keyset {
+		return errBadRequest("SetAlias", "the alias cannot point to itself")
+	}
+
+	if !ks.storage.CheckKeyset(alias.Keyset) {
+		return errKeysetNotFound("SetAlias")
+	}
+
+	ks.mutex.RLock()
+	_, chained := ks.aliases[alias.Keyset]
+	for _, other := range ks.aliases {
+		chained = chained || other.Keyset == alias.Alias
+	}
+	ks.mutex.RUnlock()
+
+	if chained {
+		return errBadRequest("SetAlias", "aliases of aliases are not allowed")
+	}
+
+	_, hasRecord := ks.Record(alias.Alias)
+	exists := ks.storage.CheckKeyset(alias.Alias) || hasRecord
+	existing, renaming := ks.Alias(alias.Alias)
+
+	// a renamed keyset may still exist after the dual write
+	if alias.DualWriteUntil == nil && exists && !renaming {
+		return errConflict("SetAlias", "there is a keyset with the alias name, set dualWriteUntil to rename it")
+	}
+
+	if alias.DualWriteUntil != nil && !exists {
+		return errBadRequest("SetAlias", "dualWriteUntil is only allowed when the alias is an existing keyset")
+	}
+
+	creationDate := time.Now().UTC().Truncate(time.Millisecond)
+	if renaming && existing.CreationDate != nil {
+		creationDate = *existing.CreationDate
+	}
+	alias.CreationDate = &creationDate
+
+	var dualWriteUntil interface{}
+	if alias.DualWriteUntil != nil {
+		date := alias.DualWriteUntil.UTC().Truncate(time.Millisecond)
+		alias.DualWriteUntil = &date
+		dualWriteUntil = date
+	}
+
+	err := ks.session.Query(
+		fmt.Sprintf(formatCreateAlias, ks.ksAdmin),
+		alias.Alias,
+		alias.Keyset,
+		dualWriteUntil,
+		creationDate,
+	).Exec()
+	if err != nil {
+		return errInternalServerError("SetAlias", err)
+	}
+
+	ks.mutex.Lock()
+	ks.aliases[alias.Alias] = *alias
+	ks.mutex.Unlock()
+
+	return nil
+}
+
+// RemoveAlias - removes the alias
+func (ks *Manager) RemoveAlias(name string) gobol.Error {
+
+	if _, ok := ks.Alias(name); !ok {
+		return errNotFound("RemoveAlias")
+	}
+
+	err := ks.session.Query(fmt.Sprintf(formatDeleteAlias, ks.ksAdmin), name).Exec()
+	if err != nil {
+		return errInternalServerError("RemoveAlias", err)
+	}
+
+	ks.mutex.Lock()
+	delete(ks.aliases, name)
+	ks.mutex.Unlock()
+
+	return nil
+}
+
+// AliasHandler - maps the aliases in the /keysets/:keyset/... paths to their target keysets, the
+// keyset record routes are not mapped because they manage the keyset with the name itself
+func (ks *Manager) AliasHandler(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if strings.HasPrefix(r.URL.Path, keysetsPath) {
+
+			path := r.URL.Path[len(keysetsPath):]
+
+			if i := strings.IndexByte(path, '/'); i > 0 && path[i:] != restorePath {
+				if target := ks.Resolve(path[:i]); target != path[:i] {
+					r.URL.Path = keysetsPath + target + path[i:]
+					r.URL.RawPath = constants.StringsEmpty
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -31,6 +31,10 @@ func errBadRequest(function, message string) gobol.Error {
 	return errBasic(function, message, http.StatusBadRequest, errors.New(message))
 }
 
+func errConflict(function, message string) gobol.Error {
+	return errBasic(function, message, http.StatusConflict, errors.New(message))
+}
+
 func errInternalServerError(function string, e error) gobol.Error {
 	return errBasic(function, e.Error(), http.StatusInternalServerError, e)
 }
@@ -1,26 +1,300 @@
 package keyset
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/gocql/gocql"
+	"github.com/uol/funks"
 	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/persistence"
 )
 
 // Manages all keyset CRUD and offers some API
 // @author: rnojiri
 
+// The keysets have records in the admin keyspace with their owners, TTLs and quotas. A deleted keyset
+// is only hidden, its collection is removed after the grace period unless it is restored before
+
+// Configuration - the keyset lifecycle settings
+type Configuration struct {
+	// GracePeriod - the time a deleted keyset can be restored before its collection is removed
+	GracePeriod funks.Duration
+	// RefreshInterval - the interval to read the keyset records, the changes made in other nodes are seen after it
+	RefreshInterval funks.Duration
+}
+
+// Quotas - the keyset limits
+type Quotas struct {
+	MaxSeries          int `json:"maxSeries,omitempty"`
+	MaxPointsPerSecond int `json:"maxPointsPerSecond,omitempty"`
+}
+
+const (
+	// TTLPolicyCoerce - the TTLs not allowed are replaced by the default TTL
+	TTLPolicyCoerce string = "coerce"
+	// TTLPolicyReject - the points with TTLs not allowed are rejected
+	TTLPolicyReject string = "reject"
+)
+
+// Record - the keyset record
+type Record struct {
+	Name         string     `json:"name"`
+	Owner        string     `json:"owner,omitempty"`
+	Contact      string     `json:"contact,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	CreationDate *time.Time `json:"creationDate,omitempty"`
+	DefaultTTL   int        `json:"defaultTTL,omitempty"`
+	AllowedTTLs  []int      `json:"allowedTTLs,omitempty"`
+	TTLPolicy    string     `json:"ttlPolicy,omitempty"`
+	Quotas       Quotas     `json:"quotas"`
+	DeletionDate *time.Time `json:"deletionDate,omitempty"`
+	PurgeDate    *time.Time `json:"purgeDate,omitempty"`
+}
+
+// Validate - validates the record fields sent in the requests
+func (r Record) Validate() gobol.Error {
+
+	if r.DefaultTTL < 0 {
+		return errBadRequest("Validate", "defaultTTL cannot be negative")
+	}
+
+	if r.Quotas.MaxSeries < 0 || r.Quotas.MaxPointsPerSecond < 0 {
+		return errBadRequest("Validate", "the quotas cannot be negative")
+	}
+
+	if r.TTLPolicy != constants.StringsEmpty && r.TTLPolicy != TTLPolicyCoerce && r.TTLPolicy != TTLPolicyReject {
+		return errBadRequest("Validate", "ttlPolicy must be coerce or reject")
+	}
+
+	if r.DefaultTTL > 0 && len(r.AllowedTTLs) > 0 {
+		for _, ttl := range r.AllowedTTLs {
+			if ttl == r.DefaultTTL {
+				return nil
+			}
+		}
+		return errBadRequest("Validate", "defaultTTL must be one of the allowedTTLs")
+	}
+
+	return nil
+}
+
+// deleted - checks if the keyset was soft deleted
+func (r *Record) deleted() bool {
+
+	return r.DeletionDate != nil
+}
+
+const (
+	formatCreateKeysetTable string = `CREATE TABLE IF NOT EXISTS %s.ts_keyset (name text PRIMARY KEY, owner text, contact text, description text, creation_date timestamp, default_ttl int, allowed_ttls set<int>, ttl_policy text, max_series int, max_points_per_second int, deletion_date timestamp)`
+	formatListRecords       string = `SELECT name, owner, contact, description, creation_date, default_ttl, allowed_ttls, ttl_policy, max_series, max_points_per_second, deletion_date FROM %s.ts_keyset`
+	formatCreateRecord      string = `INSERT INTO %s.ts_keyset (name, owner, contact, description, creation_date, default_ttl, allowed_ttls, ttl_policy, max_series, max_points_per_second, deletion_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, null)`
+	formatUpdateRecord      string = `UPDATE %s.ts_keyset SET owner = ?, contact = ?, description = ?, default_ttl = ?, allowed_ttls = ?, ttl_policy = ?, max_series = ?, max_points_per_second = ? WHERE name = ?`
+	formatSetDeletionDate   string = `UPDATE %s.ts_keyset SET deletion_date = ? WHERE name = ?`
+	formatDeleteRecord      string = `DELETE FROM %s.ts_keyset WHERE name = ?`
+)
+
 // Manager - the keyset
 type Manager struct {
-	storage      *metadata.Storage
-	keysetRegexp *regexp.Regexp
+	storage         *metadata.Storage
+	keysetRegexp    *regexp.Regexp
+	session         *gocql.Session
+	ksAdmin         string
+	keyspaceTTLMap  *persistence.KeyspaceTTLMap
+	defaultTTL      int
+	gracePeriod     time.Duration
+	refreshInterval time.Duration
+	logger          *logh.ContextualLogger
+	mutex           sync.RWMutex
+	records         map[string]Record
+	aliases         map[string]Alias
+	quotaMutex      sync.Mutex
+	usage           map[string]*quotaUsage
+	stop            chan struct{}
 }
 
 // New - initializes
-func New(storage *metadata.Storage, keysetRegexp string) *Manager {
-	return &Manager{
-		storage:      storage,
-		keysetRegexp: regexp.MustCompile(keysetRegexp),
+func New(conf Configuration, storage *metadata.Storage, keysetRegexp string, session *gocql.Session, ksAdmin string, keyspaceTTLMap *persistence.KeyspaceTTLMap, defaultTTL int) (*Manager, error) {
+
+	if conf.GracePeriod.Duration < 0 || conf.RefreshInterval.Duration <= 0 {
+		return nil, fmt.Errorf("the keyset grace period can not be negative and the refresh interval needs to be bigger than zero")
+	}
+
+	for _, format := range []string{formatCreateKeysetTable, formatCreateAliasTable} {
+		err := session.Query(fmt.Sprintf(format, ksAdmin)).Exec()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ks := &Manager{
+		storage:         storage,
+		keysetRegexp:    regexp.MustCompile(keysetRegexp),
+		session:         session,
+		ksAdmin:         ksAdmin,
+		keyspaceTTLMap:  keyspaceTTLMap,
+		defaultTTL:      defaultTTL,
+		gracePeriod:     conf.GracePeriod.Duration,
+		refreshInterval: conf.RefreshInterval.Duration,
+		logger:          logh.CreateContextualLogger(constants.StringsPKG, "keyset"),
+		records:         map[string]Record{},
+		aliases:         map[string]Alias{},
+		usage:           map[string]*quotaUsage{},
+		stop:            make(chan struct{}),
+	}
+
+	err := ks.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ks.refreshAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	ks.countSeries()
+
+	go ks.loop()
+
+	return ks, nil
+}
+
+// Stop - stops reading the records and purging the deleted keysets
+func (ks *Manager) Stop() {
+
+	close(ks.stop)
+}
+
+// loop - reads the records, aliases and series counts and removes the keysets deleted before the grace period
+func (ks *Manager) loop() {
+
+	ticker := time.NewTicker(ks.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-ks.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if err := ks.refresh(); err != nil {
+			if logh.ErrorEnabled {
+				ks.logger.Error().Str(constants.StringsFunc, "loop").Err(err).Msg("error reading the keyset records")
+			}
+			continue
+		}
+
+		if err := ks.refreshAliases(); err != nil && logh.ErrorEnabled {
+			ks.logger.Error().Str(constants.StringsFunc, "loop").Err(err).Msg("error reading the keyset aliases")
+		}
+
+		ks.countSeries()
+		ks.purge()
+	}
+}
+
+// refresh - reads all records and hides the deleted keysets
+func (ks *Manager) refresh() error {
+
+	iter := ks.session.Query(fmt.Sprintf(formatListRecords, ks.ksAdmin)).Iter()
+
+	records := map[string]Record{}
+	deleted := []string{}
+
+	var record Record
+	var creationDate, deletionDate time.Time
+
+	for iter.Scan(
+		&record.Name,
+		&record.Owner,
+		&record.Contact,
+		&record.Description,
+		&creationDate,
+		&record.DefaultTTL,
+		&record.AllowedTTLs,
+		&record.TTLPolicy,
+		&record.Quotas.MaxSeries,
+		&record.Quotas.MaxPointsPerSecond,
+		&deletionDate,
+	) {
+
+		if !creationDate.IsZero() {
+			date := creationDate
+			record.CreationDate = &date
+		}
+
+		if !deletionDate.IsZero() {
+			date := deletionDate
+			record.DeletionDate = &date
+			deleted = append(deleted, record.Name)
+		}
+
+		records[record.Name] = record
+		record = Record{}
+		creationDate, deletionDate = time.Time{}, time.Time{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	ks.mutex.Lock()
+	ks.records = records
+	ks.mutex.Unlock()
+
+	ks.storage.SetDeletedKeysets(deleted)
+
+	return nil
+}
+
+// purge - removes the collections of the keysets deleted before the grace period
+func (ks *Manager) purge() {
+
+	ks.mutex.RLock()
+	expired := []string{}
+	for name, record := range ks.records {
+		if record.deleted() && time.Since(*record.DeletionDate) >= ks.gracePeriod {
+			expired = append(expired, name)
+		}
+	}
+	ks.mutex.RUnlock()
+
+	for _, name := range expired {
+
+		// the other nodes may have removed it first, the record is kept until the collection is removed
+		if gerr := ks.storage.DeleteKeyset(name); gerr != nil {
+			if logh.ErrorEnabled {
+				ks.logger.Error().Str(constants.StringsFunc, "purge").Str(constants.StringsKeyset, name).Err(gerr).Msg("error removing the deleted keyset")
+			}
+			continue
+		}
+
+		if err := ks.session.Query(fmt.Sprintf(formatDeleteRecord, ks.ksAdmin), name).Exec(); err != nil {
+			if logh.ErrorEnabled {
+				ks.logger.Error().Str(constants.StringsFunc, "purge").Str(constants.StringsKeyset, name).Err(err).Msg("error removing the keyset record")
+			}
+			continue
+		}
+
+		if logh.InfoEnabled {
+			ks.logger.Info().Str(constants.StringsFunc, "purge").Str(constants.StringsKeyset, name).Msg("deleted keyset removed after the grace period")
+		}
+	}
+
+	if len(expired) > 0 {
+		if err := ks.refresh(); err != nil && logh.ErrorEnabled {
+			ks.logger.Error().Str(constants.StringsFunc, "purge").Err(err).Msg("error reading the keyset records")
+		}
 	}
 }
 
@@ -29,32 +303,270 @@ func (ks *Manager) IsKeysetNameValid(keyset string) bool {
 	return ks.keysetRegexp.MatchString(keyset)
 }
 
-// Create - creates a new index
-func (ks *Manager) Create(keyset string) gobol.Error {
+// validateTTLs - checks if the record TTLs have keyspaces, the configured default TTL must be allowed
+// when the record has no default TTL, the TTLs not allowed are coerced to it
+func (ks *Manager) validateTTLs(record *Record) gobol.Error {
+
+	if record.DefaultTTL == 0 && len(record.AllowedTTLs) > 0 {
+		allowed := false
+		for _, ttl := range record.AllowedTTLs {
+			if ttl == ks.defaultTTL {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return errBadRequest("validateTTLs", fmt.Sprintf("defaultTTL is required when the allowedTTLs do not have the default TTL %d", ks.defaultTTL))
+		}
+	}
+
+	ttls := record.AllowedTTLs
+	if record.DefaultTTL > 0 {
+		ttls = append([]int{record.DefaultTTL}, ttls...)
+	}
+
+	for _, ttl := range ttls {
+		if _, ok := ks.keyspaceTTLMap.Get(ttl); !ok {
+			return errBadRequest("validateTTLs", fmt.Sprintf("there is no keyspace with the TTL %d", ttl))
+		}
+	}
+
+	return nil
+}
+
+// Record - returns the record of the keyset, the deleted ones included
+func (ks *Manager) Record(keyset string) (Record, bool) {
+
+	ks.mutex.RLock()
+	record, ok := ks.records[keyset]
+	ks.mutex.RUnlock()
 
-	if !ks.IsKeysetNameValid(keyset) {
+	if ok && record.deleted() {
+		purgeDate := record.DeletionDate.Add(ks.gracePeriod)
+		record.PurgeDate = &purgeDate
+	}
+
+	return record, ok
+}
+
+// List - returns the records of the keysets sorted by name, the keysets created before the records only have the name
+func (ks *Manager) List(includeDeleted bool) []Record {
+
+	keysets := ks.storage.ListKeysets()
+	list := make([]Record, 0, len(keysets))
+	listed := map[string]bool{}
+
+	for _, keyset := range keysets {
+		record, ok := ks.Record(keyset)
+		if !ok || record.deleted() {
+			record = Record{Name: keyset}
+		}
+		list = append(list, record)
+		listed[keyset] = true
+	}
+
+	if includeDeleted {
+		ks.mutex.RLock()
+		names := make([]string, 0)
+		for name, record := range ks.records {
+			if record.deleted() && !listed[name] {
+				names = append(names, name)
+			}
+		}
+		ks.mutex.RUnlock()
+
+		for _, name := range names {
+			record, _ := ks.Record(name)
+			list = append(list, record)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// Create - creates a new index and its record
+func (ks *Manager) Create(record *Record) gobol.Error {
+
+	if !ks.IsKeysetNameValid(record.Name) {
 		return errBadRequest("Create", "invalid keyset name format")
 	}
 
-	err := ks.storage.CreateKeyset(keyset)
+	if existing, ok := ks.Record(record.Name); ok && existing.deleted() {
+		return errConflict("Create", "the keyset was deleted and can be restored")
+	}
+
+	if _, ok := ks.Alias(record.Name); ok {
+		return errConflict("Create", "there is an alias with the keyset name")
+	}
+
+	gerr := ks.validateTTLs(record)
+	if gerr != nil {
+		return gerr
+	}
+
+	err := ks.storage.CreateKeyset(record.Name)
 	if err != nil {
 		return errInternalServerError("Create", err)
 	}
 
+	creationDate := time.Now().UTC().Truncate(time.Millisecond)
+	record.CreationDate = &creationDate
+	record.DeletionDate = nil
+	record.PurgeDate = nil
+
+	qerr := ks.session.Query(
+		fmt.Sprintf(formatCreateRecord, ks.ksAdmin),
+		record.Name,
+		record.Owner,
+		record.Contact,
+		record.Description,
+		creationDate,
+		record.DefaultTTL,
+		record.AllowedTTLs,
+		record.TTLPolicy,
+		record.Quotas.MaxSeries,
+		record.Quotas.MaxPointsPerSecond,
+	).Exec()
+	if qerr != nil {
+		return errInternalServerError("Create", qerr)
+	}
+
+	ks.setRecord(*record)
+
 	return nil
 }
 
-// Delete - deletes the keyset
+// Update - replaces the owner, contact, description, TTLs and quotas of the keyset
+func (ks *Manager) Update(record *Record) gobol.Error {
+
+	existing, ok := ks.Record(record.Name)
+	if ok && existing.deleted() {
+		return errKeysetNotFound("Update")
+	}
+
+	gerr := ks.validateTTLs(record)
+	if gerr != nil {
+		return gerr
+	}
+
+	err := ks.session.Query(
+		fmt.Sprintf(formatUpdateRecord, ks.ksAdmin),
+		record.Owner,
+		record.Contact,
+		record.Description,
+		record.DefaultTTL,
+		record.AllowedTTLs,
+		record.TTLPolicy,
+		record.Quotas.MaxSeries,
+		record.Quotas.MaxPointsPerSecond,
+		record.Name,
+	).Exec()
+	if err != nil {
+		return errInternalServerError("Update", err)
+	}
+
+	record.CreationDate = existing.CreationDate
+	record.DeletionDate = nil
+	record.PurgeDate = nil
+
+	ks.setRecord(*record)
+
+	if record.Quotas.MaxSeries > 0 && existing.Quotas.MaxSeries == 0 {
+		ks.countSeries()
+	}
+
+	return nil
+}
+
+// Delete - soft deletes the keyset, its collection is removed after the grace period
 func (ks *Manager) Delete(keyset string) gobol.Error {
 
-	err := ks.storage.DeleteKeyset(keyset)
+	return ks.setDeletionDate("Delete", keyset, time.Now().UTC().Truncate(time.Millisecond))
+}
+
+// Restore - restores the keyset deleted in the grace period
+func (ks *Manager) Restore(keyset string) gobol.Error {
+
+	record, ok := ks.Record(keyset)
+	if !ok || !record.deleted() {
+		return errBadRequest("Restore", "the keyset is not deleted")
+	}
+
+	return ks.setDeletionDate("Restore", keyset, nil)
+}
+
+// setDeletionDate - marks or unmarks the keyset as deleted
+func (ks *Manager) setDeletionDate(function, keyset string, deletionDate interface{}) gobol.Error {
+
+	err := ks.session.Query(fmt.Sprintf(formatSetDeletionDate, ks.ksAdmin), deletionDate, keyset).Exec()
+	if err != nil {
+		return errInternalServerError(function, err)
+	}
+
+	err = ks.refresh()
 	if err != nil {
-		return errInternalServerError("Delete", err)
+		return errInternalServerError(function, err)
 	}
 
 	return nil
 }
 
+// setRecord - caches the record until the next refresh
+func (ks *Manager) setRecord(record Record) {
+
+	ks.mutex.Lock()
+	ks.records[record.Name] = record
+	ks.mutex.Unlock()
+}
+
+// TTLPolicy - returns the default and allowed TTLs of the keyset and if the points with other TTLs are rejected,
+// the zero values are used when the keyset has no policy
+func (ks *Manager) TTLPolicy(keyset string) (int, []int, bool) {
+
+	ks.mutex.RLock()
+	record, ok := ks.records[keyset]
+	ks.mutex.RUnlock()
+
+	if !ok || record.deleted() {
+		return 0, nil, false
+	}
+
+	return record.DefaultTTL, record.AllowedTTLs, record.TTLPolicy == TTLPolicyReject
+}
+
+// TTLReferences - returns the sorted names of the keysets having the TTL in their policy
+func (ks *Manager) TTLReferences(ttl int) []string {
+
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+
+	names := []string{}
+
+	for name, record := range ks.records {
+		if record.deleted() {
+			continue
+		}
+
+		referenced := record.DefaultTTL == ttl
+		for _, allowed := range record.AllowedTTLs {
+			referenced = referenced || allowed == ttl
+		}
+
+		if referenced {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
 // CheckKeyset - checks if keyset exists
 func (ks *Manager) CheckKeyset(keyset string) bool {
 
@@ -0,0 +1,134 @@
+package keyset
+
+import (
+	"time"
+
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// Enforces the keyset quotas. The points per second are counted by each node, the series are
+// counted in the collection on every refresh plus the series this node created after it, so the
+// series created by the other nodes are only seen after the refresh interval
+
+// quotaUsage - the points received by this node in the current second and the series of the keyset
+type quotaUsage struct {
+	second int64
+	points int
+	series int
+}
+
+// quotas - returns the quotas of the keyset, the zero values when it has none
+func (ks *Manager) quotas(keyset string) Quotas {
+
+	ks.mutex.RLock()
+	record, ok := ks.records[keyset]
+	ks.mutex.RUnlock()
+
+	if !ok || record.deleted() {
+		return Quotas{}
+	}
+
+	return record.Quotas
+}
+
+// usageOf - returns the usage of the keyset, the quota mutex must be locked
+func (ks *Manager) usageOf(keyset string) *quotaUsage {
+
+	usage, ok := ks.usage[keyset]
+	if !ok {
+		usage = &quotaUsage{}
+		ks.usage[keyset] = usage
+	}
+
+	return usage
+}
+
+// AllowPoint - counts a point of the keyset, returns false when the keyset reached its points per second in this node
+func (ks *Manager) AllowPoint(keyset string) bool {
+
+	max := ks.quotas(keyset).MaxPointsPerSecond
+	if max == 0 {
+		return true
+	}
+
+	now := time.Now().Unix()
+
+	ks.quotaMutex.Lock()
+	defer ks.quotaMutex.Unlock()
+
+	usage := ks.usageOf(keyset)
+	if usage.second != now {
+		usage.second = now
+		usage.points = 0
+	}
+
+	if usage.points >= max {
+		return false
+	}
+
+	usage.points++
+
+	return true
+}
+
+// AllowSerie - counts a new serie of the keyset, returns false when the keyset reached its series
+func (ks *Manager) AllowSerie(keyset string) bool {
+
+	max := ks.quotas(keyset).MaxSeries
+	if max == 0 {
+		return true
+	}
+
+	ks.quotaMutex.Lock()
+	defer ks.quotaMutex.Unlock()
+
+	usage := ks.usageOf(keyset)
+	if usage.series >= max {
+		return false
+	}
+
+	usage.series++
+
+	return true
+}
+
+// countSeries - reads the number of series of the keysets with a series quota
+func (ks *Manager) countSeries() {
+
+	ks.mutex.RLock()
+	limited := []string{}
+	for name, record := range ks.records {
+		if !record.deleted() && record.Quotas.MaxSeries > 0 {
+			limited = append(limited, name)
+		}
+	}
+	ks.mutex.RUnlock()
+
+	counts := make(map[string]int, len(limited))
+
+	for _, name := range limited {
+
+		if !ks.storage.CheckKeyset(name) {
+			continue
+		}
+
+		count, gerr := ks.storage.CountSeries(name)
+		if gerr != nil {
+			if logh.ErrorEnabled {
+				ks.logger.Error().Str(constants.StringsFunc, "countSeries").Str(constants.StringsKeyset, name).Err(gerr).Msg("error counting the keyset series")
+			}
+			continue
+		}
+
+		counts[name] = count
+	}
+
+	ks.quotaMutex.Lock()
+	defer ks.quotaMutex.Unlock()
+
+	for name, count := range counts {
+		ks.usageOf(name).series = count
+	}
+}
@@ -2,12 +2,35 @@ package keyset
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
 	"github.com/uol/gobol/rip"
 	"github.com/uol/mycenae/lib/constants"
 )
 
+// readRecord - reads the record sent in the request body, it is optional and only the name is set without it
+func readRecord(r *http.Request, keyset string) (*Record, gobol.Error) {
+
+	record := &Record{}
+
+	if r.ContentLength != 0 {
+		gerr := rip.FromJSON(r, record)
+		if gerr != nil {
+			return nil, gerr
+		}
+	}
+
+	if record.Name != constants.StringsEmpty && record.Name != keyset {
+		return nil, errBadRequest("readRecord", "the name of the keyset is different from the one in the path")
+	}
+
+	record.Name = keyset
+
+	return record, nil
+}
+
 // CreateKeyset - creates a new keyset
 func (ks *Manager) CreateKeyset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
@@ -27,22 +50,30 @@ func (ks *Manager) CreateKeyset(w http.ResponseWriter, r *http.Request, ps httpr
 	if exists {
 		rip.Success(w, http.StatusConflict, nil)
 	} else {
-		gerr := ks.Create(keysetParam)
+		record, gerr := readRecord(r, keysetParam)
 		if gerr != nil {
 			rip.Fail(w, gerr)
 			return
 		}
-		rip.Success(w, http.StatusCreated, nil)
+
+		gerr = ks.Create(record)
+		if gerr != nil {
+			rip.Fail(w, gerr)
+			return
+		}
+		rip.SuccessJSON(w, http.StatusCreated, record)
 	}
 
 	return
 }
 
-// GetKeysets - returns all stored keysets
+// GetKeysets - returns all stored keysets, the deleted ones in the grace period are included with "deleted=true"
 func (ks *Manager) GetKeysets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	keysets := ks.storage.ListKeysets()
-	if keysets == nil || len(keysets) == 0 {
+	includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("deleted"))
+
+	keysets := ks.List(includeDeleted)
+	if len(keysets) == 0 {
 		rip.SuccessJSON(w, http.StatusNoContent, nil)
 	} else {
 		rip.SuccessJSON(w, http.StatusOK, keysets)
@@ -51,7 +82,51 @@ func (ks *Manager) GetKeysets(w http.ResponseWriter, r *http.Request, ps httprou
 	return
 }
 
-// DeleteKeyset - deletes a keyset
+// GetKeyset - returns the keyset record
+func (ks *Manager) GetKeyset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+
+	record, ok := ks.Record(keyset)
+
+	if ks.storage.CheckKeyset(keyset) {
+		if !ok {
+			record = Record{Name: keyset}
+		}
+	} else if !ok || !record.deleted() {
+		rip.Fail(w, errKeysetNotFound("GetKeyset"))
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, record)
+}
+
+// UpdateKeyset - replaces the keyset record fields
+func (ks *Manager) UpdateKeyset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+
+	if !ks.storage.CheckKeyset(keyset) {
+		rip.Fail(w, errKeysetNotFound("UpdateKeyset"))
+		return
+	}
+
+	record, gerr := readRecord(r, keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	gerr = ks.Update(record)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, record)
+}
+
+// DeleteKeyset - deletes a keyset, it can be restored in the grace period
 func (ks *Manager) DeleteKeyset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	keysetParam := ps.ByName(constants.StringsKeyset)
@@ -68,11 +143,12 @@ func (ks *Manager) DeleteKeyset(w http.ResponseWriter, r *http.Request, ps httpr
 
 	exists := ks.storage.CheckKeyset(keysetParam)
 	if exists {
-		gerr := ks.storage.DeleteKeyset(keysetParam)
+		gerr := ks.Delete(keysetParam)
 		if gerr != nil {
 			rip.Fail(w, gerr)
 		} else {
-			rip.Success(w, http.StatusOK, nil)
+			record, _ := ks.Record(keysetParam)
+			rip.SuccessJSON(w, http.StatusOK, record)
 		}
 	} else {
 		rip.Fail(w, errNotFound("DeleteKeyset"))
@@ -81,6 +157,21 @@ func (ks *Manager) DeleteKeyset(w http.ResponseWriter, r *http.Request, ps httpr
 	return
 }
 
+// RestoreKeyset - restores a keyset deleted in the grace period
+func (ks *Manager) RestoreKeyset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+
+	gerr := ks.Restore(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	record, _ := ks.Record(keyset)
+	rip.SuccessJSON(w, http.StatusOK, record)
+}
+
 // Check if a keyspace exists
 func (ks *Manager) Check(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	keyset := ps.ByName(constants.StringsKeyset)
@@ -99,3 +190,67 @@ func (ks *Manager) Check(w http.ResponseWriter, r *http.Request, ps httprouter.P
 
 	rip.Success(w, http.StatusOK, nil)
 }
+
+// GetAliases - returns all keyset aliases
+func (ks *Manager) GetAliases(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	aliases := ks.ListAliases()
+	if len(aliases) == 0 {
+		rip.SuccessJSON(w, http.StatusNoContent, nil)
+	} else {
+		rip.SuccessJSON(w, http.StatusOK, aliases)
+	}
+}
+
+// GetAlias - returns the keyset alias
+func (ks *Manager) GetAlias(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	alias, ok := ks.Alias(ps.ByName(paramAlias))
+	if !ok {
+		rip.Fail(w, errNotFound("GetAlias"))
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, alias)
+}
+
+// PutAlias - creates or replaces the keyset alias
+func (ks *Manager) PutAlias(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	name := ps.ByName(paramAlias)
+
+	alias := &Alias{}
+
+	gerr := rip.FromJSON(r, alias)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if alias.Alias != constants.StringsEmpty && alias.Alias != name {
+		rip.Fail(w, errBadRequest("PutAlias", "the alias is different from the one in the path"))
+		return
+	}
+
+	alias.Alias = name
+
+	gerr = ks.SetAlias(alias)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, alias)
+}
+
+// DeleteAlias - removes the keyset alias
+func (ks *Manager) DeleteAlias(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	gerr := ks.RemoveAlias(ps.ByName(paramAlias))
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.Success(w, http.StatusOK, nil)
+}
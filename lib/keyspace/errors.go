@@ -38,3 +38,11 @@ func errNotFound(function string) gobol.Error {
 func errNoContent(function string) gobol.Error {
 	return errBasic(function, constants.StringsEmpty, http.StatusNoContent, errors.New(constants.StringsEmpty))
 }
+
+func errConflict(function, msg string) gobol.Error {
+	return errBasic(function, msg, http.StatusConflict, errors.New(msg))
+}
+
+func errPreconditionFailed(function, msg string) gobol.Error {
+	return errBasic(function, msg, http.StatusPreconditionFailed, errors.New(msg))
+}
@@ -1,36 +1,177 @@
 package keyspace
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
 	"regexp"
+	"time"
 
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/persistence"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
 var validKey = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z_]+$`)
 
-// New creates a new keyspace manager
+// Keysets - the keyset policies using the keyspace TTLs
+type Keysets interface {
+	// TTLReferences - returns the keysets having the TTL in their policy
+	TTLReferences(ttl int) []string
+}
+
+// New creates a new keyspace manager, the TTLs and layouts of the default
+// keyspaces are read from ts_keyspace in each refresh interval to see the
+// changes made in other nodes
 func New(
 	timelineManager *tlmanager.Instance,
 	storage *persistence.Storage,
 	devMode bool,
 	defaultTTL int,
 	maxAllowedTTL int,
+	ksAdmin string,
+	keyspaceTTLMap *persistence.KeyspaceTTLMap,
+	keyspaceLayouts *persistence.KeyspaceLayouts,
+	defaultKeyspaces map[string]int,
+	refreshInterval time.Duration,
+	keysets Keysets,
+	blocksEnabled bool,
 ) *Keyspace {
-	return &Keyspace{
-		Storage:         storage,
-		timelineManager: timelineManager,
-		devMode:         devMode,
-		defaultTTL:      defaultTTL,
-		maxAllowedTTL:   maxAllowedTTL,
+	kspace := &Keyspace{
+		Storage:          storage,
+		timelineManager:  timelineManager,
+		devMode:          devMode,
+		defaultTTL:       defaultTTL,
+		maxAllowedTTL:    maxAllowedTTL,
+		ksAdmin:          ksAdmin,
+		keyspaceTTLMap:   keyspaceTTLMap,
+		keyspaceLayouts:  keyspaceLayouts,
+		defaultKeyspaces: defaultKeyspaces,
+		keysets:          keysets,
+		blocksEnabled:    blocksEnabled,
+		refreshInterval:  refreshInterval,
+		logger:           logh.CreateContextualLogger(constants.StringsPKG, "keyspace"),
+		stop:             make(chan struct{}),
+	}
+
+	if gerr := kspace.refreshTTLMap(); gerr != nil && logh.ErrorEnabled {
+		kspace.logger.Error().Str(constants.StringsFunc, "New").Err(gerr).Msg("error reading the keyspace TTLs")
+	}
+
+	if !devMode && refreshInterval > 0 {
+		go kspace.loop(refreshInterval)
 	}
+
+	return kspace
 }
 
 // Keyspace is a structure that represents the functionality of this module
 type Keyspace struct {
 	*persistence.Storage
-	timelineManager *tlmanager.Instance
-	devMode         bool
-	defaultTTL      int
-	maxAllowedTTL   int
+	timelineManager  *tlmanager.Instance
+	devMode          bool
+	defaultTTL       int
+	maxAllowedTTL    int
+	ksAdmin          string
+	keyspaceTTLMap   *persistence.KeyspaceTTLMap
+	keyspaceLayouts  *persistence.KeyspaceLayouts
+	defaultKeyspaces map[string]int
+	keysets          Keysets
+	blocksEnabled    bool
+	refreshInterval  time.Duration
+	logger           *logh.ContextualLogger
+	stop             chan struct{}
+}
+
+// Stop - stops refreshing the keyspace TTL map
+func (kspace *Keyspace) Stop() {
+
+	close(kspace.stop)
+}
+
+// loop - refreshes the keyspace TTL map
+func (kspace *Keyspace) loop(refreshInterval time.Duration) {
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-kspace.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if gerr := kspace.refreshTTLMap(); gerr != nil && logh.ErrorEnabled {
+			kspace.logger.Error().Str(constants.StringsFunc, "loop").Err(gerr).Msg("error refreshing the keyspace TTLs")
+		}
+	}
+}
+
+// refreshTTLMap - maps the TTLs stored in ts_keyspace to the default keyspaces, the configured TTL
+// is used when the keyspace was created before the TTL was stored, the layouts are refreshed too
+func (kspace *Keyspace) refreshTTLMap() gobol.Error {
+
+	// the dev mode creates all keyspaces with the default TTL
+	if kspace.devMode {
+		return nil
+	}
+
+	keyspaces, gerr := kspace.ListKeyspaces()
+	if gerr != nil && gerr.StatusCode() != http.StatusNoContent {
+		return gerr
+	}
+
+	storedTTLs := map[string]int{}
+	layouts := map[string]constants.KeyspaceLayout{}
+	for _, ks := range keyspaces {
+		if ks.TTL > 0 {
+			storedTTLs[ks.Name] = ks.TTL
+		}
+		if _, ok := kspace.defaultKeyspaces[ks.Name]; ok {
+			layouts[ks.Name] = ks.Layout
+		}
+	}
+
+	keyspaceTTLMap := map[int]string{}
+	for name, ttl := range kspace.defaultKeyspaces {
+		if stored, ok := storedTTLs[name]; ok {
+			ttl = stored
+		}
+		keyspaceTTLMap[ttl] = name
+	}
+
+	kspace.keyspaceTTLMap.Set(keyspaceTTLMap)
+	kspace.keyspaceLayouts.Set(layouts)
+
+	return nil
+}
+
+// refreshMessage - tells the keyspace TTL changes are only seen by the other nodes in their next refresh
+func (kspace *Keyspace) refreshMessage() string {
+
+	return fmt.Sprintf("the other nodes see the keyspace TTL changes in their next refresh, within %s", kspace.refreshInterval)
+}
+
+// dropTokenWindow - the time a drop confirmation token is valid, at least
+const dropTokenWindow = 5 * time.Minute
+
+// dropToken - the token confirming the drop of the keyspace in the time window, it
+// changes when the keyspace is created again so an old token can not be reused
+func dropToken(ks persistence.Keyspace, now time.Time) string {
+
+	window := now.Truncate(dropTokenWindow).Unix()
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", ks.Name, ks.CreationDate.UnixNano(), window)))
+
+	return hex.EncodeToString(hash[:8])
+}
+
+// validDropToken - checks the token of the current or the previous time window
+func validDropToken(ks persistence.Keyspace, token string, now time.Time) bool {
+
+	return token == dropToken(ks, now) || token == dropToken(ks, now.Add(-dropTokenWindow))
 }
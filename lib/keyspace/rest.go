@@ -2,8 +2,10 @@ package keyspace
 
 import (
 	"net/http"
+	"time"
 
 	"fmt"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/uol/gobol/rip"
@@ -53,8 +55,22 @@ func (kspace *Keyspace) Create(
 		return
 	}
 
+	switch ksc.Layout {
+	case "":
+		ksc.Layout = constants.KeyspaceLayoutRow
+	case constants.KeyspaceLayoutRow:
+	case constants.KeyspaceLayoutBlock:
+		if !kspace.blocksEnabled {
+			rip.Fail(w, errValidationS("CreateKeyspace", "the block layout needs the [NumberBlocks] configuration"))
+			return
+		}
+	default:
+		rip.Fail(w, errValidationS("CreateKeyspace", "'layout' must be 'row' or 'block'"))
+		return
+	}
+
 	ksc.Name = ks
-	err = kspace.CreateKeyspace(ksc.Name, ksc.Datacenter, ksc.Contact, ksc.ReplicationFactor, ksc.TTL)
+	err = kspace.CreateKeyspace(ksc.Name, ksc.Datacenter, ksc.Contact, ksc.ReplicationFactor, ksc.TTL, ksc.Layout)
 	if err != nil {
 		rip.Fail(w, err)
 		return
@@ -171,3 +187,174 @@ func (kspace *Keyspace) ListDC(
 		Payload:      datacenters,
 	})
 }
+
+// UpdateTTL is a rest endpoint to change the TTL of the keyspace tables, the
+// TTL map of the other nodes is only refreshed in their next refresh interval,
+// as the response tells, the TTLs of the default keyspace and of the keyset
+// policies can not be changed
+func (kspace *Keyspace) UpdateTTL(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	ks := ps.ByName("keyspace")
+	if ks == constants.StringsEmpty || ks == kspace.ksAdmin {
+		rip.Fail(w, errNotFound("UpdateTTL"))
+		return
+	}
+
+	ksc := ConfigTTL{}
+
+	gerr := rip.FromJSON(r, &ksc)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if ksc.TTL > kspace.maxAllowedTTL {
+		rip.Fail(w, errValidationS("UpdateKeyspaceTTL", fmt.Sprintf("Max TTL allowed is %d", kspace.maxAllowedTTL)))
+		return
+	}
+
+	// the points of the default TTL and of the keyset policies would lose their keyspace
+	if current, ok := kspace.keyspaceTTLMap.TTL(ks); ok && current != ksc.TTL {
+
+		if current == kspace.defaultTTL {
+			rip.Fail(w, errConflict(
+				"UpdateKeyspaceTTL",
+				fmt.Sprintf("the keyspace stores the default TTL %d", current),
+			))
+			return
+		}
+
+		if keysets := kspace.keysets.TTLReferences(current); len(keysets) > 0 {
+			rip.Fail(w, errConflict(
+				"UpdateKeyspaceTTL",
+				fmt.Sprintf("the TTL %d is in the policy of the keysets %s, change them first", current, strings.Join(keysets, ", ")),
+			))
+			return
+		}
+	}
+
+	if other, ok := kspace.keyspaceTTLMap.Get(ksc.TTL); ok && other != ks {
+		rip.Fail(w, errConflict(
+			"UpdateKeyspaceTTL",
+			fmt.Sprintf("the TTL %d is already stored in the keyspace %s", ksc.TTL, other),
+		))
+		return
+	}
+
+	gerr = kspace.UpdateKeyspaceTTL(ks, ksc.TTL)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	gerr = kspace.refreshTTLMap()
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, Response{
+		Message: kspace.refreshMessage(),
+	})
+}
+
+// UpdateReplication is a rest endpoint to change the replication factor of
+// each datacenter of the keyspace
+func (kspace *Keyspace) UpdateReplication(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	ks := ps.ByName("keyspace")
+	if ks == constants.StringsEmpty || ks == kspace.ksAdmin {
+		rip.Fail(w, errNotFound("UpdateReplication"))
+		return
+	}
+
+	ksc := ConfigReplication{}
+
+	gerr := rip.FromJSON(r, &ksc)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	keyspace, found, gerr := kspace.GetKeyspace(ks)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+	if !found {
+		rip.Fail(w, errNotFound("UpdateReplication"))
+		return
+	}
+
+	if _, ok := ksc.Replication[keyspace.DC]; !ok {
+		rip.Fail(w, errValidationS(
+			"UpdateKeyspaceReplication",
+			fmt.Sprintf("the replication must include the keyspace datacenter %s", keyspace.DC),
+		))
+		return
+	}
+
+	gerr = kspace.UpdateKeyspaceReplication(ks, ksc.Replication)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.Success(w, http.StatusOK, nil)
+}
+
+// Delete is a rest endpoint to drop a keyspace, without the confirm parameter
+// it answers with the token confirming the drop, the keyspaces mapped to a TTL
+// can not be dropped, the other nodes only see a TTL change in their next refresh
+func (kspace *Keyspace) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	ks := ps.ByName("keyspace")
+	if ks == constants.StringsEmpty || ks == kspace.ksAdmin {
+		rip.Fail(w, errNotFound("Delete"))
+		return
+	}
+
+	if ttl, ok := kspace.keyspaceTTLMap.TTL(ks); ok {
+		rip.Fail(w, errConflict(
+			"DeleteKeyspace",
+			fmt.Sprintf("the keyspace stores the points with TTL %d and cannot be dropped", ttl),
+		))
+		return
+	}
+
+	keyspace, found, gerr := kspace.GetKeyspace(ks)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+	if !found {
+		rip.Fail(w, errNotFound("Delete"))
+		return
+	}
+
+	now := time.Now()
+
+	token := r.URL.Query().Get("confirm")
+	if token == constants.StringsEmpty {
+		rip.SuccessJSON(w, http.StatusPreconditionRequired, DropResponse{
+			Token: dropToken(keyspace, now),
+		})
+		return
+	}
+
+	if !validDropToken(keyspace, token, now) {
+		rip.Fail(w, errPreconditionFailed("DeleteKeyspace", "invalid or expired confirmation token"))
+		return
+	}
+
+	// the storage wrapper would also delete the keyset named as the keyspace
+	gerr = kspace.Backend.DeleteKeyspace(ks)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, Response{
+		Message: kspace.refreshMessage(),
+	})
+}
@@ -17,6 +17,8 @@ type Config struct {
 	ReplicationFactor int    `json:"replicationFactor"`
 	Contact           string `json:"contact"`
 	TTL               int    `json:"ttl"`
+
+	Layout constants.KeyspaceLayout `json:"layout,omitempty"`
 }
 
 // Validate checks if config is valid
@@ -59,6 +61,55 @@ type ConfigUpdate struct {
 	Contact string `json:"contact,omitempty"`
 }
 
+// ConfigTTL is the json format for a keyspace TTL change request
+type ConfigTTL struct {
+	TTL int `json:"ttl"`
+}
+
+// Validate checks if the TTL change is valid
+func (c *ConfigTTL) Validate() gobol.Error {
+
+	if c.TTL <= 0 {
+		return errValidationS("UpdateKeyspaceTTL", "TTL cannot be less or equal to zero")
+	}
+
+	return nil
+}
+
+// ConfigReplication is the json format for a keyspace replication change
+// request, it has the replication factor of each datacenter
+type ConfigReplication struct {
+	Replication map[string]int `json:"replication"`
+}
+
+// Validate checks if the replication change is valid
+func (c *ConfigReplication) Validate() gobol.Error {
+
+	if len(c.Replication) == 0 {
+		return errValidationS("UpdateKeyspaceReplication", "Replication cannot be empty")
+	}
+
+	for datacenter, factor := range c.Replication {
+		if !validKey.MatchString(datacenter) {
+			return errValidationS("UpdateKeyspaceReplication", "Datacenter has an invalid format")
+		}
+
+		if factor <= 0 || factor > 3 {
+			return errValidationS(
+				"UpdateKeyspaceReplication",
+				"Replication factor cannot be less than or equal to 0 or greater than 3",
+			)
+		}
+	}
+
+	return nil
+}
+
+// DropResponse is the json format for the confirmation token of a keyspace drop
+type DropResponse struct {
+	Token string `json:"token"`
+}
+
 // CreateResponse is the json format for a keyspace creation endpoint response
 type CreateResponse struct {
 	Ksid string `json:"ksid,omitempty"`
@@ -3,10 +3,12 @@ package metadata
 import (
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/uol/gobol"
 	"github.com/uol/hashing"
 	"github.com/uol/logh"
 	"github.com/uol/mycenae/lib/constants"
@@ -22,11 +24,25 @@ const (
 )
 
 var (
-	idNamespace     []byte = []byte("tsid")
-	facetsNamespace []byte = []byte("fac")
-	tsidOK          []byte = []byte("1")
+	idNamespace        []byte = []byte("tsid")
+	facetsNamespace    []byte = []byte("fac")
+	filterNamespace    []byte = []byte("flt")
+	filterGenNamespace []byte = []byte("fgen")
+	filterGenZero      []byte = []byte("0")
+	tsidOK             []byte = []byte("1")
 )
 
+// tsidRouter - returns the memcached router of the serie, the same raw hash bytes used by the collector
+func tsidRouter(tsid string) []byte {
+
+	router, err := hex.DecodeString(tsid)
+	if err != nil {
+		return []byte(tsid)
+	}
+
+	return router
+}
+
 // isIDCached - checks if a document id is cached
 func (sb *SolrBackend) isIDCached(collection, tsType string, tsid string, tsidBytes []byte) (bool, error) {
 
@@ -56,7 +72,7 @@ func (sb *SolrBackend) cacheID(collection, tsType, tsid string, tsidBytes []byte
 // deleteID - remove cached id
 func (sb *SolrBackend) deleteCachedID(collection, tsType, tsid string) error {
 
-	err := sb.memcached.Delete([]byte(tsid), idNamespace, collection, tsType, tsid)
+	err := sb.memcached.Delete(tsidRouter(tsid), idNamespace, collection, tsType, tsid)
 	if err != nil {
 		return err
 	}
@@ -105,12 +121,14 @@ func (sb *SolrBackend) cacheKeysets() {
 	}
 
 	if len(keysets) > 0 {
+		sb.keysetsMutex.RLock()
 		filteredKeysets := []string{}
 		for i := 0; i < len(keysets); i++ {
-			if _, ok := sb.blacklistedKeysetMap[keysets[i]]; !ok {
+			if _, ok := sb.blacklistedKeysetMap[keysets[i]]; !ok && !sb.deletedKeysetMap[keysets[i]] {
 				filteredKeysets = append(filteredKeysets, keysets[i])
 			}
 		}
+		sb.keysetsMutex.RUnlock()
 
 		sb.cachedKeysets = filteredKeysets
 
@@ -119,6 +137,27 @@ func (sb *SolrBackend) cacheKeysets() {
 	}
 }
 
+// SetDeletedKeysets - hides the soft deleted keysets, their collections are not listed or checked until restored
+func (sb *SolrBackend) SetDeletedKeysets(keysets []string) {
+
+	deleted := make(map[string]bool, len(keysets))
+	for _, keyset := range keysets {
+		deleted[keyset] = true
+	}
+
+	sb.keysetsMutex.Lock()
+	changed := len(deleted) != len(sb.deletedKeysetMap)
+	for keyset := range deleted {
+		changed = changed || !sb.deletedKeysetMap[keyset]
+	}
+	sb.deletedKeysetMap = deleted
+	sb.keysetsMutex.Unlock()
+
+	if changed {
+		sb.cacheKeysets()
+	}
+}
+
 // deleteKeysetMap - deletes the cached keyset map
 func (sb *SolrBackend) deleteCachedKeysets() {
 
@@ -178,3 +217,83 @@ func (sb *SolrBackend) cacheFacets(facets []string, collection, query string) er
 
 	return nil
 }
+
+// cachedFilter - the cached metadata filter result
+type cachedFilter struct {
+	Total     int        `json:"total"`
+	Metadatas []Metadata `json:"metadatas"`
+}
+
+// filterGeneration - returns the filter generation of the metric, it changes every time the metric has a new serie
+func (sb *SolrBackend) filterGeneration(collection, tsType, metric string) ([]byte, error) {
+
+	data, exists, err := sb.memcached.Get([]byte(metric), filterGenNamespace, collection, tsType, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return filterGenZero, nil
+	}
+
+	return data, nil
+}
+
+// filterHash - hashes the metadata filter query, including the metric filter generation
+func (sb *SolrBackend) filterHash(collection, tsType, metric, q string, qfs []string, from, maxResults int) ([]byte, error) {
+
+	generation, err := sb.filterGeneration(collection, tsType, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	return sb.hash(collection, string(generation), q, strings.Join(qfs, constants.StringsWhitespace), strconv.Itoa(from), strconv.Itoa(maxResults))
+}
+
+// getCachedFilter - returns the cached metadata filter result
+func (sb *SolrBackend) getCachedFilter(collection string, hash []byte) ([]Metadata, int, bool, error) {
+
+	data, exists, err := sb.memcached.Get(hash, filterNamespace, collection, hex.EncodeToString(hash))
+	if err != nil || !exists {
+		return nil, 0, false, err
+	}
+
+	cached := cachedFilter{}
+
+	err = json.Unmarshal(data, &cached)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	return cached.Metadatas, cached.Total, true, nil
+}
+
+// cacheFilter - caches the metadata filter result
+func (sb *SolrBackend) cacheFilter(collection string, hash []byte, metadatas []Metadata, total int) error {
+
+	data, err := json.Marshal(cachedFilter{Total: total, Metadatas: metadatas})
+	if err != nil {
+		return err
+	}
+
+	return sb.memcached.Put(hash, data, sb.queryCacheTTL, filterNamespace, collection, hex.EncodeToString(hash))
+}
+
+const funcInvalidateFilterCache string = "InvalidateFilterCache"
+
+// InvalidateFilterCache - invalidates all cached metadata filters of the metric changing its generation
+func (sb *SolrBackend) InvalidateFilterCache(collection, tsType, metric string) gobol.Error {
+
+	if sb.noQueryCache {
+		return nil
+	}
+
+	generation := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+
+	err := sb.memcached.Put([]byte(metric), generation, sb.queryCacheTTL, filterGenNamespace, collection, tsType, metric)
+	if err != nil {
+		return errInternalServer(funcInvalidateFilterCache, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uol/funks"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// Tracks when each serie was first and last seen, the last seen timestamp is updated once per
+// granularity, and removes the metadata of the series without points in any keyspace
+
+// ExpirySettings - the series last seen tracking and stale metadata expiry settings
+type ExpirySettings struct {
+	// Granularity - the last seen timestamp is updated at most once per granularity, disabled when zero
+	Granularity funks.Duration
+	// Reaper - enables the removal of the stale series metadata
+	Reaper bool
+	// ReaperInterval - the interval between the stale series searches
+	ReaperInterval funks.Duration
+	// GracePeriod - the time the metadata is kept after the points of the serie expired
+	GracePeriod funks.Duration
+}
+
+const (
+	fieldFirstSeen string = "first_seen"
+	fieldLastSeen  string = "last_seen"
+
+	queryStaleSeries    string = "parent_doc:true AND (last_seen:[* TO %d] OR (*:* -last_seen:[* TO *] AND creation_date:[* TO %s]))"
+	queryStaleChildren  string = "{!child of=\"parent_doc:true\"}" + queryStaleSeries
+	funcTouchDocument   string = "TouchDocument"
+	funcReapStaleSeries string = "reapStaleSeries"
+)
+
+var seenNamespace []byte = []byte("seen")
+
+// seenSet - the series this node already marked as seen in the current granularity bucket
+type seenSet struct {
+	mutex  sync.Mutex
+	bucket string
+	series map[string]struct{}
+}
+
+// add - adds the serie to the bucket, returns false when it was already there, the set is cleared when the bucket changes
+func (s *seenSet) add(bucket, key string) bool {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.bucket != bucket {
+		s.bucket = bucket
+		s.series = map[string]struct{}{}
+	}
+
+	if _, ok := s.series[key]; ok {
+		return false
+	}
+
+	s.series[key] = struct{}{}
+
+	return true
+}
+
+func validateExpiry(conf ExpirySettings) error {
+
+	if conf.Granularity.Duration < 0 {
+		return fmt.Errorf("the last seen granularity can not be negative")
+	}
+
+	if !conf.Reaper {
+		return nil
+	}
+
+	if conf.Granularity.Duration == 0 {
+		return fmt.Errorf("the stale series reaper needs the last seen tracking, set a granularity")
+	}
+
+	if conf.ReaperInterval.Duration <= 0 || conf.GracePeriod.Duration < conf.Granularity.Duration {
+		return fmt.Errorf("the reaper interval needs to be bigger than zero and the grace period at least the granularity")
+	}
+
+	return nil
+}
+
+// activeFilter - returns the filter of the series seen within the duration, the start is truncated
+// to the granularity, the last seen timestamps are not more precise than it and the query stays cacheable
+func (sb *SolrBackend) activeFilter(activeWithin time.Duration) string {
+
+	if activeWithin <= 0 {
+		return constants.StringsEmpty
+	}
+
+	granularity := int64(sb.granularity / time.Second)
+	if granularity < 60 {
+		granularity = 60
+	}
+
+	since := time.Now().Add(-activeWithin).Unix()
+	since -= since % granularity
+
+	return fieldLastSeen + ":[" + strconv.FormatInt(since, 10) + " TO *]"
+}
+
+// seenBucket - returns the granularity bucket of the timestamp
+func (sb *SolrBackend) seenBucket(timestamp int64) string {
+
+	return strconv.FormatInt(timestamp-timestamp%int64(sb.granularity/time.Second), 10)
+}
+
+// cacheSeen - marks the serie as seen in the current granularity bucket
+func (sb *SolrBackend) cacheSeen(collection, tsType, tsid string, timestamp int64) error {
+
+	bucket := sb.seenBucket(timestamp)
+
+	sb.seen.add(bucket, pendingKey(collection, tsType, tsid))
+
+	ttl := []byte(strconv.FormatInt(int64(sb.granularity/time.Second), 10))
+
+	return sb.memcached.Put(tsidRouter(tsid), tsidOK, ttl, seenNamespace, collection, tsType, tsid, bucket)
+}
+
+// TouchDocument - updates the last seen timestamp of the serie, once per granularity, memcached is only
+// read by the first point of the serie this node receives in the bucket, it shares the bucket with the other nodes,
+// returns true when the serie was touched in the bucket
+func (sb *SolrBackend) TouchDocument(collection string, m *Metadata) (bool, gobol.Error) {
+
+	if sb.granularity <= 0 {
+		return false, nil
+	}
+
+	now := time.Now().Unix()
+	bucket := sb.seenBucket(now)
+
+	if !sb.seen.add(bucket, pendingKey(collection, m.MetaType, m.ID)) {
+		return false, nil
+	}
+
+	_, seen, err := sb.memcached.Get(tsidRouter(m.ID), seenNamespace, collection, m.MetaType, m.ID, bucket)
+	if err != nil {
+		return false, errInternalServer(funcTouchDocument, err)
+	}
+
+	if seen {
+		return false, nil
+	}
+
+	err = sb.cacheSeen(collection, m.MetaType, m.ID, now)
+	if err != nil {
+		return false, errInternalServer(funcTouchDocument, err)
+	}
+
+	m.FirstSeen = 0
+	m.LastSeen = now
+
+	// the last seen timestamp is updated in the next bucket, the series row is still written
+	if !sb.indexer.add(collection, m) {
+		sb.statsIndexerQueueFull(funcTouchDocument, collection)
+	}
+
+	return true, nil
+}
+
+// StartReaper - removes periodically the metadata of the series not seen for the retention plus the grace period,
+// the retention is read on every run, it follows the keyspaces created and changed after the start
+func (sb *SolrBackend) StartReaper(retention func() time.Duration) {
+
+	if !sb.expiry.Reaper {
+		return
+	}
+
+	if logh.InfoEnabled {
+		sb.logger.Info().Msgf("starting the stale series reaper, retention: %s, grace period: %s", retention(), sb.expiry.GracePeriod.Duration)
+	}
+
+	// the series indexed before the last seen tracking only have the creation date, the active ones are
+	// touched in the first granularity, so the first search waits for it
+	wait := sb.expiry.ReaperInterval.Duration
+	if wait < sb.granularity {
+		wait = sb.granularity
+	}
+
+	go func() {
+		for {
+			<-time.After(wait)
+			wait = sb.expiry.ReaperInterval.Duration
+
+			cutoff := time.Now().Add(-retention() - sb.expiry.GracePeriod.Duration)
+
+			for _, collection := range sb.ListKeysets() {
+				sb.reapStaleSeries(collection, cutoff)
+			}
+		}
+	}()
+}
+
+// reapStaleSeries - removes the series last seen before the cutoff and their tag documents
+func (sb *SolrBackend) reapStaleSeries(collection string, cutoff time.Time) {
+
+	start := time.Now()
+
+	lastSeen, created := cutoff.Unix(), cutoff.UTC().Format(time.RFC3339)
+
+	r, err := sb.solrService.SimpleQuery(collection, fmt.Sprintf(queryStaleSeries, lastSeen, created), constants.StringsEmpty, 0, 0)
+	if err != nil {
+		sb.statsError(funcReapStaleSeries, collection, cAllMetaTypes, solrQuery)
+		if logh.ErrorEnabled {
+			sb.log(sb.logger.Error(), funcReapStaleSeries, collection).Err(err).Msg("error searching the stale series")
+		}
+		return
+	}
+
+	if r.Results.NumFound == 0 {
+		return
+	}
+
+	for _, query := range []string{queryStaleChildren, queryStaleSeries} {
+
+		err = sb.solrService.DeleteDocumentByQuery(collection, true, fmt.Sprintf(query, lastSeen, created))
+		if err != nil {
+			sb.statsError(funcReapStaleSeries, collection, cAllMetaTypes, solrDelete)
+			if logh.ErrorEnabled {
+				sb.log(sb.logger.Error(), funcReapStaleSeries, collection).Err(err).Msg("error removing the stale series")
+			}
+			return
+		}
+	}
+
+	sb.statsRequest(funcReapStaleSeries, collection, cAllMetaTypes, solrDelete, time.Since(start))
+	sb.statsReapedSeries(funcReapStaleSeries, collection, r.Results.NumFound)
+
+	if logh.InfoEnabled {
+		sb.log(sb.logger.Info(), funcReapStaleSeries, collection).Msgf("%d stale series removed", r.Results.NumFound)
+	}
+}
@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"time"
+
+	"github.com/uol/go-solr/solr"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+	"github.com/uol/restrictedhttpclient"
+)
+
+const funcImportDocuments string = "ImportDocuments"
+
+// ImportDocuments - indexes the documents at once keeping their first and last seen timestamps,
+// used to load the metadata exported from another keyset
+func (sb *SolrBackend) ImportDocuments(collection string, metas []*Metadata) gobol.Error {
+
+	if len(metas) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	now := start.Unix()
+
+	docs := make([]solr.Document, len(metas))
+	for i, m := range metas {
+
+		if m.LastSeen == 0 {
+			m.LastSeen = now
+		}
+
+		if m.FirstSeen == 0 || m.FirstSeen > m.LastSeen {
+			m.FirstSeen = m.LastSeen
+		}
+
+		doc, _ := sb.toDocument(m, collection)
+		docs[i] = *doc
+	}
+
+	err := sb.indexer.send(collection, docs)
+	if err != nil {
+		sb.statsError(funcImportDocuments, collection, cAllMetaTypes, solrNewDoc)
+		if err == restrictedhttpclient.ErrMaxRequestsReached {
+			return errServiceUnavailable(funcImportDocuments, err)
+		}
+		return errInternalServer(funcImportDocuments, err)
+	}
+
+	sb.statsRequest(funcImportDocuments, collection, cAllMetaTypes, solrNewDoc, time.Since(start))
+
+	metrics := map[indexedMetric]bool{}
+
+	for _, m := range metas {
+		if err := sb.cacheID(collection, m.MetaType, m.ID, tsidRouter(m.ID)); err != nil && logh.ErrorEnabled {
+			sb.log(sb.logger.Error(), funcImportDocuments, collection).Err(err).Msg("error caching the document id")
+		}
+		metrics[indexedMetric{tsType: m.MetaType, metric: m.Metric}] = true
+	}
+
+	for im := range metrics {
+		if gerr := sb.InvalidateFilterCache(collection, im.tsType, im.metric); gerr != nil && logh.ErrorEnabled {
+			sb.log(sb.logger.Error(), funcImportDocuments, collection).Err(gerr).Msg("error invalidating the metadata filter cache")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,449 @@
+package metadata
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uol/funks"
+	"github.com/uol/go-solr/solr"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+	"github.com/uol/restrictedhttpclient"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// Indexes the new metadata documents in batches: the concurrent first seen series are deduplicated,
+// the documents are buffered per collection and sent on size or interval using soft commits,
+// so the points ingestion never waits for solr
+
+// IndexerSettings - the metadata indexing pipeline settings
+type IndexerSettings struct {
+	// BatchSize - the number of buffered documents of a collection triggering a flush
+	BatchSize int
+	// MaxQueued - the maximum number of documents waiting to be indexed
+	MaxQueued int
+	// FlushInterval - the interval to flush all the buffered documents
+	FlushInterval funks.Duration
+	// CommitWithin - the time solr has to make the documents visible, a soft commit is done when zero
+	CommitWithin funks.Duration
+	// MaxRetries - the number of retries when the maximum number of solr requests was reached
+	MaxRetries int
+	// RetryInterval - the interval between the retries
+	RetryInterval funks.Duration
+}
+
+const (
+	funcIndexDocument  string = "IndexDocument"
+	funcFlushDocuments string = "flushDocuments"
+	cAllMetaTypes      string = "all"
+	queryFirstSeen     string = "parent_doc:true AND id:(%s)"
+	fieldListFirstSeen string = "id,first_seen,creation_date"
+	firstSeenChunkSize int    = 100
+)
+
+type indexedMetric struct {
+	tsType string
+	metric string
+}
+
+type indexer struct {
+	sb            *SolrBackend
+	url           string
+	updateClient  *restrictedhttpclient.Instance
+	interfaces    sync.Map
+	mutex         sync.Mutex
+	buffers       map[string][]*Metadata
+	pending       map[string]struct{}
+	queued        int
+	batchSize     int
+	maxQueued     int
+	flushInterval time.Duration
+	updateParams  *url.Values
+	maxRetries    int
+	retryInterval time.Duration
+	commitWithin  time.Duration
+	invalidations sync.WaitGroup
+	full          chan string
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+func newIndexer(sb *SolrBackend, settings *Settings) (*indexer, error) {
+
+	conf := settings.Indexer
+
+	if conf.BatchSize < 1 || conf.MaxQueued < conf.BatchSize {
+		return nil, fmt.Errorf("the indexer batch size needs to be bigger than zero and the max queued at least the batch size")
+	}
+
+	if conf.FlushInterval.Duration <= 0 || conf.MaxRetries < 0 || conf.RetryInterval.Duration < 0 {
+		return nil, fmt.Errorf("the indexer flush interval needs to be bigger than zero and the retries can not be negative")
+	}
+
+	updateClient, err := restrictedhttpclient.New(settings.UpdateClient)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &url.Values{}
+	if conf.CommitWithin.Duration > 0 {
+		params.Add("commitWithin", strconv.FormatInt(int64(conf.CommitWithin.Duration/time.Millisecond), 10))
+	} else {
+		params.Add("softCommit", "true")
+	}
+
+	return &indexer{
+		sb:            sb,
+		url:           settings.URL,
+		updateClient:  updateClient,
+		buffers:       map[string][]*Metadata{},
+		pending:       map[string]struct{}{},
+		batchSize:     conf.BatchSize,
+		maxQueued:     conf.MaxQueued,
+		flushInterval: conf.FlushInterval.Duration,
+		updateParams:  params,
+		maxRetries:    conf.MaxRetries,
+		retryInterval: conf.RetryInterval.Duration,
+		commitWithin:  conf.CommitWithin.Duration,
+		full:          make(chan string, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+func (idx *indexer) start() {
+
+	go func() {
+
+		ticker := time.NewTicker(idx.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case collection := <-idx.full:
+				idx.flush(collection)
+			case <-ticker.C:
+				idx.flushAll()
+			case <-idx.stop:
+				idx.flushAll()
+				close(idx.done)
+				return
+			}
+		}
+	}()
+}
+
+// shutdown - indexes all the buffered documents, stops the flush loop and waits the delayed invalidations
+func (idx *indexer) shutdown() {
+
+	close(idx.stop)
+	<-idx.done
+	idx.invalidations.Wait()
+}
+
+func pendingKey(collection, tsType, tsid string) string {
+
+	return collection + constants.StringsBar + tsType + constants.StringsBar + tsid
+}
+
+// isPending - checks if the document is waiting to be indexed
+func (idx *indexer) isPending(collection, tsType, tsid string) bool {
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	_, ok := idx.pending[pendingKey(collection, tsType, tsid)]
+
+	return ok
+}
+
+// add - buffers the document, the ones already waiting to be indexed are ignored, false when the queue is full
+func (idx *indexer) add(collection string, m *Metadata) bool {
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	key := pendingKey(collection, m.MetaType, m.ID)
+
+	if _, ok := idx.pending[key]; ok {
+		return true
+	}
+
+	if idx.queued >= idx.maxQueued {
+		return false
+	}
+
+	idx.pending[key] = struct{}{}
+	idx.buffers[collection] = append(idx.buffers[collection], m)
+	idx.queued++
+
+	if len(idx.buffers[collection]) >= idx.batchSize {
+		select {
+		case idx.full <- collection:
+		default:
+		}
+	}
+
+	return true
+}
+
+// take - removes the buffered documents of the collection
+func (idx *indexer) take(collection string) []*Metadata {
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	docs := idx.buffers[collection]
+	delete(idx.buffers, collection)
+
+	return docs
+}
+
+// release - removes the documents from the pending ones
+func (idx *indexer) release(collection string, docs []*Metadata) {
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	for _, m := range docs {
+		delete(idx.pending, pendingKey(collection, m.MetaType, m.ID))
+	}
+
+	idx.queued -= len(docs)
+}
+
+func (idx *indexer) flushAll() {
+
+	idx.mutex.Lock()
+	collections := make([]string, 0, len(idx.buffers))
+	for collection := range idx.buffers {
+		collections = append(collections, collection)
+	}
+	idx.mutex.Unlock()
+
+	for _, collection := range collections {
+		idx.flush(collection)
+	}
+
+	idx.mutex.Lock()
+	queued := idx.queued
+	idx.mutex.Unlock()
+
+	idx.sb.statsIndexerQueue(funcFlushDocuments, queued)
+}
+
+func (idx *indexer) solrInterface(collection string) (*solr.SolrInterface, error) {
+
+	if si, ok := idx.interfaces.Load(collection); ok {
+		return si.(*solr.SolrInterface), nil
+	}
+
+	si, err := solr.NewSolrInterface(idx.url, collection, nil, idx.updateClient)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.interfaces.Store(collection, si)
+
+	return si, nil
+}
+
+// flush - indexes the buffered documents of the collection, the failed ones are forgotten and
+// indexed again when their series receive new points
+func (idx *indexer) flush(collection string) {
+
+	metas := idx.take(collection)
+	if len(metas) == 0 {
+		return
+	}
+
+	defer idx.release(collection, metas)
+
+	start := time.Now()
+
+	created := make([]*Metadata, 0, len(metas))
+	touched := make([]*Metadata, 0, len(metas))
+
+	for _, m := range metas {
+		if m.FirstSeen == 0 {
+			touched = append(touched, m)
+		} else {
+			created = append(created, m)
+		}
+	}
+
+	if len(touched) > 0 {
+		err := idx.resolveFirstSeen(collection, touched)
+		if err != nil {
+			idx.sb.statsError(funcFlushDocuments, collection, cAllMetaTypes, solrDocID)
+			if logh.ErrorEnabled {
+				idx.sb.log(idx.sb.logger.Error(), funcFlushDocuments, collection).Err(err).Msgf("error reading the first seen timestamps, %d last seen updates discarded", len(touched))
+			}
+			touched = touched[:0]
+		}
+	}
+
+	docs := make([]solr.Document, 0, len(metas))
+	for _, m := range append(created, touched...) {
+		doc, _ := idx.sb.toDocument(m, collection)
+		docs = append(docs, *doc)
+	}
+
+	if len(docs) == 0 {
+		return
+	}
+
+	err := idx.send(collection, docs)
+	if err != nil {
+		idx.sb.statsError(funcFlushDocuments, collection, cAllMetaTypes, solrNewDoc)
+		idx.sb.statsIndexerDropped(funcFlushDocuments, collection, len(docs))
+		if logh.ErrorEnabled {
+			idx.sb.log(idx.sb.logger.Error(), funcFlushDocuments, collection).Err(err).Msgf("error indexing %d documents", len(docs))
+		}
+		return
+	}
+
+	idx.sb.statsRequest(funcFlushDocuments, collection, cAllMetaTypes, solrNewDoc, time.Since(start))
+
+	if logh.DebugEnabled {
+		idx.sb.log(idx.sb.logger.Debug(), funcFlushDocuments, collection).Msgf("%d documents indexed", len(docs))
+	}
+
+	metrics := map[indexedMetric]bool{}
+
+	for _, m := range created {
+		if err := idx.sb.cacheID(collection, m.MetaType, m.ID, tsidRouter(m.ID)); err != nil && logh.ErrorEnabled {
+			idx.sb.log(idx.sb.logger.Error(), funcFlushDocuments, collection).Err(err).Msg("error caching the document id")
+		}
+		metrics[indexedMetric{tsType: m.MetaType, metric: m.Metric}] = true
+	}
+
+	idx.invalidateFilters(collection, metrics)
+}
+
+// invalidateFilters - invalidates the cached filters of the metrics with new series once solr makes the
+// documents visible, otherwise a filter done before the commit would be cached again without them
+func (idx *indexer) invalidateFilters(collection string, metrics map[indexedMetric]bool) {
+
+	if len(metrics) == 0 {
+		return
+	}
+
+	invalidate := func() {
+		for im := range metrics {
+			if gerr := idx.sb.InvalidateFilterCache(collection, im.tsType, im.metric); gerr != nil && logh.ErrorEnabled {
+				idx.sb.log(idx.sb.logger.Error(), funcFlushDocuments, collection).Err(gerr).Msg("error invalidating the metadata filter cache")
+			}
+		}
+	}
+
+	if idx.commitWithin <= 0 {
+		invalidate()
+		return
+	}
+
+	idx.invalidations.Add(1)
+
+	time.AfterFunc(idx.commitWithin, func() {
+		defer idx.invalidations.Done()
+		invalidate()
+	})
+}
+
+// resolveFirstSeen - reads the first seen timestamps of the touched series, their documents are
+// replaced with all the fields, the ones not found were removed and start being seen again
+func (idx *indexer) resolveFirstSeen(collection string, touched []*Metadata) error {
+
+	for i := 0; i < len(touched); i += firstSeenChunkSize {
+
+		end := i + firstSeenChunkSize
+		if end > len(touched) {
+			end = len(touched)
+		}
+
+		chunk := touched[i:end]
+
+		ids := make([]string, len(chunk))
+		for j, m := range chunk {
+			ids[j] = m.ID
+		}
+
+		q := fmt.Sprintf(queryFirstSeen, strings.Join(ids, " OR "))
+
+		r, err := idx.sb.solrService.SimpleQuery(collection, q, fieldListFirstSeen, 0, len(chunk))
+		if err != nil {
+			return err
+		}
+
+		found := make(map[string]int64, len(chunk))
+		for _, doc := range r.Results.Docs {
+			if id, ok := doc.Get("id").(string); ok {
+				found[id] = documentFirstSeen(doc)
+			}
+		}
+
+		for _, m := range chunk {
+			if firstSeen := found[m.ID]; firstSeen > 0 {
+				m.FirstSeen = firstSeen
+			} else {
+				m.FirstSeen = m.LastSeen
+			}
+		}
+	}
+
+	return nil
+}
+
+// send - adds the documents, retrying when the maximum number of solr requests was reached
+func (idx *indexer) send(collection string, docs []solr.Document) error {
+
+	si, err := idx.solrInterface(collection)
+	if err != nil {
+		return err
+	}
+
+	for retry := 0; ; retry++ {
+
+		_, err = si.Add(docs, len(docs), idx.updateParams)
+		if err != restrictedhttpclient.ErrMaxRequestsReached || retry >= idx.maxRetries {
+			return err
+		}
+
+		idx.sb.statsIndexerRetry(funcFlushDocuments, collection)
+
+		time.Sleep(idx.retryInterval)
+	}
+}
+
+// IndexDocument - queues a new document to be indexed in the next batch of its collection
+func (sb *SolrBackend) IndexDocument(collection string, m *Metadata) gobol.Error {
+
+	now := time.Now().Unix()
+
+	m.FirstSeen = now
+	m.LastSeen = now
+
+	if sb.granularity > 0 {
+		if err := sb.cacheSeen(collection, m.MetaType, m.ID, now); err != nil && logh.ErrorEnabled {
+			sb.log(sb.logger.Error(), funcIndexDocument, collection).Err(err).Msg("error caching the last seen bucket")
+		}
+	}
+
+	// the point is still stored, the document is indexed again by the next point of the serie
+	if !sb.indexer.add(collection, m) {
+		sb.statsIndexerQueueFull(funcIndexDocument, collection)
+	}
+
+	return nil
+}
+
+// Shutdown - indexes all the queued documents
+func (sb *SolrBackend) Shutdown() {
+
+	sb.indexer.shutdown()
+}
@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"time"
+
 	"github.com/uol/gobol"
 	"github.com/uol/gobol/solar"
 	"github.com/uol/logh"
@@ -23,14 +25,17 @@ type Backend interface {
 	// CheckKeyset - verifies if a keyset exists
 	CheckKeyset(keyset string) bool
 
+	// SetDeletedKeysets - hides the soft deleted keysets
+	SetDeletedKeysets(keysets []string)
+
 	// FilterTagValues - filter tag values from a collection
-	FilterTagValues(collection, prefix string, maxResults int) ([]string, int, gobol.Error)
+	FilterTagValues(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error)
 
 	// FilterTagKeys - filter tag keys from a collection
-	FilterTagKeys(collection, prefix string, maxResults int) ([]string, int, gobol.Error)
+	FilterTagKeys(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error)
 
 	// FilterMetrics - filter metrics from a collection
-	FilterMetrics(collection, prefix string, maxResults int) ([]string, int, gobol.Error)
+	FilterMetrics(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error)
 
 	// FilterMetadata - list all metas from a collection
 	// Returns: results, total and gobol.Error
@@ -39,9 +44,18 @@ type Backend interface {
 	// AddDocument - add/update a document
 	AddDocument(collection string, metadata *Metadata) gobol.Error
 
+	// IndexDocument - queues a new document to be indexed in batches
+	IndexDocument(collection string, metadata *Metadata) gobol.Error
+
+	// Shutdown - indexes all the queued documents
+	Shutdown()
+
 	// CheckMetadata - verifies if a metadata exists
 	CheckMetadata(collection, tsType, tsid string, tsidBytes []byte) (bool, gobol.Error)
 
+	// CountSeries - returns the number of series of the collection
+	CountSeries(collection string) (int, gobol.Error)
+
 	// SetRegexValue - add slashes to the value
 	SetRegexValue(value string) string
 
@@ -51,11 +65,35 @@ type Backend interface {
 	// DeleteDocumentByID - delete a document by ID and its child documents
 	DeleteDocumentByID(collection, tsType, id string) gobol.Error
 
+	// InvalidateFilterCache - invalidates the cached metadata filters of a metric
+	InvalidateFilterCache(collection, tsType, metric string) gobol.Error
+
 	// FilterTagKeysByMetric - filter tag values from a collection given its metric
-	FilterTagKeysByMetric(collection, tsType, metric, prefix string, maxResults int) ([]string, int, gobol.Error)
+	FilterTagKeysByMetric(collection, tsType, metric, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error)
 
 	// FilterTagValuesByMetricAndTag - filter tag values from a collection given its metric and tag
-	FilterTagValuesByMetricAndTag(collection, tsType, metric, tag, prefix string, maxResults int) ([]string, int, gobol.Error)
+	FilterTagValuesByMetricAndTag(collection, tsType, metric, tag, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error)
+
+	// TouchDocument - updates the last seen timestamp of a serie, true when it was updated
+	TouchDocument(collection string, metadata *Metadata) (bool, gobol.Error)
+
+	// StartReaper - starts the removal of the series not seen for the retention, read on every run
+	StartReaper(retention func() time.Duration)
+
+	// FindDocumentIDs - returns the ids having a document of the type in the collection
+	FindDocumentIDs(collection, tsType string, ids []string) (map[string]bool, gobol.Error)
+
+	// ScanDocumentIDs - returns a page of the document ids of the type and the cursor of the next one
+	ScanDocumentIDs(collection, tsType, cursor string, size int) ([]string, string, gobol.Error)
+
+	// ScanDocuments - returns a page of the documents of the type and the cursor of the next one
+	ScanDocuments(collection, tsType, cursor string, size int) ([]Metadata, string, gobol.Error)
+
+	// FlushCachedID - removes the cached id of a serie
+	FlushCachedID(collection, tsType, tsid string) gobol.Error
+
+	// ImportDocuments - indexes the documents at once keeping their first and last seen timestamps
+	ImportDocuments(collection string, metas []*Metadata) gobol.Error
 }
 
 // Storage is a storage for metadata
@@ -77,17 +115,21 @@ type Settings struct {
 	BlacklistedKeysets            []string
 	CacheKeyHashSize              int
 	KeysetCacheAutoUpdateInterval string
+	Indexer                       IndexerSettings
+	Expiry                        ExpirySettings
 	solar.Configuration
 }
 
 // Metadata document
 type Metadata struct {
-	ID       string   `json:"id"`
-	Metric   string   `json:"metric"`
-	TagKey   []string `json:"tagKey"`
-	TagValue []string `json:"tagValue"`
-	MetaType string   `json:"type"`
-	Keyset   string   `json:"keyset"`
+	ID        string   `json:"id"`
+	Metric    string   `json:"metric"`
+	TagKey    []string `json:"tagKey"`
+	TagValue  []string `json:"tagValue"`
+	MetaType  string   `json:"type"`
+	Keyset    string   `json:"keyset"`
+	FirstSeen int64    `json:"firstSeen,omitempty"`
+	LastSeen  int64    `json:"lastSeen,omitempty"`
 }
 
 // Query - query
@@ -96,6 +138,12 @@ type Query struct {
 	MetaType string     `json:"type"`
 	Regexp   bool       `json:regexp`
 	Tags     []QueryTag `json:"tags"`
+
+	// ID - only the serie with the id, all when empty
+	ID string `json:"id"`
+
+	// ActiveWithin - only the series seen within the duration, all when zero
+	ActiveWithin time.Duration `json:"activeWithin"`
 }
 
 // QueryTag - tags for query
@@ -104,6 +152,9 @@ type QueryTag struct {
 	Values []string `json:value`
 	Negate bool     `json:negate`
 	Regexp bool     `json:regexp`
+
+	// Pair - the key and the single value must be in the same tag
+	Pair bool `json:"pair"`
 }
 
 // Create creates a metadata handler
@@ -0,0 +1,131 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uol/go-solr/solr"
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// Supports the reindex job: finds which series have documents, scans the document ids of a
+// collection and flushes the cached ids hiding the missing documents
+
+const (
+	funcFindDocumentIDs string = "FindDocumentIDs"
+	funcScanDocumentIDs string = "ScanDocumentIDs"
+	funcScanDocuments   string = "ScanDocuments"
+	funcFlushCachedID   string = "FlushCachedID"
+	queryDocumentIDs    string = "parent_doc:true AND type:%s AND id:(%s)"
+	queryScanDocuments  string = "parent_doc:true AND type:%s"
+	cursorStart         string = "*"
+)
+
+// FindDocumentIDs - returns the ids having a document of the type in the collection
+func (sb *SolrBackend) FindDocumentIDs(collection, tsType string, ids []string) (map[string]bool, gobol.Error) {
+
+	found := make(map[string]bool, len(ids))
+
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	start := time.Now()
+
+	r, err := sb.solrService.SimpleQuery(collection, fmt.Sprintf(queryDocumentIDs, tsType, strings.Join(ids, " OR ")), "id", 0, len(ids))
+	if err != nil {
+		sb.statsError(funcFindDocumentIDs, collection, tsType, solrQuery)
+		return nil, errInternalServer(funcFindDocumentIDs, err)
+	}
+
+	sb.statsRequest(funcFindDocumentIDs, collection, tsType, solrQuery, time.Since(start))
+
+	for _, doc := range r.Results.Docs {
+		if id, ok := doc.Get("id").(string); ok {
+			found[id] = true
+		}
+	}
+
+	return found, nil
+}
+
+// scanDocuments - searches a page of the documents of the type sorted by id, returns the cursor of the next page
+func (sb *SolrBackend) scanDocuments(function, collection, tsType, cursor, fieldList string, size int) (*solr.SolrResult, string, gobol.Error) {
+
+	if cursor == constants.StringsEmpty {
+		cursor = cursorStart
+	}
+
+	si, err := sb.indexer.solrInterface(collection)
+	if err != nil {
+		return nil, constants.StringsEmpty, errInternalServer(function, err)
+	}
+
+	start := time.Now()
+
+	q := solr.NewQuery()
+	q.Q(fmt.Sprintf(queryScanDocuments, tsType))
+	q.FieldList(fieldList)
+	q.Sort("id asc")
+	q.Rows(size)
+	q.AddParam("cursorMark", cursor)
+
+	r, err := si.Search(q).Result(nil)
+	if err != nil {
+		sb.statsError(function, collection, tsType, solrQuery)
+		return nil, constants.StringsEmpty, errInternalServer(function, err)
+	}
+
+	sb.statsRequest(function, collection, tsType, solrQuery, time.Since(start))
+
+	if r.NextCursorMark == cursor || len(r.Results.Docs) == 0 {
+		return r, constants.StringsEmpty, nil
+	}
+
+	return r, r.NextCursorMark, nil
+}
+
+// ScanDocumentIDs - returns a page of the document ids of the type sorted by id and the cursor of the
+// next one, the scan starts with an empty cursor and ends when the returned cursor is empty
+func (sb *SolrBackend) ScanDocumentIDs(collection, tsType, cursor string, size int) ([]string, string, gobol.Error) {
+
+	r, next, gerr := sb.scanDocuments(funcScanDocumentIDs, collection, tsType, cursor, "id", size)
+	if gerr != nil {
+		return nil, constants.StringsEmpty, gerr
+	}
+
+	ids := make([]string, 0, len(r.Results.Docs))
+	for _, doc := range r.Results.Docs {
+		if id, ok := doc.Get("id").(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, next, nil
+}
+
+// ScanDocuments - returns a page of the documents of the type sorted by id with their tags and the cursor
+// of the next one, the pages do not move when documents are indexed or removed during the scan
+func (sb *SolrBackend) ScanDocuments(collection, tsType, cursor string, size int) ([]Metadata, string, gobol.Error) {
+
+	r, next, gerr := sb.scanDocuments(funcScanDocuments, collection, tsType, cursor, sb.fieldListQuery, size)
+	if gerr != nil {
+		return nil, constants.StringsEmpty, gerr
+	}
+
+	return sb.fromDocuments(r.Results, collection), next, nil
+}
+
+// FlushCachedID - removes the cached id of the serie, its next point checks the document again
+func (sb *SolrBackend) FlushCachedID(collection, tsType, tsid string) gobol.Error {
+
+	err := sb.deleteCachedID(collection, tsType, tsid)
+	if err != nil {
+		return errInternalServer(funcFlushCachedID, err)
+	}
+
+	return nil
+}
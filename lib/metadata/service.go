@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/uol/restrictedhttpclient"
@@ -38,11 +39,17 @@ type SolrBackend struct {
 	zookeeperConfig               string
 	maxReturnedMetadata           int
 	blacklistedKeysetMap          map[string]bool
+	deletedKeysetMap              map[string]bool
+	keysetsMutex                  sync.RWMutex
 	solrSpecialCharRegexp         *regexp.Regexp
 	solrRegexpSpecialCharRegexp   *regexp.Regexp
 	cacheKeyHashSize              int
 	cachedKeysets                 []string
 	keysetCacheAutoUpdateInterval time.Duration
+	indexer                       *indexer
+	expiry                        ExpirySettings
+	granularity                   time.Duration
+	seen                          seenSet
 }
 
 // NewSolrBackend - creates a new instance
@@ -53,9 +60,6 @@ func NewSolrBackend(settings *Settings, mc *tlmanager.Instance, memcached *memca
 		return nil, err
 	}
 
-	baseWordRegexp := "[0-9A-Za-z\\-\\.\\_\\%\\&\\#\\;\\/\\?]+(\\{[0-9]+\\})?"
-	rp := regexp.MustCompile("^\\.?\\*" + baseWordRegexp + "|" + baseWordRegexp + "\\.?\\*$|\\[" + baseWordRegexp + "\\][\\+\\*]{1}|\\(" + baseWordRegexp + "\\)|" + baseWordRegexp + "\\{[0-9]+\\}")
-
 	blacklistedKeysetMap := map[string]bool{}
 	for _, value := range settings.BlacklistedKeysets {
 		blacklistedKeysetMap[value] = true
@@ -66,6 +70,11 @@ func NewSolrBackend(settings *Settings, mc *tlmanager.Instance, memcached *memca
 		return nil, fmt.Errorf("error parsing keysetCacheAutoUpdateInterval")
 	}
 
+	err = validateExpiry(settings.Expiry)
+	if err != nil {
+		return nil, err
+	}
+
 	logger := logh.CreateContextualLogger(constants.StringsPKG, "metadata")
 
 	if logh.InfoEnabled {
@@ -78,7 +87,7 @@ func NewSolrBackend(settings *Settings, mc *tlmanager.Instance, memcached *memca
 		logger:                        logger,
 		replicationFactor:             settings.ReplicationFactor,
 		numShards:                     settings.NumShards,
-		regexPattern:                  rp,
+		regexPattern:                  newRegexPattern(),
 		memcached:                     memcached,
 		idCacheTTL:                    []byte(strconv.Itoa(settings.IDCacheTTL)),
 		noIDCache:                     settings.IDCacheTTL < 0,
@@ -92,10 +101,18 @@ func NewSolrBackend(settings *Settings, mc *tlmanager.Instance, memcached *memca
 		solrRegexpSpecialCharRegexp:   regexp.MustCompile(`(\/)`),
 		cacheKeyHashSize:              settings.CacheKeyHashSize,
 		keysetCacheAutoUpdateInterval: keysetCacheAutoUpdateIntervalDuration,
+		expiry:                        settings.Expiry,
+		granularity:                   settings.Expiry.Granularity.Duration,
+	}
+
+	sb.indexer, err = newIndexer(sb, settings)
+	if err != nil {
+		return nil, err
 	}
 
 	sb.cacheKeysets()
 	sb.autoUpdateCachedKeysets()
+	sb.indexer.start()
 
 	return sb, nil
 }
@@ -110,6 +127,14 @@ func (sb *SolrBackend) removeRegexpSlashes(value string) string {
 	return value
 }
 
+// newRegexPattern - matches the values using regular expressions
+func newRegexPattern() *regexp.Regexp {
+
+	baseWordRegexp := "[0-9A-Za-z\\-\\.\\_\\%\\&\\#\\;\\/\\?]+(\\{[0-9]+\\})?"
+
+	return regexp.MustCompile("^\\.?\\*" + baseWordRegexp + "|" + baseWordRegexp + "\\.?\\*$|\\[" + baseWordRegexp + "\\][\\+\\*]{1}|\\(" + baseWordRegexp + "\\)|" + baseWordRegexp + "\\{[0-9]+\\}")
+}
+
 // HasRegexPattern - check if the value has a regular expression
 func (sb *SolrBackend) HasRegexPattern(value string) bool {
 
@@ -189,11 +214,11 @@ func (sb *SolrBackend) cropFacets(facets []string, size int) []string {
 }
 
 // filterFieldValues - filter by field value using wildcard
-func (sb *SolrBackend) filterFieldValues(function, collection, field, value string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) filterFieldValues(function, collection, field, value string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	start := time.Now()
 
-	var query Query
+	query := Query{ActiveWithin: activeWithin}
 	var facetFields, childFacetFields []string
 
 	isRegex := sb.regexPattern.MatchString(value)
@@ -262,9 +287,9 @@ func (sb *SolrBackend) filterFieldValues(function, collection, field, value stri
 const funcFilterTagValues string = "FilterTagValues"
 
 // FilterTagValues - list all tag values from a collection
-func (sb *SolrBackend) FilterTagValues(collection, prefix string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) FilterTagValues(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
-	tags, total, err := sb.filterFieldValues(funcFilterTagValues, collection, "tag_value", prefix, maxResults)
+	tags, total, err := sb.filterFieldValues(funcFilterTagValues, collection, "tag_value", prefix, maxResults, activeWithin)
 	if err != nil {
 		if err == restrictedhttpclient.ErrMaxRequestsReached {
 			return nil, 0, errServiceUnavailable(funcFilterTagValues, err)
@@ -278,9 +303,9 @@ func (sb *SolrBackend) FilterTagValues(collection, prefix string, maxResults int
 const funcFilterTagKeys string = "FilterTagKeys"
 
 // FilterTagKeys - list all tag keys from a collection
-func (sb *SolrBackend) FilterTagKeys(collection, prefix string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) FilterTagKeys(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
-	tags, total, err := sb.filterFieldValues(funcFilterTagKeys, collection, "tag_key", prefix, maxResults)
+	tags, total, err := sb.filterFieldValues(funcFilterTagKeys, collection, "tag_key", prefix, maxResults, activeWithin)
 	if err != nil {
 		if err == restrictedhttpclient.ErrMaxRequestsReached {
 			return nil, 0, errServiceUnavailable(funcFilterTagKeys, err)
@@ -294,9 +319,9 @@ func (sb *SolrBackend) FilterTagKeys(collection, prefix string, maxResults int)
 const funcFilterMetrics string = "FilterMetrics"
 
 // FilterMetrics - list all metrics from a collection
-func (sb *SolrBackend) FilterMetrics(collection, prefix string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) FilterMetrics(collection, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
-	metrics, total, err := sb.filterFieldValues(funcFilterMetrics, collection, "metric", prefix, maxResults)
+	metrics, total, err := sb.filterFieldValues(funcFilterMetrics, collection, "metric", prefix, maxResults, activeWithin)
 	if err != nil {
 		if err == restrictedhttpclient.ErrMaxRequestsReached {
 			return nil, 0, errServiceUnavailable(funcFilterMetrics, err)
@@ -405,6 +430,14 @@ func (sb *SolrBackend) buildMetadataQuery(query *Query, parentQueryOnly bool) (s
 		parentQuery += " AND metric:" + query.Metric
 	}
 
+	if query.ID != constants.StringsEmpty {
+		parentQuery += " AND id:" + sb.escapeSolrSpecialChars(query.ID)
+	}
+
+	if active := sb.activeFilter(query.ActiveWithin); active != constants.StringsEmpty {
+		parentQuery += " AND " + active
+	}
+
 	parentQuery += "\"}"
 
 	numTags := len(query.Tags)
@@ -458,6 +491,13 @@ func (sb *SolrBackend) buildMetadataQuery(query *Query, parentQueryOnly bool) (s
 
 		numValues := len(query.Tags[i].Values)
 
+		if query.Tags[i].Pair && !query.Tags[i].Negate && numValues == 1 &&
+			!sb.leaveEmpty(query.Tags[i].Key) && !sb.leaveEmpty(query.Tags[i].Values[0]) {
+			filterQueries = append(filterQueries, fmt.Sprintf("{!parent which=\"parent_doc:true\"}(tag_key:%s AND tag_value:%s)",
+				sb.escapeSolrSpecialChars(query.Tags[i].Key), sb.escapeSolrSpecialChars(query.Tags[i].Values[0])))
+			continue
+		}
+
 		if !sb.leaveEmpty(query.Tags[i].Key) {
 			if query.Tags[i].Regexp {
 				query.Tags[i].Key = sb.SetRegexValue(query.Tags[i].Key)
@@ -492,13 +532,47 @@ func (sb *SolrBackend) buildMetadataQuery(query *Query, parentQueryOnly bool) (s
 
 const funcFilterMetadata string = "FilterMetadata"
 
+// filterCacheMetric - returns the metric generation the query cache depends on, only the exact metrics are cached,
+// they are invalidated when the metric has a new serie, so the metric must be read before the query escapes it
+func (sb *SolrBackend) filterCacheMetric(query *Query) (string, bool) {
+
+	if sb.noQueryCache || query.Regexp || query.MetaType == constants.StringsEmpty || sb.leaveEmpty(query.Metric) || sb.HasRegexPattern(query.Metric) {
+		return constants.StringsEmpty, false
+	}
+
+	return query.Metric, true
+}
+
 // FilterMetadata - list all metas from a collection
 func (sb *SolrBackend) FilterMetadata(collection string, query *Query, from, maxResults int) ([]Metadata, int, gobol.Error) {
 
 	start := time.Now()
 
+	metric, cacheable := sb.filterCacheMetric(query)
+
 	q, qfs := sb.buildMetadataQuery(query, false)
 
+	var hash []byte
+	if cacheable {
+
+		var err error
+		hash, err = sb.filterHash(collection, query.MetaType, metric, q, qfs, from, maxResults)
+		if err != nil {
+			if logh.ErrorEnabled {
+				sb.log(sb.logger.Error(), funcFilterMetadata, collection).Err(err).Msg("error hashing the metadata filter")
+			}
+		} else {
+			metadatas, total, cached, err := sb.getCachedFilter(collection, hash)
+			if err != nil && logh.ErrorEnabled {
+				sb.log(sb.logger.Error(), funcFilterMetadata, collection).Err(err).Msg("error getting the metadata filter from the cache")
+			}
+
+			if cached {
+				return metadatas, total, nil
+			}
+		}
+	}
+
 	r, err := sb.solrService.FilteredQuery(collection, q, sb.fieldListQuery, from, maxResults, qfs)
 	if err != nil {
 		sb.statsError(funcFilterMetadata, collection, query.MetaType, solrQuery)
@@ -510,7 +584,16 @@ func (sb *SolrBackend) FilterMetadata(collection string, query *Query, from, max
 
 	sb.statsRequest(funcFilterMetadata, collection, query.MetaType, solrQuery, time.Since(start))
 
-	return sb.fromDocuments(r.Results, collection), r.Results.NumFound, nil
+	metadatas := sb.fromDocuments(r.Results, collection)
+
+	if hash != nil {
+		err = sb.cacheFilter(collection, hash, metadatas, r.Results.NumFound)
+		if err != nil && logh.ErrorEnabled {
+			sb.log(sb.logger.Error(), funcFilterMetadata, collection).Err(err).Msg("error caching the metadata filter")
+		}
+	}
+
+	return metadatas, r.Results.NumFound, nil
 }
 
 // toDocument - changes the metadata to the document format
@@ -540,6 +623,11 @@ func (sb *SolrBackend) toDocument(metadata *Metadata, collection string) (docs *
 		"_childDocuments_": tagDocs,
 	}
 
+	if metadata.FirstSeen > 0 {
+		doc.Set(fieldFirstSeen, metadata.FirstSeen)
+		doc.Set(fieldLastSeen, metadata.LastSeen)
+	}
+
 	return doc, metadata.ID
 }
 
@@ -596,17 +684,45 @@ func (sb *SolrBackend) fromDocuments(results *solr.Collection, collection string
 		keys, values := sb.getTagKeysAndValues(&doc, collection)
 
 		metadatas[i] = Metadata{
-			ID:       doc.Get("id").(string),
-			MetaType: doc.Get("type").(string),
-			Metric:   doc.Get("metric").(string),
-			TagKey:   keys,
-			TagValue: values,
+			ID:        doc.Get("id").(string),
+			MetaType:  doc.Get("type").(string),
+			Metric:    doc.Get("metric").(string),
+			TagKey:    keys,
+			TagValue:  values,
+			FirstSeen: documentTimestamp(doc, fieldFirstSeen),
+			LastSeen:  documentTimestamp(doc, fieldLastSeen),
 		}
 	}
 
 	return metadatas
 }
 
+// documentTimestamp - returns the timestamp field in seconds, zero when the document does not have it
+func documentTimestamp(doc solr.Document, field string) int64 {
+
+	if value, ok := doc.Get(field).(float64); ok {
+		return int64(value)
+	}
+
+	return 0
+}
+
+// documentFirstSeen - returns the first seen timestamp, the documents indexed before the tracking only have the creation date
+func documentFirstSeen(doc solr.Document) int64 {
+
+	if firstSeen := documentTimestamp(doc, fieldFirstSeen); firstSeen > 0 {
+		return firstSeen
+	}
+
+	if created, ok := doc.Get("creation_date").(string); ok {
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			return t.Unix()
+		}
+	}
+
+	return 0
+}
+
 // log - add the common log fields
 func (sb *SolrBackend) log(event *zerolog.Event, funcName, keyset string) *zerolog.Event {
 	return event.Str(constants.StringsFunc, funcName).Str(constants.StringsKeyset, keyset)
@@ -634,7 +750,7 @@ func (sb *SolrBackend) AddDocument(collection string, m *Metadata) gobol.Error {
 		return errInternalServer(funcAddDocument, err)
 	}
 
-	go sb.cacheID(collection, m.MetaType, m.ID, []byte(m.ID))
+	go sb.cacheID(collection, m.MetaType, m.ID, tsidRouter(m.ID))
 
 	sb.statsRequest(funcAddDocument, collection, m.MetaType, solrNewDoc, time.Since(start))
 
@@ -649,6 +765,10 @@ const (
 // CheckMetadata - verifies if a metadata exists
 func (sb *SolrBackend) CheckMetadata(collection, tsType, tsid string, tsidBytes []byte) (bool, gobol.Error) {
 
+	if sb.indexer.isPending(collection, tsType, tsid) {
+		return true, nil
+	}
+
 	isCached, err := sb.isIDCached(collection, tsType, tsid, tsidBytes)
 	if err != nil {
 		if err == restrictedhttpclient.ErrMaxRequestsReached {
@@ -684,6 +804,30 @@ func (sb *SolrBackend) CheckMetadata(collection, tsType, tsid string, tsidBytes
 	return false, nil
 }
 
+const (
+	funcCountSeries  string = "CountSeries"
+	queryCountSeries string = "parent_doc:true"
+)
+
+// CountSeries - returns the number of series of the collection, all types included
+func (sb *SolrBackend) CountSeries(collection string) (int, gobol.Error) {
+
+	start := time.Now()
+
+	r, err := sb.solrService.SimpleQuery(collection, queryCountSeries, constants.StringsEmpty, 0, 0)
+	if err != nil {
+		sb.statsError(funcCountSeries, collection, cAllMetaTypes, solrQuery)
+		if err == restrictedhttpclient.ErrMaxRequestsReached {
+			return 0, errServiceUnavailable(funcCountSeries, err)
+		}
+		return 0, errInternalServer(funcCountSeries, err)
+	}
+
+	sb.statsRequest(funcCountSeries, collection, cAllMetaTypes, solrQuery, time.Since(start))
+
+	return r.Results.NumFound, nil
+}
+
 const (
 	funcDeleteDocumentByID  string = "DeleteDocumentByID"
 	queryDeleteDocumentByID string = "/%s.*/"
@@ -751,17 +895,17 @@ func (sb *SolrBackend) DeleteCachedIDifExist(collection, tsType, id string) gobo
 const funcFilterTagValuesByMetricAndTag string = "FilterTagValuesByMetricAndTag"
 
 // FilterTagValuesByMetricAndTag - returns all tag values related to the specified metric and tag
-func (sb *SolrBackend) FilterTagValuesByMetricAndTag(collection, tsType, metric, tag, prefix string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) FilterTagValuesByMetricAndTag(collection, tsType, metric, tag, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	childrenQuery := "tag_key:" + tag
 
-	return sb.filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, "tag_value", funcFilterTagValuesByMetricAndTag, maxResults)
+	return sb.filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, "tag_value", funcFilterTagValuesByMetricAndTag, maxResults, activeWithin)
 }
 
 const funcFilterTagKeysByMetric string = "FilterTagKeysByMetric"
 
 // FilterTagKeysByMetric - returns all tag keys related to the specified metric
-func (sb *SolrBackend) FilterTagKeysByMetric(collection, tsType, metric, prefix string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) FilterTagKeysByMetric(collection, tsType, metric, prefix string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	childrenQuery := "tag_key:"
 
@@ -771,11 +915,11 @@ func (sb *SolrBackend) FilterTagKeysByMetric(collection, tsType, metric, prefix
 		childrenQuery += sb.escapeSolrSpecialChars(prefix)
 	}
 
-	return sb.filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, "tag_key", funcFilterTagKeysByMetric, maxResults)
+	return sb.filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, "tag_key", funcFilterTagKeysByMetric, maxResults, activeWithin)
 }
 
 // filterTagsByMetric - returns all tag keys or values related to the specified metric
-func (sb *SolrBackend) filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, field, functionName string, maxResults int) ([]string, int, gobol.Error) {
+func (sb *SolrBackend) filterTagsByMetric(collection, tsType, metric, childrenQuery, prefix, field, functionName string, maxResults int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	query := "{!parent which=\"parent_doc:true\"}" + childrenQuery
 	filterQueries := []string{
@@ -783,14 +927,16 @@ func (sb *SolrBackend) filterTagsByMetric(collection, tsType, metric, childrenQu
 		"metric:" + metric,
 	}
 
-	maxResultsStr := strconv.Itoa(maxResults)
+	if active := sb.activeFilter(activeWithin); active != constants.StringsEmpty {
+		filterQueries = append(filterQueries, active)
+	}
+
 	strBuilder := strings.Builder{}
-	strBuilder.Grow(len(query) + len(filterQueries[0]) + len(filterQueries[1]) + len(maxResultsStr) + 3)
 	strBuilder.WriteString(query)
-	strBuilder.WriteString(constants.StringsBar)
-	strBuilder.WriteString(filterQueries[0])
-	strBuilder.WriteString(constants.StringsBar)
-	strBuilder.WriteString(filterQueries[1])
+	for _, filterQuery := range filterQueries {
+		strBuilder.WriteString(constants.StringsBar)
+		strBuilder.WriteString(filterQuery)
+	}
 	strBuilder.WriteString(constants.StringsBar)
 	strBuilder.WriteString(strconv.Itoa(maxResults))
 
@@ -30,6 +30,21 @@ const (
 
 	// metricListCollectionsError - metric name for solr list collections request error
 	metricListCollectionsError string = "solr.list.collections.error"
+
+	// metricIndexerQueue - metric name for the number of documents waiting to be indexed
+	metricIndexerQueue string = "solr.indexer.queue"
+
+	// metricIndexerRetry - metric name for the indexer retries when the maximum number of requests was reached
+	metricIndexerRetry string = "solr.indexer.retry"
+
+	// metricIndexerDropped - metric name for the documents not indexed after all retries
+	metricIndexerDropped string = "solr.indexer.dropped"
+
+	// metricIndexerQueueFull - metric name for the documents not queued because the indexer queue was full
+	metricIndexerQueueFull string = "solr.indexer.queue.full"
+
+	// metricReapedSeries - metric name for the stale series removed
+	metricReapedSeries string = "solr.reaped.series"
 )
 
 // solrOperation - identifies some solr operation
@@ -103,3 +118,55 @@ func (sb *SolrBackend) statsListCollectionsError(function string) {
 		metricListCollectionsError,
 	)
 }
+
+// statsIndexerQueue - stores the number of documents waiting to be indexed
+func (sb *SolrBackend) statsIndexerQueue(function string, queued int) {
+
+	sb.timelineManager.FlattenMaxN(
+		function,
+		float64(queued),
+		metricIndexerQueue,
+	)
+}
+
+// statsIndexerRetry - stores an indexer retry
+func (sb *SolrBackend) statsIndexerRetry(function, collection string) {
+
+	sb.timelineManager.FlattenCountIncN(
+		function,
+		metricIndexerRetry,
+		constants.StringsTargetKSID, utils.ValidateExpectedValue(collection),
+	)
+}
+
+// statsIndexerDropped - stores the number of documents not indexed
+func (sb *SolrBackend) statsIndexerDropped(function, collection string, count int) {
+
+	sb.timelineManager.FlattenCountN(
+		function,
+		float64(count),
+		metricIndexerDropped,
+		constants.StringsTargetKSID, utils.ValidateExpectedValue(collection),
+	)
+}
+
+// statsIndexerQueueFull - stores a document not queued because the indexer queue was full
+func (sb *SolrBackend) statsIndexerQueueFull(function, collection string) {
+
+	sb.timelineManager.FlattenCountIncN(
+		function,
+		metricIndexerQueueFull,
+		constants.StringsTargetKSID, utils.ValidateExpectedValue(collection),
+	)
+}
+
+// statsReapedSeries - stores the number of stale series removed
+func (sb *SolrBackend) statsReapedSeries(function, collection string, count int) {
+
+	sb.timelineManager.FlattenCountN(
+		function,
+		float64(count),
+		metricReapedSeries,
+		constants.StringsTargetKSID, utils.ValidateExpectedValue(collection),
+	)
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/tserr"
+)
+
+const (
+	cPackage string = "migration"
+)
+
+func errBasic(function, message string, code int, e error) gobol.Error {
+	if e != nil {
+		return tserr.New(
+			e,
+			message,
+			cPackage,
+			function,
+			code,
+		)
+	}
+	return nil
+}
+
+func errBadRequest(function, message string) gobol.Error {
+	return errBasic(function, message, http.StatusBadRequest, errors.New(message))
+}
+
+func errKeysetNotFound(function string) gobol.Error {
+	return errBasic(function, "keyset not found", http.StatusNotFound, errors.New("keyset not found"))
+}
@@ -0,0 +1,285 @@
+package migration
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/collector"
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/structs"
+	"github.com/uol/mycenae/lib/validation"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Moves the metadata of a keyset to another cluster or keyset: the export streams all the series
+// documents as NDJSON and the import loads them in a target keyset, optionally renaming the metric
+// prefixes, tag keys and tag values. The tsids are generated again in the import because they are
+// hashes of the keyset, the metric and the tags
+
+const (
+	paramMetricPrefix string = "metricPrefix"
+	paramTagKey       string = "tagKey"
+	paramTagValue     string = "tagValue"
+	ruleSeparator     string = ":"
+	anyValue          string = "*"
+	maxLineErrors     int    = 100
+)
+
+// metaTypes - the point type of each metadata type
+var metaTypes = map[string]constants.PointType{
+	"meta":          constants.PointTypeNumber,
+	"metatext":      constants.PointTypeText,
+	"metahistogram": constants.PointTypeHistogram,
+}
+
+// exportOrder - the metadata types exported when no type is requested
+var exportOrder = []string{"meta", "metatext", "metahistogram"}
+
+// Migration - exports and imports the keyset metadata
+type Migration struct {
+	metaStorage *metadata.Storage
+	collector   *collector.Collector
+	validation  *validation.Service
+	pageSize    int
+	logger      *logh.ContextualLogger
+}
+
+// New - creates the metadata migration, the page size is used to read the exported documents and
+// to index the imported ones
+func New(metaStorage *metadata.Storage, collector *collector.Collector, validation *validation.Service, pageSize int) *Migration {
+
+	return &Migration{
+		metaStorage: metaStorage,
+		collector:   collector,
+		validation:  validation,
+		pageSize:    pageSize,
+		logger:      logh.CreateContextualLogger(constants.StringsPKG, "migration"),
+	}
+}
+
+type prefixRule struct {
+	from string
+	to   string
+}
+
+// rewriteRules - the renames applied to the imported series, the tag value rules use the original tag keys
+type rewriteRules struct {
+	metricPrefixes []prefixRule
+	tagKeys        map[string]string
+	tagValues      map[string]map[string]string
+}
+
+// splitRule - splits the rule in the number of parts, the names can not have the separator
+func splitRule(param, rule string, parts int) ([]string, gobol.Error) {
+
+	split := strings.Split(rule, ruleSeparator)
+	if len(split) != parts {
+		return nil, errBadRequest("parseRewriteRules", fmt.Sprintf("invalid %s rule: %s", param, rule))
+	}
+
+	// the metric prefixes can be removed, all the other names are required
+	for i, s := range split {
+		if s == constants.StringsEmpty && !(param == paramMetricPrefix && i == 1) {
+			return nil, errBadRequest("parseRewriteRules", fmt.Sprintf("invalid %s rule: %s", param, rule))
+		}
+	}
+
+	return split, nil
+}
+
+// parseRewriteRules - parses the metricPrefix=from:to, tagKey=from:to and tagValue=key:from:to
+// parameters, the "*" tag value rewrites all the values of the key
+func parseRewriteRules(params url.Values) (*rewriteRules, gobol.Error) {
+
+	rules := &rewriteRules{
+		tagKeys:   map[string]string{},
+		tagValues: map[string]map[string]string{},
+	}
+
+	for _, rule := range params[paramMetricPrefix] {
+
+		split, gerr := splitRule(paramMetricPrefix, rule, 2)
+		if gerr != nil {
+			return nil, gerr
+		}
+
+		rules.metricPrefixes = append(rules.metricPrefixes, prefixRule{from: split[0], to: split[1]})
+	}
+
+	// the longest prefix is applied when many match
+	sort.SliceStable(rules.metricPrefixes, func(i, j int) bool {
+		return len(rules.metricPrefixes[i].from) > len(rules.metricPrefixes[j].from)
+	})
+
+	for _, rule := range params[paramTagKey] {
+
+		split, gerr := splitRule(paramTagKey, rule, 2)
+		if gerr != nil {
+			return nil, gerr
+		}
+
+		rules.tagKeys[split[0]] = split[1]
+	}
+
+	for _, rule := range params[paramTagValue] {
+
+		split, gerr := splitRule(paramTagValue, rule, 3)
+		if gerr != nil {
+			return nil, gerr
+		}
+
+		if rules.tagValues[split[0]] == nil {
+			rules.tagValues[split[0]] = map[string]string{}
+		}
+
+		rules.tagValues[split[0]][split[1]] = split[2]
+	}
+
+	return rules, nil
+}
+
+// apply - renames the metric and the tags of the serie
+func (rules *rewriteRules) apply(m *metadata.Metadata) {
+
+	for _, rule := range rules.metricPrefixes {
+		if strings.HasPrefix(m.Metric, rule.from) {
+			m.Metric = rule.to + strings.TrimPrefix(m.Metric, rule.from)
+			break
+		}
+	}
+
+	for i, key := range m.TagKey {
+
+		if values, ok := rules.tagValues[key]; ok {
+			if value, ok := values[m.TagValue[i]]; ok {
+				m.TagValue[i] = value
+			} else if value, ok := values[anyValue]; ok {
+				m.TagValue[i] = value
+			}
+		}
+
+		if newKey, ok := rules.tagKeys[key]; ok {
+			m.TagKey[i] = newKey
+		}
+	}
+}
+
+// LineError - the error of an import line
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportReport - the result of the import
+type ImportReport struct {
+	Imported int         `json:"imported"`
+	Skipped  int         `json:"skipped"`
+	Errors   []LineError `json:"errors,omitempty"`
+	// Error - the error stopping the import, the documents counted as imported were indexed
+	Error string `json:"error,omitempty"`
+}
+
+// skip - counts the skipped line keeping the first errors
+func (report *ImportReport) skip(line int, message string) {
+
+	report.Skipped++
+
+	if len(report.Errors) < maxLineErrors {
+		report.Errors = append(report.Errors, LineError{Line: line, Message: message})
+	}
+}
+
+// convert - validates the serie like a received point and generates its tsid in the keyset
+func (mig *Migration) convert(keyset string, m *metadata.Metadata) (*metadata.Metadata, gobol.Error) {
+
+	pointType, ok := metaTypes[m.MetaType]
+	if !ok {
+		return nil, errBadRequest("convert", fmt.Sprintf("unknown metadata type: %s", m.MetaType))
+	}
+
+	if len(m.TagKey) != len(m.TagValue) {
+		return nil, errBadRequest("convert", "the number of tag keys and values are different")
+	}
+
+	gerr := mig.validation.ValidateProperty(m.Metric, validation.MetricType)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	point := &structs.TSDBpoint{
+		Metric: m.Metric,
+		Keyset: keyset,
+		Tags:   make([]structs.TSDBTag, 0, len(m.TagKey)+2),
+	}
+
+	ttlValue := constants.StringsEmpty
+
+	for i, key := range m.TagKey {
+
+		value := m.TagValue[i]
+
+		switch key {
+		case constants.StringsKSID:
+			// the keyset of the serie is always the target one
+			continue
+		case constants.StringsTTL:
+			// the ttl tag is added when the keyset policy is applied
+			ttlValue = value
+			continue
+		default:
+			if gerr = mig.validation.ValidateProperty(key, validation.TagKeyType); gerr != nil {
+				return nil, gerr
+			}
+
+			if gerr = mig.validation.ValidateProperty(value, validation.TagValueType); gerr != nil {
+				return nil, gerr
+			}
+		}
+
+		point.Tags = append(point.Tags, structs.TSDBTag{Name: key, Value: value})
+	}
+
+	_, gerr = mig.validation.ApplyTTLPolicy(point, ttlValue)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	point.Tags = append(point.Tags, structs.TSDBTag{Name: constants.StringsKSID, Value: keyset})
+
+	gerr = mig.validation.ValidateTags(point)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	packet, gerr := mig.collector.MakePacket(point, pointType)
+	if gerr != nil {
+		return nil, gerr
+	}
+
+	imported := &metadata.Metadata{
+		ID:        packet.ID,
+		Metric:    point.Metric,
+		MetaType:  m.MetaType,
+		TagKey:    make([]string, 0, len(point.Tags)-1),
+		TagValue:  make([]string, 0, len(point.Tags)-1),
+		FirstSeen: m.FirstSeen,
+		LastSeen:  m.LastSeen,
+	}
+
+	for _, tag := range point.Tags {
+		if tag.Name != constants.StringsKSID {
+			imported.TagKey = append(imported.TagKey, tag.Name)
+			imported.TagValue = append(imported.TagValue, tag.Value)
+		}
+	}
+
+	return imported, nil
+}
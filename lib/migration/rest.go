@@ -0,0 +1,211 @@
+package migration
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/metadata"
+)
+
+const (
+	funcExport string = "Export"
+	funcImport string = "Import"
+
+	contentTypeNDJSON string = "application/x-ndjson"
+	maxLineSize       int    = 1024 * 1024
+)
+
+// getKeyset - returns the keyset parameter when it exists
+func (mig *Migration) getKeyset(ps httprouter.Params, function string) (string, gobol.Error) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty || !mig.metaStorage.CheckKeyset(keyset) {
+		return constants.StringsEmpty, errKeysetNotFound(function)
+	}
+
+	return keyset, nil
+}
+
+// Export - streams all the metadata of the keyset as NDJSON, one document per line
+func (mig *Migration) Export(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, gerr := mig.getKeyset(ps, funcExport)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	types := exportOrder
+
+	if tsType := r.URL.Query().Get("type"); tsType != constants.StringsEmpty {
+		if _, ok := metaTypes[tsType]; !ok {
+			rip.Fail(w, errBadRequest(funcExport, fmt.Sprintf("unknown metadata type: %s", tsType)))
+			return
+		}
+		types = []string{tsType}
+	}
+
+	encoder := json.NewEncoder(w)
+	written := 0
+
+	for _, tsType := range types {
+
+		// the cursor keeps the pages stable while the collector indexes new series
+		for cursor := constants.StringsEmpty; ; {
+
+			metas, next, gerr := mig.metaStorage.ScanDocuments(keyset, tsType, cursor, mig.pageSize)
+			if gerr != nil {
+				if written == 0 {
+					rip.Fail(w, gerr)
+					return
+				}
+
+				if logh.ErrorEnabled {
+					mig.logger.Error().Str(constants.StringsFunc, funcExport).Str(constants.StringsKeyset, keyset).Err(gerr).Msgf("export aborted after %d documents", written)
+				}
+
+				// the status was already sent, the connection is closed so the client does not take the export as complete
+				panic(http.ErrAbortHandler)
+			}
+
+			if written == 0 && len(metas) > 0 {
+				w.Header().Set("Content-Type", contentTypeNDJSON)
+				w.WriteHeader(http.StatusOK)
+			}
+
+			for i := range metas {
+
+				metas[i].Keyset = keyset
+
+				if err := encoder.Encode(&metas[i]); err != nil {
+					if logh.ErrorEnabled {
+						mig.logger.Error().Str(constants.StringsFunc, funcExport).Str(constants.StringsKeyset, keyset).Err(err).Msg("error writing the exported document")
+					}
+					return
+				}
+
+				written++
+			}
+
+			if next == constants.StringsEmpty {
+				break
+			}
+
+			cursor = next
+		}
+	}
+
+	if written == 0 {
+		rip.Success(w, http.StatusNoContent, nil)
+	}
+}
+
+// Import - loads the NDJSON metadata documents in the keyset, applying the metric prefix and tag rewrite rules
+func (mig *Migration) Import(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, gerr := mig.getKeyset(ps, funcImport)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rules, gerr := parseRewriteRules(r.URL.Query())
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	var body io.Reader = r.Body
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			rip.Fail(w, errBadRequest(funcImport, err.Error()))
+			return
+		}
+		defer reader.Close()
+		body = reader
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	report := &ImportReport{}
+	batch := make([]*metadata.Metadata, 0, mig.pageSize)
+	line := 0
+
+	for scanner.Scan() {
+
+		line++
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		m := &metadata.Metadata{}
+
+		if err := json.Unmarshal(scanner.Bytes(), m); err != nil {
+			report.skip(line, err.Error())
+			continue
+		}
+
+		rules.apply(m)
+
+		imported, gerr := mig.convert(keyset, m)
+		if gerr != nil {
+			report.skip(line, gerr.Message())
+			continue
+		}
+
+		batch = append(batch, imported)
+
+		if len(batch) == mig.pageSize {
+			if gerr = mig.metaStorage.ImportDocuments(keyset, batch); gerr != nil {
+				mig.failImport(w, keyset, report, gerr)
+				return
+			}
+			report.Imported += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		mig.failImport(w, keyset, report, errBadRequest(funcImport, fmt.Sprintf("error reading line %d: %s", line+1, err.Error())))
+		return
+	}
+
+	if gerr = mig.metaStorage.ImportDocuments(keyset, batch); gerr != nil {
+		mig.failImport(w, keyset, report, gerr)
+		return
+	}
+
+	report.Imported += len(batch)
+
+	if logh.InfoEnabled {
+		mig.logger.Info().Str(constants.StringsFunc, funcImport).Str(constants.StringsKeyset, keyset).Msgf("%d documents imported, %d skipped", report.Imported, report.Skipped)
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, report)
+}
+
+// failImport - returns the error status with the report, the documents already imported are kept
+// and the client can resume the import after them
+func (mig *Migration) failImport(w http.ResponseWriter, keyset string, report *ImportReport, gerr gobol.Error) {
+
+	if logh.ErrorEnabled {
+		mig.logger.Error().Str(constants.StringsFunc, funcImport).Str(constants.StringsKeyset, keyset).Err(gerr).Msgf("import aborted after %d documents", report.Imported)
+	}
+
+	report.Error = gerr.Message()
+
+	rip.SuccessJSON(w, gerr.StatusCode(), report)
+}
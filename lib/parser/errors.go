@@ -72,3 +72,12 @@ func errRateResetValue(e error) gobol.Error {
 func errUnkFunc(msg string) gobol.Error {
 	return errBasic("parseExpression", msg, errors.New(msg))
 }
+
+func errTimeshiftOption(option string) gobol.Error {
+	s := fmt.Sprintf("timeshift option, the 2nd parameter, needs to be 'compare' but found '%s'", option)
+	return errBasic("parseTimeshift", s, errors.New(s))
+}
+
+func errSmoothingFactor(function, name string, e error) gobol.Error {
+	return errBasic(function, fmt.Sprintf("%s, needs to be a number bigger than 0 and at most 1", name), e)
+}
@@ -14,16 +14,20 @@ func parseDownsample(exp string, tsdb *structs.TSDBquery) (string, gobol.Error)
 
 	params := parseParams(string(exp[10:]))
 
-	if len(params) != 4 {
+	if len(params) != 4 && len(params) != 5 {
 		return constants.StringsEmpty, errParams(
 			"parseDownsample",
-			"downsample needs 4 parameters: downsample operation, downsample period, fill option and a function",
-			fmt.Errorf("downsample expects 4 parameters but found %d: %v", len(params), params),
+			"downsample needs 4 or 5 parameters: downsample operation, downsample period, fill option, an optional max gap and a function",
+			fmt.Errorf("downsample expects 4 or 5 parameters but found %d: %v", len(params), params),
 		)
 	}
 
 	tsdb.Downsample = fmt.Sprintf("%s-%s-%s", params[0], params[1], params[2])
 
+	if len(params) == 5 {
+		tsdb.MaxGap = params[3]
+	}
+
 	for _, oper := range tsdb.Order {
 		if oper == "downsample" {
 			return constants.StringsEmpty, errDoubleFunc("parseDownsample", "downsample")
@@ -32,15 +36,18 @@ func parseDownsample(exp string, tsdb *structs.TSDBquery) (string, gobol.Error)
 
 	tsdb.Order = append([]string{"downsample"}, tsdb.Order...)
 
-	return params[3], nil
+	return params[len(params)-1], nil
 }
 
-func writeDownsample(exp, dsInfo string) string {
+func writeDownsample(exp, dsInfo, maxGap string) string {
 	if dsInfo != constants.StringsEmpty {
-		info := strings.Split(dsInfo, "-")
+		info := strings.SplitN(dsInfo, "-", 3)
 		if len(info) == 2 {
 			info = append(info, "none")
 		}
+		if maxGap != constants.StringsEmpty {
+			return fmt.Sprintf("downsample(%s,%s,%s,%s,%s)", info[0], info[1], info[2], maxGap, exp)
+		}
 		exp = fmt.Sprintf("downsample(%s,%s,%s,%s)", info[0], info[1], info[2], exp)
 	}
 	return exp
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// parseRolling - parses the rolling functions: movingAverage, rollingSum, rollingMax and rollingMin
+func parseRolling(name, exp string, tsdb *structs.TSDBquery) (string, gobol.Error) {
+
+	params := parseParams(string(exp[len(name):]))
+
+	if len(params) != 2 {
+		return constants.StringsEmpty, errParams(
+			"parseRolling",
+			fmt.Sprintf("%s needs 2 parameters: a time window and a function", name),
+			fmt.Errorf("%s expects 2 parameters but found %d: %v", name, len(params), params),
+		)
+	}
+
+	if err := checkDoubleSmoothing("parseRolling", name, tsdb); err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	switch name {
+	case "movingAverage":
+		tsdb.MovingAverage = params[0]
+	case "rollingSum":
+		tsdb.RollingSum = params[0]
+	case "rollingMax":
+		tsdb.RollingMax = params[0]
+	case "rollingMin":
+		tsdb.RollingMin = params[0]
+	}
+
+	tsdb.Order = append([]string{name}, tsdb.Order...)
+
+	return params[1], nil
+}
+
+// parseEwma - parses the exponentially weighted moving average function
+func parseEwma(exp string, tsdb *structs.TSDBquery) (string, gobol.Error) {
+
+	params := parseParams(string(exp[4:]))
+
+	if len(params) != 2 {
+		return constants.StringsEmpty, errParams(
+			"parseEwma",
+			"ewma needs 2 parameters: a smoothing factor and a function",
+			fmt.Errorf("ewma expects 2 parameters but found %d: %v", len(params), params),
+		)
+	}
+
+	if err := checkDoubleSmoothing("parseEwma", "ewma", tsdb); err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	alpha, err := parseSmoothingFactor("parseEwma", "ewma alpha, the 1st parameter", params[0])
+	if err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	tsdb.Ewma = alpha
+
+	tsdb.Order = append([]string{"ewma"}, tsdb.Order...)
+
+	return params[1], nil
+}
+
+// parseHoltWinters - parses the double exponential smoothing function
+func parseHoltWinters(exp string, tsdb *structs.TSDBquery) (string, gobol.Error) {
+
+	params := parseParams(string(exp[11:]))
+
+	if len(params) != 3 {
+		return constants.StringsEmpty, errParams(
+			"parseHoltWinters",
+			"holtWinters needs 3 parameters: a smoothing factor, a trend factor and a function",
+			fmt.Errorf("holtWinters expects 3 parameters but found %d: %v", len(params), params),
+		)
+	}
+
+	if err := checkDoubleSmoothing("parseHoltWinters", "holtWinters", tsdb); err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	alpha, err := parseSmoothingFactor("parseHoltWinters", "holtWinters alpha, the 1st parameter", params[0])
+	if err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	beta, err := parseSmoothingFactor("parseHoltWinters", "holtWinters beta, the 2nd parameter", params[1])
+	if err != nil {
+		return constants.StringsEmpty, err
+	}
+
+	tsdb.HoltWinters = &structs.TSDBholtWinters{
+		Alpha: alpha,
+		Beta:  beta,
+	}
+
+	tsdb.Order = append([]string{"holtWinters"}, tsdb.Order...)
+
+	return params[2], nil
+}
+
+func checkDoubleSmoothing(function, name string, tsdb *structs.TSDBquery) gobol.Error {
+
+	for _, oper := range tsdb.Order {
+		if oper == name {
+			return errDoubleFunc(function, name)
+		}
+	}
+
+	return nil
+}
+
+func parseSmoothingFactor(function, name, param string) (float64, gobol.Error) {
+
+	factor, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, errSmoothingFactor(function, name, err)
+	}
+
+	if factor <= 0 || factor > 1 {
+		return 0, errSmoothingFactor(function, name, fmt.Errorf("%s is out of range: %s", name, param))
+	}
+
+	return factor, nil
+}
+
+func writeRolling(name, exp, window string) string {
+	if window != constants.StringsEmpty {
+		return fmt.Sprintf("%s(%s,%s)", name, window, exp)
+	}
+	return exp
+}
+
+func writeEwma(exp string, alpha float64) string {
+	if alpha != 0 {
+		return fmt.Sprintf("ewma(%s,%s)", strconv.FormatFloat(alpha, 'f', -1, 64), exp)
+	}
+	return exp
+}
+
+func writeHoltWinters(exp string, options *structs.TSDBholtWinters) string {
+	if options != nil {
+		return fmt.Sprintf(
+			"holtWinters(%s,%s,%s)",
+			strconv.FormatFloat(options.Alpha, 'f', -1, 64),
+			strconv.FormatFloat(options.Beta, 'f', -1, 64),
+			exp,
+		)
+	}
+	return exp
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+func parseTimeshift(exp string, tsdb *structs.TSDBquery) (string, gobol.Error) {
+
+	params := parseParams(string(exp[9:]))
+
+	if len(params) != 2 && len(params) != 3 {
+		return constants.StringsEmpty, errParams(
+			"parseTimeshift",
+			"timeshift needs 2 or 3 parameters: a duration, an optional 'compare' option and a function",
+			fmt.Errorf("timeshift expects 2 or 3 parameters but found %d: %v", len(params), params),
+		)
+	}
+
+	if tsdb.TimeShift != constants.StringsEmpty {
+		return constants.StringsEmpty, errDoubleFunc("parseTimeshift", "timeshift")
+	}
+
+	tsdb.TimeShift = params[0]
+
+	if len(params) == 3 {
+		if params[1] != "compare" {
+			return constants.StringsEmpty, errTimeshiftOption(params[1])
+		}
+		tsdb.Compare = true
+	}
+
+	return params[len(params)-1], nil
+}
+
+func writeTimeshift(exp, timeShift string, compare bool) string {
+	if timeShift != constants.StringsEmpty {
+		if compare {
+			return fmt.Sprintf("timeshift(%s,compare,%s)", timeShift, exp)
+		}
+		return fmt.Sprintf("timeshift(%s,%s)", timeShift, exp)
+	}
+	return exp
+}
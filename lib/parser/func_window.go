@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// parseWindow - parses the window functions: increase, delta, irate and deriv
+func parseWindow(name, exp string, tsdb *structs.TSDBquery) (string, gobol.Error) {
+
+	params := parseParams(string(exp[len(name):]))
+
+	if len(params) != 2 {
+		return constants.StringsEmpty, errParams(
+			"parseWindow",
+			fmt.Sprintf("%s needs 2 parameters: a time window and a function", name),
+			fmt.Errorf("%s expects 2 parameters but found %d: %v", name, len(params), params),
+		)
+	}
+
+	for _, oper := range tsdb.Order {
+		if oper == name {
+			return constants.StringsEmpty, errDoubleFunc("parseWindow", name)
+		}
+	}
+
+	switch name {
+	case "increase":
+		tsdb.Increase = params[0]
+	case "delta":
+		tsdb.Delta = params[0]
+	case "irate":
+		tsdb.Irate = params[0]
+	case "deriv":
+		tsdb.Deriv = params[0]
+	}
+
+	tsdb.Order = append([]string{name}, tsdb.Order...)
+
+	return params[1], nil
+}
+
+func writeWindow(name, exp, window string) string {
+	if window != constants.StringsEmpty {
+		return fmt.Sprintf("%s(%s,%s)", name, window, exp)
+	}
+	return exp
+}
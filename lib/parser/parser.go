@@ -53,6 +53,16 @@ func parseExpression(exp string, tsdb *structs.TSDBquery) (relative string, err
 		exp, err = parseRate(exp, tsdb)
 	case "filter":
 		exp, err = parseFilter(exp, tsdb)
+	case "timeshift":
+		exp, err = parseTimeshift(exp, tsdb)
+	case "increase", "delta", "irate", "deriv":
+		exp, err = parseWindow(string(name), exp, tsdb)
+	case "movingAverage", "rollingSum", "rollingMax", "rollingMin":
+		exp, err = parseRolling(string(name), exp, tsdb)
+	case "ewma":
+		exp, err = parseEwma(exp, tsdb)
+	case "holtWinters":
+		exp, err = parseHoltWinters(exp, tsdb)
 	default:
 		return constants.StringsEmpty, errUnkFunc(fmt.Sprintf("unkown function %s", string(name)))
 	}
@@ -80,15 +90,25 @@ func CompileExpression(tsQueries []structs.TSDBqueryPayload) (exps []string) {
 				case "aggregation":
 					exp = writeMerge(exp, query.Aggregator)
 				case "downsample":
-					exp = writeDownsample(exp, query.Downsample)
+					exp = writeDownsample(exp, query.Downsample, query.MaxGap)
 				case "rate":
 					exp = writeRate(exp, query.Rate, query.RateOptions)
 				case "filterValue":
 					exp = writeFilter(exp, query.FilterValue)
+				case "increase", "delta", "irate", "deriv":
+					exp = writeWindow(operation, exp, query.WindowFunctions()[operation])
+				case "movingAverage", "rollingSum", "rollingMax", "rollingMin":
+					exp = writeRolling(operation, exp, query.RollingFunctions()[operation])
+				case "ewma":
+					exp = writeEwma(exp, query.Ewma)
+				case "holtWinters":
+					exp = writeHoltWinters(exp, query.HoltWinters)
 				}
 
 			}
 
+			exp = writeTimeshift(exp, query.TimeShift, query.Compare)
+
 			exp = writeGroup(exp, query.Filters)
 
 			exps = append(exps, exp)
@@ -1,5 +1,12 @@
 package persistence
 
+import (
+	"sync"
+	"time"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
 // Keyspace represents a keyspace within the database
 type Keyspace struct {
 	// Name is a human-friendly name for the keyspace
@@ -10,6 +17,125 @@ type Keyspace struct {
 	DC string `json:"datacenter"`
 	// TTL is the time-to-live for the keyspace data
 	TTL int `json:"ttl"`
+	// Layout is how the number points are stored: row (default) or block
+	Layout constants.KeyspaceLayout `json:"layout"`
 	// --- This will be removed ---
 	Replication int `json:"replicationFactor"`
+	// Replications is the replication factor of each datacenter when it was altered
+	Replications map[string]int `json:"replication,omitempty"`
+	// CreationDate is when the keyspace was created
+	CreationDate time.Time `json:"-"`
+}
+
+// KeyspaceTTLMap - the keyspace storing the points of each TTL, it is changed
+// while the points are written when the keyspace TTLs are altered
+type KeyspaceTTLMap struct {
+	mutex     sync.RWMutex
+	keyspaces map[int]string
+}
+
+// NewKeyspaceTTLMap - creates the map with the keyspace of each TTL
+func NewKeyspaceTTLMap(keyspaces map[int]string) *KeyspaceTTLMap {
+
+	return &KeyspaceTTLMap{
+		keyspaces: keyspaces,
+	}
+}
+
+// Get - returns the keyspace of the TTL
+func (m *KeyspaceTTLMap) Get(ttl int) (string, bool) {
+
+	m.mutex.RLock()
+	keyspace, ok := m.keyspaces[ttl]
+	m.mutex.RUnlock()
+
+	return keyspace, ok
+}
+
+// TTL - returns the TTL of the keyspace
+func (m *KeyspaceTTLMap) TTL(keyspace string) (int, bool) {
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for ttl, name := range m.keyspaces {
+		if name == keyspace {
+			return ttl, true
+		}
+	}
+
+	return 0, false
+}
+
+// Map - returns a copy of the keyspace of each TTL
+func (m *KeyspaceTTLMap) Map() map[int]string {
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keyspaces := make(map[int]string, len(m.keyspaces))
+	for ttl, name := range m.keyspaces {
+		keyspaces[ttl] = name
+	}
+
+	return keyspaces
+}
+
+// Set - replaces the keyspace of each TTL
+func (m *KeyspaceTTLMap) Set(keyspaces map[int]string) {
+
+	m.mutex.Lock()
+	m.keyspaces = keyspaces
+	m.mutex.Unlock()
+}
+
+// KeyspaceLayouts - how each keyspace stores its number points, it is
+// refreshed with the keyspace TTL map
+type KeyspaceLayouts struct {
+	mutex   sync.RWMutex
+	layouts map[string]constants.KeyspaceLayout
+}
+
+// NewKeyspaceLayouts - creates the layouts of the keyspaces
+func NewKeyspaceLayouts(layouts map[string]constants.KeyspaceLayout) *KeyspaceLayouts {
+
+	return &KeyspaceLayouts{
+		layouts: layouts,
+	}
+}
+
+// Get - returns the layout of the keyspace, the row layout when it is unknown
+func (l *KeyspaceLayouts) Get(keyspace string) constants.KeyspaceLayout {
+
+	l.mutex.RLock()
+	layout, ok := l.layouts[keyspace]
+	l.mutex.RUnlock()
+
+	if !ok {
+		return constants.KeyspaceLayoutRow
+	}
+
+	return layout
+}
+
+// Map - returns a copy of the layout of each keyspace
+func (l *KeyspaceLayouts) Map() map[string]constants.KeyspaceLayout {
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	layouts := make(map[string]constants.KeyspaceLayout, len(l.layouts))
+	for keyspace, layout := range l.layouts {
+		layouts[keyspace] = layout
+	}
+
+	return layouts
+}
+
+// Set - replaces the layout of each keyspace
+func (l *KeyspaceLayouts) Set(layouts map[string]constants.KeyspaceLayout) {
+
+	l.mutex.Lock()
+	l.layouts = layouts
+	l.mutex.Unlock()
 }
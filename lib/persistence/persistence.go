@@ -10,6 +10,7 @@ import (
 	"github.com/uol/gobol"
 	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/rollup"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
@@ -19,7 +20,17 @@ type Backend interface {
 	CreateKeyspace(
 		name, datacenter, contact string,
 		replication int, ttl int,
+		layout constants.KeyspaceLayout,
 	) gobol.Error
+	// CreateRollupTables should create the missing rollup tables of an
+	// existing keyspace
+	CreateRollupTables(name string) gobol.Error
+	// CreateHistogramTable should create the histogram table of an
+	// existing keyspace
+	CreateHistogramTable(name string, ttl int) gobol.Error
+	// CreateSeriesTable should create the series table of an existing
+	// keyspace
+	CreateSeriesTable(name string, ttl int) gobol.Error
 	// DeleteKeyspace should delete a keyspace from the database
 	DeleteKeyspace(id string) gobol.Error
 	// ListKeyspaces should return a list of all available keyspaces
@@ -29,6 +40,12 @@ type Backend interface {
 	// UpdateKeyspace should update metadata and contact information about the
 	// keyspace
 	UpdateKeyspace(ksid, contact string) gobol.Error
+	// UpdateKeyspaceTTL should change the default TTL and the compaction
+	// window of the keyspace tables
+	UpdateKeyspaceTTL(ksid string, ttl int) gobol.Error
+	// UpdateKeyspaceReplication should replace the replication factor of
+	// each datacenter of the keyspace
+	UpdateKeyspaceReplication(ksid string, replication map[string]int) gobol.Error
 
 	// ListDatacenters should list all available datacenters
 	ListDatacenters() ([]string, gobol.Error)
@@ -53,9 +70,10 @@ func NewStorage(
 	devMode bool,
 	defaultTTL int,
 	clusteringOrder string,
+	rollups []rollup.Resolution,
 ) (*Storage, error) {
 	backend, err := newScyllaPersistence(
-		ksAdmin, grantUser, session, timelineManager, devMode, defaultTTL, clusteringOrder,
+		ksAdmin, grantUser, session, timelineManager, devMode, defaultTTL, clusteringOrder, rollups,
 	)
 	if err != nil {
 		return nil, err
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/uol/gobol"
+	"github.com/uol/mycenae/lib/constants"
 )
 
 // DatacenterExists checks whether a given datacenter exists
@@ -25,6 +26,7 @@ func (storage *Storage) DatacenterExists(dc string) (bool, gobol.Error) {
 func (storage *Storage) CreateKeyspace(
 	name, datacenter, contact string,
 	replication int, ttl int,
+	layout constants.KeyspaceLayout,
 ) gobol.Error {
 	if exists, err := storage.DatacenterExists(datacenter); err != nil {
 		return err
@@ -37,7 +39,7 @@ func (storage *Storage) CreateKeyspace(
 		)
 	}
 	if err := storage.Backend.CreateKeyspace(
-		name, datacenter, contact, replication, ttl,
+		name, datacenter, contact, replication, ttl, layout,
 	); err != nil {
 		return err
 	}
@@ -55,3 +57,21 @@ func (storage *Storage) DeleteKeyspace(id string) gobol.Error {
 	}
 	return nil
 }
+
+// UpdateKeyspaceReplication is a wrapper around the Backend in order to check
+// the datacenters before altering the keyspace
+func (storage *Storage) UpdateKeyspaceReplication(ksid string, replication map[string]int) gobol.Error {
+	for datacenter := range replication {
+		if exists, err := storage.DatacenterExists(datacenter); err != nil {
+			return err
+		} else if !exists {
+			return errNoDatacenter("UpdateKeyspaceReplication", "Storage",
+				fmt.Sprintf(
+					"Cannot alter because datacenter \"%s\" not exists",
+					datacenter,
+				),
+			)
+		}
+	}
+	return storage.Backend.UpdateKeyspaceReplication(ksid, replication)
+}
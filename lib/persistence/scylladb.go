@@ -9,6 +9,7 @@ import (
 	"github.com/gocql/gocql"
 	"github.com/uol/gobol"
 	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/rollup"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
@@ -23,6 +24,7 @@ type scylladb struct {
 	devMode         bool
 	defaultTTL      int
 	clusteringOrder string
+	rollups         []rollup.Resolution
 }
 
 func newScyllaPersistence(
@@ -33,8 +35,9 @@ func newScyllaPersistence(
 	devMode bool,
 	defaultTTL int,
 	clusteringOrder string,
+	rollups []rollup.Resolution,
 ) (Backend, error) {
-	return &scylladb{
+	backend := &scylladb{
 		session:         session,
 		logger:          logh.CreateContextualLogger(constants.StringsPKG, "persistence"),
 		timelineManager: timelineManager,
@@ -43,7 +46,14 @@ func newScyllaPersistence(
 		devMode:         devMode,
 		defaultTTL:      defaultTTL,
 		clusteringOrder: clusteringOrder,
-	}, nil
+		rollups:         rollups,
+	}
+
+	if err := backend.migrateKeyspaceTable(); err != nil {
+		return nil, err
+	}
+
+	return backend, nil
 }
 
 const funcCreateKeyspace string = "CreateKeyspace"
@@ -51,6 +61,7 @@ const funcCreateKeyspace string = "CreateKeyspace"
 func (backend *scylladb) CreateKeyspace(
 	name, datacenter, contact string,
 	replication int, ttl int,
+	layout constants.KeyspaceLayout,
 ) gobol.Error {
 	keyspace := Keyspace{
 		Name:        name,
@@ -58,6 +69,7 @@ func (backend *scylladb) CreateKeyspace(
 		Contact:     contact,
 		TTL:         ttl,
 		Replication: replication,
+		Layout:      layout,
 	}
 
 	if _, found, err := backend.GetKeyspace(name); err != nil {
@@ -75,6 +87,10 @@ func (backend *scylladb) CreateKeyspace(
 		keyspace.TTL = backend.defaultTTL
 	}
 
+	if string(keyspace.Layout) == constants.StringsEmpty {
+		keyspace.Layout = constants.KeyspaceLayoutRow
+	}
+
 	// Timing for this management part is executed separately
 	if err := backend.addKeyspaceMetadata(keyspace); err != nil {
 		return err
@@ -84,12 +100,27 @@ func (backend *scylladb) CreateKeyspace(
 	if err := backend.createKeyspace(keyspace); err != nil {
 		return err
 	}
-	if err := backend.createNumericTable(keyspace); err != nil {
-		return err
+	if keyspace.Layout == constants.KeyspaceLayoutBlock {
+		if err := backend.createBlockTable(keyspace); err != nil {
+			return err
+		}
+	} else {
+		if err := backend.createNumericTable(keyspace); err != nil {
+			return err
+		}
 	}
 	if err := backend.createTextTable(keyspace); err != nil {
 		return err
 	}
+	if err := backend.createHistogramTable(keyspace); err != nil {
+		return err
+	}
+	if err := backend.createRollupTables(keyspace.Name); err != nil {
+		return err
+	}
+	if err := backend.createSeriesTable(keyspace); err != nil {
+		return err
+	}
 	if err := backend.setPermissions(keyspace); err != nil {
 		return err
 	}
@@ -98,6 +129,24 @@ func (backend *scylladb) CreateKeyspace(
 	return nil
 }
 
+// CreateHistogramTable - creates the histogram table of a keyspace created before it existed
+func (backend *scylladb) CreateHistogramTable(name string, ttl int) gobol.Error {
+
+	return backend.createHistogramTable(Keyspace{Name: name, TTL: ttl})
+}
+
+// CreateSeriesTable - creates the series table of a keyspace created before it existed
+func (backend *scylladb) CreateSeriesTable(name string, ttl int) gobol.Error {
+
+	return backend.createSeriesTable(Keyspace{Name: name, TTL: ttl})
+}
+
+// CreateRollupTables - creates the missing rollup tables of an existing keyspace
+func (backend *scylladb) CreateRollupTables(name string) gobol.Error {
+
+	return backend.createRollupTables(name)
+}
+
 const cFuncDeleteKeyspace string = "DeleteKeyspace"
 
 func (backend *scylladb) DeleteKeyspace(id string) gobol.Error {
@@ -110,13 +159,18 @@ func (backend *scylladb) DeleteKeyspace(id string) gobol.Error {
 		return errPersist(cFuncDeleteKeyspace, structName, err)
 	}
 
+	if err := backend.session.Query(fmt.Sprintf(formatDeleteKeyspaceMetadata, backend.ksMngr), id).Exec(); err != nil {
+		backend.statsQueryError(cFuncDeleteKeyspace, backend.ksMngr, constants.CRUDOperationDelete)
+		return errPersist(cFuncDeleteKeyspace, structName, err)
+	}
+
 	backend.statsQuery(cFuncDeleteKeyspace, id, constants.CRUDOperationDrop, time.Since(start))
 	return nil
 }
 
 const (
 	funcListKeyspaces  string = "ListKeyspaces"
-	queryListKeyspaces string = `SELECT key, contact, datacenter, replication_factor FROM %s.ts_keyspace`
+	queryListKeyspaces string = `SELECT key, contact, datacenter, replication_factor, layout, ttl, replication FROM %s.ts_keyspace`
 )
 
 func (backend *scylladb) ListKeyspaces() ([]Keyspace, gobol.Error) {
@@ -132,10 +186,17 @@ func (backend *scylladb) ListKeyspaces() ([]Keyspace, gobol.Error) {
 		&current.Contact,
 		&current.DC,
 		&current.Replication,
+		&current.Layout,
+		&current.TTL,
+		&current.Replications,
 	) {
+		if string(current.Layout) == constants.StringsEmpty {
+			current.Layout = constants.KeyspaceLayoutRow
+		}
 		if current.Name != backend.ksMngr {
 			keyspaces = append(keyspaces, current)
 		}
+		current = Keyspace{}
 	}
 	if err := iter.Close(); err != nil {
 		if err == gocql.ErrNotFound {
@@ -184,6 +245,10 @@ func (backend *scylladb) GetKeyspace(id string) (Keyspace, bool, gobol.Error) {
 		&ks.Contact,
 		&ks.DC,
 		&ks.Replication,
+		&ks.Layout,
+		&ks.TTL,
+		&ks.Replications,
+		&ks.CreationDate,
 	); err == gocql.ErrNotFound {
 
 		backend.statsQuery(
@@ -202,6 +267,10 @@ func (backend *scylladb) GetKeyspace(id string) (Keyspace, bool, gobol.Error) {
 		return Keyspace{}, false, errPersist(funcGetKeyspace, structName, err)
 	}
 
+	if string(ks.Layout) == constants.StringsEmpty {
+		ks.Layout = constants.KeyspaceLayoutRow
+	}
+
 	backend.statsQuery(
 		funcListKeyspaces,
 		id,
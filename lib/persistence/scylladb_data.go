@@ -1,6 +1,6 @@
 package persistence
 
-const formatAddKeyspace = `INSERT INTO %s.ts_keyspace (key, contact, datacenter, replication_factor, creation_date) VALUES (?, ?, ?, ?, dateof(now()))`
+const formatAddKeyspace = `INSERT INTO %s.ts_keyspace (key, contact, datacenter, replication_factor, layout, ttl, creation_date) VALUES (?, ?, ?, ?, ?, ?, dateof(now()))`
 
 const formatCreateKeyspace = `
     CREATE KEYSPACE %s WITH replication={
@@ -24,9 +24,53 @@ const formatCreateTable = `
 	AND read_repair_chance = 0.01
 	AND speculative_retry = '70.0PERCENTILE'
 `
+const formatCreateRollupTable = `
+	CREATE TABLE IF NOT EXISTS %s.%s (id text, date timestamp, min double, max double, sum double, count bigint, PRIMARY KEY (id, date))
+	WITH CLUSTERING ORDER BY (date %s)
+	AND bloom_filter_fp_chance = 0.01
+	AND caching = {'keys':'ALL', 'rows_per_partition':'ALL'}
+	AND comment = ''
+	AND compaction = {'compaction_window_unit': 'DAYS', 'compaction_window_size': %d, 'class':'TimeWindowCompactionStrategy'}
+	AND compression = {'crc_check_chance': '0.25', 'sstable_compression': 'org.apache.cassandra.io.compress.LZ4Compressor', 'chunk_length_kb': 4}
+	AND dclocal_read_repair_chance = 0.05
+	AND default_time_to_live = %d
+	AND max_index_interval = 2048
+	AND min_index_interval = 128
+	AND read_repair_chance = 0.01
+	AND speculative_retry = '70.0PERCENTILE'
+`
+
+const formatCreateBlockTable = `
+	CREATE TABLE IF NOT EXISTS %s.ts_number_block (id text, window timestamp, block timeuuid, points blob, PRIMARY KEY (id, window, block))
+	WITH CLUSTERING ORDER BY (window %s, block ASC)
+	AND bloom_filter_fp_chance = 0.01
+	AND caching = {'keys':'ALL', 'rows_per_partition':'ALL'}
+	AND comment = ''
+	AND compaction = {'compaction_window_unit': 'DAYS', 'compaction_window_size': 1, 'class':'TimeWindowCompactionStrategy'}
+	AND compression = {'crc_check_chance': '0.25', 'sstable_compression': 'org.apache.cassandra.io.compress.LZ4Compressor', 'chunk_length_kb': 4}
+	AND dclocal_read_repair_chance = 0.05
+	AND default_time_to_live = %d
+	AND max_index_interval = 2048
+	AND min_index_interval = 128
+	AND read_repair_chance = 0.01
+	AND speculative_retry = '70.0PERCENTILE'
+`
+
+const formatCreateSeriesTable = `
+	CREATE TABLE IF NOT EXISTS %s.ts_series (id text, type text, keyset text, metric text, tag_keys list<text>, tag_values list<text>, PRIMARY KEY (id, type))
+	WITH bloom_filter_fp_chance = 0.01
+	AND caching = {'keys':'ALL', 'rows_per_partition':'ALL'}
+	AND comment = ''
+	AND compaction = {'class':'SizeTieredCompactionStrategy'}
+	AND compression = {'crc_check_chance': '0.25', 'sstable_compression': 'org.apache.cassandra.io.compress.LZ4Compressor', 'chunk_length_kb': 4}
+	AND dclocal_read_repair_chance = 0.05
+	AND default_time_to_live = %d
+	AND read_repair_chance = 0.01
+`
+
 const formatDeleteKeyspace = `DROP KEYSPACE IF EXISTS %s`
 
-const formatGetKeyspace = `SELECT key, contact, datacenter, replication_factor FROM %s.ts_keyspace WHERE key = ?`
+const formatGetKeyspace = `SELECT key, contact, datacenter, replication_factor, layout, ttl, replication, creation_date FROM %s.ts_keyspace WHERE key = ?`
 
 var formatGrants = []string{
 	`GRANT MODIFY ON KEYSPACE %s TO %s`,
@@ -35,4 +79,14 @@ var formatGrants = []string{
 
 const formatUpdateKeyspace = `UPDATE %s.ts_keyspace SET contact = ? WHERE key = ?`
 
+const formatDeleteKeyspaceMetadata = `DELETE FROM %s.ts_keyspace WHERE key = ?`
+
+const formatAlterTableTTL = `ALTER TABLE %s.%s WITH default_time_to_live = %d AND compaction = {'compaction_window_unit': 'DAYS', 'compaction_window_size': %d, 'class':'TimeWindowCompactionStrategy'}`
+
+const formatUpdateKeyspaceTTL = `UPDATE %s.ts_keyspace SET ttl = ? WHERE key = ?`
+
+const formatAlterKeyspaceReplication = `ALTER KEYSPACE %s WITH replication = {'class': 'NetworkTopologyStrategy', %s}`
+
+const formatUpdateKeyspaceReplication = `UPDATE %s.ts_keyspace SET replication = ?, replication_factor = ? WHERE key = ?`
+
 const formatListDatacenters = `SELECT datacenter FROM %s.ts_datacenter`
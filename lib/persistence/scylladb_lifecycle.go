@@ -0,0 +1,159 @@
+package persistence
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uol/gobol"
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	funcUpdateKeyspaceTTL string = "UpdateKeyspaceTTL"
+	formatListTableTTLs   string = `SELECT table_name, default_time_to_live FROM system_schema.tables WHERE keyspace_name = ?`
+)
+
+// UpdateKeyspaceTTL - changes the default TTL of the keyspace tables, the compaction window is recomputed
+// from the TTL, the points already written keep their TTL. Only the tables the keyspace has are altered,
+// the older keyspaces do not have all of them, and the altered ones get their TTL back when one fails
+func (backend *scylladb) UpdateKeyspaceTTL(ksid string, ttl int) gobol.Error {
+
+	ks, found, gerr := backend.GetKeyspace(ksid)
+	if gerr != nil {
+		return gerr
+	} else if !found {
+		return errNotFound(funcUpdateKeyspaceTTL, structName, constants.StringsEmpty)
+	}
+
+	tables, err := backend.ttlTables(ks)
+	if err != nil {
+		backend.statsQueryError(funcUpdateKeyspaceTTL, ksid, constants.CRUDOperationSelect)
+		return errPersist(funcUpdateKeyspaceTTL, structName, err)
+	}
+
+	start := time.Now()
+
+	for i, table := range tables {
+
+		if err := backend.alterTableTTL(ksid, table.name, ttl); err != nil {
+			backend.statsQueryError(funcUpdateKeyspaceTTL, ksid, constants.CRUDOperationUpdate)
+			return errPersist(funcUpdateKeyspaceTTL, structName, backend.restoreTableTTLs(ksid, tables[:i], err))
+		}
+	}
+
+	if err := backend.session.Query(fmt.Sprintf(formatUpdateKeyspaceTTL, backend.ksMngr), ttl, ksid).Exec(); err != nil {
+		backend.statsQueryError(funcUpdateKeyspaceTTL, backend.ksMngr, constants.CRUDOperationUpdate)
+		return errPersist(funcUpdateKeyspaceTTL, structName, backend.restoreTableTTLs(ksid, tables, err))
+	}
+
+	backend.statsQuery(funcUpdateKeyspaceTTL, ksid, constants.CRUDOperationUpdate, time.Since(start))
+
+	return nil
+}
+
+// tableTTL - a table of the keyspace and its TTL in days
+type tableTTL struct {
+	name string
+	ttl  int
+}
+
+// ttlTables - returns the tables of the keyspace having the keyspace TTL and their current TTLs
+func (backend *scylladb) ttlTables(ks Keyspace) ([]tableTTL, error) {
+
+	iter := backend.session.Query(formatListTableTTLs, ks.Name).Iter()
+
+	existing := map[string]int{}
+
+	var name string
+	var seconds int
+	for iter.Scan(&name, &seconds) {
+		existing[name] = seconds / 86400
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	numberTable := "ts_number_stamp"
+	if ks.Layout == constants.KeyspaceLayoutBlock {
+		numberTable = "ts_number_block"
+	}
+
+	tables := []tableTTL{}
+	for _, table := range []string{numberTable, "ts_text_stamp", "ts_histogram_stamp", "ts_series"} {
+		if ttl, ok := existing[table]; ok {
+			tables = append(tables, tableTTL{name: table, ttl: ttl})
+		}
+	}
+
+	return tables, nil
+}
+
+// alterTableTTL - changes the default TTL and the compaction window of the table
+func (backend *scylladb) alterTableTTL(ksid, table string, ttl int) error {
+
+	return backend.session.Query(fmt.Sprintf(formatAlterTableTTL, ksid, table, uint64(ttl)*86400, compactionWindow(ttl))).Exec()
+}
+
+// restoreTableTTLs - gives the altered tables their TTL back, the error reports the
+// tables left with the new TTL when they can not be restored
+func (backend *scylladb) restoreTableTTLs(ksid string, tables []tableTTL, cause error) error {
+
+	failed := []string{}
+	for _, table := range tables {
+		if err := backend.alterTableTTL(ksid, table.name, table.ttl); err != nil {
+			failed = append(failed, table.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s, the tables %s were left with the new TTL", cause.Error(), strings.Join(failed, ", "))
+	}
+
+	return cause
+}
+
+const funcUpdateKeyspaceReplication string = "UpdateKeyspaceReplication"
+
+// UpdateKeyspaceReplication - replaces the replication factor of each datacenter of the keyspace, a repair
+// is needed in the cluster to stream the existing data to the new replicas
+func (backend *scylladb) UpdateKeyspaceReplication(ksid string, replication map[string]int) gobol.Error {
+
+	ks, found, gerr := backend.GetKeyspace(ksid)
+	if gerr != nil {
+		return gerr
+	} else if !found {
+		return errNotFound(funcUpdateKeyspaceReplication, structName, constants.StringsEmpty)
+	}
+
+	datacenters := make([]string, 0, len(replication))
+	for dc, factor := range replication {
+		datacenters = append(datacenters, fmt.Sprintf("'%s': %d", dc, factor))
+	}
+	sort.Strings(datacenters)
+
+	start := time.Now()
+
+	query := fmt.Sprintf(formatAlterKeyspaceReplication, ksid, strings.Join(datacenters, ", "))
+
+	if err := backend.session.Query(query).Exec(); err != nil {
+		backend.statsQueryError(funcUpdateKeyspaceReplication, ksid, constants.CRUDOperationUpdate)
+		return errPersist(funcUpdateKeyspaceReplication, structName, err)
+	}
+
+	if err := backend.session.Query(
+		fmt.Sprintf(formatUpdateKeyspaceReplication, backend.ksMngr),
+		replication,
+		replication[ks.DC],
+		ksid,
+	).Exec(); err != nil {
+		backend.statsQueryError(funcUpdateKeyspaceReplication, backend.ksMngr, constants.CRUDOperationUpdate)
+		return errPersist(funcUpdateKeyspaceReplication, structName, err)
+	}
+
+	backend.statsQuery(funcUpdateKeyspaceReplication, ksid, constants.CRUDOperationUpdate, time.Since(start))
+
+	return nil
+}
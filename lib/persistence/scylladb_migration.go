@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"fmt"
+)
+
+// The admin tables created before a column was added are altered when the
+// service starts, so the queries reading the new columns do not fail
+
+const (
+	formatListTableColumns string = `SELECT column_name FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?`
+	formatAddTableColumn   string = `ALTER TABLE %s.%s ADD %s %s`
+)
+
+type tableColumn struct {
+	name    string
+	cqlType string
+}
+
+// keyspaceTableColumns - the ts_keyspace columns added after the table was first created
+var keyspaceTableColumns = []tableColumn{
+	{name: "layout", cqlType: "text"},
+	{name: "ttl", cqlType: "int"},
+	{name: "replication", cqlType: "map<text, int>"},
+}
+
+// migrateKeyspaceTable - adds the missing columns to ts_keyspace
+func (backend *scylladb) migrateKeyspaceTable() error {
+
+	return backend.addMissingColumns("ts_keyspace", keyspaceTableColumns)
+}
+
+// addMissingColumns - adds the columns the admin table does not have
+func (backend *scylladb) addMissingColumns(table string, columns []tableColumn) error {
+
+	iter := backend.session.Query(formatListTableColumns, backend.ksMngr, table).Iter()
+
+	existing := map[string]bool{}
+
+	var name string
+	for iter.Scan(&name) {
+		existing[name] = true
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// the table is created by the deploy scripts, nothing to migrate before it
+	if len(existing) == 0 {
+		return nil
+	}
+
+	for _, column := range columns {
+
+		if existing[column.name] {
+			continue
+		}
+
+		err := backend.session.Query(fmt.Sprintf(formatAddTableColumn, backend.ksMngr, table, column.name, column.cqlType)).Exec()
+		if err != nil {
+			return fmt.Errorf("error adding the column %s to %s: %s", column.name, table, err.Error())
+		}
+	}
+
+	return nil
+}
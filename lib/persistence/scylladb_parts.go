@@ -21,6 +21,8 @@ func (backend *scylladb) addKeyspaceMetadata(ks Keyspace) gobol.Error {
 		ks.Contact,
 		ks.DC,
 		ks.Replication,
+		string(ks.Layout),
+		ks.TTL,
 	).Exec(); err != nil {
 		backend.statsQueryError(funcAddKeyspaceMetadata, backend.ksMngr, constants.CRUDOperationInsert)
 		return errPersist(funcAddKeyspaceMetadata, structName, err)
@@ -80,10 +82,102 @@ func (backend *scylladb) createNumericTable(ks Keyspace) gobol.Error {
 	return backend.createTable(ks.Name, "double", "ts_number_stamp", backend.clusteringOrder, "createNumericTable", ks.TTL)
 }
 
+const funcCreateBlockTable string = "createBlockTable"
+
+func (backend *scylladb) createBlockTable(ks Keyspace) gobol.Error {
+
+	query := fmt.Sprintf(
+		formatCreateBlockTable,
+		ks.Name,
+		backend.clusteringOrder,
+		uint64(ks.TTL)*86400,
+	)
+
+	start := time.Now()
+
+	if err := backend.session.Query(query).Exec(); err != nil {
+		backend.statsQueryError(funcCreateBlockTable, ks.Name, constants.CRUDOperationCreate)
+		return errPersist(funcCreateBlockTable, structName, err)
+	}
+
+	backend.statsQuery(funcCreateBlockTable, ks.Name, constants.CRUDOperationCreate, time.Since(start))
+
+	return nil
+}
+
 func (backend *scylladb) createTextTable(ks Keyspace) gobol.Error {
 	return backend.createTable(ks.Name, "text", "ts_text_stamp", backend.clusteringOrder, "createTextTable", ks.TTL)
 }
 
+func (backend *scylladb) createHistogramTable(ks Keyspace) gobol.Error {
+	return backend.createTable(ks.Name, "blob", "ts_histogram_stamp", backend.clusteringOrder, "createHistogramTable", ks.TTL)
+}
+
+const funcCreateSeriesTable string = "createSeriesTable"
+
+// createSeriesTable - creates the table of the series metric and tags, it rebuilds the lost metadata
+// documents, the rows expire with the points since the collector writes them again when it touches a serie
+func (backend *scylladb) createSeriesTable(ks Keyspace) gobol.Error {
+
+	query := fmt.Sprintf(
+		formatCreateSeriesTable,
+		ks.Name,
+		uint64(ks.TTL)*86400,
+	)
+
+	start := time.Now()
+
+	if err := backend.session.Query(query).Exec(); err != nil {
+		backend.statsQueryError(funcCreateSeriesTable, ks.Name, constants.CRUDOperationCreate)
+		return errPersist(funcCreateSeriesTable, structName, err)
+	}
+
+	backend.statsQuery(funcCreateSeriesTable, ks.Name, constants.CRUDOperationCreate, time.Since(start))
+
+	return nil
+}
+
+const funcCreateRollupTables string = "createRollupTables"
+
+// compactionWindow - the window size in days of a table with the TTL, the
+// TimeWindowCompactionStrategy works best with around 30 windows per table
+func compactionWindow(ttl int) int {
+
+	windowSize := ttl / 30
+	if windowSize < 1 {
+		windowSize = 1
+	}
+
+	return windowSize
+}
+
+// createRollupTables - creates one table per rollup resolution, with the resolution TTL
+func (backend *scylladb) createRollupTables(keyspace string) gobol.Error {
+
+	for _, r := range backend.rollups {
+
+		query := fmt.Sprintf(
+			formatCreateRollupTable,
+			keyspace,
+			r.Table,
+			backend.clusteringOrder,
+			compactionWindow(r.TTL),
+			uint64(r.TTL)*86400,
+		)
+
+		start := time.Now()
+
+		if err := backend.session.Query(query).Exec(); err != nil {
+			backend.statsQueryError(funcCreateRollupTables, keyspace, constants.CRUDOperationCreate)
+			return errPersist(funcCreateRollupTables, structName, err)
+		}
+
+		backend.statsQuery(funcCreateRollupTables, keyspace, constants.CRUDOperationCreate, time.Since(start))
+	}
+
+	return nil
+}
+
 func (backend *scylladb) setPermissions(ks Keyspace) gobol.Error {
 	if len(backend.grantUsername) <= 0 {
 		return nil
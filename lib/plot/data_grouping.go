@@ -2,6 +2,7 @@ package plot
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/uol/mycenae/lib/structs"
@@ -15,6 +16,11 @@ const (
 	msWeek = 6.048e+8
 )
 
+var (
+	windowOperations  = []string{"increase", "delta", "irate", "deriv"}
+	rollingOperations = []string{"movingAverage", "rollingSum", "rollingMax", "rollingMin"}
+)
+
 func basic(totalPoints int, serie []Pnt) (groupSerie []Pnt) {
 
 	total := len(serie)
@@ -109,7 +115,7 @@ func rate(options structs.TSDBrateOptions, serie Pnts) Pnts {
 		var value float64
 
 		if options.Counter && serie[i].Value < serie[i-1].Value {
-			value = (float64(*options.CounterMax) + serie[i].Value - serie[i-1].Value) / float64((serie[i].Date/int64(1000))-(serie[i-1].Date/int64(1000)))
+			value = counterDelta(options, serie[i-1].Value, serie[i].Value) / float64((serie[i].Date/int64(1000))-(serie[i-1].Date/int64(1000)))
 			if options.ResetValue != 0 && float64(options.ResetValue) <= value {
 				value = 0
 			}
@@ -129,68 +135,25 @@ func rate(options structs.TSDBrateOptions, serie Pnts) Pnts {
 	return rateSerie
 }
 
-func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, serie Pnts) Pnts {
+// counterDelta - the difference between two values of a counter, a counter that decreased
+// wrapped around counterMax when it is configured, otherwise it was reset to zero
+func counterDelta(options structs.TSDBrateOptions, previous, current float64) float64 {
 
-	startDate := time.Unix(0, start*1e+6)
+	if current >= previous {
+		return current - previous
+	}
 
-	switch options.Unit {
-	case "sec":
-		base := time.Date(
-			startDate.Year(),
-			startDate.Month(),
-			startDate.Day(),
-			startDate.Hour(),
-			startDate.Minute(),
-			startDate.Second(),
-			0,
-			time.Local,
-		)
-		start = base.Unix() * 1e+3
-	case "min":
-		base := time.Date(
-			startDate.Year(),
-			startDate.Month(),
-			startDate.Day(),
-			startDate.Hour(),
-			startDate.Minute(),
-			0,
-			0,
-			time.Local,
-		)
-		start = base.Unix() * 1e+3
-	case "hour":
-		base := time.Date(
-			startDate.Year(),
-			startDate.Month(),
-			startDate.Day(),
-			startDate.Hour(),
-			0,
-			0,
-			0,
-			time.Local,
-		)
-		start = base.Unix() * 1e+3
-	case "day":
-		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
-		start = base.Unix() * 1e+3
-	case "week":
-		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
-		for base.Weekday() != time.Monday {
-			base = base.AddDate(0, 0, -1)
-		}
-		start = base.Unix() * 1e+3
-	case "month":
-		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
-		for base.Month() == startDate.Month() {
-			base = base.AddDate(0, 0, -1)
-		}
-		base = base.AddDate(0, 0, 1)
-		start = base.Unix() * 1e+3
-	case "year":
-		base := time.Date(startDate.Year(), time.January, 1, 0, 0, 0, 0, time.Local)
-		start = base.Unix() * 1e+3
+	if options.CounterMax != nil {
+		return float64(*options.CounterMax) + current - previous
 	}
 
+	return current
+}
+
+func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, serie Pnts) Pnts {
+
+	start = alignStart(start, options.Unit)
+
 	groupDate := start
 
 	endInterval := getEndInterval(start, options.Unit, options.Value)
@@ -201,6 +164,8 @@ func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, s
 
 	groupedSerie := Pnts{}
 
+	var lastPoint *Pnt
+
 	for i := 0; i < len(serie); i++ {
 
 		point := serie[i]
@@ -208,17 +173,7 @@ func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, s
 		//Ajusting for missing points
 		for point.Date >= endInterval {
 			if keepEmpties {
-				groupedPoint.Date = groupDate
-
-				if options.Fill == "zero" {
-					groupedPoint.Value = 0
-				} else {
-					groupedPoint.Empty = true
-				}
-
-				groupedSerie = append(groupedSerie, groupedPoint)
-
-				groupedPoint = Pnt{}
+				groupedSerie = append(groupedSerie, fillPoint(options, groupDate, lastPoint))
 			}
 
 			groupDate = endInterval
@@ -263,6 +218,9 @@ func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, s
 
 			groupedSerie = append(groupedSerie, groupedPoint)
 
+			filledFrom := groupedPoint
+			lastPoint = &filledFrom
+
 			groupedCount = 0
 
 			groupedPoint = Pnt{}
@@ -277,23 +235,566 @@ func downsample(options structs.DSoptions, keepEmpties bool, start, end int64, s
 	if keepEmpties {
 		for i := endInterval; i < end; i = endInterval {
 
-			groupedPoint := Pnt{
-				Date: endInterval,
+			groupedSerie = append(groupedSerie, fillPoint(options, endInterval, lastPoint))
+
+			endInterval = getEndInterval(i, options.Unit, options.Value)
+		}
+
+		if options.Fill == "linear" {
+			fillLinear(options.MaxGap, groupedSerie)
+		}
+	}
+
+	return groupedSerie
+}
+
+// downsampleRollup - downsamples rollup buckets, all of them must fit inside the downsample intervals,
+// the raw points newer than the rollups come as buckets of a single point
+func downsampleRollup(options structs.DSoptions, keepEmpties bool, start, end int64, serie []RollupPnt) Pnts {
+
+	grouping := options
+	grouping.Fill = "none"
+
+	values := make(Pnts, len(serie))
+
+	for i, bucket := range serie {
+
+		values[i].Date = bucket.Date
+
+		switch options.Downsample {
+		case "min":
+			values[i].Value = bucket.Min
+		case "max":
+			values[i].Value = bucket.Max
+		case "sum", "avg":
+			values[i].Value = bucket.Sum
+		case "pnt":
+			values[i].Value = float64(bucket.Count)
+		}
+	}
+
+	if options.Downsample != "min" && options.Downsample != "max" {
+		grouping.Downsample = "sum"
+	}
+
+	groupedSerie := downsample(grouping, false, start, end, values)
+
+	if options.Downsample == "avg" {
+
+		counts := make(Pnts, len(serie))
+
+		for i, bucket := range serie {
+			counts[i].Date = bucket.Date
+			counts[i].Value = float64(bucket.Count)
+		}
+
+		groupedCounts := downsample(grouping, false, start, end, counts)
+
+		for i := range groupedSerie {
+			groupedSerie[i].Value = groupedSerie[i].Value / groupedCounts[i].Value
+		}
+	}
+
+	if !keepEmpties {
+		return groupedSerie
+	}
+
+	// there is one point per interval now, so the max keeps them and only the fill policy is applied
+	filling := options
+	filling.Downsample = "max"
+
+	return downsample(filling, true, start, end, groupedSerie)
+}
+
+// fillPoint - builds the point of an interval without data following the fill policy,
+// previous/last carries the last value forward while it is not older than maxGap
+func fillPoint(options structs.DSoptions, date int64, lastPoint *Pnt) Pnt {
+
+	point := Pnt{
+		Date: date,
+	}
+
+	switch options.Fill {
+	case "zero":
+		point.Value = 0
+	case "value":
+		point.Value = options.FillValue
+	case "previous", "last":
+		if lastPoint != nil && (options.MaxGap == 0 || date-lastPoint.Date <= options.MaxGap) {
+			point.Value = lastPoint.Value
+		} else {
+			point.Empty = true
+		}
+	default:
+		point.Empty = true
+	}
+
+	return point
+}
+
+// fillLinear - replaces the empty points between two points with data by their linear
+// interpolation, unless the distance between the points with data is bigger than maxGap
+func fillLinear(maxGap int64, serie Pnts) {
+
+	previous := -1
+
+	for i := range serie {
+
+		if serie[i].Empty {
+			continue
+		}
+
+		if previous >= 0 && i-previous > 1 {
+
+			first := serie[previous]
+			last := serie[i]
+
+			if maxGap == 0 || last.Date-first.Date <= maxGap {
+				for j := previous + 1; j < i; j++ {
+					serie[j].Value = first.Value + (last.Value-first.Value)*float64(serie[j].Date-first.Date)/float64(last.Date-first.Date)
+					serie[j].Empty = false
+				}
 			}
+		}
+
+		previous = i
+	}
+}
+
+// windowed - computes an increase, delta, irate or deriv for each window of the serie, the
+// difference between the last point of a window and the first point of the next one belongs
+// to the later window, so counter resets inside or between windows are never lost
+func windowed(function string, oper structs.WindowOperation, start int64, serie Pnts) Pnts {
 
-			if options.Fill == "zero" {
-				groupedPoint.Value = 0
-			} else {
-				groupedPoint.Empty = true
+	windowStart := alignStart(start, oper.Unit)
+
+	windowEnd := getEndInterval(windowStart, oper.Unit, oper.Value)
+
+	acc := windowAccumulator{
+		function: function,
+		options:  oper.Options,
+		start:    windowStart,
+	}
+
+	windowedSerie := Pnts{}
+
+	var previous Pnt
+
+	hasPrevious := false
+
+	for _, point := range serie {
+
+		if point.Empty {
+			continue
+		}
+
+		for point.Date >= windowEnd {
+
+			if value, ok := acc.result(); ok {
+				windowedSerie = append(windowedSerie, Pnt{Date: windowStart, Value: value})
 			}
 
-			groupedSerie = append(groupedSerie, groupedPoint)
+			acc.reset(windowEnd)
 
-			endInterval = getEndInterval(i, options.Unit, options.Value)
+			windowStart = windowEnd
+
+			windowEnd = getEndInterval(windowEnd, oper.Unit, oper.Value)
 		}
+
+		acc.addPoint(point)
+
+		if hasPrevious {
+			acc.addPair(previous, point)
+		}
+
+		previous = point
+
+		hasPrevious = true
 	}
 
-	return groupedSerie
+	if value, ok := acc.result(); ok {
+		windowedSerie = append(windowedSerie, Pnt{Date: windowStart, Value: value})
+	}
+
+	return windowedSerie
+}
+
+type windowAccumulator struct {
+	function string
+	options  structs.TSDBrateOptions
+	start    int64
+	pairs    int
+	value    float64
+	n        float64
+	sumX     float64
+	sumY     float64
+	sumXX    float64
+	sumXY    float64
+}
+
+func (acc *windowAccumulator) reset(start int64) {
+	*acc = windowAccumulator{
+		function: acc.function,
+		options:  acc.options,
+		start:    start,
+	}
+}
+
+// addPoint - feeds the least squares regression used by deriv
+func (acc *windowAccumulator) addPoint(point Pnt) {
+
+	if acc.function != "deriv" {
+		return
+	}
+
+	x := float64(point.Date-acc.start) / msSec
+
+	acc.n++
+	acc.sumX += x
+	acc.sumY += point.Value
+	acc.sumXX += x * x
+	acc.sumXY += x * point.Value
+}
+
+// addPair - feeds the difference between two consecutive points used by increase, delta and irate
+func (acc *windowAccumulator) addPair(previous, current Pnt) {
+
+	switch acc.function {
+	case "increase":
+		value := counterDelta(acc.options, previous.Value, current.Value)
+		if acc.options.ResetValue != 0 && float64(acc.options.ResetValue) <= value {
+			value = 0
+		}
+		acc.value += value
+	case "delta":
+		acc.value += current.Value - previous.Value
+	case "irate":
+		seconds := float64(current.Date-previous.Date) / msSec
+		if seconds <= 0 {
+			return
+		}
+		value := counterDelta(acc.options, previous.Value, current.Value) / seconds
+		if acc.options.ResetValue != 0 && float64(acc.options.ResetValue) <= value {
+			value = 0
+		}
+		acc.value = value
+	}
+
+	acc.pairs++
+}
+
+func (acc *windowAccumulator) result() (float64, bool) {
+
+	if acc.function == "deriv" {
+
+		denominator := acc.n*acc.sumXX - acc.sumX*acc.sumX
+
+		if acc.n < 2 || denominator == 0 {
+			return 0, false
+		}
+
+		return (acc.n*acc.sumXY - acc.sumX*acc.sumY) / denominator, true
+	}
+
+	return acc.value, acc.pairs > 0
+}
+
+// rollingWindow - computes the average, sum, max or min of the points inside the window ending on each point,
+// empty points are kept as they are and left out of the windows
+func rollingWindow(function string, window int64, serie Pnts) Pnts {
+
+	rolledSerie := make(Pnts, 0, len(serie))
+
+	values := Pnts{}
+
+	first := 0
+
+	var sum float64
+
+	// indexes of the values that can still be the max or min of a window, the first one is the current
+	extremes := []int{}
+
+	for _, point := range serie {
+
+		if point.Empty {
+			rolledSerie = append(rolledSerie, point)
+			continue
+		}
+
+		values = append(values, point)
+
+		last := len(values) - 1
+
+		sum += point.Value
+
+		for len(extremes) > 0 && !outranks(function, values[extremes[len(extremes)-1]].Value, point.Value) {
+			extremes = extremes[:len(extremes)-1]
+		}
+
+		extremes = append(extremes, last)
+
+		for values[first].Date <= point.Date-window {
+			sum -= values[first].Value
+			first++
+		}
+
+		for extremes[0] < first {
+			extremes = extremes[1:]
+		}
+
+		var value float64
+
+		switch function {
+		case "movingAverage":
+			value = sum / float64(last-first+1)
+		case "rollingSum":
+			value = sum
+		case "rollingMax", "rollingMin":
+			value = values[extremes[0]].Value
+		}
+
+		rolledSerie = append(rolledSerie, Pnt{Date: point.Date, Value: value})
+	}
+
+	return rolledSerie
+}
+
+// outranks - tells if an older value stays ahead of a newer one as the max or min of a window
+func outranks(function string, older, newer float64) bool {
+
+	if function == "rollingMin" {
+		return older < newer
+	}
+
+	return older > newer
+}
+
+// ewma - computes the exponentially weighted moving average of the serie
+func ewma(alpha float64, serie Pnts) Pnts {
+
+	smoothedSerie := make(Pnts, 0, len(serie))
+
+	var level float64
+
+	started := false
+
+	for _, point := range serie {
+
+		if point.Empty {
+			smoothedSerie = append(smoothedSerie, point)
+			continue
+		}
+
+		if started {
+			level = alpha*point.Value + (1-alpha)*level
+		} else {
+			level = point.Value
+			started = true
+		}
+
+		smoothedSerie = append(smoothedSerie, Pnt{Date: point.Date, Value: level})
+	}
+
+	return smoothedSerie
+}
+
+// holtWinters - computes the double exponential smoothing of the serie, the level is smoothed by
+// alpha and the trend by beta, the first trend is the difference between the first two points
+func holtWinters(alpha, beta float64, serie Pnts) Pnts {
+
+	smoothedSerie := make(Pnts, 0, len(serie))
+
+	var level, trend float64
+
+	seen := 0
+
+	for _, point := range serie {
+
+		if point.Empty {
+			smoothedSerie = append(smoothedSerie, point)
+			continue
+		}
+
+		switch seen {
+		case 0:
+			level = point.Value
+		case 1:
+			trend = point.Value - level
+			fallthrough
+		default:
+			previous := level
+			level = alpha*point.Value + (1-alpha)*(level+trend)
+			trend = beta*(level-previous) + (1-beta)*trend
+		}
+
+		seen++
+
+		smoothedSerie = append(smoothedSerie, Pnt{Date: point.Date, Value: level})
+	}
+
+	return smoothedSerie
+}
+
+// readExtension - returns how many milliseconds the read must go back so the first point of the
+// query has a full rolling window, rounded to whole downsample and window intervals, so they keep
+// the same alignment as the original start
+func readExtension(opers structs.DataOperations) int64 {
+
+	var extension int64
+
+	for _, name := range rollingOperations {
+		if oper := opers.Rolling(name); oper.Enabled && oper.Window > extension {
+			extension = oper.Window
+		}
+	}
+
+	if extension == 0 {
+		return 0
+	}
+
+	var interval int64 = 1
+
+	if opers.Downsample.Enabled {
+		interval = lcm(interval, fixedInterval(opers.Downsample.Options.Unit, opers.Downsample.Options.Value))
+	}
+
+	for _, name := range windowOperations {
+		if oper := opers.Window(name); oper.Enabled {
+			interval = lcm(interval, fixedInterval(oper.Unit, oper.Value))
+		}
+	}
+
+	return (extension + interval - 1) / interval * interval
+}
+
+// outputStart - returns the date of the first point the query can return, the
+// downsample and window intervals begin at the start aligned to their units
+func outputStart(start int64, opers structs.DataOperations) int64 {
+
+	outStart := start
+
+	if opers.Downsample.Enabled {
+		if aligned := alignStart(start, opers.Downsample.Options.Unit); aligned < outStart {
+			outStart = aligned
+		}
+	}
+
+	for _, name := range windowOperations {
+		if oper := opers.Window(name); oper.Enabled {
+			if aligned := alignStart(start, oper.Unit); aligned < outStart {
+				outStart = aligned
+			}
+		}
+	}
+
+	return outStart
+}
+
+// fixedInterval - returns the length in ms of an interval, months and years
+// have no fixed length, any extension keeps them aligned to their units
+func fixedInterval(unit string, value int) int64 {
+
+	switch unit {
+	case "month", "year":
+		return 1
+	}
+
+	if interval := getEndInterval(0, unit, value); interval > 0 {
+		return interval
+	}
+
+	return 1
+}
+
+func lcm(a, b int64) int64 {
+
+	x, y := a, b
+
+	for y != 0 {
+		x, y = y, x%y
+	}
+
+	return a / x * b
+}
+
+// trimBefore - drops the points older than the start
+func trimBefore(start int64, serie Pnts) Pnts {
+
+	trimmedSerie := make(Pnts, 0, len(serie))
+
+	for _, point := range serie {
+		if point.Date >= start {
+			trimmedSerie = append(trimmedSerie, point)
+		}
+	}
+
+	return trimmedSerie
+}
+
+// alignStart - moves the start back to the beginning of its unit, so the intervals begin at round dates
+func alignStart(start int64, unit string) int64 {
+
+	startDate := time.Unix(0, start*1e+6)
+
+	switch unit {
+	case "sec":
+		base := time.Date(
+			startDate.Year(),
+			startDate.Month(),
+			startDate.Day(),
+			startDate.Hour(),
+			startDate.Minute(),
+			startDate.Second(),
+			0,
+			time.Local,
+		)
+		start = base.Unix() * 1e+3
+	case "min":
+		base := time.Date(
+			startDate.Year(),
+			startDate.Month(),
+			startDate.Day(),
+			startDate.Hour(),
+			startDate.Minute(),
+			0,
+			0,
+			time.Local,
+		)
+		start = base.Unix() * 1e+3
+	case "hour":
+		base := time.Date(
+			startDate.Year(),
+			startDate.Month(),
+			startDate.Day(),
+			startDate.Hour(),
+			0,
+			0,
+			0,
+			time.Local,
+		)
+		start = base.Unix() * 1e+3
+	case "day":
+		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
+		start = base.Unix() * 1e+3
+	case "week":
+		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
+		for base.Weekday() != time.Monday {
+			base = base.AddDate(0, 0, -1)
+		}
+		start = base.Unix() * 1e+3
+	case "month":
+		base := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
+		for base.Month() == startDate.Month() {
+			base = base.AddDate(0, 0, -1)
+		}
+		base = base.AddDate(0, 0, 1)
+		start = base.Unix() * 1e+3
+	case "year":
+		base := time.Date(startDate.Year(), time.January, 1, 0, 0, 0, 0, time.Local)
+		start = base.Unix() * 1e+3
+	}
+
+	return start
 }
 
 func getEndInterval(start int64, unit string, value int) int64 {
@@ -457,6 +958,107 @@ func merge(mergeType string, keepEmpties bool, serie Pnts) Pnts {
 	return mergedSerie
 }
 
+// lerp - merges the series on every date found in any of them, linearly interpolating the
+// value of the series that have no point on that date but have points before and after it
+func lerp(mergeType string, keepEmpties bool, series []Pnts) Pnts {
+
+	total := 0
+	for _, serie := range series {
+		total += len(serie)
+	}
+
+	dates := make([]int64, 0, total)
+	for _, serie := range series {
+		for _, point := range serie {
+			dates = append(dates, point.Date)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i] < dates[j] })
+
+	cursors := make([]int, len(series))
+
+	mergedSerie := make(Pnts, 0, total)
+
+	for i, date := range dates {
+
+		if i > 0 && date == dates[i-1] {
+			continue
+		}
+
+		var mergedValue, mergedCount float64
+
+		for s, serie := range series {
+
+			value, ok := lerpValue(serie, &cursors[s], date)
+			if !ok {
+				continue
+			}
+
+			mergedCount++
+
+			switch mergeType {
+			case "avg", "sum":
+				mergedValue += value
+			case "max":
+				if mergedCount == 1 || value > mergedValue {
+					mergedValue = value
+				}
+			case "min":
+				if mergedCount == 1 || value < mergedValue {
+					mergedValue = value
+				}
+			}
+		}
+
+		if mergedCount == 0 {
+			if keepEmpties {
+				mergedSerie = append(mergedSerie, Pnt{Date: date, Empty: true})
+			}
+			continue
+		}
+
+		if mergeType == "avg" {
+			mergedValue = mergedValue / mergedCount
+		}
+
+		mergedSerie = append(mergedSerie, Pnt{Date: date, Value: mergedValue})
+	}
+
+	return mergedSerie
+}
+
+// lerpValue - returns the value of a date sorted serie on the given date, the cursor keeps the
+// position of the first point not before the last date looked up, so the dates must be increasing
+func lerpValue(serie Pnts, cursor *int, date int64) (float64, bool) {
+
+	for *cursor < len(serie) && serie[*cursor].Date < date {
+		*cursor++
+	}
+
+	if *cursor == len(serie) {
+		return 0, false
+	}
+
+	next := serie[*cursor]
+
+	if next.Date == date {
+		return next.Value, !next.Empty
+	}
+
+	if *cursor == 0 {
+		return 0, false
+	}
+
+	previous := serie[*cursor-1]
+
+	if previous.Empty || next.Empty {
+		return 0, false
+	}
+
+	return previous.Value + (next.Value-previous.Value)*float64(date-previous.Date)/float64(next.Date-previous.Date), true
+}
+
 func filterValues(oper structs.FilterValueOperation, serie Pnts) Pnts {
 
 	filteredSerie := Pnts{}
@@ -503,6 +1105,31 @@ func filterValues(oper structs.FilterValueOperation, serie Pnts) Pnts {
 	return filteredSerie
 }
 
+// delta - subtracts the shifted serie from the original one on the dates both have values
+func delta(original, shifted Pnts) Pnts {
+
+	shiftedValues := make(map[int64]float64, len(shifted))
+
+	for _, pnt := range shifted {
+		if !pnt.Empty {
+			shiftedValues[pnt.Date] = pnt.Value
+		}
+	}
+
+	deltaSerie := Pnts{}
+
+	for _, pnt := range original {
+		if pnt.Empty {
+			continue
+		}
+		if v, ok := shiftedValues[pnt.Date]; ok {
+			deltaSerie = append(deltaSerie, Pnt{Date: pnt.Date, Value: pnt.Value - v})
+		}
+	}
+
+	return deltaSerie
+}
+
 func round(val float64, roundOn float64, places int) (newVal float64) {
 
 	var round float64
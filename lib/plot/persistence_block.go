@@ -0,0 +1,260 @@
+package plot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/gorilla"
+)
+
+const (
+	funcGetBlockTS     string = "GetBlockTS"
+	queryGetBlockTS    string = `SELECT id, points FROM %s.ts_number_block WHERE id in (%s) AND window >= ? AND window <= ? ALLOW FILTERING`
+	funcGetBlockLastTS string = "GetBlockLastTS"
+	queryGetBlockLast  string = `SELECT window, points FROM %s.ts_number_block WHERE id = ? AND window <= ? ORDER BY window DESC`
+)
+
+var errNoBlockWindow = errors.New("the number blocks window is not configured")
+
+// isBlockLayout - checks if the keyspace stores its numbers in compressed blocks
+func (persist *persistence) isBlockLayout(keyspace string) bool {
+
+	return persist.keyspaceLayouts.Get(keyspace) == constants.KeyspaceLayoutBlock
+}
+
+// blockWindowOf - returns the window of the block containing the date
+func (persist *persistence) blockWindowOf(date int64) int64 {
+
+	return date - date%persist.blockWindow
+}
+
+// dedupePoints - sorts the points by date keeping the last written value of repeated dates,
+// the points must be in the write order of their blocks
+func dedupePoints(points []Pnt) []Pnt {
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Date < points[j].Date
+	})
+
+	result := points[:0]
+
+	for _, p := range points {
+		if len(result) > 0 && result[len(result)-1].Date == p.Date {
+			result[len(result)-1] = p
+			continue
+		}
+		result = append(result, p)
+	}
+
+	return result
+}
+
+// getBlockTS - reads the number points of a keyspace with the block layout
+func (persist *persistence) getBlockTS(keyspace string, keys []string, start, end int64, ms, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]Pnt, uint32, gobol.Error) {
+
+	track := time.Now()
+
+	var tsid string
+	var points []byte
+	var numBytes uint32
+	countRows := 0
+	limitReached := false
+	tsMap := map[string][]Pnt{}
+	_, unlimitedBytes := persist.unlimitedBytesKeysetWhiteList[keyset]
+	allowFullFetch = allowFullFetch || unlimitedBytes
+
+	if persist.blockWindow <= 0 {
+		return nil, 0, errInternalServer(funcGetBlockTS, errNoBlockWindow)
+	}
+
+	iter := persist.cassandra.Query(
+		fmt.Sprintf(
+			queryGetBlockTS,
+			keyspace,
+			persist.buildInGroup(keys),
+		),
+		persist.blockWindowOf(start),
+		persist.blockWindowOf(end),
+	).Iter()
+
+scanLoop:
+	for iter.Scan(&tsid, &points) {
+
+		countRows++
+
+		decoder := gorilla.NewDecoder(points)
+
+		for decoder.Next() {
+
+			date, value := decoder.At()
+
+			if date < start || date > end {
+				continue
+			}
+
+			if _, ok := tsMap[tsid]; !ok {
+				numBytes += uint32(persist.getStringSize(tsid))
+			}
+
+			tsMap[tsid] = append(tsMap[tsid], Pnt{Date: date, Value: value})
+
+			numBytes += uint32(persist.constPartBytesFromNumberPoint)
+
+			if !allowFullFetch && numBytes >= maxBytesLimit {
+				limitReached = true
+				break scanLoop
+			}
+		}
+
+		if err := decoder.Err(); err != nil {
+			if logh.ErrorEnabled {
+				persist.logger.Error().Str(constants.StringsFunc, funcGetBlockTS).Str("tsid", tsid).Err(err).Send()
+			}
+		}
+	}
+
+	persist.statsQueryBytes(funcGetBlockTS, keyset, keyspace, typeNumber, float64(numBytes))
+
+	if err := iter.Close(); err != nil {
+		if logh.ErrorEnabled {
+			logh.Error().Str(constants.StringsFunc, funcGetBlockTS).Err(err).Send()
+		}
+
+		if err == gocql.ErrNotFound {
+			persist.statsSelect(funcGetBlockTS, keyset, keyspace, typeNumber, time.Since(track), countRows)
+			return map[string][]Pnt{}, 0, errNoContent(funcGetBlockTS)
+		}
+
+		persist.statsQueryError(funcGetBlockTS, keyset, keyspace, typeNumber)
+		return map[string][]Pnt{}, 0, errPersist(funcGetBlockTS, err)
+	}
+
+	persist.statsSelect(funcGetBlockTS, keyset, keyspace, typeNumber, time.Since(track), countRows)
+
+	if limitReached && !allowFullFetch {
+		return map[string][]Pnt{}, numBytes, errMaxBytesLimitWrapper(funcGetBlockTS, persist.maxBytesErr)
+	}
+
+	for id, serie := range tsMap {
+
+		serie = dedupePoints(serie)
+
+		if !ms {
+			for i := range serie {
+				serie[i].Date = (serie[i].Date / 1000) * 1000
+			}
+		}
+
+		tsMap[id] = serie
+	}
+
+	return tsMap, numBytes, nil
+}
+
+// getBlockLastTS - reads the last number point before the end of each timeseries of a keyspace with the block layout
+func (persist *persistence) getBlockLastTS(keyspace string, keys []string, end int64, ms, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string]Pnt, uint32, gobol.Error) {
+
+	var window int64
+	var points []byte
+	var numBytes uint32
+	limitReached := false
+	tsMap := map[string]Pnt{}
+	countRows := 0
+	_, unlimitedBytes := persist.unlimitedBytesKeysetWhiteList[keyset]
+	allowFullFetch = allowFullFetch || unlimitedBytes
+
+	if persist.blockWindow <= 0 {
+		return nil, 0, errInternalServer(funcGetBlockLastTS, errNoBlockWindow)
+	}
+
+	lastWindow := persist.blockWindowOf(end)
+	if end == 0 {
+		lastWindow = persist.blockWindowOf(time.Now().UnixNano()/int64(time.Millisecond)) + persist.blockWindow
+	}
+
+mainLoop:
+	for _, id := range keys {
+
+		track := time.Now()
+
+		iter := persist.cassandra.Query(
+			fmt.Sprintf(queryGetBlockLast, keyspace),
+			id,
+			lastWindow,
+		).Iter()
+
+		var last *Pnt
+
+		for iter.Scan(&window, &points) {
+
+			if last != nil && persist.blockWindowOf(last.Date) > window {
+				break
+			}
+
+			decoder := gorilla.NewDecoder(points)
+
+			for decoder.Next() {
+
+				date, value := decoder.At()
+
+				if end != 0 && date >= end {
+					continue
+				}
+
+				if last == nil || date >= last.Date {
+					last = &Pnt{Date: date, Value: value}
+				}
+			}
+		}
+
+		if err := iter.Close(); err != nil {
+
+			if err == gocql.ErrNotFound {
+				continue mainLoop
+			}
+
+			if logh.ErrorEnabled {
+				logh.Error().Str(constants.StringsFunc, funcGetBlockLastTS).Err(err).Send()
+			}
+
+			persist.statsQueryError(funcGetBlockLastTS, keyset, keyspace, typeNumber)
+			return map[string]Pnt{}, 0, errPersist(funcGetBlockLastTS, err)
+		}
+
+		if last != nil {
+
+			if !ms {
+				last.Date = (last.Date / 1000) * 1000
+			}
+
+			tsMap[id] = *last
+
+			numBytes += uint32(persist.getStringSize(id))
+			numBytes += uint32(persist.constPartBytesFromNumberPoint)
+
+			countRows++
+		}
+
+		persist.statsSelect(funcGetBlockLastTS, keyset, keyspace, typeNumber, time.Since(track), countRows)
+
+		if !allowFullFetch && numBytes >= maxBytesLimit {
+			limitReached = true
+			break
+		}
+	}
+
+	persist.statsQueryBytes(funcGetBlockLastTS, keyset, keyspace, typeNumber, float64(numBytes))
+
+	if limitReached && !allowFullFetch {
+		return map[string]Pnt{}, numBytes, errMaxBytesLimitWrapper(funcGetBlockLastTS, persist.maxBytesErr)
+	}
+
+	return tsMap, numBytes, nil
+}
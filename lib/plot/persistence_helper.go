@@ -18,3 +18,107 @@ func (persist *persistence) buildInGroup(keys []string) string {
 
 	return strings.TrimRight(value, ",")
 }
+
+// pageCapacity - the initial capacity of a serie, the rows of the current page are shared by all keys
+func pageCapacity(pageRows, numKeys int) int {
+
+	if numKeys < 1 {
+		numKeys = 1
+	}
+
+	return pageRows/numKeys + 1
+}
+
+// pointsAssembler - groups the scanned number rows by tsid, the rows are always appended
+// and the series are reversed once at the end if the table is in descending order
+type pointsAssembler struct {
+	tsMap   map[string][]Pnt
+	desc    bool
+	numKeys int
+}
+
+func newPointsAssembler(clusteringOrder constants.ClusteringOrder, numKeys int) *pointsAssembler {
+
+	return &pointsAssembler{
+		tsMap:   map[string][]Pnt{},
+		desc:    clusteringOrder == constants.ClusteringOrderDESC,
+		numKeys: numKeys,
+	}
+}
+
+// add - appends the row to its serie and tells if it is the first row of the serie
+func (a *pointsAssembler) add(tsid string, date int64, value float64, pageRows int) bool {
+
+	serie, ok := a.tsMap[tsid]
+	if !ok {
+		serie = make([]Pnt, 0, pageCapacity(pageRows, a.numKeys))
+	}
+
+	a.tsMap[tsid] = append(serie, Pnt{
+		Date:  date,
+		Value: value,
+	})
+
+	return !ok
+}
+
+// series - returns the series in ascending date order
+func (a *pointsAssembler) series() map[string][]Pnt {
+
+	if a.desc {
+		for _, serie := range a.tsMap {
+			for i, j := 0, len(serie)-1; i < j; i, j = i+1, j-1 {
+				serie[i], serie[j] = serie[j], serie[i]
+			}
+		}
+	}
+
+	return a.tsMap
+}
+
+// textPointsAssembler - groups the scanned text rows by tsid, the rows are always appended
+// and the series are reversed once at the end if the table is in descending order
+type textPointsAssembler struct {
+	tsMap   map[string][]TextPnt
+	desc    bool
+	numKeys int
+}
+
+func newTextPointsAssembler(clusteringOrder constants.ClusteringOrder, numKeys int) *textPointsAssembler {
+
+	return &textPointsAssembler{
+		tsMap:   map[string][]TextPnt{},
+		desc:    clusteringOrder == constants.ClusteringOrderDESC,
+		numKeys: numKeys,
+	}
+}
+
+// add - appends the row to its serie and tells if it is the first row of the serie
+func (a *textPointsAssembler) add(tsid string, date int64, value string, pageRows int) bool {
+
+	serie, ok := a.tsMap[tsid]
+	if !ok {
+		serie = make([]TextPnt, 0, pageCapacity(pageRows, a.numKeys))
+	}
+
+	a.tsMap[tsid] = append(serie, TextPnt{
+		Date:  date,
+		Value: value,
+	})
+
+	return !ok
+}
+
+// series - returns the series in ascending date order
+func (a *textPointsAssembler) series() map[string][]TextPnt {
+
+	if a.desc {
+		for _, serie := range a.tsMap {
+			for i, j := 0, len(serie)-1; i < j; i, j = i+1, j-1 {
+				serie[i], serie[j] = serie[j], serie[i]
+			}
+		}
+	}
+
+	return a.tsMap
+}
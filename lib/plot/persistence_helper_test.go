@@ -0,0 +1,108 @@
+package plot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	benchmarkPageSize int   = 5000
+	benchmarkStart    int64 = 1448452800000
+)
+
+var benchmarkKeys = []string{"tsid1", "tsid2", "tsid3", "tsid4"}
+
+// scanNumbers - feeds the assembler as the scylla iterator does, one page at a time
+func scanNumbers(clusteringOrder constants.ClusteringOrder, numPoints int) map[string][]Pnt {
+
+	assembler := newPointsAssembler(clusteringOrder, len(benchmarkKeys))
+
+	perKey := numPoints / len(benchmarkKeys)
+
+	for _, key := range benchmarkKeys {
+		for i := 0; i < perKey; i++ {
+
+			step := int64(i)
+			if clusteringOrder == constants.ClusteringOrderDESC {
+				step = int64(perKey - 1 - i)
+			}
+
+			assembler.add(key, benchmarkStart+step*1000, float64(step), benchmarkPageSize)
+		}
+	}
+
+	return assembler.series()
+}
+
+func scanTexts(clusteringOrder constants.ClusteringOrder, numPoints int) map[string][]TextPnt {
+
+	assembler := newTextPointsAssembler(clusteringOrder, len(benchmarkKeys))
+
+	perKey := numPoints / len(benchmarkKeys)
+
+	for _, key := range benchmarkKeys {
+		for i := 0; i < perKey; i++ {
+
+			step := int64(i)
+			if clusteringOrder == constants.ClusteringOrderDESC {
+				step = int64(perKey - 1 - i)
+			}
+
+			assembler.add(key, benchmarkStart+step*1000, "text", benchmarkPageSize)
+		}
+	}
+
+	return assembler.series()
+}
+
+func TestAssemblersReturnAscendingSeries(t *testing.T) {
+
+	for _, order := range []constants.ClusteringOrder{constants.ClusteringOrderASC, constants.ClusteringOrderDESC} {
+
+		numbers := scanNumbers(order, 1000)
+		texts := scanTexts(order, 1000)
+
+		for _, key := range benchmarkKeys {
+
+			if len(numbers[key]) != 250 || len(texts[key]) != 250 {
+				t.Fatalf("%s: expected 250 points for %s, found %d and %d", order, key, len(numbers[key]), len(texts[key]))
+			}
+
+			for i := 1; i < len(numbers[key]); i++ {
+				if numbers[key][i-1].Date >= numbers[key][i].Date || texts[key][i-1].Date >= texts[key][i].Date {
+					t.Fatalf("%s: %s is not in ascending order at %d", order, key, i)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkAssemblePoints(b *testing.B) {
+
+	for _, order := range []constants.ClusteringOrder{constants.ClusteringOrderASC, constants.ClusteringOrderDESC} {
+		for _, numPoints := range []int{10000, 100000, 1000000} {
+			b.Run(fmt.Sprintf("%s/%d", order, numPoints), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					scanNumbers(order, numPoints)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkAssembleTextPoints(b *testing.B) {
+
+	for _, order := range []constants.ClusteringOrder{constants.ClusteringOrderASC, constants.ClusteringOrderDESC} {
+		for _, numPoints := range []int{10000, 100000, 1000000} {
+			b.Run(fmt.Sprintf("%s/%d", order, numPoints), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					scanTexts(order, numPoints)
+				}
+			})
+		}
+	}
+}
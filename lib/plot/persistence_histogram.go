@@ -0,0 +1,103 @@
+package plot
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/sketch"
+)
+
+const (
+	funcGetHistogramTS  string = "GetHistogramTS"
+	queryGetHistogramTS string = `SELECT id, date, value FROM %s.ts_histogram_stamp WHERE id in (%s) AND date > ? AND date < ? ALLOW FILTERING`
+)
+
+// GetHistogramTS - reads the histogram points of the timeseries, the series are not sorted by date
+func (persist *persistence) GetHistogramTS(keyspace string, keys []string, start, end int64, ms, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]HistogramPnt, uint32, gobol.Error) {
+
+	track := time.Now()
+	start--
+	end++
+
+	var tsid string
+	var date int64
+	var value []byte
+	var numBytes uint32
+	_, unlimitedBytes := persist.unlimitedBytesKeysetWhiteList[keyset]
+	allowFullFetch = allowFullFetch || unlimitedBytes
+	ms = ms || persist.isMillisecondKeyset(keyset)
+
+	iter := persist.cassandra.Query(
+		fmt.Sprintf(
+			queryGetHistogramTS,
+			keyspace,
+			persist.buildInGroup(keys),
+		),
+		start,
+		end,
+	).Iter()
+
+	tsMap := map[string][]HistogramPnt{}
+	countRows := 0
+	limitReached := false
+
+	for iter.Scan(&tsid, &date, &value) {
+
+		s, err := sketch.FromBytes(value)
+		if err != nil {
+			if logh.ErrorEnabled {
+				persist.logger.Error().Str(constants.StringsFunc, funcGetHistogramTS).Str("tsid", tsid).Int64("date", date).Err(err).Send()
+			}
+			continue
+		}
+
+		if !ms {
+			date = (date / 1000) * 1000
+		}
+
+		if _, ok := tsMap[tsid]; !ok {
+			numBytes += uint32(persist.getStringSize(tsid))
+		}
+
+		tsMap[tsid] = append(tsMap[tsid], HistogramPnt{Date: date, Sketch: s})
+
+		numBytes += uint32(unsafe.Sizeof(HistogramPnt{})) + uint32(len(value))
+
+		countRows++
+
+		if !allowFullFetch && numBytes >= maxBytesLimit {
+			limitReached = true
+			break
+		}
+	}
+
+	persist.statsQueryBytes(funcGetHistogramTS, keyset, keyspace, typeHistogram, float64(numBytes))
+
+	if err := iter.Close(); err != nil {
+		if logh.ErrorEnabled {
+			logh.Error().Str(constants.StringsFunc, funcGetHistogramTS).Err(err).Send()
+		}
+
+		if err == gocql.ErrNotFound {
+			persist.statsSelect(funcGetHistogramTS, keyset, keyspace, typeHistogram, time.Since(track), countRows)
+			return map[string][]HistogramPnt{}, 0, errNoContent(funcGetHistogramTS)
+		}
+
+		persist.statsQueryError(funcGetHistogramTS, keyset, keyspace, typeHistogram)
+		return map[string][]HistogramPnt{}, 0, errPersist(funcGetHistogramTS, err)
+	}
+
+	persist.statsSelect(funcGetHistogramTS, keyset, keyspace, typeHistogram, time.Since(track), countRows)
+
+	if limitReached && !allowFullFetch {
+		return map[string][]HistogramPnt{}, numBytes, errMaxBytesLimitWrapper(funcGetHistogramTS, persist.maxBytesErr)
+	}
+
+	return tsMap, numBytes, nil
+}
@@ -21,6 +21,12 @@ const (
 
 func (persist *persistence) GetTS(keyspace string, keys []string, start, end int64, ms, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]Pnt, uint32, gobol.Error) {
 
+	ms = ms || persist.isMillisecondKeyset(keyset)
+
+	if persist.isBlockLayout(keyspace) {
+		return persist.getBlockTS(keyspace, keys, start, end, ms, allowFullFetch, maxBytesLimit, keyset)
+	}
+
 	track := time.Now()
 	start--
 	end++
@@ -44,7 +50,7 @@ func (persist *persistence) GetTS(keyspace string, keys []string, start, end int
 		end,
 	).Iter()
 
-	tsMap := map[string][]Pnt{}
+	assembler := newPointsAssembler(persist.clusteringOrder, len(keys))
 	countRows := 0
 	limitReached := false
 
@@ -54,22 +60,10 @@ func (persist *persistence) GetTS(keyspace string, keys []string, start, end int
 			date = (date / 1000) * 1000
 		}
 
-		if _, ok := tsMap[tsid]; !ok {
+		if assembler.add(tsid, date, value, iter.NumRows()) {
 			numBytes += uint32(persist.getStringSize(tsid))
 		}
 
-		if persist.clusteringOrder == constants.ClusteringOrderDESC {
-			tsMap[tsid] = append(tsMap[tsid], Pnt{})
-			copy(tsMap[tsid][1:], tsMap[tsid])
-			tsMap[tsid][0].Date = date
-			tsMap[tsid][0].Value = value
-		} else {
-			tsMap[tsid] = append(tsMap[tsid], Pnt{
-				Date:  date,
-				Value: value,
-			})
-		}
-
 		numBytes += uint32(persist.constPartBytesFromNumberPoint)
 
 		countRows++
@@ -103,11 +97,17 @@ func (persist *persistence) GetTS(keyspace string, keys []string, start, end int
 		return map[string][]Pnt{}, numBytes, errMaxBytesLimitWrapper(funcGetTS, persist.maxBytesErr)
 	}
 
-	return tsMap, numBytes, nil
+	return assembler.series(), numBytes, nil
 }
 
 func (persist *persistence) GetLastTS(keyspace string, keys []string, end int64, ms, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string]Pnt, uint32, gobol.Error) {
 
+	ms = ms || persist.isMillisecondKeyset(keyset)
+
+	if persist.isBlockLayout(keyspace) {
+		return persist.getBlockLastTS(keyspace, keys, end, ms, allowFullFetch, maxBytesLimit, keyset)
+	}
+
 	var tsid string
 	var date int64
 	var value float64
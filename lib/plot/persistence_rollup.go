@@ -0,0 +1,99 @@
+package plot
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	funcGetRollupTS  string       = "GetRollupTS"
+	queryGetRollupTS string       = `SELECT id, date, min, max, sum, count FROM %s.%s WHERE id in (%s) AND date >= ? AND date < ? ALLOW FILTERING`
+	typeRollup       keyspaceType = "rollup"
+)
+
+// GetRollupTS - reads the rollup buckets of the keys starting between start (inclusive) and end (exclusive)
+func (persist *persistence) GetRollupTS(keyspace, table string, keys []string, start, end int64, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]RollupPnt, uint32, gobol.Error) {
+
+	track := time.Now()
+
+	var tsid string
+	var point RollupPnt
+	var err error
+	var numBytes uint32
+	idsGroup := persist.buildInGroup(keys)
+	_, unlimitedBytes := persist.unlimitedBytesKeysetWhiteList[keyset]
+	allowFullFetch = allowFullFetch || unlimitedBytes
+	pointSize := uint32(unsafe.Sizeof(RollupPnt{}))
+
+	iter := persist.cassandra.Query(
+		fmt.Sprintf(
+			queryGetRollupTS,
+			keyspace,
+			table,
+			idsGroup,
+		),
+		start,
+		end,
+	).Iter()
+
+	tsMap := map[string][]RollupPnt{}
+	countRows := 0
+	limitReached := false
+
+	for iter.Scan(&tsid, &point.Date, &point.Min, &point.Max, &point.Sum, &point.Count) {
+
+		if _, ok := tsMap[tsid]; !ok {
+			numBytes += uint32(persist.getStringSize(tsid))
+		}
+
+		tsMap[tsid] = append(tsMap[tsid], point)
+
+		numBytes += pointSize
+
+		countRows++
+
+		if !allowFullFetch && numBytes >= maxBytesLimit {
+			limitReached = true
+			break
+		}
+	}
+
+	persist.statsQueryBytes(funcGetRollupTS, keyset, keyspace, typeRollup, float64(numBytes))
+
+	if err = iter.Close(); err != nil {
+		if logh.ErrorEnabled {
+			logh.Error().Str(constants.StringsFunc, funcGetRollupTS).Err(err).Send()
+		}
+
+		if err == gocql.ErrNotFound {
+			persist.statsSelect(funcGetRollupTS, keyset, keyspace, typeRollup, time.Since(track), countRows)
+			return map[string][]RollupPnt{}, 0, errNoContent(funcGetRollupTS)
+		}
+
+		persist.statsQueryError(funcGetRollupTS, keyset, keyspace, typeRollup)
+		return map[string][]RollupPnt{}, 0, errPersist(funcGetRollupTS, err)
+	}
+
+	persist.statsSelect(funcGetRollupTS, keyset, keyspace, typeRollup, time.Since(track), countRows)
+
+	if limitReached && !allowFullFetch {
+		return map[string][]RollupPnt{}, numBytes, errMaxBytesLimitWrapper(funcGetRollupTS, persist.maxBytesErr)
+	}
+
+	if persist.clusteringOrder == constants.ClusteringOrderDESC {
+		for _, points := range tsMap {
+			for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+				points[i], points[j] = points[j], points[i]
+			}
+		}
+	}
+
+	return tsMap, numBytes, nil
+}
@@ -14,8 +14,9 @@ const (
 type keyspaceType string
 
 const (
-	typeNumber keyspaceType = "number"
-	typeText   keyspaceType = "text"
+	typeNumber    keyspaceType = "number"
+	typeText      keyspaceType = "text"
+	typeHistogram keyspaceType = "histogram"
 )
 
 func (persist *persistence) statsQueryError(function, keyset, keyspace string, ksType keyspaceType) {
@@ -17,8 +17,79 @@ const (
 	funcGetLastTST             string = "GetLastTST"
 	queryGetLastTSTNoTimestamp string = `SELECT id, date, value FROM %s.ts_text_stamp WHERE id = ? limit 1`              // given that clustering order MUST be date desc
 	queryGetLastTST            string = `SELECT id, date, value FROM %s.ts_text_stamp WHERE id = ? AND date < ? limit 1` // given that clustering order MUST be date desc
+	funcGetTSTPage             string = "GetTSTPage"
+	queryGetTSTPage            string = `SELECT date, value FROM %s.ts_text_stamp WHERE id = ? AND date >= ? AND date <= ? ORDER BY date ASC`
 )
 
+// GetTSTPage - reads up to limit texts of each key in ascending date order, skipping the texts not
+// positioned after the cursor, so only the texts of the requested page are loaded
+func (persist *persistence) GetTSTPage(keyspace string, keys []string, start, end int64, search *regexp.Regexp, limit int, cursor *textCursor, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]TextPnt, uint32, gobol.Error) {
+
+	track := time.Now()
+
+	var date int64
+	var value string
+	var numBytes uint32
+	_, unlimitedBytes := persist.unlimitedBytesKeysetWhiteList[keyset]
+	allowFullFetch = allowFullFetch || unlimitedBytes
+
+	tsMap := map[string][]TextPnt{}
+	countRows := 0
+
+	for _, tsid := range keys {
+
+		iter := persist.cassandra.Query(fmt.Sprintf(queryGetTSTPage, keyspace), tsid, start, end).PageSize(limit).Iter()
+
+		points := []TextPnt{}
+		limitReached := false
+
+		for len(points) < limit && iter.Scan(&date, &value) {
+
+			if !cursor.after(date, tsid) || (search != nil && !search.MatchString(value)) {
+				continue
+			}
+
+			if len(points) == 0 {
+				numBytes += uint32(persist.getStringSize(tsid))
+			}
+
+			points = append(points, TextPnt{Date: date, Value: value})
+
+			numBytes += uint32(persist.constPartBytesFromTextPoint + persist.getStringSize(value))
+
+			countRows++
+
+			if !allowFullFetch && numBytes >= maxBytesLimit {
+				limitReached = true
+				break
+			}
+		}
+
+		if err := iter.Close(); err != nil && err != gocql.ErrNotFound {
+			if logh.ErrorEnabled {
+				logh.Error().Str(constants.StringsFunc, funcGetTSTPage).Err(err).Send()
+			}
+
+			persist.statsQueryError(funcGetTSTPage, keyset, keyspace, typeText)
+			return map[string][]TextPnt{}, 0, errPersist(funcGetTSTPage, err)
+		}
+
+		if limitReached {
+			persist.statsQueryBytes(funcGetTSTPage, keyset, keyspace, typeText, float64(numBytes))
+			return map[string][]TextPnt{}, numBytes, errMaxBytesLimitWrapper(funcGetTSTPage, persist.maxBytesErr)
+		}
+
+		if len(points) > 0 {
+			tsMap[tsid] = points
+		}
+	}
+
+	persist.statsQueryBytes(funcGetTSTPage, keyset, keyspace, typeText, float64(numBytes))
+	persist.statsSelect(funcGetTSTPage, keyset, keyspace, typeText, time.Since(track), countRows)
+
+	return tsMap, numBytes, nil
+}
+
 func (persist *persistence) GetTST(keyspace string, keys []string, start, end int64, search *regexp.Regexp, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string][]TextPnt, uint32, gobol.Error) {
 
 	track := time.Now()
@@ -44,7 +115,7 @@ func (persist *persistence) GetTST(keyspace string, keys []string, start, end in
 		end,
 	).Iter()
 
-	tsMap := map[string][]TextPnt{}
+	assembler := newTextPointsAssembler(persist.clusteringOrder, len(keys))
 	countRows := 0
 	limitReached := false
 
@@ -57,22 +128,10 @@ func (persist *persistence) GetTST(keyspace string, keys []string, start, end in
 
 		if add {
 
-			if _, ok := tsMap[tsid]; !ok {
+			if assembler.add(tsid, date, value, iter.NumRows()) {
 				numBytes += uint32(persist.getStringSize(tsid))
 			}
 
-			if persist.clusteringOrder == constants.ClusteringOrderDESC {
-				tsMap[tsid] = append(tsMap[tsid], TextPnt{})
-				copy(tsMap[tsid][1:], tsMap[tsid])
-				tsMap[tsid][0].Date = date
-				tsMap[tsid][0].Value = value
-			} else {
-				tsMap[tsid] = append(tsMap[tsid], TextPnt{
-					Date:  date,
-					Value: value,
-				})
-			}
-
 			numBytes += uint32(persist.constPartBytesFromTextPoint + persist.getStringSize(value))
 
 			if !allowFullFetch && numBytes >= maxBytesLimit {
@@ -106,7 +165,7 @@ func (persist *persistence) GetTST(keyspace string, keys []string, start, end in
 		return map[string][]TextPnt{}, numBytes, errMaxBytesLimitWrapper(funcGetTST, persist.maxBytesErr)
 	}
 
-	return tsMap, numBytes, nil
+	return assembler.series(), numBytes, nil
 }
 
 func (persist *persistence) GetLastTST(keyspace string, keys []string, end int64, search *regexp.Regexp, allowFullFetch bool, maxBytesLimit uint32, keyset string) (map[string]TextPnt, uint32, gobol.Error) {
@@ -2,6 +2,7 @@ package plot
 
 import (
 	"errors"
+	"time"
 	"unsafe"
 
 	"github.com/uol/logh"
@@ -9,8 +10,13 @@ import (
 	"github.com/gocql/gocql"
 	"github.com/uol/gobol"
 
+	"github.com/uol/mycenae/lib/catalog"
 	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/memcached"
 	"github.com/uol/mycenae/lib/metadata"
+	storage "github.com/uol/mycenae/lib/persistence"
+	"github.com/uol/mycenae/lib/rollup"
+	"github.com/uol/mycenae/lib/structs"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
@@ -25,6 +31,15 @@ type persistence struct {
 	unlimitedBytesKeysetWhiteList map[string]bool
 	logger                        *logh.ContextualLogger
 	clusteringOrder               constants.ClusteringOrder
+	keyspaceLayouts               *storage.KeyspaceLayouts
+	blockWindow                   int64
+	keysetPrecision               map[string]constants.TimestampPrecision
+}
+
+// isMillisecondKeyset - checks if the keyset keeps the milliseconds of its timestamps
+func (persist *persistence) isMillisecondKeyset(keyset string) bool {
+
+	return persist.keysetPrecision[keyset] == constants.TimestampPrecisionMillisecond
 }
 
 func New(
@@ -32,13 +47,20 @@ func New(
 	metaStorage *metadata.Storage,
 	maxTimeseries int,
 	logQueryTSthreshold int,
-	keyspaceTTLMap map[int]string,
+	keyspaceTTLMap *storage.KeyspaceTTLMap,
 	defaultTTL int,
 	defaultMaxResults int,
 	maxBytesLimit uint32,
 	unlimitedBytesKeysetWhiteList []string,
 	timelineManager *tlmanager.Instance,
 	clusteringOrder constants.ClusteringOrder,
+	rollups *rollup.Compactor,
+	keyspaceLayouts *storage.KeyspaceLayouts,
+	blockWindow time.Duration,
+	keysetPrecision map[string]constants.TimestampPrecision,
+	queryCacheConf structs.QueryCacheConfiguration,
+	memcachedConn *memcached.Memcached,
+	metricCatalog *catalog.Catalog,
 ) (*Plot, gobol.Error) {
 
 	if maxTimeseries < 1 {
@@ -49,6 +71,20 @@ func New(
 		return nil, errInit("LogQueryTSthreshold needs to be bigger than zero")
 	}
 
+	// the block keyspaces created later are refused by the keyspace manager without the window
+	if blockWindow <= 0 {
+		for _, layout := range keyspaceLayouts.Map() {
+			if layout == constants.KeyspaceLayoutBlock {
+				return nil, errInit("the keyspaces with the block layout need the NumberBlocks window")
+			}
+		}
+	}
+
+	queryCache, gerr := newQueryCache(queryCacheConf, memcachedConn)
+	if gerr != nil {
+		return nil, gerr
+	}
+
 	stringSize := unsafe.Sizeof(constants.StringsEmpty)
 
 	unlimitedBytesKeysetWhiteMap := map[string]bool{}
@@ -70,6 +106,9 @@ func New(
 			unlimitedBytesKeysetWhiteList: unlimitedBytesKeysetWhiteMap,
 			logger:                        logh.CreateContextualLogger(constants.StringsPKG, "plot/persistence"),
 			clusteringOrder:               clusteringOrder,
+			keyspaceLayouts:               keyspaceLayouts,
+			blockWindow:                   int64(blockWindow / time.Millisecond),
+			keysetPrecision:               keysetPrecision,
 		},
 		keyspaceTTLMap:    keyspaceTTLMap,
 		defaultTTL:        defaultTTL,
@@ -77,6 +116,9 @@ func New(
 		maxBytesLimit:     maxBytesLimit,
 		logger:            logh.CreateContextualLogger(constants.StringsPKG, "plot"),
 		timelineManager:   timelineManager,
+		rollups:           rollups,
+		queryCache:        queryCache,
+		catalog:           metricCatalog,
 	}, nil
 }
 
@@ -84,11 +126,14 @@ type Plot struct {
 	MaxTimeseries       int
 	LogQueryTSThreshold int
 	persist             *persistence
-	keyspaceTTLMap      map[int]string
+	keyspaceTTLMap      *storage.KeyspaceTTLMap
 	defaultTTL          int
 	defaultMaxResults   int
 	maxBytesLimit       uint32
 	timelineManager     *tlmanager.Instance
+	rollups             *rollup.Compactor
+	queryCache          *queryCache
+	catalog             *catalog.Catalog
 	logger              *logh.ContextualLogger
 }
 
@@ -0,0 +1,73 @@
+package plot
+
+import (
+	"github.com/uol/mycenae/lib/catalog"
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// catalogEntries - returns the catalog entries of the metrics, the ones not in the catalog only have the metric name
+func (plot *Plot) catalogEntries(keyset string, metrics []string) []catalog.Entry {
+
+	found := plot.catalog.Lookup(keyset, metrics...)
+
+	entries := make([]catalog.Entry, len(metrics))
+
+	for i, metric := range metrics {
+		if entry, ok := found[metric]; ok {
+			entries[i] = entry
+		} else {
+			entries[i] = catalog.Entry{Metric: metric}
+		}
+	}
+
+	return entries
+}
+
+// setUnits - sets the catalog unit of the metric of each response
+func (plot *Plot) setUnits(keyset string, resps TSDBresponses) {
+
+	if len(resps) == 0 {
+		return
+	}
+
+	metrics := make([]string, len(resps))
+	for i := range resps {
+		metrics[i] = resps[i].Metric
+	}
+
+	entries := plot.catalog.Lookup(keyset, metrics...)
+
+	for i := range resps {
+		resps[i].Unit = entries[resps[i].Metric].Unit
+	}
+}
+
+// defaultCounterRate - queries the counter metrics by their rate when no rate or window function was used
+func (plot *Plot) defaultCounterRate(keyset string, tsdb *structs.TSDBquery) {
+
+	if tsdb.Rate || tsdb.Increase != constants.StringsEmpty || tsdb.Delta != constants.StringsEmpty ||
+		tsdb.Irate != constants.StringsEmpty || tsdb.Deriv != constants.StringsEmpty {
+		return
+	}
+
+	if !plot.catalog.IsCounter(keyset, tsdb.Metric) {
+		return
+	}
+
+	tsdb.Rate = true
+	tsdb.RateOptions = structs.TSDBrateOptions{Counter: true}
+
+	// the rate of each counter is computed before they are aggregated
+	order := make([]string, 0, len(tsdb.Order)+1)
+	for i, oper := range tsdb.Order {
+		if oper == "aggregation" {
+			order = append(append(order, "rate"), tsdb.Order[i:]...)
+			tsdb.Order = order
+			return
+		}
+		order = append(order, oper)
+	}
+
+	tsdb.Order = append(order, "rate")
+}
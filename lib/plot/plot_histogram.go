@@ -0,0 +1,72 @@
+package plot
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/sketch"
+)
+
+// GetHistogramSeries - reads the histograms of the timeseries and merges them in a single serie,
+// one sketch per timestamp or, when the interval is set, per interval bucket
+func (plot *Plot) GetHistogramSeries(
+	ttl int,
+	keys []string,
+	start,
+	end,
+	interval int64,
+	ms,
+	allowFullFetch bool,
+	keyset string,
+) ([]HistogramPnt, uint32, gobol.Error) {
+
+	keyspace, ok := plot.keyspaceTTLMap.Get(ttl)
+	if !ok {
+		return nil, 0, errNotFound("invalid ttl found: " + strconv.Itoa(ttl))
+	}
+
+	tsMap, numBytes, gerr := plot.persist.GetHistogramTS(keyspace, keys, start, end, ms, allowFullFetch, plot.maxBytesLimit, keyset)
+	if gerr != nil {
+		return nil, numBytes, gerr
+	}
+
+	return mergeHistograms(tsMap, interval), numBytes, nil
+}
+
+// mergeHistograms - merges the sketches of all series with the same bucket date
+func mergeHistograms(tsMap map[string][]HistogramPnt, interval int64) []HistogramPnt {
+
+	buckets := map[int64]*sketch.Sketch{}
+
+	for _, serie := range tsMap {
+		for _, p := range serie {
+
+			date := p.Date
+			if interval > 0 {
+				date -= date % interval
+			}
+
+			merged, ok := buckets[date]
+			if !ok {
+				merged = sketch.New()
+				buckets[date] = merged
+			}
+
+			merged.Merge(p.Sketch)
+		}
+	}
+
+	result := make([]HistogramPnt, 0, len(buckets))
+
+	for date, s := range buckets {
+		result = append(result, HistogramPnt{Date: date, Sketch: s})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result
+}
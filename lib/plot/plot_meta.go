@@ -1,6 +1,8 @@
 package plot
 
 import (
+	"time"
+
 	"github.com/uol/logh"
 
 	"github.com/uol/gobol"
@@ -59,7 +61,7 @@ func (plot *Plot) checkTotalTSLimits(message, keyset, metric string, total int)
 	return nil
 }
 
-func (plot *Plot) FilterMetrics(keyset, metricName string, size int) ([]string, int, gobol.Error) {
+func (plot *Plot) FilterMetrics(keyset, metricName string, size int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	err := plot.validateKeyset(keyset)
 	if err != nil {
@@ -70,10 +72,10 @@ func (plot *Plot) FilterMetrics(keyset, metricName string, size int) ([]string,
 		size = plot.defaultMaxResults
 	}
 
-	return plot.persist.metaStorage.FilterMetrics(keyset, metricName, size)
+	return plot.persist.metaStorage.FilterMetrics(keyset, metricName, size, activeWithin)
 }
 
-func (plot *Plot) FilterTagKeys(keyset, tagKname string, size int) ([]string, int, gobol.Error) {
+func (plot *Plot) FilterTagKeys(keyset, tagKname string, size int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	err := plot.validateKeyset(keyset)
 	if err != nil {
@@ -84,10 +86,10 @@ func (plot *Plot) FilterTagKeys(keyset, tagKname string, size int) ([]string, in
 		size = plot.defaultMaxResults
 	}
 
-	return plot.persist.metaStorage.FilterTagKeys(keyset, tagKname, size)
+	return plot.persist.metaStorage.FilterTagKeys(keyset, tagKname, size, activeWithin)
 }
 
-func (plot *Plot) FilterTagValues(keyset, tagVname string, size int) ([]string, int, gobol.Error) {
+func (plot *Plot) FilterTagValues(keyset, tagVname string, size int, activeWithin time.Duration) ([]string, int, gobol.Error) {
 
 	err := plot.validateKeyset(keyset)
 	if err != nil {
@@ -98,7 +100,7 @@ func (plot *Plot) FilterTagValues(keyset, tagVname string, size int) ([]string,
 		size = plot.defaultMaxResults
 	}
 
-	return plot.persist.metaStorage.FilterTagValues(keyset, tagVname, size)
+	return plot.persist.metaStorage.FilterTagValues(keyset, tagVname, size, activeWithin)
 }
 
 // toMetaParam - converts metric and tags to a Metadata struct to be used as query
@@ -207,3 +209,33 @@ func (plot *Plot) ListMeta(keyset, tsType, metric string, tags map[string]string
 
 	return tsMetaInfos, total, gerr
 }
+
+// FilterTagKeysByMetric - returns the tag keys of the metric timeseries
+func (plot *Plot) FilterTagKeysByMetric(keyset, tsType, metric, prefix string, size int, activeWithin time.Duration) ([]string, int, gobol.Error) {
+
+	err := plot.validateKeyset(keyset)
+	if err != nil {
+		return nil, 0, errNotFound("FilterTagKeysByMetric")
+	}
+
+	if size <= 0 {
+		size = plot.defaultMaxResults
+	}
+
+	return plot.persist.metaStorage.FilterTagKeysByMetric(keyset, tsType, metric, prefix, size, activeWithin)
+}
+
+// FilterTagValuesByMetricAndTag - returns the tag values of the tag key of the metric timeseries
+func (plot *Plot) FilterTagValuesByMetricAndTag(keyset, tsType, metric, tag, prefix string, size int, activeWithin time.Duration) ([]string, int, gobol.Error) {
+
+	err := plot.validateKeyset(keyset)
+	if err != nil {
+		return nil, 0, errNotFound("FilterTagValuesByMetricAndTag")
+	}
+
+	if size <= 0 {
+		size = plot.defaultMaxResults
+	}
+
+	return plot.persist.metaStorage.FilterTagValuesByMetricAndTag(keyset, tsType, metric, tag, prefix, size, activeWithin)
+}
@@ -24,23 +24,25 @@ func (plot *Plot) GetTimeSeries(
 
 	var keyspace string
 	var ok bool
-	if keyspace, ok = plot.keyspaceTTLMap[ttl]; !ok {
+	if keyspace, ok = plot.keyspaceTTLMap.Get(ttl); !ok {
 		return TS{}, 0, errNotFound("invalid ttl found: " + strconv.Itoa(int(ttl)))
 	}
 
-	tsMap, numBytes, gerr := plot.getTimeSerie(keyspace, keys, start, end, ms, keepEmpties, allowFullFetch, opers, keyset)
+	readStart := start - readExtension(opers)
+
+	tsMap, numBytes, gerr := plot.getTimeSerie(keyspace, keys, readStart, end, ms, keepEmpties, allowFullFetch, opers, keyset)
 
 	if gerr != nil {
 		return TS{}, numBytes, gerr
 	}
 
 	resultTSs := TS{}
-	numNonEmptyTS := 0
+	nonEmptySeries := []Pnts{}
 
 	for _, ts := range tsMap {
 
 		if ts.Count > 0 {
-			numNonEmptyTS++
+			nonEmptySeries = append(nonEmptySeries, ts.Data)
 			resultTSs.Data = append(resultTSs.Data, ts.Data...)
 			resultTSs.Total += ts.Total
 		}
@@ -51,13 +53,17 @@ func (plot *Plot) GetTimeSeries(
 		switch oper {
 		case "downsample":
 			if resultTSs.Total > 0 && opers.Downsample.Enabled && exec {
-				resultTSs.Data = downsample(opers.Downsample.Options, keepEmpties, start, end, resultTSs.Data)
+				resultTSs.Data = downsample(opers.Downsample.Options, keepEmpties, readStart, end, resultTSs.Data)
 			}
 		case "aggregation":
 			exec = true
-			if numNonEmptyTS > 1 {
-				sort.Sort(resultTSs.Data)
-				resultTSs.Data = merge(opers.Merge, keepEmpties, resultTSs.Data)
+			if len(nonEmptySeries) > 1 {
+				if opers.Interpolate {
+					resultTSs.Data = lerp(opers.Merge, keepEmpties, nonEmptySeries)
+				} else {
+					sort.Sort(resultTSs.Data)
+					resultTSs.Data = merge(opers.Merge, keepEmpties, resultTSs.Data)
+				}
 			}
 		case "rate":
 			if opers.Rate.Enabled && exec {
@@ -67,9 +73,29 @@ func (plot *Plot) GetTimeSeries(
 			if opers.FilterValue.Enabled && exec {
 				resultTSs.Data = filterValues(opers.FilterValue, resultTSs.Data)
 			}
+		case "increase", "delta", "irate", "deriv":
+			if window := opers.Window(oper); window.Enabled && exec {
+				resultTSs.Data = windowed(oper, window, readStart, resultTSs.Data)
+			}
+		case "movingAverage", "rollingSum", "rollingMax", "rollingMin":
+			if rolling := opers.Rolling(oper); rolling.Enabled && exec {
+				resultTSs.Data = rollingWindow(oper, rolling.Window, resultTSs.Data)
+			}
+		case "ewma":
+			if opers.Ewma.Enabled && exec {
+				resultTSs.Data = ewma(opers.Ewma.Alpha, resultTSs.Data)
+			}
+		case "holtWinters":
+			if opers.HoltWinters.Enabled && exec {
+				resultTSs.Data = holtWinters(opers.HoltWinters.Alpha, opers.HoltWinters.Beta, resultTSs.Data)
+			}
 		}
 	}
 
+	if readStart < start {
+		resultTSs.Data = trimBefore(outputStart(start, opers), resultTSs.Data)
+	}
+
 	if opers.Downsample.PointLimit && len(resultTSs.Data) > opers.Downsample.TotalPoints {
 		resultTSs.Data = basic(opers.Downsample.TotalPoints, resultTSs.Data)
 	}
@@ -91,24 +117,47 @@ func (plot *Plot) getTimeSerie(
 	keyset string,
 ) (map[string]TS, uint32, gobol.Error) {
 
-	resultMap, numBytes, gerr := plot.persist.GetTS(keyspace, keys, start, end, ms, allowFullFetch, plot.maxBytesLimit, keyset)
+	resultMap := map[string]TS{}
 
-	if gerr != nil {
-		return map[string]TS{}, numBytes, gerr
+	var numBytes uint32
+
+	r, rollupDownsampled := plot.pickRollup(keyspace, start, opers)
+
+	if rollupDownsampled {
+
+		rollupMap, rollupBytes, gerr := plot.getRollupTimeSerie(keyspace, keys, start, end, ms, keepEmpties, allowFullFetch, opers, keyset, r)
+		if gerr != nil {
+			return map[string]TS{}, rollupBytes, gerr
+		}
+
+		resultMap = rollupMap
+		numBytes = rollupBytes
+
+	} else {
+
+		rawMap, rawBytes, gerr := plot.persist.GetTS(keyspace, keys, start, end, ms, allowFullFetch, plot.maxBytesLimit, keyset)
+		if gerr != nil {
+			return map[string]TS{}, rawBytes, gerr
+		}
+
+		numBytes = rawBytes
+
+		for tsid, points := range rawMap {
+			resultMap[tsid] = TS{
+				Total: len(points),
+				Data:  points,
+			}
+		}
 	}
 
 	transformedMap := map[string]TS{}
 
-	for tsid, points := range resultMap {
-		ts := TS{
-			Total: len(points),
-			Data:  points,
-		}
+	for tsid, ts := range resultMap {
 		for _, oper := range opers.Order {
 			exit := false
 			switch oper {
 			case "downsample":
-				if ts.Total > 0 && opers.Downsample.Enabled {
+				if ts.Total > 0 && opers.Downsample.Enabled && !rollupDownsampled {
 					ts.Data = downsample(opers.Downsample.Options, keepEmpties, start, end, ts.Data)
 				}
 			case "aggregation":
@@ -122,6 +171,22 @@ func (plot *Plot) getTimeSerie(
 				if opers.FilterValue.Enabled {
 					ts.Data = filterValues(opers.FilterValue, ts.Data)
 				}
+			case "increase", "delta", "irate", "deriv":
+				if window := opers.Window(oper); window.Enabled {
+					ts.Data = windowed(oper, window, start, ts.Data)
+				}
+			case "movingAverage", "rollingSum", "rollingMax", "rollingMin":
+				if rolling := opers.Rolling(oper); rolling.Enabled {
+					ts.Data = rollingWindow(oper, rolling.Window, ts.Data)
+				}
+			case "ewma":
+				if opers.Ewma.Enabled {
+					ts.Data = ewma(opers.Ewma.Alpha, ts.Data)
+				}
+			case "holtWinters":
+				if opers.HoltWinters.Enabled {
+					ts.Data = holtWinters(opers.HoltWinters.Alpha, opers.HoltWinters.Beta, ts.Data)
+				}
 			}
 
 			if exit {
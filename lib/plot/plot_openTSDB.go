@@ -2,6 +2,7 @@ package plot
 
 import (
 	"strings"
+	"time"
 
 	"github.com/uol/gobol"
 
@@ -97,14 +98,21 @@ func (plot *Plot) splitTagFilters(value string) []string {
 }
 
 // MetaFilterOpenTSDB - creates a metadata query
-func (plot *Plot) MetaFilterOpenTSDB(keyset, metric string, filters []structs.TSDBfilter, size int) ([]TSDBobj, int, gobol.Error) {
+func (plot *Plot) MetaFilterOpenTSDB(keyset, metric string, filters []structs.TSDBfilter, size int, activeWithin time.Duration) ([]TSDBobj, int, gobol.Error) {
+
+	return plot.metaFilter(keyset, "meta", metric, filters, size, activeWithin)
+}
+
+// metaFilter - creates a metadata query for the timeseries type
+func (plot *Plot) metaFilter(keyset, tsType, metric string, filters []structs.TSDBfilter, size int, activeWithin time.Duration) ([]TSDBobj, int, gobol.Error) {
 
 	from, size := plot.checkParams(0, size)
 
 	query := &metadata.Query{
-		Metric:   metric,
-		MetaType: "meta",
-		Tags:     make([]metadata.QueryTag, len(filters)),
+		Metric:       metric,
+		MetaType:     tsType,
+		Tags:         make([]metadata.QueryTag, len(filters)),
+		ActiveWithin: activeWithin,
 	}
 
 	for i, filter := range filters {
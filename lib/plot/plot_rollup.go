@@ -0,0 +1,201 @@
+package plot
+
+import (
+	"time"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/rollup"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// pickRollup - returns the coarsest rollup table whose buckets fit inside the downsample intervals,
+// it is only possible when the downsample is the first operation over the raw points of a row keyspace
+func (plot *Plot) pickRollup(keyspace string, start int64, opers structs.DataOperations) (rollup.Resolution, bool) {
+
+	if plot.rollups == nil || !opers.Downsample.Enabled || plot.persist.isBlockLayout(keyspace) {
+		return rollup.Resolution{}, false
+	}
+
+	switch opers.Downsample.Options.Downsample {
+	case "avg", "sum", "max", "min", "pnt":
+	default:
+		return rollup.Resolution{}, false
+	}
+
+	for _, oper := range opers.Order {
+
+		if oper == "downsample" {
+			break
+		}
+
+		if oper == "aggregation" || operationEnabled(opers, oper) {
+			return rollup.Resolution{}, false
+		}
+	}
+
+	switch opers.Downsample.Options.Unit {
+	case "month", "year":
+		return rollup.Resolution{}, false
+	}
+
+	interval := getEndInterval(0, opers.Downsample.Options.Unit, opers.Downsample.Options.Value)
+
+	alignedStart := alignStart(start, opers.Downsample.Options.Unit)
+
+	now := time.Now()
+
+	resolutions := plot.rollups.Resolutions()
+
+	// the downsample intervals begin at round local dates and the rollup buckets at round UTC
+	// epoch dates, a table is only used when its buckets fit inside the intervals
+	for i := len(resolutions) - 1; i >= 0; i-- {
+
+		r := resolutions[i]
+
+		if interval%r.Interval == 0 && r.Aligned(alignedStart) && r.Covers(alignedStart, now) {
+			return r, true
+		}
+	}
+
+	return rollup.Resolution{}, false
+}
+
+// operationEnabled - tells if an operation of the order changes the points
+func operationEnabled(opers structs.DataOperations, oper string) bool {
+
+	switch oper {
+	case "downsample":
+		return opers.Downsample.Enabled
+	case "rate":
+		return opers.Rate.Enabled
+	case "filterValue":
+		return opers.FilterValue.Enabled
+	case "increase", "delta", "irate", "deriv":
+		return opers.Window(oper).Enabled
+	case "movingAverage", "rollingSum", "rollingMax", "rollingMin":
+		return opers.Rolling(oper).Enabled
+	case "ewma":
+		return opers.Ewma.Enabled
+	case "holtWinters":
+		return opers.HoltWinters.Enabled
+	}
+
+	return false
+}
+
+// rollupRanges - splits the query in the raw points before the compacted buckets [start, rollupStart),
+// the compacted buckets [rollupStart, rollupEnd) and the raw points after them [rollupEnd, end]
+func rollupRanges(start, end, alignedStart, from, until int64) (int64, int64) {
+
+	if until > end+1 {
+		until = end + 1
+	}
+
+	rollupStart := alignedStart
+	if rollupStart < from {
+		rollupStart = from
+	}
+
+	if rollupStart >= until {
+		return start, start
+	}
+
+	return rollupStart, until
+}
+
+// getRollupTimeSerie - reads the rollup buckets known to be compacted and the raw points
+// around them, returning the series downsampled and the number of raw points they represent
+func (plot *Plot) getRollupTimeSerie(
+	keyspace string,
+	keys []string,
+	start,
+	end int64,
+	ms,
+	keepEmpties,
+	allowFullFetch bool,
+	opers structs.DataOperations,
+	keyset string,
+	r rollup.Resolution,
+) (map[string]TS, uint32, gobol.Error) {
+
+	alignedStart := alignStart(start, opers.Downsample.Options.Unit)
+
+	from, until := plot.rollups.Compacted(keyspace, r, time.Now())
+
+	rollupStart, rollupEnd := rollupRanges(start, end, alignedStart, from, until)
+
+	series := map[string][]RollupPnt{}
+
+	var numBytes uint32
+
+	readRaw := func(rawStart, rawEnd int64) gobol.Error {
+
+		raw, rawBytes, gerr := plot.persist.GetTS(keyspace, keys, rawStart, rawEnd, ms, allowFullFetch, plot.maxBytesLimit-numBytes, keyset)
+		numBytes += rawBytes
+		if gerr != nil {
+			return gerr
+		}
+
+		for tsid, points := range raw {
+			for _, point := range points {
+				series[tsid] = append(series[tsid], RollupPnt{
+					Date:  point.Date,
+					Min:   point.Value,
+					Max:   point.Value,
+					Sum:   point.Value,
+					Count: 1,
+				})
+			}
+		}
+
+		return nil
+	}
+
+	if start < rollupStart {
+		if gerr := readRaw(start, rollupStart-1); gerr != nil {
+			return map[string]TS{}, numBytes, gerr
+		}
+	}
+
+	if rollupStart < rollupEnd {
+
+		buckets, rollupBytes, gerr := plot.persist.GetRollupTS(keyspace, r.Table, keys, rollupStart, rollupEnd, allowFullFetch, plot.maxBytesLimit-numBytes, keyset)
+		numBytes += rollupBytes
+		if gerr != nil {
+			return map[string]TS{}, numBytes, gerr
+		}
+
+		for tsid, points := range buckets {
+			series[tsid] = append(series[tsid], points...)
+		}
+	}
+
+	rawStart := rollupEnd
+	if rawStart < start {
+		rawStart = start
+	}
+
+	if rawStart <= end {
+		if gerr := readRaw(rawStart, end); gerr != nil {
+			return map[string]TS{}, numBytes, gerr
+		}
+	}
+
+	tsMap := map[string]TS{}
+
+	for tsid, buckets := range series {
+
+		ts := TS{}
+
+		for _, bucket := range buckets {
+			ts.Total += int(bucket.Count)
+		}
+
+		ts.Data = downsampleRollup(opers.Downsample.Options, keepEmpties, start, end, buckets)
+
+		tsMap[tsid] = ts
+	}
+
+	return tsMap, numBytes, nil
+}
@@ -0,0 +1,51 @@
+package plot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uol/mycenae/lib/rollup"
+)
+
+func TestRollupRanges(t *testing.T) {
+
+	const hour int64 = 3600 * 1000
+
+	start, end := 10*hour+10, 20*hour
+
+	rollupStart, rollupEnd := rollupRanges(start, end, 10*hour, 12*hour, 18*hour)
+	assert.Equal(t, 12*hour, rollupStart, "the buckets before the first compacted one are read from the raw points")
+	assert.Equal(t, 18*hour, rollupEnd)
+
+	rollupStart, rollupEnd = rollupRanges(start, end, 10*hour, 5*hour, 30*hour)
+	assert.Equal(t, 10*hour, rollupStart)
+	assert.Equal(t, end+1, rollupEnd, "the compacted range ends with the query")
+
+	rollupStart, rollupEnd = rollupRanges(start, end, 10*hour, 18*hour, 18*hour)
+	assert.Equal(t, rollupStart, rollupEnd, "nothing known to be compacted")
+	assert.Equal(t, start, rollupStart)
+}
+
+func TestRollupAlignedToUTCEpoch(t *testing.T) {
+
+	local := time.Local
+	defer func() { time.Local = local }()
+
+	time.Local = time.FixedZone("BRT", -3*3600)
+
+	day := rollup.Resolution{Interval: 24 * 3600 * 1000}
+	hour := rollup.Resolution{Interval: 3600 * 1000}
+
+	start := time.Date(2020, 5, 10, 15, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	alignedStart := alignStart(start, "day")
+
+	assert.False(t, day.Aligned(alignedStart), "the local midnight is not a daily bucket")
+	assert.True(t, hour.Aligned(alignedStart))
+
+	time.Local = time.UTC
+
+	assert.True(t, day.Aligned(alignStart(start, "day")))
+}
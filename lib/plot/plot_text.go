@@ -21,11 +21,11 @@ func (plot *Plot) GetTextSeries(
 
 	var keyspace string
 	var ok bool
-	if keyspace, ok = plot.keyspaceTTLMap[ttl]; !ok {
+	if keyspace, ok = plot.keyspaceTTLMap.Get(ttl); !ok {
 		return TST{}, 0, errNotFound("invalid ttl found: " + strconv.Itoa(int(ttl)))
 	}
 
-	tsMap, numBytes, gerr := plot.getTextSerie(keyspace, keys, start, end, search, keyset, allowFullFetch)
+	tsMap, numBytes, gerr := plot.getTextSerie(keyspace, keys, start, end, search, 0, nil, keyset, allowFullFetch)
 
 	if gerr != nil {
 		return TST{}, numBytes, gerr
@@ -51,17 +51,28 @@ func (plot *Plot) GetTextSeries(
 	return resultTSTs, numBytes, nil
 }
 
+// getTextSerie - reads the texts of the keys, with a limit only the first texts of each key after the cursor are read
 func (plot *Plot) getTextSerie(
 	keyspace string,
 	keys []string,
 	start,
 	end int64,
 	search *regexp.Regexp,
+	limit int,
+	cursor *textCursor,
 	keyset string,
 	allowFullFetch bool,
 ) (map[string]TST, uint32, gobol.Error) {
 
-	resultMap, numBytes, gerr := plot.persist.GetTST(keyspace, keys, start, end, search, allowFullFetch, plot.maxBytesLimit, keyset)
+	var resultMap map[string][]TextPnt
+	var numBytes uint32
+	var gerr gobol.Error
+
+	if limit > 0 {
+		resultMap, numBytes, gerr = plot.persist.GetTSTPage(keyspace, keys, start, end, search, limit, cursor, allowFullFetch, plot.maxBytesLimit, keyset)
+	} else {
+		resultMap, numBytes, gerr = plot.persist.GetTST(keyspace, keys, start, end, search, allowFullFetch, plot.maxBytesLimit, keyset)
+	}
 
 	if gerr != nil {
 		return map[string]TST{}, numBytes, gerr
@@ -0,0 +1,568 @@
+package plot
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uol/gobol"
+	"github.com/uol/hashing"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/memcached"
+	"github.com/uol/mycenae/lib/structs"
+	"github.com/uol/mycenae/lib/utils"
+)
+
+// Caches the computed query results in memcached: the query window is split in chunks aligned
+// to the chunk size, the historical ones are cached and reused by the same query later, while
+// the unaligned head and the fresh tail are always computed
+
+const (
+	queryCacheHeader   string = "X-Query-Cache"
+	queryCacheHit      string = "hit"
+	queryCacheMiss     string = "miss"
+	queryCachePartial  string = "partial"
+	queryCacheBypass   string = "bypass"
+	queryCacheHashSize int    = 16
+	funcQueryCache     string = "queryCache"
+)
+
+// the namespace is versioned by the format of the cached chunks
+var queryCacheNamespace []byte = []byte("qry2")
+
+// chunkStore - stores the cached chunks, implemented by memcached
+type chunkStore interface {
+	Get(router, namespace []byte, fqnKeys ...string) ([]byte, bool, error)
+	Put(router, value, ttl, namespace []byte, fqnKeys ...string) error
+}
+
+// cachedResponse - the cached response keeps the fields needed to merge it with the other chunks
+type cachedResponse struct {
+	TSDBresponse
+	Query     int                 `json:"query"`
+	Group     string              `json:"group"`
+	TagValues map[string][]string `json:"tagValues"`
+}
+
+type queryCache struct {
+	store     chunkStore
+	chunkSize int64
+	freshTail int64
+	maxChunks int
+	ttl       int64
+	keysetTTL map[string]int64
+}
+
+// newQueryCache - creates the query cache, nil when it is disabled
+func newQueryCache(conf structs.QueryCacheConfiguration, mc *memcached.Memcached) (*queryCache, gobol.Error) {
+
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	if mc == nil {
+		return nil, errInit("QueryCache needs a memcached connection")
+	}
+
+	if conf.ChunkSize.Duration < time.Second {
+		return nil, errInit("QueryCache.ChunkSize needs to be at least one second")
+	}
+
+	if conf.TTL.Duration < time.Second {
+		return nil, errInit("QueryCache.TTL needs to be at least one second")
+	}
+
+	if conf.MaxChunks < 1 {
+		return nil, errInit("QueryCache.MaxChunks needs to be bigger than zero")
+	}
+
+	keysetTTL := make(map[string]int64, len(conf.KeysetTTL))
+	for keyset, ttl := range conf.KeysetTTL {
+		keysetTTL[keyset] = int64(ttl.Duration / time.Second)
+	}
+
+	return &queryCache{
+		store:     mc,
+		chunkSize: int64(conf.ChunkSize.Duration / time.Millisecond),
+		freshTail: int64(conf.FreshTail.Duration / time.Millisecond),
+		maxChunks: conf.MaxChunks,
+		ttl:       int64(conf.TTL.Duration / time.Second),
+		keysetTTL: keysetTTL,
+	}, nil
+}
+
+// ttlOf - returns the chunk TTL of the keyset in seconds, zero when its cache is disabled
+func (qc *queryCache) ttlOf(keyset string) int64 {
+
+	if ttl, ok := qc.keysetTTL[keyset]; ok {
+		return ttl
+	}
+
+	return qc.ttl
+}
+
+// hash - hashes the query without its time window, the same query always have the same chunks
+func (qc *queryCache) hash(keyset string, query structs.TSDBqueryPayload) ([]byte, error) {
+
+	query.Relative = constants.StringsEmpty
+	query.Start = 0
+	query.End = 0
+
+	queries := make([]structs.TSDBquery, len(query.Queries))
+
+	for i, q := range query.Queries {
+
+		q.Filters = append([]structs.TSDBfilter{}, q.Filters...)
+
+		sort.Slice(q.Filters, func(i, j int) bool {
+			a, b := q.Filters[i], q.Filters[j]
+			if a.Tagk != b.Tagk {
+				return a.Tagk < b.Tagk
+			}
+			if a.Ftype != b.Ftype {
+				return a.Ftype < b.Ftype
+			}
+			return a.Filter < b.Filter
+		})
+
+		queries[i] = q
+	}
+
+	query.Queries = queries
+
+	normalized, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashing.GenerateSHAKE128(queryCacheHashSize, keyset, string(normalized))
+}
+
+// get - returns the cached chunk
+func (qc *queryCache) get(hash []byte, keyset string, chunk int64) (TSDBresponses, bool, error) {
+
+	data, exists, err := qc.store.Get(hash, queryCacheNamespace, keyset, hex.EncodeToString(hash), strconv.FormatInt(qc.chunkSize, 10), strconv.FormatInt(chunk, 10))
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	cached := []cachedResponse{}
+
+	err = json.Unmarshal(data, &cached)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resps := make(TSDBresponses, len(cached))
+
+	for i, c := range cached {
+
+		resps[i] = c.TSDBresponse
+		resps[i].query = c.Query
+		resps[i].group = c.Group
+		resps[i].tagValues = make(map[string]map[string]bool, len(c.TagValues))
+
+		for k, values := range c.TagValues {
+			resps[i].tagValues[k] = make(map[string]bool, len(values))
+			for _, v := range values {
+				resps[i].tagValues[k][v] = true
+			}
+		}
+	}
+
+	return resps, true, nil
+}
+
+// put - caches the chunk
+func (qc *queryCache) put(hash []byte, keyset string, chunk, ttl int64, resps TSDBresponses) error {
+
+	cached := make([]cachedResponse, len(resps))
+
+	for i, resp := range resps {
+
+		tagValues := make(map[string][]string, len(resp.tagValues))
+
+		for k, kv := range resp.tagValues {
+			for v := range kv {
+				tagValues[k] = append(tagValues[k], v)
+			}
+			sort.Strings(tagValues[k])
+		}
+
+		cached[i] = cachedResponse{
+			TSDBresponse: resp,
+			Query:        resp.query,
+			Group:        resp.group,
+			TagValues:    tagValues,
+		}
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	return qc.store.Put(hash, data, []byte(strconv.FormatInt(ttl, 10)), queryCacheNamespace, keyset, hex.EncodeToString(hash), strconv.FormatInt(qc.chunkSize, 10), strconv.FormatInt(chunk, 10))
+}
+
+// downsampleGrid - the bucket unit and interval of a downsampled query
+type downsampleGrid struct {
+	unit     string
+	interval int64
+}
+
+// cacheableInterval - returns the least common multiple of the downsample intervals and their grids,
+// the query can only be split in chunks when its operations only depend on the points of the same interval,
+// the interpolating aggregators are accepted on downsampled queries, all their series have points in the same
+// buckets and the only difference from the whole query is a serie gap spanning a chunk edge
+func cacheableInterval(query structs.TSDBqueryPayload) (int64, []downsampleGrid, bool) {
+
+	var interval int64 = 1
+	grids := []downsampleGrid{}
+
+	for _, q := range query.Queries {
+
+		if q.Rate || q.Increase != constants.StringsEmpty || q.Delta != constants.StringsEmpty ||
+			q.Irate != constants.StringsEmpty || q.Deriv != constants.StringsEmpty || len(q.RollingFunctions()) > 0 ||
+			q.Ewma != 0 || q.HoltWinters != nil || q.TimeShift != constants.StringsEmpty || q.Compare {
+			return 0, nil, false
+		}
+
+		if q.Downsample == constants.StringsEmpty {
+			if _, interpolate := toMergeOperation(q.Aggregator); interpolate {
+				return 0, nil, false
+			}
+			continue
+		}
+
+		ds := strings.SplitN(q.Downsample, "-", 3)
+
+		if len(ds) == 3 {
+			switch {
+			case ds[2] == "none", ds[2] == "null", ds[2] == "nan", ds[2] == "zero", strings.HasPrefix(ds[2], "value("):
+			default:
+				return 0, nil, false
+			}
+		}
+
+		unit, value := parseInterval(ds[0])
+
+		switch unit {
+		case "sec", "min", "hour":
+		default:
+			return 0, nil, false
+		}
+
+		grid := downsampleGrid{unit: unit, interval: fixedInterval(unit, value)}
+
+		interval = lcm(interval, grid.interval)
+		grids = append(grids, grid)
+	}
+
+	return interval, grids, true
+}
+
+// chunkPlan - splits the query window in the head [start, firstChunk), the cached chunks
+// and the tail [firstChunk + numChunks * chunkSize, end], the points of both ends are inclusive
+type chunkPlan struct {
+	start      int64
+	firstChunk int64
+	numChunks  int
+	end        int64
+}
+
+// tail - returns the start of the tail, right after the last chunk
+func (p chunkPlan) tail(chunkSize int64) int64 {
+
+	return p.firstChunk + int64(p.numChunks)*chunkSize
+}
+
+// plan - splits the window in chunks, only the ones older than the fresh tail are cached, the chunks
+// must begin in the downsample buckets of the whole query, otherwise they would return other buckets
+func (qc *queryCache) plan(start, end, now int64, grids []downsampleGrid) (chunkPlan, bool) {
+
+	limit := now - qc.freshTail
+	if end < limit {
+		limit = end
+	}
+
+	firstChunk := start + (qc.chunkSize-start%qc.chunkSize)%qc.chunkSize
+
+	numChunks := 0
+	if firstChunk+qc.chunkSize <= limit {
+		numChunks = int((limit - firstChunk) / qc.chunkSize)
+	}
+
+	if numChunks == 0 || numChunks > qc.maxChunks {
+		return chunkPlan{}, false
+	}
+
+	for _, grid := range grids {
+		if alignStart(firstChunk, grid.unit) != firstChunk || (firstChunk-alignStart(start, grid.unit))%grid.interval != 0 {
+			return chunkPlan{}, false
+		}
+	}
+
+	return chunkPlan{
+		start:      start,
+		firstChunk: firstChunk,
+		numChunks:  numChunks,
+		end:        end,
+	}, true
+}
+
+// chunkCompute - computes the query in the window [from, to)
+type chunkCompute func(from, to int64) (TSDBresponses, uint32, gobol.Error)
+
+// getCachedTimeseries - computes the query joining its cached chunks, returns the cache status
+func (plot *Plot) getCachedTimeseries(keyset string, query structs.TSDBqueryPayload) (TSDBresponses, uint32, string, gobol.Error) {
+
+	qc := plot.queryCache
+
+	// the active series change with the query time, the cached chunks would keep the old ones
+	if qc == nil || query.EstimateSize || query.ActiveWithin != constants.StringsEmpty {
+		return plot.getUncachedTimeseries(keyset, query)
+	}
+
+	ttl := qc.ttlOf(keyset)
+	if ttl <= 0 {
+		return plot.getUncachedTimeseries(keyset, query)
+	}
+
+	interval, grids, ok := cacheableInterval(query)
+	if !ok || qc.chunkSize%interval != 0 {
+		return plot.getUncachedTimeseries(keyset, query)
+	}
+
+	start, end, gerr := queryTimeRange(funcGetTimeseries, query.Relative, query.Start, query.End)
+	if gerr != nil {
+		return nil, 0, queryCacheBypass, gerr
+	}
+
+	plan, ok := qc.plan(start, end, utils.GetTimeMillis(), grids)
+	if !ok {
+		return plot.getUncachedTimeseries(keyset, query)
+	}
+
+	hash, err := qc.hash(keyset, query)
+	if err != nil {
+		if logh.ErrorEnabled {
+			plot.logger.Error().Str(constants.StringsFunc, funcQueryCache).Err(err).Msg("error hashing the query")
+		}
+		return plot.getUncachedTimeseries(keyset, query)
+	}
+
+	gerr = plot.checkQueryTimeseries(keyset, query)
+	if gerr != nil {
+		return nil, 0, queryCacheBypass, gerr
+	}
+
+	query.Relative = constants.StringsEmpty
+
+	compute := func(from, to int64) (TSDBresponses, uint32, gobol.Error) {
+		segment := query
+		segment.Start = from
+		segment.End = to - 1
+		return plot.getTimeseries(keyset, segment)
+	}
+
+	resps, sumBytes, status, gerr := plot.computeChunks(keyset, hash, ttl, plan, compute)
+	if gerr != nil {
+		return nil, sumBytes, status, gerr
+	}
+
+	plot.statsQueryCache(keyset, status)
+
+	return resps, sumBytes, status, nil
+}
+
+// checkQueryTimeseries - checks the number of series of each query once, before any chunk is computed
+func (plot *Plot) checkQueryTimeseries(keyset string, query structs.TSDBqueryPayload) gobol.Error {
+
+	for _, q := range query.Queries {
+
+		_, filters, gerr := plot.splitTTLFilter(funcQueryCache, append(append([]structs.TSDBfilter{}, q.Filters...), tagsToFilters(q.Tags)...))
+		if gerr != nil {
+			return gerr
+		}
+
+		_, total, gerr := plot.MetaFilterOpenTSDB(keyset, q.Metric, filters, plot.MaxTimeseries, 0)
+		if gerr != nil {
+			return gerr
+		}
+
+		logIfExceeded := fmt.Sprintf("TS THRESHOLD/MAX EXCEEDED for query: %+v", query)
+		gerr = plot.checkTotalTSLimits(logIfExceeded, keyset, q.Metric, total)
+		if gerr != nil {
+			return gerr
+		}
+	}
+
+	return nil
+}
+
+// computeChunks - computes the head, the missing chunks and the tail of the plan, the bytes
+// limit applies to the sum of all computed parts, not to each one of them
+func (plot *Plot) computeChunks(keyset string, hash []byte, ttl int64, plan chunkPlan, compute chunkCompute) (TSDBresponses, uint32, string, gobol.Error) {
+
+	qc := plot.queryCache
+
+	_, unlimitedBytes := plot.persist.unlimitedBytesKeysetWhiteList[keyset]
+
+	var sumBytes uint32
+
+	run := func(from, to int64) (TSDBresponses, gobol.Error) {
+		resps, numBytes, gerr := compute(from, to)
+		sumBytes += numBytes
+		if gerr != nil {
+			return nil, gerr
+		}
+		if !unlimitedBytes && sumBytes >= plot.maxBytesLimit {
+			return nil, errMaxBytesLimitWrapper(funcQueryCache, plot.persist.maxBytesErr)
+		}
+		return resps, nil
+	}
+
+	chunks := make([]TSDBresponses, 0, plan.numChunks+2)
+
+	if plan.start < plan.firstChunk {
+		resps, gerr := run(plan.start, plan.firstChunk)
+		if gerr != nil {
+			return nil, sumBytes, queryCacheMiss, gerr
+		}
+		chunks = append(chunks, resps)
+	}
+
+	hits := 0
+
+	for i := 0; i < plan.numChunks; i++ {
+
+		chunk := plan.firstChunk + int64(i)*qc.chunkSize
+
+		resps, cached, err := qc.get(hash, keyset, chunk)
+		if err != nil && logh.ErrorEnabled {
+			plot.logger.Error().Str(constants.StringsFunc, funcQueryCache).Err(err).Msg("error reading a cached chunk")
+		}
+
+		if cached {
+			hits++
+			chunks = append(chunks, resps)
+			continue
+		}
+
+		resps, gerr := run(chunk, chunk+qc.chunkSize)
+		if gerr != nil {
+			return nil, sumBytes, queryCacheMiss, gerr
+		}
+
+		err = qc.put(hash, keyset, chunk, ttl, resps)
+		if err != nil && logh.ErrorEnabled {
+			plot.logger.Error().Str(constants.StringsFunc, funcQueryCache).Err(err).Msg("error caching a chunk")
+		}
+
+		chunks = append(chunks, resps)
+	}
+
+	if tail := plan.tail(qc.chunkSize); tail <= plan.end {
+		resps, gerr := run(tail, plan.end+1)
+		if gerr != nil {
+			return nil, sumBytes, queryCacheMiss, gerr
+		}
+		chunks = append(chunks, resps)
+	}
+
+	status := queryCachePartial
+	switch hits {
+	case 0:
+		status = queryCacheMiss
+	case plan.numChunks:
+		status = queryCacheHit
+	}
+
+	return mergeChunks(chunks), sumBytes, status, nil
+}
+
+func (plot *Plot) getUncachedTimeseries(keyset string, query structs.TSDBqueryPayload) (TSDBresponses, uint32, string, gobol.Error) {
+
+	resps, numBytes, gerr := plot.getTimeseries(keyset, query)
+
+	return resps, numBytes, queryCacheBypass, gerr
+}
+
+// mergeChunks - joins the points of the same group from all chunks, the series of a group may
+// change between the chunks, so its tags are computed again from the tag values of all chunks
+func mergeChunks(chunks []TSDBresponses) TSDBresponses {
+
+	index := map[string]int{}
+	merged := TSDBresponses{}
+
+	for _, chunk := range chunks {
+		for _, resp := range chunk {
+
+			key := fmt.Sprintf("%d/%s/%s/%s", resp.query, resp.group, resp.TimeShift, resp.Series)
+
+			i, ok := index[key]
+			if !ok {
+				dps := make(map[string]interface{}, len(resp.Dps))
+				for k, v := range resp.Dps {
+					dps[k] = v
+				}
+				resp.Dps = dps
+				resp.Tsuids = append([]string{}, resp.Tsuids...)
+				resp.tagValues = mergeTagValues(map[string]map[string]bool{}, resp.tagValues)
+
+				index[key] = len(merged)
+				merged = append(merged, resp)
+				continue
+			}
+
+			for k, v := range resp.Dps {
+				merged[i].Dps[k] = v
+			}
+
+			for _, tsuid := range resp.Tsuids {
+				found := false
+				for _, existing := range merged[i].Tsuids {
+					if existing == tsuid {
+						found = true
+						break
+					}
+				}
+				if !found {
+					merged[i].Tsuids = append(merged[i].Tsuids, tsuid)
+				}
+			}
+
+			merged[i].tagValues = mergeTagValues(merged[i].tagValues, resp.tagValues)
+		}
+	}
+
+	for i := range merged {
+		merged[i].Tags, merged[i].AggregatedTags = splitTagValues(merged[i].tagValues)
+	}
+
+	sort.Stable(merged)
+
+	return merged
+}
+
+// mergeTagValues - adds the tag values of the source to the destination
+func mergeTagValues(dst, src map[string]map[string]bool) map[string]map[string]bool {
+
+	for k, kv := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = make(map[string]bool, len(kv))
+		}
+		for v := range kv {
+			dst[k][v] = true
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,209 @@
+package plot
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/uol/gobol"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uol/mycenae/lib/structs"
+)
+
+func TestCacheableInterval(t *testing.T) {
+
+	cases := map[string]struct {
+		queries   []structs.TSDBquery
+		interval  int64
+		cacheable bool
+	}{
+		"NoDownsample":           {[]structs.TSDBquery{{Aggregator: "zimsum"}}, 1, true},
+		"InterpolatedRaw":        {[]structs.TSDBquery{{Aggregator: "sum"}}, 0, false},
+		"Downsampled":            {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "5m-avg"}}, 300000, true},
+		"LeastCommonMultiple":    {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "2m-avg"}, {Aggregator: "sum", Downsample: "3m-max-zero"}}, 360000, true},
+		"Rate":                   {[]structs.TSDBquery{{Aggregator: "zimsum", Rate: true}}, 0, false},
+		"RollingWindow":          {[]structs.TSDBquery{{Aggregator: "zimsum", MovingAverage: "5m"}}, 0, false},
+		"TimeShift":              {[]structs.TSDBquery{{Aggregator: "zimsum", TimeShift: "1d"}}, 0, false},
+		"LinearFill":             {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "1m-avg-linear"}}, 0, false},
+		"CalendarDownsample":     {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "1d-avg"}}, 0, false},
+		"ValueFill":              {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "30s-sum-value(1)"}}, 30000, true},
+		"MillisecondsDownsample": {[]structs.TSDBquery{{Aggregator: "sum", Downsample: "500ms-sum"}}, 0, false},
+	}
+
+	for name, c := range cases {
+
+		interval, _, cacheable := cacheableInterval(structs.TSDBqueryPayload{Queries: c.queries})
+
+		assert.Equal(t, c.cacheable, cacheable, name)
+		assert.Equal(t, c.interval, interval, name)
+	}
+}
+
+func TestMergeChunks(t *testing.T) {
+
+	values := func(tags ...string) map[string]map[string]bool {
+		tagK := map[string]map[string]bool{}
+		for i := 0; i < len(tags); i += 2 {
+			if _, ok := tagK[tags[i]]; !ok {
+				tagK[tags[i]] = map[string]bool{}
+			}
+			tagK[tags[i]][tags[i+1]] = true
+		}
+		return tagK
+	}
+
+	chunks := []TSDBresponses{
+		{
+			{Metric: "m", Tags: map[string]string{"host": "b"}, Dps: map[string]interface{}{"1": 1.0}, group: "host=b", tagValues: values("host", "b")},
+			{Metric: "m", Tags: map[string]string{"host": "a", "pod": "p1"}, Tsuids: []string{"t1"}, Dps: map[string]interface{}{"1": 2.0}, group: "host=a", tagValues: values("host", "a", "pod", "p1")},
+		},
+		{
+			{Metric: "m", Tags: map[string]string{"host": "a"}, AggregatedTags: []string{"pod"}, Tsuids: []string{"t1", "t2"}, Dps: map[string]interface{}{"2": 3.0}, group: "host=a", tagValues: values("host", "a", "pod", "p1", "pod", "p2")},
+			{Metric: "m", Tags: map[string]string{"host": "c"}, Dps: map[string]interface{}{"2": 4.0}, group: "host=c", tagValues: values("host", "c")},
+			{Metric: "m", Tags: map[string]string{"host": "a"}, Dps: map[string]interface{}{"2": 5.0}, query: 1, group: "host=a", tagValues: values("host", "a")},
+		},
+	}
+
+	merged := mergeChunks(chunks)
+
+	if assert.Len(t, merged, 4) {
+		assert.Equal(t, map[string]string{"host": "a"}, merged[0].Tags)
+		assert.Equal(t, []string{"pod"}, merged[0].AggregatedTags, "the tags are computed from the series of all chunks")
+		assert.Equal(t, map[string]interface{}{"1": 2.0, "2": 3.0}, merged[0].Dps)
+		assert.Equal(t, []string{"t1", "t2"}, merged[0].Tsuids)
+		assert.Equal(t, map[string]interface{}{"2": 5.0}, merged[1].Dps, "the groups of other queries are not merged")
+		assert.Equal(t, map[string]interface{}{"1": 1.0}, merged[2].Dps)
+		assert.Equal(t, map[string]interface{}{"2": 4.0}, merged[3].Dps)
+	}
+
+	assert.Len(t, chunks[0][1].Dps, 1, "the chunks must not be changed")
+	assert.Len(t, chunks[0][1].tagValues["pod"], 1)
+}
+
+// memoryChunkStore - keeps the cached chunks in a map
+type memoryChunkStore map[string][]byte
+
+func (s memoryChunkStore) Get(router, namespace []byte, fqnKeys ...string) ([]byte, bool, error) {
+
+	data, ok := s[strings.Join(fqnKeys, "/")]
+
+	return data, ok, nil
+}
+
+func (s memoryChunkStore) Put(router, value, ttl, namespace []byte, fqnKeys ...string) error {
+
+	s[strings.Join(fqnKeys, "/")] = value
+
+	return nil
+}
+
+func newTestCachePlot(maxBytesLimit uint32) *Plot {
+
+	return &Plot{
+		queryCache: &queryCache{
+			store:     memoryChunkStore{},
+			chunkSize: 3600000,
+			maxChunks: 10,
+			ttl:       60,
+		},
+		persist: &persistence{
+			maxBytesErr:                   errors.New("max bytes reached"),
+			unlimitedBytesKeysetWhiteList: map[string]bool{},
+		},
+		maxBytesLimit: maxBytesLimit,
+	}
+}
+
+func TestQueryCachePlan(t *testing.T) {
+
+	qc := newTestCachePlot(0).queryCache
+
+	const hour int64 = 3600000
+	start := 10*hour + 120000
+	end := 14*hour + 300000
+
+	plan, ok := qc.plan(start, end, 20*hour, []downsampleGrid{{unit: "min", interval: 60000}})
+	if assert.True(t, ok) {
+		assert.Equal(t, chunkPlan{start: start, firstChunk: 11 * hour, numChunks: 3, end: end}, plan)
+		assert.Equal(t, 14*hour, plan.tail(qc.chunkSize))
+	}
+
+	_, ok = qc.plan(start, end, 20*hour, []downsampleGrid{{unit: "min", interval: 300000}})
+	assert.False(t, ok, "the chunks would not begin in the 5 minutes buckets of the query")
+
+	_, ok = qc.plan(start, 11*hour+120000, 20*hour, nil)
+	assert.False(t, ok, "the window has no whole chunk")
+}
+
+func TestComputeChunks(t *testing.T) {
+
+	plot := newTestCachePlot(1000)
+	qc := plot.queryCache
+
+	const hour int64 = 3600000
+	plan, ok := qc.plan(10*hour+120000, 14*hour+300000, 20*hour, nil)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	windows := [][2]int64{}
+
+	compute := func(from, to int64) (TSDBresponses, uint32, gobol.Error) {
+		windows = append(windows, [2]int64{from, to})
+		return TSDBresponses{{Metric: "m", Dps: map[string]interface{}{strconv.FormatInt(from, 10): float64(to - from)}}}, 10, nil
+	}
+
+	first, numBytes, status, gerr := plot.computeChunks("ks", []byte("hash"), 60, plan, compute)
+	if assert.Nil(t, gerr) {
+		assert.Equal(t, queryCacheMiss, status)
+		assert.Equal(t, uint32(50), numBytes)
+		assert.Equal(t, [][2]int64{
+			{10*hour + 120000, 11 * hour},
+			{11 * hour, 12 * hour},
+			{12 * hour, 13 * hour},
+			{13 * hour, 14 * hour},
+			{14 * hour, 14*hour + 300001},
+		}, windows, "the parts must cover the whole window without overlaps")
+		assert.Len(t, first[0].Dps, 5)
+	}
+
+	windows = windows[:0]
+
+	second, numBytes, status, gerr := plot.computeChunks("ks", []byte("hash"), 60, plan, compute)
+	if assert.Nil(t, gerr) {
+		assert.Equal(t, queryCacheHit, status)
+		assert.Equal(t, uint32(20), numBytes)
+		assert.Equal(t, [][2]int64{{10*hour + 120000, 11 * hour}, {14 * hour, 14*hour + 300001}}, windows, "only the head and the tail are computed again")
+		assert.Equal(t, first, second)
+	}
+}
+
+func TestComputeChunksMaxBytes(t *testing.T) {
+
+	plot := newTestCachePlot(25)
+
+	const hour int64 = 3600000
+	plan, ok := plot.queryCache.plan(10*hour, 14*hour, 20*hour, nil)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	compute := func(from, to int64) (TSDBresponses, uint32, gobol.Error) {
+		return TSDBresponses{}, 10, nil
+	}
+
+	_, numBytes, _, gerr := plot.computeChunks("ks", []byte("hash"), 60, plan, compute)
+	if assert.NotNil(t, gerr, "the limit applies to the whole query, not to each chunk") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, gerr.StatusCode())
+		assert.Equal(t, uint32(30), numBytes)
+	}
+
+	plot.persist.unlimitedBytesKeysetWhiteList["ks"] = true
+
+	_, _, _, gerr = plot.computeChunks("ks", []byte("hash"), 60, plan, compute)
+	assert.Nil(t, gerr)
+}
@@ -0,0 +1,154 @@
+package plot
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/parser"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// queryTimeRange - resolves the query start and end in milliseconds, the relative duration has precedence
+func queryTimeRange(function, relative string, start, end int64) (int64, int64, gobol.Error) {
+
+	if relative != constants.StringsEmpty {
+		now := time.Now()
+		relativeStart, gerr := parser.GetRelativeStart(now, relative)
+		if gerr != nil {
+			return 0, 0, gerr
+		}
+		return relativeStart.UnixNano() / 1e+6, now.UnixNano() / 1e+6, nil
+	}
+
+	if start == 0 {
+		return 0, 0, errValidationS(function, "start cannot be zero")
+	}
+
+	if end == 0 {
+		end = time.Now().UnixNano() / 1e+6
+	}
+
+	if end < start {
+		return 0, 0, errValidationS(function, "end date should be equal or bigger than start date")
+	}
+
+	return start, end, nil
+}
+
+// splitTTLFilter - removes the ttl filter, returning its value or the default ttl
+func (plot *Plot) splitTTLFilter(function string, filters []structs.TSDBfilter) (int, []structs.TSDBfilter, gobol.Error) {
+
+	ttl := plot.defaultTTL
+	result := make([]structs.TSDBfilter, 0, len(filters))
+
+	for _, filter := range filters {
+
+		if filter.Tagk == constants.StringsTTL {
+			v, err := strconv.Atoi(filter.Filter)
+			if err != nil {
+				return 0, nil, errValidationE(function, err)
+			}
+			ttl = v
+			continue
+		}
+
+		result = append(result, filter)
+	}
+
+	return ttl, result, nil
+}
+
+// tagsToFilters - converts the OpenTSDB query tags to wildcard filters, grouping by the ones with many values
+func tagsToFilters(tags map[string]string) []structs.TSDBfilter {
+
+	filters := make([]structs.TSDBfilter, 0, len(tags))
+
+	for k, v := range tags {
+
+		members := strings.Split(v, "|")
+		filters = append(filters, structs.TSDBfilter{
+			Ftype:   "wildcard",
+			Tagk:    k,
+			Filter:  v,
+			GroupBy: members[0] == "*" || len(members) > 1,
+		})
+	}
+
+	return filters
+}
+
+// groupTags - returns the tsids of the group, the tags shared by all of them and the aggregated tag keys
+func groupTags(group []TSDBobj) ([]string, map[string]string, []string) {
+
+	ids := make([]string, 0, len(group))
+
+	for _, tsd := range group {
+		ids = append(ids, tsd.Tsuid)
+	}
+
+	tags, aggTags := splitTagValues(groupTagValues(group))
+
+	return ids, tags, aggTags
+}
+
+// groupTagValues - returns all values of each tag of the group
+func groupTagValues(group []TSDBobj) map[string]map[string]bool {
+
+	tagK := map[string]map[string]bool{}
+
+	for _, tsd := range group {
+
+		for k, v := range tsd.Tags {
+			if _, ok := tagK[k]; !ok {
+				tagK[k] = map[string]bool{}
+			}
+			tagK[k][v] = true
+		}
+	}
+
+	return tagK
+}
+
+// splitTagValues - returns the tags with a single value and the sorted keys of the aggregated ones
+func splitTagValues(tagK map[string]map[string]bool) (map[string]string, []string) {
+
+	tags := map[string]string{}
+	aggTags := []string{}
+
+	for k, kv := range tagK {
+
+		if len(kv) > 1 {
+			aggTags = append(aggTags, k)
+			continue
+		}
+
+		for v := range kv {
+			tags[k] = v
+		}
+	}
+
+	sort.Strings(aggTags)
+
+	return tags, aggTags
+}
+
+// groupKey - returns the values of the group by tags, shared by all series of the group
+func groupKey(filters []structs.TSDBfilter, tsd TSDBobj) string {
+
+	keys := []string{}
+
+	for _, filter := range filters {
+		if filter.GroupBy {
+			keys = append(keys, filter.Tagk+"="+tsd.Tags[filter.Tagk])
+		}
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/uol/gobol"
@@ -34,6 +35,23 @@ func (plot *Plot) getSizeParameter(w http.ResponseWriter, q url.Values, function
 	return size, false
 }
 
+// getActiveWithinParameter - returns the activeWithin parameter, only the series seen within it are listed
+func (plot *Plot) getActiveWithinParameter(w http.ResponseWriter, q url.Values) (time.Duration, bool) {
+
+	activeWithin := q.Get("activeWithin")
+	if activeWithin == constants.StringsEmpty {
+		return 0, false
+	}
+
+	ms, gerr := durationToMs(activeWithin)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return 0, true
+	}
+
+	return time.Duration(ms) * time.Millisecond, false
+}
+
 func (plot *Plot) ListTagsNumber(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	plot.listTags(w, r, ps, "number")
 }
@@ -57,7 +75,12 @@ func (plot *Plot) listTags(w http.ResponseWriter, r *http.Request, ps httprouter
 		return
 	}
 
-	tags, total, gerr := plot.FilterTagKeys(keyset, q.Get("tag"), size)
+	activeWithin, fail := plot.getActiveWithinParameter(w, q)
+	if fail {
+		return
+	}
+
+	tags, total, gerr := plot.FilterTagKeys(keyset, q.Get("tag"), size, activeWithin)
 	if gerr != nil {
 		rip.Fail(w, gerr)
 		return
@@ -103,7 +126,12 @@ func (plot *Plot) listMetrics(w http.ResponseWriter, r *http.Request, ps httprou
 		return
 	}
 
-	metrics, total, gerr := plot.FilterMetrics(keyset, q.Get("metric"), size)
+	activeWithin, fail := plot.getActiveWithinParameter(w, q)
+	if fail {
+		return
+	}
+
+	metrics, total, gerr := plot.FilterMetrics(keyset, q.Get("metric"), size, activeWithin)
 	if gerr != nil {
 		rip.Fail(w, gerr)
 		return
@@ -117,6 +145,7 @@ func (plot *Plot) listMetrics(w http.ResponseWriter, r *http.Request, ps httprou
 	out := Response{
 		TotalRecords: total,
 		Payload:      metrics,
+		Catalog:      plot.catalog.Lookup(keyset, metrics...),
 	}
 
 	rip.SuccessJSON(w, http.StatusOK, out)
@@ -301,12 +330,23 @@ func (plot *Plot) deleteTS(w http.ResponseWriter, r *http.Request, ps httprouter
 
 	} else {
 
+		metrics := map[string]bool{}
+
 		for _, key := range keys {
 			gerr = plot.persist.metaStorage.DeleteDocumentByID(*keyset, tsType, key.TsId)
 			if gerr != nil {
 				rip.Fail(w, gerr)
 				return
 			}
+			metrics[key.Metric] = true
+		}
+
+		for metric := range metrics {
+			gerr = plot.persist.metaStorage.InvalidateFilterCache(*keyset, tsType, metric)
+			if gerr != nil {
+				rip.Fail(w, gerr)
+				return
+			}
 		}
 
 		rip.SuccessJSON(w, http.StatusAccepted, out)
@@ -354,6 +394,11 @@ func (plot *Plot) listTagsByMetric(w http.ResponseWriter, r *http.Request, ps ht
 		return
 	}
 
+	activeWithin, fail := plot.getActiveWithinParameter(w, q)
+	if fail {
+		return
+	}
+
 	var results []string
 	var total int
 	var gerr gobol.Error
@@ -371,7 +416,7 @@ func (plot *Plot) listTagsByMetric(w http.ResponseWriter, r *http.Request, ps ht
 			value = "*"
 		}
 
-		results, total, gerr = plot.persist.metaStorage.FilterTagValuesByMetricAndTag(*keyset, tsType, metric, tag, value, size)
+		results, total, gerr = plot.persist.metaStorage.FilterTagValuesByMetricAndTag(*keyset, tsType, metric, tag, value, size, activeWithin)
 
 	} else {
 
@@ -380,7 +425,7 @@ func (plot *Plot) listTagsByMetric(w http.ResponseWriter, r *http.Request, ps ht
 			tag = "*"
 		}
 
-		results, total, gerr = plot.persist.metaStorage.FilterTagKeysByMetric(*keyset, tsType, metric, tag, size)
+		results, total, gerr = plot.persist.metaStorage.FilterTagKeysByMetric(*keyset, tsType, metric, tag, size, activeWithin)
 	}
 
 	if gerr != nil {
@@ -0,0 +1,192 @@
+package plot
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// Annotation - returns the texts of the text timeseries as OpenTSDB annotations
+func (plot *Plot) Annotation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound("Annotation"))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	query := structs.AnnotationQueryPayload{}
+
+	gerr = rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	start, end, gerr := queryTimeRange(funcGetAnnotations, query.Relative, query.Start, query.End)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	annotations, numBytes, gerr := plot.getAnnotations(keyset, query.Metric, query.Filters, start, end)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	addProcessedBytesHeader(w, numBytes)
+
+	resps := make([]OpenTSDBannotation, len(annotations))
+
+	for i, a := range annotations {
+		resps[i] = OpenTSDBannotation{
+			Tsuid:       a.tsuid,
+			Description: a.text,
+			Notes:       a.text,
+			Custom:      a.tags,
+			StartTime:   a.date / 1000,
+		}
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, resps)
+}
+
+// GrafanaAnnotations - returns the texts of the text timeseries as grafana simple json annotations
+func (plot *Plot) GrafanaAnnotations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound("GrafanaAnnotations"))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	query := structs.GrafanaAnnotationPayload{}
+
+	gerr = rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	metric, filters, gerr := query.Annotation.ParseQuery()
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	start := query.Range.From.UnixNano() / 1e+6
+	end := query.Range.To.UnixNano() / 1e+6
+
+	annotations, numBytes, gerr := plot.getAnnotations(keyset, metric, filters, start, end)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	addProcessedBytesHeader(w, numBytes)
+
+	resps := make([]GrafanaAnnotationResponse, len(annotations))
+
+	for i, a := range annotations {
+
+		tags := make([]string, 0, len(a.tags))
+		for k, v := range a.tags {
+			tags = append(tags, k+":"+v)
+		}
+		sort.Strings(tags)
+
+		resps[i] = GrafanaAnnotationResponse{
+			Annotation: query.Annotation,
+			Time:       a.date,
+			Title:      a.text,
+			Text:       a.text,
+			Tags:       tags,
+		}
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, resps)
+}
+
+const funcGetAnnotations string = "getAnnotations"
+
+type annotation struct {
+	tsuid string
+	tags  map[string]string
+	date  int64
+	text  string
+}
+
+// getAnnotations - reads the texts of each text timeseries matching the metric and filters, sorted by date
+func (plot *Plot) getAnnotations(keyset, metric string, filters []structs.TSDBfilter, start, end int64) ([]annotation, uint32, gobol.Error) {
+
+	ttl, filters, gerr := plot.splitTTLFilter(funcGetAnnotations, filters)
+	if gerr != nil {
+		return nil, 0, gerr
+	}
+
+	tsobs, total, gerr := plot.metaFilter(keyset, "metatext", metric, filters, plot.MaxTimeseries, 0)
+	if gerr != nil {
+		return nil, 0, gerr
+	}
+
+	logIfExceeded := fmt.Sprintf("TS THRESHOLD/MAX EXCEEDED for annotation query: metric %s, filters %+v", metric, filters)
+	gerr = plot.checkTotalTSLimits(logIfExceeded, keyset, metric, total)
+	if gerr != nil {
+		return nil, 0, gerr
+	}
+
+	var sumBytes uint32
+	annotations := []annotation{}
+
+	for _, tsobj := range tsobs {
+
+		serie, numBytes, gerr := plot.GetTextSeries(ttl, []string{tsobj.Tsuid}, start, end, nil, keyset, false)
+		sumBytes += numBytes
+		if gerr != nil {
+			if gerr.Error() == plot.persist.maxBytesErr.Error() {
+				return nil, sumBytes, errMaxBytesLimit(funcGetAnnotations, keyset, metric, start, end, ttl)
+			}
+			return nil, sumBytes, gerr
+		}
+
+		for _, p := range serie.Data {
+			annotations = append(annotations, annotation{
+				tsuid: tsobj.Tsuid,
+				tags:  tsobj.Tags,
+				date:  p.Date,
+				text:  p.Value,
+			})
+		}
+	}
+
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if annotations[i].date != annotations[j].date {
+			return annotations[i].date < annotations[j].date
+		}
+		return annotations[i].tsuid < annotations[j].tsuid
+	})
+
+	plot.statsActiveMetric(funcGetAnnotations, keyset, metric)
+
+	return annotations, sumBytes, nil
+}
@@ -127,6 +127,8 @@ func (plot *Plot) expressionQuery(w http.ResponseWriter, r *http.Request, keyset
 		return
 	}
 
+	plot.defaultCounterRate(keyset, &tsdb)
+
 	tsuid := false
 	tsuidStr := r.URL.Query().Get("tsuid")
 	if tsuidStr != constants.StringsEmpty {
@@ -166,6 +168,8 @@ func (plot *Plot) expressionQuery(w http.ResponseWriter, r *http.Request, keyset
 		return
 	}
 
+	plot.setUnits(keyset, resps)
+
 	rip.SuccessJSON(w, http.StatusOK, resps)
 	return
 }
@@ -364,6 +368,8 @@ func (plot *Plot) expressionExpand(w http.ResponseWriter, keyset string, expQuer
 		return
 	}
 
+	plot.defaultCounterRate(keyset, &tsdb)
+
 	payload := structs.TSDBqueryPayload{
 		Queries: []structs.TSDBquery{
 			tsdb,
@@ -420,7 +426,7 @@ func (plot *Plot) expandStruct(
 
 	if needExpand {
 
-		tsobs, total, gerr := plot.MetaFilterOpenTSDB(keyset, tsdb.Metric, tsdb.Filters, plot.MaxTimeseries)
+		tsobs, total, gerr := plot.MetaFilterOpenTSDB(keyset, tsdb.Metric, tsdb.Filters, plot.MaxTimeseries, 0)
 		if gerr != nil {
 			return groupQueries, gerr
 		}
@@ -485,6 +491,7 @@ func (plot *Plot) expandStruct(
 					{
 						Aggregator:  tsdb.Aggregator,
 						Downsample:  tsdb.Downsample,
+						MaxGap:      tsdb.MaxGap,
 						Metric:      tsdb.Metric,
 						Tags:        map[string]string{},
 						Rate:        tsdb.Rate,
@@ -492,6 +499,19 @@ func (plot *Plot) expandStruct(
 						Order:       tsdb.Order,
 						FilterValue: tsdb.FilterValue,
 						Filters:     filtersPlain,
+						Increase:    tsdb.Increase,
+						Delta:       tsdb.Delta,
+						Irate:       tsdb.Irate,
+						Deriv:       tsdb.Deriv,
+
+						MovingAverage: tsdb.MovingAverage,
+						RollingSum:    tsdb.RollingSum,
+						RollingMax:    tsdb.RollingMax,
+						RollingMin:    tsdb.RollingMin,
+						Ewma:          tsdb.Ewma,
+						HoltWinters:   tsdb.HoltWinters,
+						TimeShift:     tsdb.TimeShift,
+						Compare:       tsdb.Compare,
 					},
 				},
 			}
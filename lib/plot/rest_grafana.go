@@ -0,0 +1,358 @@
+package plot
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/parser"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// grafanaKeyset - returns the validated keyset of the grafana datasource
+func (plot *Plot) grafanaKeyset(w http.ResponseWriter, ps httprouter.Params, function string) (string, bool) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound(function))
+		return constants.StringsEmpty, false
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return constants.StringsEmpty, false
+	}
+
+	return keyset, true
+}
+
+// GrafanaCheck - the grafana json datasource connection test
+func (plot *Plot) GrafanaCheck(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	if _, ok := plot.grafanaKeyset(w, ps, "GrafanaCheck"); !ok {
+		return
+	}
+
+	rip.Success(w, http.StatusOK, nil)
+}
+
+// GrafanaSearch - returns the metrics starting with the target
+func (plot *Plot) GrafanaSearch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, ok := plot.grafanaKeyset(w, ps, "GrafanaSearch")
+	if !ok {
+		return
+	}
+
+	query := structs.GrafanaSearchPayload{}
+
+	gerr := rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	metrics, _, gerr := plot.FilterMetrics(keyset, fmt.Sprintf("%v*", query.Target), plot.defaultMaxResults, 0)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if metrics == nil {
+		metrics = []string{}
+	}
+
+	sort.Strings(metrics)
+
+	rip.SuccessJSON(w, http.StatusOK, metrics)
+}
+
+// GrafanaTagKeys - returns the tag keys, only the ones of the metric timeseries when the metric is set
+func (plot *Plot) GrafanaTagKeys(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, ok := plot.grafanaKeyset(w, ps, "GrafanaTagKeys")
+	if !ok {
+		return
+	}
+
+	query := structs.GrafanaTagPayload{}
+
+	gerr := rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	keys, _, gerr := plot.FilterTagKeysByMetric(keyset, "meta", grafanaMetric(query.Metric), "*", plot.defaultMaxResults, 0)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, toGrafanaTags(keys, "string"))
+}
+
+// GrafanaTagValues - returns the values of the tag key, only the ones of the metric timeseries when the metric is set
+func (plot *Plot) GrafanaTagValues(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, ok := plot.grafanaKeyset(w, ps, "GrafanaTagValues")
+	if !ok {
+		return
+	}
+
+	query := structs.GrafanaTagPayload{}
+
+	gerr := rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	if query.Key == constants.StringsEmpty {
+		rip.Fail(w, errValidationS("GrafanaTagValues", "key is mandatory"))
+		return
+	}
+
+	values, _, gerr := plot.FilterTagValuesByMetricAndTag(keyset, "meta", grafanaMetric(query.Metric), query.Key, "*", plot.defaultMaxResults, 0)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, toGrafanaTags(values, constants.StringsEmpty))
+}
+
+// grafanaMetric - all metrics when the metric is not set
+func grafanaMetric(metric string) string {
+
+	if metric == constants.StringsEmpty {
+		return "*"
+	}
+
+	return metric
+}
+
+func toGrafanaTags(values []string, tagType string) []GrafanaTag {
+
+	sort.Strings(values)
+
+	tags := make([]GrafanaTag, len(values))
+
+	for i, v := range values {
+		tags[i] = GrafanaTag{Type: tagType, Text: v}
+	}
+
+	return tags
+}
+
+// GrafanaQuery - runs the mycenae expressions of the targets in the grafana time range
+func (plot *Plot) GrafanaQuery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset, ok := plot.grafanaKeyset(w, ps, "GrafanaQuery")
+	if !ok {
+		return
+	}
+
+	query := structs.GrafanaQueryPayload{}
+
+	gerr := rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	resps, numBytes, gerr := plot.getGrafanaTargets(keyset, query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	addProcessedBytesHeader(w, numBytes)
+
+	rip.SuccessJSON(w, http.StatusOK, resps)
+}
+
+const funcGetGrafanaTargets string = "getGrafanaTargets"
+
+func (plot *Plot) getGrafanaTargets(keyset string, query structs.GrafanaQueryPayload) ([]interface{}, uint32, gobol.Error) {
+
+	adhocFilters, gerr := query.Filters()
+	if gerr != nil {
+		return nil, 0, gerr
+	}
+
+	start := query.Range.From.UnixNano() / 1e+6
+	end := query.Range.To.UnixNano() / 1e+6
+
+	var sumBytes uint32
+	resps := []interface{}{}
+
+	for _, target := range query.Targets {
+
+		if target.Hide || strings.TrimSpace(target.Target) == constants.StringsEmpty {
+			continue
+		}
+
+		tsdb := structs.TSDBquery{}
+
+		_, gerr := parser.ParseExpression(target.Target, &tsdb)
+		if gerr != nil {
+			return nil, sumBytes, gerr
+		}
+
+		tsdb.Filters = append(tsdb.Filters, adhocFilters...)
+
+		payload := structs.TSDBqueryPayload{
+			Start:        start,
+			End:          end,
+			MsResolution: true,
+			Queries:      []structs.TSDBquery{tsdb},
+		}
+
+		gerr = payload.Validate()
+		if gerr != nil {
+			return nil, sumBytes, gerr
+		}
+
+		series, numBytes, gerr := plot.getTimeseries(keyset, payload)
+		sumBytes += numBytes
+		if gerr != nil {
+			return nil, sumBytes, gerr
+		}
+
+		if target.Type == structs.GrafanaTargetTable {
+			resps = append(resps, toGrafanaTable(series))
+			continue
+		}
+
+		for _, serie := range series {
+			resps = append(resps, GrafanaTimeserie{
+				Target:     grafanaSerieName(serie),
+				Datapoints: toGrafanaDatapoints(serie.Dps),
+			})
+		}
+	}
+
+	return resps, sumBytes, nil
+}
+
+// grafanaSerieName - the metric followed by the sorted tags: metric{tagk=tagv,tagk=tagv}
+func grafanaSerieName(serie TSDBresponse) string {
+
+	keys := make([]string, 0, len(serie.Tags))
+	for k := range serie.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, len(keys))
+	for i, k := range keys {
+		tags[i] = k + "=" + serie.Tags[k]
+	}
+
+	name := serie.Metric + "{" + strings.Join(tags, constants.StringsComma) + "}"
+
+	if serie.TimeShift != constants.StringsEmpty {
+		name += " (" + serie.TimeShift + ")"
+	}
+
+	return name
+}
+
+type grafanaPoint struct {
+	date  int64
+	value interface{}
+}
+
+// toGrafanaPoints - converts the dps to points sorted by date, the not a number values are null
+func toGrafanaPoints(dps map[string]interface{}) []grafanaPoint {
+
+	points := make([]grafanaPoint, 0, len(dps))
+
+	for k, v := range dps {
+
+		date, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := v.(float64); !ok {
+			v = nil
+		}
+
+		points = append(points, grafanaPoint{date: date, value: v})
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].date < points[j].date
+	})
+
+	return points
+}
+
+// toGrafanaDatapoints - the grafana datapoints are [value, time in milliseconds] pairs
+func toGrafanaDatapoints(dps map[string]interface{}) [][]interface{} {
+
+	points := toGrafanaPoints(dps)
+	datapoints := make([][]interface{}, len(points))
+
+	for i, p := range points {
+		datapoints[i] = []interface{}{p.value, p.date}
+	}
+
+	return datapoints
+}
+
+// toGrafanaTable - one row per point with the time, the metric, the tag values and the value columns
+func toGrafanaTable(series TSDBresponses) GrafanaTable {
+
+	tagKeys := []string{}
+	seen := map[string]bool{}
+
+	for _, serie := range series {
+		for k := range serie.Tags {
+			if !seen[k] {
+				seen[k] = true
+				tagKeys = append(tagKeys, k)
+			}
+		}
+	}
+
+	sort.Strings(tagKeys)
+
+	table := GrafanaTable{
+		Type:    structs.GrafanaTargetTable,
+		Columns: make([]GrafanaColumn, 0, len(tagKeys)+3),
+		Rows:    [][]interface{}{},
+	}
+
+	table.Columns = append(table.Columns, GrafanaColumn{Text: "Time", Type: "time"}, GrafanaColumn{Text: "Metric", Type: "string"})
+	for _, k := range tagKeys {
+		table.Columns = append(table.Columns, GrafanaColumn{Text: k, Type: "string"})
+	}
+	table.Columns = append(table.Columns, GrafanaColumn{Text: "Value", Type: "number"})
+
+	for _, serie := range series {
+		for _, p := range toGrafanaPoints(serie.Dps) {
+
+			row := make([]interface{}, 0, len(table.Columns))
+			row = append(row, p.date, serie.Metric)
+			for _, k := range tagKeys {
+				row = append(row, serie.Tags[k])
+			}
+			row = append(row, p.value)
+
+			table.Rows = append(table.Rows, row)
+		}
+	}
+
+	return table
+}
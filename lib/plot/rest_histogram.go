@@ -0,0 +1,170 @@
+package plot
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// HistogramQuery - queries histogram metrics, the quantiles are computed from the merged sketches
+func (plot *Plot) HistogramQuery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound("HistogramQuery"))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	query := structs.HistogramQueryPayload{}
+
+	gerr = rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	resps, numBytes, gerr := plot.getHistograms(keyset, query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	addProcessedBytesHeader(w, numBytes)
+
+	if !query.EstimateSize {
+
+		if len(resps) == 0 {
+			rip.SuccessJSON(w, http.StatusOK, []string{})
+			return
+		}
+
+		rip.SuccessJSON(w, http.StatusOK, resps)
+		return
+	}
+
+	rip.Success(w, http.StatusOK, []byte(fmt.Sprintf("%d bytes", numBytes)))
+}
+
+const funcGetHistograms string = "getHistograms"
+
+func (plot *Plot) getHistograms(keyset string, query structs.HistogramQueryPayload) (resps TSDBhistogramResponses, sumBytes uint32, gerr gobol.Error) {
+
+	query.Start, query.End, gerr = queryTimeRange(funcGetHistograms, query.Relative, query.Start, query.End)
+	if gerr != nil {
+		return resps, 0, gerr
+	}
+
+	if plot.persist.isMillisecondKeyset(keyset) {
+		query.MsResolution = true
+	}
+
+	for _, q := range query.Queries {
+
+		var interval int64
+
+		if q.Downsample != constants.StringsEmpty {
+			if interval, gerr = durationToMs(q.Downsample); gerr != nil {
+				return resps, sumBytes, gerr
+			}
+		}
+
+		ttl, filters, gerr := plot.splitTTLFilter(funcGetHistograms, q.Filters)
+		if gerr != nil {
+			return resps, sumBytes, gerr
+		}
+
+		tsobs, total, gerr := plot.metaFilter(keyset, "metahistogram", q.Metric, filters, plot.MaxTimeseries, 0)
+		if gerr != nil {
+			return resps, sumBytes, gerr
+		}
+
+		logIfExceeded := fmt.Sprintf("TS THRESHOLD/MAX EXCEEDED for histogram query: %+v", query)
+		gerr = plot.checkTotalTSLimits(logIfExceeded, keyset, q.Metric, total)
+		if gerr != nil {
+			return TSDBhistogramResponses{}, sumBytes, gerr
+		}
+
+		for _, group := range plot.GetGroups(filters, tsobs) {
+
+			ids, tags, aggTags := groupTags(group)
+
+			serie, numBytes, gerr := plot.GetHistogramSeries(ttl, ids, query.Start, query.End, interval, query.MsResolution, query.EstimateSize, keyset)
+			sumBytes += numBytes
+			if gerr != nil {
+				if gerr.Error() == plot.persist.maxBytesErr.Error() {
+					return resps, sumBytes, errMaxBytesLimit(funcGetHistograms, keyset, q.Metric, query.Start, query.End, ttl)
+				}
+				return resps, sumBytes, gerr
+			}
+
+			if len(serie) == 0 {
+				continue
+			}
+
+			resps = append(resps, TSDBhistogramResponse{
+				Metric:         q.Metric,
+				Tags:           tags,
+				AggregatedTags: aggTags,
+				Dps:            toHistogramDps(serie, query.MsResolution, q.Quantiles),
+			})
+		}
+
+		plot.statsActiveMetric(funcGetHistograms, keyset, q.Metric)
+	}
+
+	sort.Stable(resps)
+
+	return resps, sumBytes, nil
+}
+
+// quantileName - formats the quantile as a percentile: 0.5 is p50 and 0.999 is p99.9
+func quantileName(q float64) string {
+
+	return "p" + strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64)
+}
+
+// toHistogramDps - computes the statistics and the quantiles of each merged sketch
+func toHistogramDps(serie []HistogramPnt, msResolution bool, quantiles []float64) map[string]HistogramStats {
+
+	dps := make(map[string]HistogramStats, len(serie))
+
+	for _, p := range serie {
+
+		k := p.Date
+		if !msResolution {
+			k = p.Date / 1000
+		}
+
+		stats := HistogramStats{
+			Count:     p.Sketch.Count(),
+			Sum:       p.Sketch.Sum(),
+			Min:       p.Sketch.Min(),
+			Max:       p.Sketch.Max(),
+			Avg:       p.Sketch.Avg(),
+			Quantiles: make(map[string]float64, len(quantiles)),
+		}
+
+		for _, q := range quantiles {
+			stats.Quantiles[quantileName(q)] = p.Sketch.Quantile(q)
+		}
+
+		dps[strconv.FormatInt(k, 10)] = stats
+	}
+
+	return dps
+}
@@ -0,0 +1,301 @@
+package plot
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// TextQuery - queries text metrics by tag filters, searching the texts, with pagination or counts
+func (plot *Plot) TextQuery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound("TextQuery"))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	query := structs.TextQueryPayload{}
+
+	gerr = rip.FromJSON(r, &query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	resps, numBytes, gerr := plot.getTextSeries(keyset, query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	addProcessedBytesHeader(w, numBytes)
+
+	if !query.EstimateSize {
+
+		if len(resps) == 0 {
+			rip.SuccessJSON(w, http.StatusOK, []string{})
+			return
+		}
+
+		rip.SuccessJSON(w, http.StatusOK, resps)
+		return
+	}
+
+	rip.Success(w, http.StatusOK, []byte(fmt.Sprintf("%d bytes", numBytes)))
+}
+
+const funcGetTextSeries string = "getTextSeries"
+
+// textCursor - the position of the last returned text of a group, the next page of the group starts right after it
+type textCursor struct {
+	date  int64
+	tsid  string
+	group string
+}
+
+func (c textCursor) String() string {
+
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.date, 10) + constants.StringsBar + c.tsid + constants.StringsBar + c.group))
+}
+
+func parseTextCursor(cursor string) (*textCursor, gobol.Error) {
+
+	if cursor == constants.StringsEmpty {
+		return nil, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errValidationS(funcGetTextSeries, "invalid cursor")
+	}
+
+	parts := strings.SplitN(string(decoded), constants.StringsBar, 3)
+	if len(parts) != 3 {
+		return nil, errValidationS(funcGetTextSeries, "invalid cursor")
+	}
+
+	date, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errValidationS(funcGetTextSeries, "invalid cursor")
+	}
+
+	return &textCursor{date: date, tsid: parts[1], group: parts[2]}, nil
+}
+
+// textGroupKey - identifies a group of text series by its tags, it does not change when new series join the group
+func textGroupKey(tags map[string]string, aggTags []string) string {
+
+	keys := make([]string, 0, len(tags)+len(aggTags))
+
+	for k, v := range tags {
+		keys = append(keys, k+"="+v)
+	}
+
+	for _, k := range aggTags {
+		keys = append(keys, k+"=*")
+	}
+
+	sort.Strings(keys)
+
+	hash := fnv.New64a()
+	hash.Write([]byte(strings.Join(keys, ",")))
+
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
+
+// after - checks if the text is positioned after the cursor
+func (c *textCursor) after(date int64, tsid string) bool {
+
+	return c == nil || date > c.date || (date == c.date && tsid > c.tsid)
+}
+
+type textQueryPoint struct {
+	date  int64
+	tsid  string
+	value string
+}
+
+func (plot *Plot) getTextSeries(keyset string, query structs.TextQueryPayload) (resps TextQueryResponses, sumBytes uint32, gerr gobol.Error) {
+
+	query.Start, query.End, gerr = queryTimeRange(funcGetTextSeries, query.Relative, query.Start, query.End)
+	if gerr != nil {
+		return resps, 0, gerr
+	}
+
+	if plot.persist.isMillisecondKeyset(keyset) {
+		query.MsResolution = true
+	}
+
+	for _, q := range query.Queries {
+
+		cursor, gerr := parseTextCursor(q.Cursor)
+		if gerr != nil {
+			return resps, sumBytes, gerr
+		}
+
+		var interval int64
+		if q.Count != constants.StringsEmpty {
+			if interval, gerr = durationToMs(q.Count); gerr != nil {
+				return resps, sumBytes, gerr
+			}
+		}
+
+		limit := q.Limit
+		if limit == 0 {
+			limit = plot.defaultMaxResults
+		}
+
+		ttl, filters, gerr := plot.splitTTLFilter(funcGetTextSeries, q.Filters)
+		if gerr != nil {
+			return resps, sumBytes, gerr
+		}
+
+		keyspace, ok := plot.keyspaceTTLMap.Get(ttl)
+		if !ok {
+			return resps, sumBytes, errNotFound("invalid ttl found: " + strconv.Itoa(ttl))
+		}
+
+		tsobs, total, gerr := plot.metaFilter(keyset, "metatext", q.Metric, filters, plot.MaxTimeseries, 0)
+		if gerr != nil {
+			return resps, sumBytes, gerr
+		}
+
+		logIfExceeded := fmt.Sprintf("TS THRESHOLD/MAX EXCEEDED for text query: %+v", query)
+		gerr = plot.checkTotalTSLimits(logIfExceeded, keyset, q.Metric, total)
+		if gerr != nil {
+			return TextQueryResponses{}, sumBytes, gerr
+		}
+
+		search := q.SearchRegexp()
+
+		for _, group := range plot.GetGroups(filters, tsobs) {
+
+			ids, tags, aggTags := groupTags(group)
+
+			// a cursor only continues the pages of its own group
+			groupKey := textGroupKey(tags, aggTags)
+			if cursor != nil && cursor.group != groupKey {
+				continue
+			}
+
+			start := query.Start
+			if cursor != nil && cursor.date > start {
+				start = cursor.date
+			}
+
+			// one more text than the page tells if there is a next page
+			pageLimit := 0
+			if interval == 0 {
+				pageLimit = limit + 1
+			}
+
+			tsMap, numBytes, gerr := plot.getTextSerie(keyspace, ids, start, query.End, search, pageLimit, cursor, keyset, query.EstimateSize)
+			sumBytes += numBytes
+			if gerr != nil {
+				if gerr.Error() == plot.persist.maxBytesErr.Error() {
+					return resps, sumBytes, errMaxBytesLimit(funcGetTextSeries, keyset, q.Metric, query.Start, query.End, ttl)
+				}
+				return resps, sumBytes, gerr
+			}
+
+			points := []textQueryPoint{}
+
+			for tsid, ts := range tsMap {
+				for _, p := range ts.Data {
+					if cursor.after(p.Date, tsid) {
+						points = append(points, textQueryPoint{date: p.Date, tsid: tsid, value: p.Value})
+					}
+				}
+			}
+
+			if len(points) == 0 {
+				continue
+			}
+
+			resp := TextQueryResponse{
+				Metric:         q.Metric,
+				Tags:           tags,
+				AggregatedTags: aggTags,
+			}
+
+			if interval > 0 {
+				resp.Counts = countTexts(points, interval, query.MsResolution)
+				resps = append(resps, resp)
+				continue
+			}
+
+			sort.Slice(points, func(i, j int) bool {
+				if points[i].date != points[j].date {
+					return points[i].date < points[j].date
+				}
+				return points[i].tsid < points[j].tsid
+			})
+
+			if len(points) > limit {
+				points = points[:limit]
+				last := points[limit-1]
+				resp.NextCursor = textCursor{date: last.date, tsid: last.tsid, group: groupKey}.String()
+			}
+
+			resp.Texts = make([]TextQueryPoint, len(points))
+
+			for i, p := range points {
+
+				date := p.date
+				if !query.MsResolution {
+					date /= 1000
+				}
+
+				resp.Texts[i] = TextQueryPoint{
+					Timestamp: date,
+					Text:      p.value,
+					Tsuid:     p.tsid,
+				}
+			}
+
+			resps = append(resps, resp)
+		}
+
+		plot.statsActiveMetric(funcGetTextSeries, keyset, q.Metric)
+	}
+
+	sort.Stable(resps)
+
+	return resps, sumBytes, nil
+}
+
+// countTexts - counts the texts of each interval bucket
+func countTexts(points []textQueryPoint, interval int64, msResolution bool) map[string]int {
+
+	counts := map[string]int{}
+
+	for _, p := range points {
+
+		date := p.date - p.date%interval
+		if !msResolution {
+			date /= 1000
+		}
+
+		counts[strconv.FormatInt(date, 10)]++
+	}
+
+	return counts
+}
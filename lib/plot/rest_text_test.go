@@ -0,0 +1,43 @@
+package plot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextCursorKeepsTheGroup(t *testing.T) {
+
+	cursor := textCursor{date: 1590000000123, tsid: "T1abc", group: textGroupKey(map[string]string{"host": "a"}, nil)}
+
+	parsed, gerr := parseTextCursor(cursor.String())
+	assert.Nil(t, gerr)
+	assert.Equal(t, cursor, *parsed)
+
+	_, gerr = parseTextCursor("MTU5MDAwMDAwMDEyM3xUMWFiYw")
+	assert.NotNil(t, gerr, "a cursor without the group is invalid")
+
+	none, gerr := parseTextCursor("")
+	assert.Nil(t, gerr)
+	assert.True(t, none.after(0, ""))
+}
+
+func TestTextGroupKey(t *testing.T) {
+
+	a := textGroupKey(map[string]string{"host": "a", "app": "x"}, []string{"pid"})
+
+	assert.Equal(t, a, textGroupKey(map[string]string{"app": "x", "host": "a"}, []string{"pid"}))
+	assert.NotEqual(t, a, textGroupKey(map[string]string{"host": "b", "app": "x"}, []string{"pid"}))
+	assert.NotEqual(t, a, textGroupKey(map[string]string{"host": "a", "app": "x"}, nil))
+}
+
+func TestTextCursorAfter(t *testing.T) {
+
+	cursor := &textCursor{date: 100, tsid: "b"}
+
+	assert.False(t, cursor.after(99, "z"))
+	assert.False(t, cursor.after(100, "a"))
+	assert.False(t, cursor.after(100, "b"))
+	assert.True(t, cursor.after(100, "c"))
+	assert.True(t, cursor.after(101, "a"))
+}
@@ -110,6 +110,11 @@ func (plot *Plot) Suggest(w http.ResponseWriter, r *http.Request, ps httprouter.
 		}
 	}
 
+	activeWithin, fail := plot.getActiveWithinParameter(w, queryString)
+	if fail {
+		return
+	}
+
 	switch queryString.Get("type") {
 	case constants.StringsEmpty:
 		gerr = errValidationS("Suggest", "type required")
@@ -117,13 +122,13 @@ func (plot *Plot) Suggest(w http.ResponseWriter, r *http.Request, ps httprouter.
 		return
 	case "metrics":
 		q := fmt.Sprintf("%v*", queryString.Get("q"))
-		resp, _, gerr = plot.FilterMetrics(keyset, q, max)
+		resp, _, gerr = plot.FilterMetrics(keyset, q, max, activeWithin)
 	case "tagk":
 		q := fmt.Sprintf("%v*", queryString.Get("q"))
-		resp, _, gerr = plot.FilterTagKeys(keyset, q, max)
+		resp, _, gerr = plot.FilterTagKeys(keyset, q, max, activeWithin)
 	case "tagv":
 		q := fmt.Sprintf("%v*", queryString.Get("q"))
-		resp, _, gerr = plot.FilterTagValues(keyset, q, max)
+		resp, _, gerr = plot.FilterTagValues(keyset, q, max, activeWithin)
 	default:
 		gerr = errValidationS("Suggest", "unsupported type")
 		rip.Fail(w, gerr)
@@ -136,6 +141,11 @@ func (plot *Plot) Suggest(w http.ResponseWriter, r *http.Request, ps httprouter.
 
 	sort.Strings(resp)
 
+	if queryString.Get("type") == "metrics" && queryString.Get("catalog") == "true" {
+		rip.SuccessJSON(w, http.StatusOK, plot.catalogEntries(keyset, resp))
+		return
+	}
+
 	rip.SuccessJSON(w, http.StatusOK, resp)
 	return
 }
@@ -162,13 +172,14 @@ func (plot *Plot) Query(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
-	resps, numBytes, gerr := plot.getTimeseries(keyset, query)
+	resps, numBytes, cacheStatus, gerr := plot.getCachedTimeseries(keyset, query)
 	if gerr != nil {
 		rip.Fail(w, gerr)
 		return
 	}
 
 	addProcessedBytesHeader(w, numBytes)
+	w.Header().Set(queryCacheHeader, cacheStatus)
 
 	if !query.EstimateSize {
 
@@ -177,6 +188,8 @@ func (plot *Plot) Query(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 			return
 		}
 
+		plot.setUnits(keyset, resps)
+
 		rip.SuccessJSON(w, http.StatusOK, resps)
 		return
 	}
@@ -214,26 +227,29 @@ func (plot *Plot) getTimeseries(
 		}
 	}
 
+	var activeWithin time.Duration
+	if query.ActiveWithin != constants.StringsEmpty {
+		ms, gerr := durationToMs(query.ActiveWithin)
+		if gerr != nil {
+			return resps, 0, gerr
+		}
+		activeWithin = time.Duration(ms) * time.Millisecond
+	}
+
+	if plot.persist.isMillisecondKeyset(keyset) {
+		query.MsResolution = true
+	}
+
 	oldDs := structs.Downsample{}
 
 	sumTotalPoints := 0
 	sumCountPoints := 0
 
-	for _, q := range query.Queries {
+	for qi, q := range query.Queries {
 
 		if q.Downsample != constants.StringsEmpty {
 
-			ds := strings.Split(q.Downsample, "-")
-			var unit string
-			var val int
-
-			if string(ds[0][len(ds[0])-2:]) == "ms" {
-				unit = ds[0][len(ds[0])-2:]
-				val, _ = strconv.Atoi(ds[0][:len(ds[0])-2])
-			} else {
-				unit = ds[0][len(ds[0])-1:]
-				val, _ = strconv.Atoi(ds[0][:len(ds[0])-1])
-			}
+			ds := strings.SplitN(q.Downsample, "-", 3)
 
 			apporx := ds[1]
 
@@ -241,24 +257,9 @@ func (plot *Plot) getTimeseries(
 				apporx = "pnt"
 			}
 
-			switch unit {
-			case "ms":
-				oldDs.Options.Unit = "ms"
-			case "s":
-				oldDs.Options.Unit = "sec"
-			case "m":
-				oldDs.Options.Unit = "min"
-			case "h":
-				oldDs.Options.Unit = "hour"
-			case "d":
-				oldDs.Options.Unit = "day"
-			case "w":
-				oldDs.Options.Unit = "week"
-			case "n":
-				oldDs.Options.Unit = "month"
-			case "y":
-				oldDs.Options.Unit = "year"
-			}
+			unit, val := parseInterval(ds[0])
+
+			oldDs.Options.Unit = unit
 
 			if len(ds) == 3 {
 				oldDs.Options.Fill = ds[2]
@@ -266,25 +267,35 @@ func (plot *Plot) getTimeseries(
 				oldDs.Options.Fill = "none"
 			}
 
-			oldDs.Options.Downsample = apporx
-			oldDs.Options.Value = val
-			oldDs.Enabled = true
+			oldDs.Options.FillValue = 0
 
-		}
+			if strings.HasPrefix(oldDs.Options.Fill, "value(") {
+				fillValue, err := structs.ParseFillValue(oldDs.Options.Fill)
+				if err != nil {
+					return resps, sumBytes, errValidationE(funcGetTimeseries, err)
+				}
+				oldDs.Options.Fill = "value"
+				oldDs.Options.FillValue = fillValue
+			}
 
-		for k, v := range q.Tags {
+			oldDs.Options.MaxGap = 0
 
-			members := strings.Split(v, "|")
-			filter := structs.TSDBfilter{
-				Ftype:   "wildcard",
-				Tagk:    k,
-				Filter:  v,
-				GroupBy: members[0] == "*" || len(members) > 1,
+			if q.MaxGap != constants.StringsEmpty {
+				maxGap, gerr := durationToMs(q.MaxGap)
+				if gerr != nil {
+					return resps, sumBytes, gerr
+				}
+				oldDs.Options.MaxGap = maxGap
 			}
 
-			q.Filters = append(q.Filters, filter)
+			oldDs.Options.Downsample = apporx
+			oldDs.Options.Value = val
+			oldDs.Enabled = true
+
 		}
 
+		q.Filters = append(q.Filters, tagsToFilters(q.Tags)...)
+
 		tagMap := map[string][]string{}
 		ttl := plot.defaultTTL
 		ttlIndex := -1
@@ -309,7 +320,7 @@ func (plot *Plot) getTimeseries(
 			q.Filters = append(q.Filters[:ttlIndex], q.Filters[ttlIndex+1:]...)
 		}
 
-		tsobs, total, gerr := plot.MetaFilterOpenTSDB(keyset, q.Metric, q.Filters, plot.MaxTimeseries)
+		tsobs, total, gerr := plot.MetaFilterOpenTSDB(keyset, q.Metric, q.Filters, plot.MaxTimeseries, activeWithin)
 		if gerr != nil {
 			return resps, sumBytes, gerr
 		}
@@ -324,6 +335,46 @@ func (plot *Plot) getTimeseries(
 			continue
 		}
 
+		var shift int64
+
+		if q.TimeShift != constants.StringsEmpty {
+			shiftedStart, gerr := parser.GetRelativeStart(msToTime(query.Start), q.TimeShift)
+			if gerr != nil {
+				return resps, sumBytes, gerr
+			}
+			shift = query.Start - timeToMs(shiftedStart)
+		}
+
+		counterOptions := q.RateOptions
+
+		rollings := map[string]structs.RollingOperation{}
+
+		for oper, window := range q.RollingFunctions() {
+			ms, gerr := durationToMs(window)
+			if gerr != nil {
+				return resps, sumBytes, gerr
+			}
+			rollings[oper] = structs.RollingOperation{
+				Enabled: true,
+				Window:  ms,
+			}
+		}
+
+		ewma := structs.SmoothingOperation{}
+
+		if q.Ewma != 0 {
+			ewma.Enabled = true
+			ewma.Alpha = q.Ewma
+		}
+
+		holtWinters := structs.SmoothingOperation{}
+
+		if q.HoltWinters != nil {
+			holtWinters.Enabled = true
+			holtWinters.Alpha = q.HoltWinters.Alpha
+			holtWinters.Beta = q.HoltWinters.Beta
+		}
+
 		groups := plot.GetGroups(q.Filters, tsobs)
 
 		for _, group := range groups {
@@ -375,21 +426,29 @@ func (plot *Plot) getTimeseries(
 				}
 			}
 
-			merge := q.Aggregator
-
-			if q.Aggregator == "count" {
-				merge = "pnt"
-			}
+			merge, interpolate := toMergeOperation(q.Aggregator)
 
 			opers := structs.DataOperations{
-				Downsample: oldDs,
-				Merge:      merge,
+				Downsample:  oldDs,
+				Merge:       merge,
+				Interpolate: interpolate,
 				Rate: structs.RateOperation{
 					Enabled: q.Rate,
 					Options: q.RateOptions,
 				},
 				FilterValue: filterV,
 				Order:       q.Order,
+				Increase:    toWindowOperation(q.Increase, counterOptions),
+				Delta:       toWindowOperation(q.Delta, counterOptions),
+				Irate:       toWindowOperation(q.Irate, counterOptions),
+				Deriv:       toWindowOperation(q.Deriv, counterOptions),
+
+				MovingAverage: rollings["movingAverage"],
+				RollingSum:    rollings["rollingSum"],
+				RollingMax:    rollings["rollingMax"],
+				RollingMin:    rollings["rollingMin"],
+				Ewma:          ewma,
+				HoltWinters:   holtWinters,
 			}
 
 			keepEmpty := false
@@ -398,22 +457,8 @@ func (plot *Plot) getTimeseries(
 				keepEmpty = true
 			}
 
-			serie, numBytes, gerr := plot.GetTimeSeries(
-				ttl,
-				ids,
-				query.Start,
-				query.End,
-				opers,
-				query.MsResolution,
-				keepEmpty,
-				query.EstimateSize,
-				keyset,
-			)
+			serie, numBytes, gerr := plot.getShiftedTimeSeries(ttl, ids, query, shift, opers, keepEmpty, keyset, q.Metric)
 			if gerr != nil {
-				if gerr.Error() == plot.persist.maxBytesErr.Error() {
-					return resps, sumBytes, errMaxBytesLimit(funcGetTimeseries, keyset, q.Metric, query.Start, query.End, ttl)
-				}
-
 				return resps, sumBytes, gerr
 			}
 
@@ -421,6 +466,26 @@ func (plot *Plot) getTimeseries(
 			sumCountPoints += serie.Count
 			sumBytes += numBytes
 
+			series := []namedSerie{{data: serie.Data}}
+
+			if q.Compare {
+
+				original, numBytes, gerr := plot.getShiftedTimeSeries(ttl, ids, query, 0, opers, keepEmpty, keyset, q.Metric)
+				if gerr != nil {
+					return resps, sumBytes, gerr
+				}
+
+				sumTotalPoints += original.Total
+				sumCountPoints += original.Count
+				sumBytes += numBytes
+
+				series = []namedSerie{
+					{name: "original", data: original.Data},
+					{name: "shifted", data: serie.Data},
+					{name: "delta", data: delta(original.Data, serie.Data)},
+				}
+			}
+
 			for k, kv := range tagK {
 				if len(kv) > 1 {
 					aggTags = append(aggTags, k)
@@ -429,48 +494,34 @@ func (plot *Plot) getTimeseries(
 
 			sort.Strings(aggTags)
 
-			points := map[string]interface{}{}
+			tagsU := make(map[string]string)
 
-			for _, point := range serie.Data {
-
-				k := point.Date
-
-				if !query.MsResolution {
-					k = point.Date / 1000
-				}
-
-				ksrt := strconv.FormatInt(k, 10)
-				if point.Empty {
-					switch oldDs.Options.Fill {
-					case "null":
-						points[ksrt] = nil
-					case "nan":
-						points[ksrt] = "NaN"
-					default:
-						points[ksrt] = point.Value
+			for k, kv := range tagK {
+				if len(kv) == 1 {
+					for v := range kv {
+						tagsU[k] = v
 					}
-				} else {
-					points[ksrt] = point.Value
 				}
-
 			}
 
-			if len(points) > 0 {
-				tagsU := make(map[string]string)
+			for _, s := range series {
 
-				for k, kv := range tagK {
-					if len(kv) == 1 {
-						for v := range kv {
-							tagsU[k] = v
-						}
-					}
+				points := toDps(s.data, query.MsResolution, oldDs.Options.Fill)
+
+				if len(points) == 0 {
+					continue
 				}
 
 				resp := TSDBresponse{
 					Metric:         q.Metric,
 					Tags:           tagsU,
 					AggregatedTags: aggTags,
+					TimeShift:      q.TimeShift,
+					Series:         s.name,
 					Dps:            points,
+					query:          qi,
+					group:          groupKey(q.Filters, group[0]),
+					tagValues:      groupTagValues(group),
 				}
 
 				if query.ShowTSUIDs {
@@ -487,11 +538,172 @@ func (plot *Plot) getTimeseries(
 
 	plot.statsPlotSummaryPoints(funcGetTimeseries, keyset, sumCountPoints, sumTotalPoints)
 
-	sort.Sort(resps)
+	sort.Stable(resps)
 
 	return resps, sumBytes, gerr
 }
 
+// parseInterval - splits an interval like 5m into the downsample unit and its value
+func parseInterval(interval string) (string, int) {
+
+	var unit string
+	var val int
+
+	if string(interval[len(interval)-2:]) == "ms" {
+		unit = interval[len(interval)-2:]
+		val, _ = strconv.Atoi(interval[:len(interval)-2])
+	} else {
+		unit = interval[len(interval)-1:]
+		val, _ = strconv.Atoi(interval[:len(interval)-1])
+	}
+
+	switch unit {
+	case "s":
+		unit = "sec"
+	case "m":
+		unit = "min"
+	case "h":
+		unit = "hour"
+	case "d":
+		unit = "day"
+	case "w":
+		unit = "week"
+	case "n":
+		unit = "month"
+	case "y":
+		unit = "year"
+	}
+
+	return unit, val
+}
+
+// toWindowOperation - builds the window operation of an increase, delta, irate or deriv function
+func toWindowOperation(window string, options structs.TSDBrateOptions) structs.WindowOperation {
+
+	if window == constants.StringsEmpty {
+		return structs.WindowOperation{}
+	}
+
+	unit, val := parseInterval(window)
+
+	return structs.WindowOperation{
+		Enabled: true,
+		Unit:    unit,
+		Value:   val,
+		Options: options,
+	}
+}
+
+// durationToMs - converts a duration like 30s or 5m to milliseconds
+func durationToMs(duration string) (int64, gobol.Error) {
+
+	now := time.Now()
+
+	start, gerr := parser.GetRelativeStart(now, duration)
+	if gerr != nil {
+		return 0, gerr
+	}
+
+	return timeToMs(now) - timeToMs(start), nil
+}
+
+// toMergeOperation - maps an OpenTSDB aggregator to its merge type and tells if the
+// series must be linearly interpolated before being merged
+func toMergeOperation(aggregator string) (string, bool) {
+
+	switch aggregator {
+	case "count":
+		return "pnt", false
+	case "zimsum":
+		return "sum", false
+	case "mimmin":
+		return "min", false
+	case "mimmax":
+		return "max", false
+	}
+
+	return aggregator, true
+}
+
+type namedSerie struct {
+	name string
+	data Pnts
+}
+
+// getShiftedTimeSeries - reads the query window moved back by shift milliseconds
+// and moves the resulting points forward again, aligning them with the original window
+func (plot *Plot) getShiftedTimeSeries(
+	ttl int,
+	ids []string,
+	query structs.TSDBqueryPayload,
+	shift int64,
+	opers structs.DataOperations,
+	keepEmpty bool,
+	keyset, metric string,
+) (TS, uint32, gobol.Error) {
+
+	start := query.Start - shift
+	end := query.End - shift
+
+	serie, numBytes, gerr := plot.GetTimeSeries(
+		ttl,
+		ids,
+		start,
+		end,
+		opers,
+		query.MsResolution,
+		keepEmpty,
+		query.EstimateSize,
+		keyset,
+	)
+	if gerr != nil {
+		if gerr.Error() == plot.persist.maxBytesErr.Error() {
+			return TS{}, numBytes, errMaxBytesLimit(funcGetTimeseries, keyset, metric, start, end, ttl)
+		}
+
+		return TS{}, numBytes, gerr
+	}
+
+	if shift != 0 {
+		for i := range serie.Data {
+			serie.Data[i].Date += shift
+		}
+	}
+
+	return serie, numBytes, nil
+}
+
+// toDps - encodes the points as an OpenTSDB dps map, honoring the fill policy for empty points,
+// the ones the policy could not fill (no previous value or a gap bigger than maxGap) are left out
+func toDps(data Pnts, msResolution bool, fill string) map[string]interface{} {
+
+	points := map[string]interface{}{}
+
+	for _, point := range data {
+
+		k := point.Date
+
+		if !msResolution {
+			k = point.Date / 1000
+		}
+
+		ksrt := strconv.FormatInt(k, 10)
+		if point.Empty {
+			switch fill {
+			case "null":
+				points[ksrt] = nil
+			case "nan":
+				points[ksrt] = "NaN"
+			}
+		} else {
+			points[ksrt] = point.Value
+		}
+
+	}
+
+	return points
+}
+
 func parseQuery(query string) (string, []Tag, gobol.Error) {
 
 	metric, sub := getMetric(query)
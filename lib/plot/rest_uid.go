@@ -0,0 +1,307 @@
+package plot
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol"
+	"github.com/uol/gobol/rip"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/structs"
+)
+
+// The OpenTSDB search lookup and uid metadata endpoints. The names have no uids in mycenae, so the uid
+// of a metric, tag key or tag value is its name in hexadecimal, and the serie uid is its tsid
+
+const (
+	funcSearchLookup string = "SearchLookup"
+	funcTSMeta       string = "TSMeta"
+	funcUIDMeta      string = "UIDMeta"
+
+	uidTypeMetric string = "METRIC"
+	uidTypeTagk   string = "TAGK"
+	uidTypeTagv   string = "TAGV"
+)
+
+// nameUID - returns the uid of a metric, tag key or tag value name
+func nameUID(name string) string {
+
+	return strings.ToUpper(hex.EncodeToString([]byte(name)))
+}
+
+// newUIDMeta - creates the uid metadata of a name
+func newUIDMeta(uidType, name string) TSDBuidMeta {
+
+	return TSDBuidMeta{
+		UID:    nameUID(name),
+		Type:   uidType,
+		Name:   name,
+		Custom: map[string]string{},
+	}
+}
+
+// toLookupQuery - converts the lookup to a metadata query, each tag key and value pair must be in the same tag
+func toLookupQuery(tsType, metric string, tags []structs.TSDBlookupTag) *metadata.Query {
+
+	q := &metadata.Query{
+		Metric:   metric,
+		MetaType: tsType,
+		Tags:     make([]metadata.QueryTag, 0, len(tags)),
+	}
+
+	for _, tag := range tags {
+
+		qt := metadata.QueryTag{Key: tag.Key}
+
+		if tag.Value != constants.StringsEmpty && tag.Value != "*" {
+			qt.Values = []string{tag.Value}
+			qt.Pair = true
+		}
+
+		q.Tags = append(q.Tags, qt)
+	}
+
+	return q
+}
+
+// SearchLookup - finds the series by metric and tag pairs, the OpenTSDB search lookup with paging
+func (plot *Plot) SearchLookup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	start := time.Now()
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound(funcSearchLookup))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	lookup := structs.TSDBlookupPayload{}
+
+	gerr = rip.FromJSON(r, &lookup)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	limit := lookup.Limit
+	if limit == 0 {
+		limit = structs.TSDBlookupDefaultLimit
+	}
+
+	if limit > plot.MaxTimeseries {
+		rip.Fail(w, errValidationS(funcSearchLookup, fmt.Sprintf("the limit cannot be bigger than %d", plot.MaxTimeseries)))
+		return
+	}
+
+	// all the series metadata is indexed, so the lookup has the same results with or without useMeta
+	metadatas, total, gerr := plot.persist.metaStorage.FilterMetadata(keyset, toLookupQuery("meta", lookup.Metric, lookup.Tags), lookup.StartIndex, limit)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	results := make([]TSDBobj, len(metadatas))
+
+	for i := range metadatas {
+		results[i] = TSDBobj{
+			Tsuid:  metadatas[i].ID,
+			Metric: metadatas[i].Metric,
+			Tags:   plot.extractTagMap(&metadatas[i]),
+		}
+	}
+
+	tags := lookup.Tags
+	if tags == nil {
+		tags = []structs.TSDBlookupTag{}
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, TSDBsearchLookup{
+		Type:         "LOOKUP",
+		Metric:       lookup.Metric,
+		Tags:         tags,
+		Limit:        limit,
+		Time:         int64(time.Since(start) / time.Millisecond),
+		Results:      results,
+		StartIndex:   lookup.StartIndex,
+		TotalResults: total,
+	})
+}
+
+// toTSMeta - converts the serie metadata to the OpenTSDB format
+func toTSMeta(m *metadata.Metadata) TSDBtsMeta {
+
+	tsMeta := TSDBtsMeta{
+		Tsuid:        m.ID,
+		Metric:       newUIDMeta(uidTypeMetric, m.Metric),
+		Tags:         make([]TSDBuidMeta, 0, 2*len(m.TagKey)),
+		Created:      m.FirstSeen,
+		Custom:       map[string]string{},
+		LastReceived: m.LastSeen,
+	}
+
+	for i := 0; i < len(m.TagKey); i++ {
+
+		tsMeta.Tags = append(tsMeta.Tags, newUIDMeta(uidTypeTagk, m.TagKey[i]), newUIDMeta(uidTypeTagv, m.TagValue[i]))
+
+		if m.TagKey[i] == constants.StringsTTL {
+			tsMeta.Retention, _ = strconv.Atoi(m.TagValue[i])
+		}
+	}
+
+	return tsMeta
+}
+
+// TSMeta - returns the metadata of the serie by its tsuid, or of all series matching the "m" query
+func (plot *Plot) TSMeta(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound(funcTSMeta))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	params := r.URL.Query()
+
+	if tsuid := params.Get("tsuid"); tsuid != constants.StringsEmpty {
+
+		metadatas, _, gerr := plot.persist.metaStorage.FilterMetadata(keyset, &metadata.Query{ID: tsuid}, 0, 1)
+		if gerr != nil {
+			rip.Fail(w, gerr)
+			return
+		}
+
+		if len(metadatas) == 0 {
+			rip.Fail(w, errNotFound(funcTSMeta))
+			return
+		}
+
+		rip.SuccessJSON(w, http.StatusOK, toTSMeta(&metadatas[0]))
+		return
+	}
+
+	m := params.Get("m")
+	if m == constants.StringsEmpty {
+		rip.Fail(w, errValidationS(funcTSMeta, `missing query parameter "tsuid" or "m"`))
+		return
+	}
+
+	metric, tags, gerr := parseQuery(m)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	lookupTags := make([]structs.TSDBlookupTag, len(tags))
+	for i, tag := range tags {
+		lookupTags[i] = structs.TSDBlookupTag{Key: tag.Key, Value: tag.Value}
+	}
+
+	metadatas, total, gerr := plot.persist.metaStorage.FilterMetadata(keyset, toLookupQuery("meta", metric, lookupTags), 0, plot.MaxTimeseries)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	logIfExceeded := fmt.Sprintf("TS THRESHOLD/MAX EXCEEDED: %+v", m)
+	gerr = plot.checkTotalTSLimits(logIfExceeded, keyset, metric, total)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	tsMetas := make([]TSDBtsMeta, len(metadatas))
+	for i := range metadatas {
+		tsMetas[i] = toTSMeta(&metadatas[i])
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, tsMetas)
+}
+
+// UIDMeta - returns the metadata of a metric, tag key or tag value by its uid and type
+func (plot *Plot) UIDMeta(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	keyset := ps.ByName(constants.StringsKeyset)
+	if keyset == constants.StringsEmpty {
+		rip.Fail(w, errNotFound(funcUIDMeta))
+		return
+	}
+
+	gerr := plot.validateKeyset(keyset)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	params := r.URL.Query()
+
+	uid := params.Get("uid")
+	if uid == constants.StringsEmpty {
+		rip.Fail(w, errMandatoryParam(funcUIDMeta, "uid"))
+		return
+	}
+
+	decoded, err := hex.DecodeString(uid)
+	if err != nil || len(decoded) == 0 {
+		rip.Fail(w, errValidationS(funcUIDMeta, "invalid uid: "+uid))
+		return
+	}
+
+	name := string(decoded)
+	uidType := strings.ToUpper(params.Get("type"))
+
+	var query *metadata.Query
+
+	switch uidType {
+	case uidTypeMetric:
+		query = &metadata.Query{Metric: name}
+	case uidTypeTagk:
+		query = &metadata.Query{Tags: []metadata.QueryTag{{Key: name}}}
+	case uidTypeTagv:
+		query = &metadata.Query{Tags: []metadata.QueryTag{{Values: []string{name}}}}
+	default:
+		rip.Fail(w, errValidationS(funcUIDMeta, `query parameter "type" must be metric, tagk or tagv`))
+		return
+	}
+
+	gerr = plot.checkUIDName(keyset, query)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, newUIDMeta(uidType, name))
+}
+
+// checkUIDName - checks if any serie of the keyset has the name
+func (plot *Plot) checkUIDName(keyset string, query *metadata.Query) gobol.Error {
+
+	_, total, gerr := plot.persist.metaStorage.FilterMetadata(keyset, query, 0, 1)
+	if gerr != nil {
+		return gerr
+	}
+
+	if total == 0 {
+		return errNotFound(funcUIDMeta)
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ const (
 	metricPlotCountPoints string = "plot.count.points"
 	metricPlotTotalPoints string = "plot.total.points"
 	metricActiveMetric    string = "mycenae.active.metric"
+	metricQueryCache      string = "mycenae.query.cache"
 )
 
 func (plot *Plot) statsQueryTSThreshold(function, keyset string, total int) {
@@ -58,3 +59,13 @@ func (plot *Plot) statsActiveMetric(function, keyset, metric string) {
 		constants.StringsMetric, metric,
 	)
 }
+
+func (plot *Plot) statsQueryCache(keyset, status string) {
+
+	plot.timelineManager.FlattenCountIncN(
+		funcQueryCache,
+		metricQueryCache,
+		constants.StringsKeyset, keyset,
+		"status", status,
+	)
+}
@@ -1,6 +1,7 @@
 package plot
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"regexp"
@@ -9,7 +10,10 @@ import (
 	"github.com/buger/jsonparser"
 
 	"github.com/uol/gobol"
+	"github.com/uol/mycenae/lib/catalog"
 	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/sketch"
+	"github.com/uol/mycenae/lib/structs"
 )
 
 var (
@@ -187,9 +191,10 @@ type Tag struct {
 }
 
 type Response struct {
-	TotalRecords int         `json:"totalRecords,omitempty"`
-	Payload      interface{} `json:"payload,omitempty"`
-	Message      interface{} `json:"message,omitempty"`
+	TotalRecords int                      `json:"totalRecords,omitempty"`
+	Payload      interface{}              `json:"payload,omitempty"`
+	Message      interface{}              `json:"message,omitempty"`
+	Catalog      map[string]catalog.Entry `json:"catalog,omitempty"`
 }
 
 type TS struct {
@@ -210,6 +215,21 @@ type Pnt struct {
 	Empty bool
 }
 
+// RollupPnt - a rollup bucket, the min, max, sum and count of the raw points inside it
+type RollupPnt struct {
+	Date  int64
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count int64
+}
+
+// HistogramPnt - a histogram point, the sketch of the values of its timestamp
+type HistogramPnt struct {
+	Date   int64
+	Sketch *sketch.Sketch
+}
+
 type TextPnt struct {
 	Date  int64  `json:"x"`
 	Value string `json:"title"`
@@ -291,6 +311,47 @@ type TSDBlookup struct {
 	TotalResults int       `json:"totalResults"`
 }
 
+// TSDBsearchLookup - the OpenTSDB search lookup response, the tags are the ones of the request
+type TSDBsearchLookup struct {
+	Type         string                  `json:"type"`
+	Metric       string                  `json:"metric"`
+	Tags         []structs.TSDBlookupTag `json:"tags"`
+	Limit        int                     `json:"limit"`
+	Time         int64                   `json:"time"`
+	Results      []TSDBobj               `json:"results"`
+	StartIndex   int                     `json:"startIndex"`
+	TotalResults int                     `json:"totalResults"`
+}
+
+// TSDBuidMeta - the OpenTSDB metadata of a metric, tag key or tag value name
+type TSDBuidMeta struct {
+	UID         string            `json:"uid"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	Created     int64             `json:"created"`
+	Custom      map[string]string `json:"custom"`
+	DisplayName string            `json:"displayName"`
+}
+
+// TSDBtsMeta - the OpenTSDB metadata of a serie, the tags are the key and value pairs in sequence
+type TSDBtsMeta struct {
+	Tsuid           string            `json:"tsuid"`
+	Metric          TSDBuidMeta       `json:"metric"`
+	Tags            []TSDBuidMeta     `json:"tags"`
+	Description     string            `json:"description"`
+	Notes           string            `json:"notes"`
+	Created         int64             `json:"created"`
+	Custom          map[string]string `json:"custom"`
+	Units           string            `json:"units"`
+	DataType        string            `json:"dataType"`
+	Retention       int               `json:"retention"`
+	DisplayName     string            `json:"displayName"`
+	LastReceived    int64             `json:"lastReceived"`
+	TotalDatapoints int64             `json:"totalDatapoints"`
+}
+
 type TSDBresponses []TSDBresponse
 
 func (r TSDBresponses) Len() int {
@@ -365,7 +426,128 @@ type TSDBresponse struct {
 	Tags           map[string]string      `json:"tags"`
 	AggregatedTags []string               `json:"aggregateTags"`
 	Tsuids         []string               `json:"tsuids,omitempty"`
+	TimeShift      string                 `json:"timeShift,omitempty"`
+	Series         string                 `json:"series,omitempty"`
+	Unit           string                 `json:"unit,omitempty"`
 	Dps            map[string]interface{} `json:"dps"`
+
+	// the query index, the group by values and all tag values of the group, used to merge the cached chunks
+	query     int
+	group     string
+	tagValues map[string]map[string]bool
+}
+
+// HistogramStats - the statistics of a merged sketch and its quantiles
+type HistogramStats struct {
+	Count     uint64             `json:"count"`
+	Sum       float64            `json:"sum"`
+	Min       float64            `json:"min"`
+	Max       float64            `json:"max"`
+	Avg       float64            `json:"avg"`
+	Quantiles map[string]float64 `json:"quantiles"`
+}
+
+// TSDBhistogramResponse - a group of histogram series merged by date
+type TSDBhistogramResponse struct {
+	Metric         string                    `json:"metric"`
+	Tags           map[string]string         `json:"tags"`
+	AggregatedTags []string                  `json:"aggregateTags"`
+	Dps            map[string]HistogramStats `json:"dps"`
+}
+
+type TSDBhistogramResponses []TSDBhistogramResponse
+
+func (r TSDBhistogramResponses) Len() int {
+	return len(r)
+}
+
+func (r TSDBhistogramResponses) Less(i, j int) bool {
+	if r[i].Metric != r[j].Metric {
+		return r[i].Metric < r[j].Metric
+	}
+	return fmt.Sprint(r[i].Tags) < fmt.Sprint(r[j].Tags)
+}
+
+func (r TSDBhistogramResponses) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// TextQueryPoint - a text of a text query page
+type TextQueryPoint struct {
+	Timestamp int64  `json:"timestamp"`
+	Text      string `json:"text"`
+	Tsuid     string `json:"tsuid"`
+}
+
+// TextQueryResponse - a group of text series, a page of its texts or their counts per interval
+type TextQueryResponse struct {
+	Metric         string            `json:"metric"`
+	Tags           map[string]string `json:"tags"`
+	AggregatedTags []string          `json:"aggregateTags"`
+	Texts          []TextQueryPoint  `json:"texts,omitempty"`
+	NextCursor     string            `json:"nextCursor,omitempty"`
+	Counts         map[string]int    `json:"counts,omitempty"`
+}
+
+type TextQueryResponses []TextQueryResponse
+
+func (r TextQueryResponses) Len() int {
+	return len(r)
+}
+
+func (r TextQueryResponses) Less(i, j int) bool {
+	if r[i].Metric != r[j].Metric {
+		return r[i].Metric < r[j].Metric
+	}
+	return fmt.Sprint(r[i].Tags) < fmt.Sprint(r[j].Tags)
+}
+
+func (r TextQueryResponses) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// OpenTSDBannotation - an annotation in the OpenTSDB format, the start time is in seconds
+type OpenTSDBannotation struct {
+	Tsuid       string            `json:"tsuid"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	Custom      map[string]string `json:"custom"`
+	StartTime   int64             `json:"startTime"`
+	EndTime     int64             `json:"endTime"`
+}
+
+// GrafanaAnnotationResponse - an annotation in the grafana simple json format, the time is in milliseconds
+type GrafanaAnnotationResponse struct {
+	Annotation structs.GrafanaAnnotation `json:"annotation"`
+	Time       int64                     `json:"time"`
+	Title      string                    `json:"title"`
+	Text       string                    `json:"text"`
+	Tags       []string                  `json:"tags"`
+}
+
+// GrafanaTag - a tag key or tag value of the grafana json datasource
+type GrafanaTag struct {
+	Type string `json:"type,omitempty"`
+	Text string `json:"text"`
+}
+
+// GrafanaTimeserie - a grafana timeserie target result, the datapoints are [value, time in milliseconds]
+type GrafanaTimeserie struct {
+	Target     string          `json:"target"`
+	Datapoints [][]interface{} `json:"datapoints"`
+}
+
+// GrafanaColumn - a grafana table column
+type GrafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// GrafanaTable - a grafana table target result
+type GrafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []GrafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
 }
 
 type ExpParse struct {
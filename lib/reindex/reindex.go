@@ -0,0 +1,531 @@
+package reindex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/gobol"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/persistence"
+	"github.com/uol/mycenae/lib/rollup"
+)
+
+// Repairs the metadata of the series with points: the recent tsids of each TTL keyspace are compared
+// to the documents of all keysets. The tsids are hashes of the metric and tags, so a missing document
+// is rebuilt from the series table of the keyspace, the series indexed before it existed only have
+// their cached ids flushed and the collector indexes them again with their next point. The documents
+// without points in any keyspace are counted too
+
+const (
+	statusRunning string = "running"
+	statusDone    string = "done"
+
+	metaTypeNumber    string = "meta"
+	metaTypeText      string = "metatext"
+	metaTypeHistogram string = "metahistogram"
+
+	defaultSince string = "24h"
+	batchSize    int    = 100
+	pageSize     int    = 1000
+	maxErrors    int    = 100
+
+	formatScanIDs     string = `SELECT DISTINCT id FROM %s.%s`
+	formatRecentPoint string = `SELECT id FROM %s.%s WHERE id = ? AND %s >= ? LIMIT 1`
+	formatAnyPoint    string = `SELECT id FROM %s.%s WHERE id = ? LIMIT 1`
+	formatGetSeries   string = `SELECT keyset, metric, tag_keys, tag_values FROM %s.ts_series WHERE id = ? AND type = ?`
+	formatListTables  string = `SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?`
+	tableSeries       string = "ts_series"
+)
+
+// Request - the reindex job parameters
+type Request struct {
+	// Since - only the tsids with points within the duration are checked
+	Since string `json:"since"`
+	// Keyspaces - the TTL keyspaces scanned, all when empty
+	Keyspaces []string `json:"keyspaces"`
+	// Keysets - the keysets searched for the documents, all when empty
+	Keysets []string `json:"keysets"`
+	// Orphans - also scans the documents of the keysets, counting the ones without points
+	Orphans bool `json:"orphans"`
+}
+
+// Validate - validates the reindex request
+func (r Request) Validate() gobol.Error {
+
+	if r.Since == constants.StringsEmpty {
+		return nil
+	}
+
+	since, err := time.ParseDuration(r.Since)
+	if err != nil || since <= 0 {
+		return errBadRequest("Validate", "since must be a positive duration")
+	}
+
+	return nil
+}
+
+// Report - the progress and the result of the reindex job
+type Report struct {
+	Status           string     `json:"status"`
+	Request          Request    `json:"request"`
+	Started          time.Time  `json:"started"`
+	Finished         *time.Time `json:"finished,omitempty"`
+	ScannedTSIDs     int        `json:"scannedTSIDs"`
+	MissingDocuments int        `json:"missingDocuments"`
+	RebuiltDocuments int        `json:"rebuiltDocuments"`
+	FlushedIDs       int        `json:"flushedIDs"`
+	ScannedDocuments int        `json:"scannedDocuments"`
+	OrphanDocuments  int        `json:"orphanDocuments"`
+	Errors           []string   `json:"errors,omitempty"`
+}
+
+type table struct {
+	name       string
+	dateColumn string
+	tsType     string
+}
+
+// Job - the metadata reindex job, only one runs at a time
+type Job struct {
+	session         *gocql.Session
+	metaStorage     *metadata.Storage
+	keyspaceLayouts *persistence.KeyspaceLayouts
+	rollupTables    []string
+	blockWindow     time.Duration
+	logger          *logh.ContextualLogger
+	mutex           sync.Mutex
+	report          *Report
+}
+
+// New - creates the reindex job
+func New(session *gocql.Session, metaStorage *metadata.Storage, keyspaceLayouts *persistence.KeyspaceLayouts, rollups []rollup.Resolution, blockWindow time.Duration) *Job {
+
+	rollupTables := make([]string, len(rollups))
+	for i, r := range rollups {
+		rollupTables[i] = r.Table
+	}
+
+	return &Job{
+		session:         session,
+		metaStorage:     metaStorage,
+		keyspaceLayouts: keyspaceLayouts,
+		rollupTables:    rollupTables,
+		blockWindow:     blockWindow,
+		logger:          logh.CreateContextualLogger(constants.StringsPKG, "reindex"),
+	}
+}
+
+// Start - starts the job in background, fails when one is already running
+func (job *Job) Start(request Request) (Report, gobol.Error) {
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	if job.report != nil && job.report.Status == statusRunning {
+		return Report{}, errConflict("Start", "a reindex job is already running")
+	}
+
+	if request.Since == constants.StringsEmpty {
+		request.Since = defaultSince
+	}
+
+	if len(request.Keyspaces) == 0 {
+		for keyspace := range job.keyspaceLayouts.Map() {
+			request.Keyspaces = append(request.Keyspaces, keyspace)
+		}
+	}
+
+	layouts := job.keyspaceLayouts.Map()
+
+	for _, keyspace := range request.Keyspaces {
+		if _, ok := layouts[keyspace]; !ok {
+			return Report{}, errBadRequest("Start", fmt.Sprintf("keyspace not found: %s", keyspace))
+		}
+	}
+
+	if len(request.Keysets) == 0 {
+		request.Keysets = job.metaStorage.ListKeysets()
+	}
+
+	for _, keyset := range request.Keysets {
+		if !job.metaStorage.CheckKeyset(keyset) {
+			return Report{}, errBadRequest("Start", fmt.Sprintf("keyset not found: %s", keyset))
+		}
+	}
+
+	job.report = &Report{
+		Status:  statusRunning,
+		Request: request,
+		Started: time.Now(),
+	}
+
+	go job.run(request)
+
+	return job.snapshot(), nil
+}
+
+// Report - returns the report of the running or the last job
+func (job *Job) Report() (Report, bool) {
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	if job.report == nil {
+		return Report{}, false
+	}
+
+	return job.snapshot(), true
+}
+
+// snapshot - copies the report, the mutex must be locked
+func (job *Job) snapshot() Report {
+
+	report := *job.report
+	report.Errors = append([]string{}, job.report.Errors...)
+
+	return report
+}
+
+func (job *Job) update(f func(report *Report)) {
+
+	job.mutex.Lock()
+	f(job.report)
+	job.mutex.Unlock()
+}
+
+func (job *Job) addError(function string, err error) {
+
+	if logh.ErrorEnabled {
+		job.logger.Error().Str(constants.StringsFunc, function).Err(err).Send()
+	}
+
+	job.update(func(report *Report) {
+		if len(report.Errors) < maxErrors {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	})
+}
+
+const funcListTables string = "listTables"
+
+// listTables - returns the tables of the keyspace, the older keyspaces do not have all of them
+func (job *Job) listTables(keyspace string) (map[string]bool, error) {
+
+	iter := job.session.Query(formatListTables, keyspace).Iter()
+
+	existing := map[string]bool{}
+
+	var name string
+	for iter.Scan(&name) {
+		existing[name] = true
+	}
+
+	return existing, iter.Close()
+}
+
+// tables - returns the point tables of the keyspace found in the existing ones
+func (job *Job) tables(keyspace string, existing map[string]bool) []table {
+
+	number := table{name: "ts_number_stamp", dateColumn: "date", tsType: metaTypeNumber}
+	if job.keyspaceLayouts.Get(keyspace) == constants.KeyspaceLayoutBlock {
+		number = table{name: "ts_number_block", dateColumn: "window", tsType: metaTypeNumber}
+	}
+
+	tables := []table{}
+
+	for _, t := range []table{
+		number,
+		{name: "ts_text_stamp", dateColumn: "date", tsType: metaTypeText},
+		{name: "ts_histogram_stamp", dateColumn: "date", tsType: metaTypeHistogram},
+	} {
+		if existing[t.name] {
+			tables = append(tables, t)
+		}
+	}
+
+	return tables
+}
+
+func (job *Job) run(request Request) {
+
+	if logh.InfoEnabled {
+		job.logger.Info().Str(constants.StringsFunc, "run").Msgf("reindex job started: %+v", request)
+	}
+
+	since, _ := time.ParseDuration(request.Since)
+
+	existing := map[string]map[string]bool{}
+	listed := true
+
+	for _, keyspace := range request.Keyspaces {
+
+		tables, err := job.listTables(keyspace)
+		if err != nil {
+			job.addError(funcListTables, fmt.Errorf("error listing the tables of %s: %s", keyspace, err))
+			listed = false
+			continue
+		}
+
+		existing[keyspace] = tables
+	}
+
+	checked := map[string]map[string]bool{}
+
+	for keyspace, tables := range existing {
+		for _, t := range job.tables(keyspace, tables) {
+
+			if checked[t.tsType] == nil {
+				checked[t.tsType] = map[string]bool{}
+			}
+
+			job.scanTable(keyspace, t, time.Now().Add(-since), request.Keysets, checked[t.tsType], tables[tableSeries])
+		}
+	}
+
+	// a document can only be an orphan when the points of all keyspaces are checked
+	if request.Orphans && !listed {
+		job.addError(funcScanDocuments, fmt.Errorf("the orphan documents were not counted, the tables of some keyspaces are unknown"))
+	}
+
+	if request.Orphans && listed {
+		for _, keyset := range request.Keysets {
+			for _, tsType := range []string{metaTypeNumber, metaTypeText, metaTypeHistogram} {
+				job.scanDocuments(keyset, tsType, existing)
+			}
+		}
+	}
+
+	job.update(func(report *Report) {
+		now := time.Now()
+		report.Status = statusDone
+		report.Finished = &now
+	})
+
+	if logh.InfoEnabled {
+		report, _ := job.Report()
+		job.logger.Info().Str(constants.StringsFunc, "run").Msgf("reindex job finished: %d tsids scanned, %d missing documents, %d rebuilt documents, %d flushed ids, %d documents scanned, %d orphan documents",
+			report.ScannedTSIDs, report.MissingDocuments, report.RebuiltDocuments, report.FlushedIDs, report.ScannedDocuments, report.OrphanDocuments)
+	}
+}
+
+const funcScanTable string = "scanTable"
+
+// scanTable - checks the documents of the tsids with points since the date, a tsid found in many keyspaces is checked once
+func (job *Job) scanTable(keyspace string, t table, since time.Time, keysets []string, checked map[string]bool, hasSeries bool) {
+
+	// a block starts before its points, so the last block of a serie can begin one window before the date
+	if t.dateColumn == "window" {
+		since = since.Add(-job.blockWindow)
+	}
+
+	recentQuery := fmt.Sprintf(formatRecentPoint, keyspace, t.name, t.dateColumn)
+
+	iter := job.session.Query(fmt.Sprintf(formatScanIDs, keyspace, t.name)).PageSize(pageSize).Iter()
+
+	batch := make([]string, 0, batchSize)
+	var id, found string
+
+	for iter.Scan(&id) {
+
+		if checked[id] {
+			continue
+		}
+
+		err := job.session.Query(recentQuery, id, since).Scan(&found)
+		if err == gocql.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			job.addError(funcScanTable, err)
+			continue
+		}
+
+		checked[id] = true
+		batch = append(batch, id)
+
+		if len(batch) == batchSize {
+			job.checkDocuments(keyspace, t.tsType, batch, keysets, hasSeries)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		job.checkDocuments(keyspace, t.tsType, batch, keysets, hasSeries)
+	}
+
+	if err := iter.Close(); err != nil {
+		job.addError(funcScanTable, fmt.Errorf("error scanning %s.%s: %s", keyspace, t.name, err))
+	}
+}
+
+const funcCheckDocuments string = "checkDocuments"
+
+// checkDocuments - searches the documents of the tsids in all keysets, the missing ones are rebuilt from the series
+// table of the keyspace, the cached ids of the ones not found there are flushed
+func (job *Job) checkDocuments(keyspace, tsType string, tsids []string, keysets []string, hasSeries bool) {
+
+	missing := append([]string{}, tsids...)
+
+	for _, keyset := range keysets {
+
+		found, gerr := job.metaStorage.FindDocumentIDs(keyset, tsType, missing)
+		if gerr != nil {
+			job.addError(funcCheckDocuments, gerr)
+			return
+		}
+
+		remaining := missing[:0]
+		for _, tsid := range missing {
+			if !found[tsid] {
+				remaining = append(remaining, tsid)
+			}
+		}
+
+		missing = remaining
+		if len(missing) == 0 {
+			break
+		}
+	}
+
+	requested := make(map[string]bool, len(keysets))
+	for _, keyset := range keysets {
+		requested[keyset] = true
+	}
+
+	rebuilt, flushed := 0, 0
+
+	for _, tsid := range missing {
+
+		if hasSeries {
+			keyset, m, found := job.getSeries(keyspace, tsType, tsid)
+			if found && requested[keyset] {
+				if gerr := job.metaStorage.IndexDocument(keyset, m); gerr != nil {
+					job.addError(funcCheckDocuments, gerr)
+					continue
+				}
+				rebuilt++
+				continue
+			}
+		}
+
+		// the keyset of a missing document is unknown, so its id is flushed from all of them
+		for _, keyset := range keysets {
+			if gerr := job.metaStorage.FlushCachedID(keyset, tsType, tsid); gerr != nil {
+				job.addError(funcCheckDocuments, gerr)
+				continue
+			}
+			flushed++
+		}
+	}
+
+	job.update(func(report *Report) {
+		report.ScannedTSIDs += len(tsids)
+		report.MissingDocuments += len(missing)
+		report.RebuiltDocuments += rebuilt
+		report.FlushedIDs += flushed
+	})
+}
+
+const funcGetSeries string = "getSeries"
+
+// getSeries - reads the keyset, the metric and the tags of the serie stored by the collector
+func (job *Job) getSeries(keyspace, tsType, tsid string) (string, *metadata.Metadata, bool) {
+
+	var keyset string
+
+	m := &metadata.Metadata{ID: tsid, MetaType: tsType}
+
+	err := job.session.Query(fmt.Sprintf(formatGetSeries, keyspace), tsid, tsType).Scan(&keyset, &m.Metric, &m.TagKey, &m.TagValue)
+	if err == gocql.ErrNotFound {
+		return constants.StringsEmpty, nil, false
+	}
+
+	if err != nil {
+		job.addError(funcGetSeries, err)
+		return constants.StringsEmpty, nil, false
+	}
+
+	return keyset, m, true
+}
+
+const funcScanDocuments string = "scanDocuments"
+
+// scanDocuments - counts the documents of the keyset without points in any keyspace
+func (job *Job) scanDocuments(keyset, tsType string, keyspaces map[string]map[string]bool) {
+
+	cursor := constants.StringsEmpty
+
+	for {
+		ids, next, gerr := job.metaStorage.ScanDocumentIDs(keyset, tsType, cursor, pageSize)
+		if gerr != nil {
+			job.addError(funcScanDocuments, gerr)
+			return
+		}
+
+		orphans := 0
+		for _, id := range ids {
+			if !job.hasPoints(tsType, id, keyspaces) {
+				orphans++
+			}
+		}
+
+		job.update(func(report *Report) {
+			report.ScannedDocuments += len(ids)
+			report.OrphanDocuments += orphans
+		})
+
+		if next == constants.StringsEmpty {
+			return
+		}
+
+		cursor = next
+	}
+}
+
+const funcHasPoints string = "hasPoints"
+
+// hasPoints - checks if the serie has points in any keyspace, including the rollups of the numbers
+func (job *Job) hasPoints(tsType, tsid string, keyspaces map[string]map[string]bool) bool {
+
+	var found string
+
+	for keyspace, existing := range keyspaces {
+
+		tables := []string{}
+		for _, t := range job.tables(keyspace, existing) {
+			if t.tsType == tsType {
+				tables = append(tables, t.name)
+			}
+		}
+
+		if tsType == metaTypeNumber {
+			for _, name := range job.rollupTables {
+				if existing[name] {
+					tables = append(tables, name)
+				}
+			}
+		}
+
+		for _, name := range tables {
+
+			err := job.session.Query(fmt.Sprintf(formatAnyPoint, keyspace, name), tsid).Scan(&found)
+			if err == nil {
+				return true
+			}
+
+			if err != gocql.ErrNotFound {
+				// an unknown state is never reported as an orphan
+				job.addError(funcHasPoints, err)
+				return true
+			}
+		}
+	}
+
+	return false
+}
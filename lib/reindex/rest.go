@@ -0,0 +1,40 @@
+package reindex
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uol/gobol/rip"
+)
+
+// StartJob - starts the reindex job, the report is returned while it runs in background
+func (job *Job) StartJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	request := Request{}
+
+	gerr := rip.FromJSON(r, &request)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	report, gerr := job.Start(request)
+	if gerr != nil {
+		rip.Fail(w, gerr)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusAccepted, report)
+}
+
+// JobStatus - returns the report of the running or the last reindex job
+func (job *Job) JobStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	report, ok := job.Report()
+	if !ok {
+		rip.Success(w, http.StatusNoContent, nil)
+		return
+	}
+
+	rip.SuccessJSON(w, http.StatusOK, report)
+}
@@ -15,12 +15,15 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/uol/gobol/rip"
 
+	"github.com/uol/mycenae/lib/catalog"
 	"github.com/uol/mycenae/lib/collector"
 	"github.com/uol/mycenae/lib/config"
 	"github.com/uol/mycenae/lib/keyset"
 	"github.com/uol/mycenae/lib/keyspace"
 	"github.com/uol/mycenae/lib/memcached"
+	"github.com/uol/mycenae/lib/migration"
 	"github.com/uol/mycenae/lib/plot"
+	"github.com/uol/mycenae/lib/reindex"
 	"github.com/uol/mycenae/lib/structs"
 	tlmanager "github.com/uol/timelinemanager"
 )
@@ -35,6 +38,9 @@ func New(
 	set structs.SettingsHTTP,
 	ks *keyset.Manager,
 	telnetManager *telnetmgr.Manager,
+	metricCatalog *catalog.Catalog,
+	reindexJob *reindex.Job,
+	metadataMigration *migration.Migration,
 ) *REST {
 
 	return &REST{
@@ -48,6 +54,9 @@ func New(
 		settings:        set,
 		keyset:          ks,
 		telnetManager:   telnetManager,
+		catalog:         metricCatalog,
+		reindex:         reindexJob,
+		migration:       metadataMigration,
 	}
 }
 
@@ -65,6 +74,9 @@ type REST struct {
 	server          *http.Server
 	keyset          *keyset.Manager
 	telnetManager   *telnetmgr.Manager
+	catalog         *catalog.Catalog
+	reindex         *reindex.Job
+	migration       *migration.Migration
 }
 
 // Start asynchronously the handler of the APIs
@@ -110,15 +122,32 @@ func (trest *REST) asyncStart() {
 	router.HEAD("/keyspaces/:keyspace", trest.kspace.Check)
 	router.POST("/keyspaces/:keyspace", trest.kspace.Create)
 	router.PUT("/keyspaces/:keyspace", trest.kspace.Update)
+	router.DELETE("/keyspaces/:keyspace", trest.kspace.Delete)
+	router.PUT("/keyspaces/:keyspace/ttl", trest.kspace.UpdateTTL)
+	router.PUT("/keyspaces/:keyspace/replication", trest.kspace.UpdateReplication)
 	router.GET("/keyspaces", trest.kspace.GetAll)
 	//WRITE
 	router.POST("/api/put", trest.writer.HandleNumber)
 	router.PUT("/api/put", trest.writer.HandleNumber)
 	router.POST("/api/text/put", trest.writer.HandleText)
+	router.POST("/api/histogram/put", trest.writer.HandleHistogram)
 	//OPENTSDB
 	router.POST("/keysets/:keyset/api/query", trest.reader.Query)
+	router.POST("/keysets/:keyset/api/histogram/query", trest.reader.HistogramQuery)
+	router.POST("/keysets/:keyset/api/text/query", trest.reader.TextQuery)
+	router.POST("/keysets/:keyset/api/annotation", trest.reader.Annotation)
+	router.GET("/keysets/:keyset/grafana", trest.reader.GrafanaCheck)
+	router.GET("/keysets/:keyset/grafana/", trest.reader.GrafanaCheck)
+	router.POST("/keysets/:keyset/grafana/search", trest.reader.GrafanaSearch)
+	router.POST("/keysets/:keyset/grafana/query", trest.reader.GrafanaQuery)
+	router.POST("/keysets/:keyset/grafana/tag-keys", trest.reader.GrafanaTagKeys)
+	router.POST("/keysets/:keyset/grafana/tag-values", trest.reader.GrafanaTagValues)
+	router.POST("/keysets/:keyset/grafana/annotations", trest.reader.GrafanaAnnotations)
 	router.GET("/keysets/:keyset/api/suggest", trest.reader.Suggest)
 	router.GET("/keysets/:keyset/api/search/lookup", trest.reader.Lookup)
+	router.POST("/keysets/:keyset/api/search/lookup", trest.reader.SearchLookup)
+	router.GET("/keysets/:keyset/api/uid/tsmeta", trest.reader.TSMeta)
+	router.GET("/keysets/:keyset/api/uid/uidmeta", trest.reader.UIDMeta)
 	router.GET("/keysets/:keyset/api/aggregators", config.Aggregators)
 	router.GET("/keysets/:keyset/api/config/filters", config.Filters)
 	//HYBRIDS
@@ -129,8 +158,24 @@ func (trest *REST) asyncStart() {
 	//KEYSETS
 	router.POST("/keysets/:keyset", trest.keyset.CreateKeyset)
 	router.HEAD("/keysets/:keyset", trest.keyset.Check)
+	router.GET("/keysets/:keyset", trest.keyset.GetKeyset)
+	router.PUT("/keysets/:keyset", trest.keyset.UpdateKeyset)
 	router.DELETE("/keysets/:keyset", trest.keyset.DeleteKeyset)
+	router.POST("/keysets/:keyset/restore", trest.keyset.RestoreKeyset)
 	router.GET("/keysets", trest.keyset.GetKeysets)
+	//KEYSET ALIASES
+	router.GET("/aliases", trest.keyset.GetAliases)
+	router.GET("/aliases/:alias", trest.keyset.GetAlias)
+	router.PUT("/aliases/:alias", trest.keyset.PutAlias)
+	router.DELETE("/aliases/:alias", trest.keyset.DeleteAlias)
+	//METRIC CATALOG
+	router.GET("/keysets/:keyset/catalog", trest.catalog.ListEntries)
+	router.GET("/keysets/:keyset/catalog/*metric", trest.catalog.GetEntry)
+	router.PUT("/keysets/:keyset/catalog/*metric", trest.catalog.PutEntry)
+	router.DELETE("/keysets/:keyset/catalog/*metric", trest.catalog.DeleteEntry)
+	//METADATA MIGRATION
+	router.GET("/keysets/:keyset/metadata/export", trest.migration.Export)
+	router.POST("/keysets/:keyset/metadata/import", trest.migration.Import)
 	//DELETE
 	router.POST("/keysets/:keyset/delete/meta", trest.reader.DeleteNumberTS)
 	router.POST("/keysets/:keyset/delete/text/meta", trest.reader.DeleteTextTS)
@@ -140,6 +185,8 @@ func (trest *REST) asyncStart() {
 	router.POST("/admin/free-os-memory", trest.freeOSMemory)
 	router.POST("/admin/set-gc-percent", trest.setGCPercent)
 	router.GET("/admin/read-gc-stats", trest.readGCStats)
+	router.POST("/admin/metadata/reindex", trest.reindex.StartJob)
+	router.GET("/admin/metadata/reindex", trest.reindex.JobStatus)
 
 	if trest.settings.EnableProfiling {
 
@@ -152,7 +199,7 @@ func (trest *REST) asyncStart() {
 
 	var compositeHTTPHandlers http.Handler
 
-	logHandler := rip.NewLogMiddleware(router, trest.settings.Port, newRestStatistics(trest.timelineManager))
+	logHandler := rip.NewLogMiddleware(trest.keyset.AliasHandler(router), trest.settings.Port, newRestStatistics(trest.timelineManager))
 
 	if trest.settings.AllowCORS {
 		compositeHTTPHandlers = cors.AllowAll().Handler(logHandler)
@@ -0,0 +1,349 @@
+package rollup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/logh"
+	tlmanager "github.com/uol/timelinemanager"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	queryAggregate string = `SELECT count(value), min(value), max(value), sum(value) FROM %s.ts_number_stamp WHERE id = ? AND date >= ? AND date < ?`
+	queryInsert    string = `INSERT INTO %s.%s (id, date, min, max, sum, count) VALUES (?, ?, ?, ?, ?, ?)`
+	queryDelete    string = `DELETE FROM %s.%s WHERE id = ? AND date = ?`
+)
+
+type bucket struct {
+	keyspace   string
+	tsid       string
+	resolution int
+	date       int64
+}
+
+// Compactor - computes the rollup buckets of the timeseries that received points,
+// always from the raw points, so it does not matter which node received each point
+type Compactor struct {
+	cassandra       *gocql.Session
+	ksAdmin         string
+	timelineManager *tlmanager.Instance
+	resolutions     []Resolution
+	interval        time.Duration
+	delay           time.Duration
+	dirty           map[bucket]struct{}
+	pending         map[stateKey]int64
+	persisted       map[stateKey]int64
+	states          map[stateKey]int64
+	queued          []bucket
+	unregistered    map[stateKey]int64
+	mutex           sync.Mutex
+	stop            chan struct{}
+	flushed         chan struct{}
+	done            chan struct{}
+	logger          *logh.ContextualLogger
+}
+
+// NewCompactor - creates a new compactor, it returns nil if there are no resolutions configured
+func NewCompactor(
+	configuration *Configuration,
+	resolutions []Resolution,
+	cassandra *gocql.Session,
+	ksAdmin string,
+	timelineManager *tlmanager.Instance,
+) (*Compactor, error) {
+
+	if len(resolutions) == 0 {
+		return nil, nil
+	}
+
+	interval, err := time.ParseDuration(configuration.CompactionInterval)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CompactionInterval: %s", configuration.CompactionInterval)
+	}
+
+	delay, err := time.ParseDuration(configuration.CompactionDelay)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CompactionDelay: %s", configuration.CompactionDelay)
+	}
+
+	c := newCompactor(resolutions, interval, delay)
+	c.cassandra = cassandra
+	c.ksAdmin = ksAdmin
+	c.timelineManager = timelineManager
+
+	if err := c.createTables(); err != nil {
+		return nil, fmt.Errorf("error creating the rollup tables: %s", err.Error())
+	}
+
+	if err := c.loadDirty(); err != nil {
+		return nil, fmt.Errorf("error reading the rollup dirty buckets: %s", err.Error())
+	}
+
+	if err := c.refreshStates(); err != nil {
+		return nil, fmt.Errorf("error reading the rollup states: %s", err.Error())
+	}
+
+	if err := c.refreshPersisted(); err != nil {
+		return nil, fmt.Errorf("error reading the rollup dirty buckets: %s", err.Error())
+	}
+
+	return c, nil
+}
+
+func newCompactor(resolutions []Resolution, interval, delay time.Duration) *Compactor {
+
+	return &Compactor{
+		resolutions:  resolutions,
+		interval:     interval,
+		delay:        delay,
+		dirty:        map[bucket]struct{}{},
+		pending:      map[stateKey]int64{},
+		persisted:    map[stateKey]int64{},
+		states:       map[stateKey]int64{},
+		unregistered: map[stateKey]int64{},
+		stop:         make(chan struct{}),
+		flushed:      make(chan struct{}),
+		done:         make(chan struct{}),
+		logger:       logh.CreateContextualLogger(constants.StringsPKG, "rollup"),
+	}
+}
+
+// Mark - flags the buckets containing the point to be computed again, they are stored by the persist loop
+func (c *Compactor) Mark(keyspace, tsid string, timestamp int64) {
+
+	c.mark(keyspace, tsid, timestamp, time.Now())
+}
+
+// mark - flags the buckets in memory and queues them to be stored, returning the ones not flagged
+// before and the keyspace resolutions tracked for the first time with their first bucket
+func (c *Compactor) mark(keyspace, tsid string, timestamp int64, now time.Time) ([]bucket, map[stateKey]int64) {
+
+	var added []bucket
+	var unregistered map[stateKey]int64
+
+	nowMillis := now.UnixNano() / int64(time.Millisecond)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, r := range c.resolutions {
+
+		b := bucket{
+			keyspace:   keyspace,
+			tsid:       tsid,
+			resolution: i,
+			date:       r.BucketDate(timestamp),
+		}
+
+		if c.addDirty(b) {
+			added = append(added, b)
+			c.queued = append(c.queued, b)
+		}
+
+		key := stateKey{keyspace: keyspace, interval: r.Interval}
+
+		if _, ok := c.states[key]; !ok {
+			from := r.BucketDate(nowMillis) + r.Interval
+			c.states[key] = from
+			c.unregistered[key] = from
+
+			if unregistered == nil {
+				unregistered = map[stateKey]int64{}
+			}
+			unregistered[key] = from
+		}
+	}
+
+	return added, unregistered
+}
+
+// addDirty - flags the bucket, the mutex must be locked
+func (c *Compactor) addDirty(b bucket) bool {
+
+	if _, ok := c.dirty[b]; ok {
+		return false
+	}
+
+	c.dirty[b] = struct{}{}
+
+	key := stateKey{keyspace: b.keyspace, interval: c.resolutions[b.resolution].Interval}
+	if pending, ok := c.pending[key]; !ok || b.date < pending {
+		c.pending[key] = b.date
+	}
+
+	return true
+}
+
+// Start - starts the persist and the compaction loops
+func (c *Compactor) Start() {
+
+	go func() {
+
+		ticker := time.NewTicker(persistInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.persist()
+			case <-c.stop:
+				c.persist()
+				close(c.flushed)
+				return
+			}
+		}
+	}()
+
+	go func() {
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.refreshStates(); err != nil {
+					c.logError("refreshStates", constants.StringsEmpty, err)
+				}
+				if err := c.refreshPersisted(); err != nil {
+					c.logError("refreshPersisted", constants.StringsEmpty, err)
+				}
+				c.compact(false)
+			case <-c.stop:
+				// the queued buckets are stored before the compacted ones are removed
+				<-c.flushed
+				c.compact(true)
+				close(c.done)
+				return
+			}
+		}
+	}()
+}
+
+// Stop - compacts all the flagged buckets, even the open ones, and stops the loop,
+// any point the open buckets receive later flags them again on the node receiving it
+func (c *Compactor) Stop() {
+
+	close(c.stop)
+	<-c.done
+}
+
+// Resolutions - returns the rollup tables, from the finest to the coarsest
+func (c *Compactor) Resolutions() []Resolution {
+
+	return c.resolutions
+}
+
+func (c *Compactor) ready(all bool) []bucket {
+
+	limit := time.Now().Add(-c.delay).UnixNano() / int64(time.Millisecond)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	buckets := []bucket{}
+
+	for b := range c.dirty {
+		if all || b.date+c.resolutions[b.resolution].Interval <= limit {
+			buckets = append(buckets, b)
+			delete(c.dirty, b)
+		}
+	}
+
+	return buckets
+}
+
+func (c *Compactor) compact(all bool) {
+
+	buckets := c.ready(all)
+
+	for _, b := range buckets {
+
+		if err := c.compactBucket(b); err != nil {
+
+			if logh.ErrorEnabled {
+				c.logger.Error().Str(constants.StringsFunc, "compact").Str("keyspace", b.keyspace).Str("tsid", b.tsid).Int64("date", b.date).Err(err).Send()
+			}
+
+			statsCompactionError(c.timelineManager, b.keyspace)
+
+			if !all {
+				c.mutex.Lock()
+				c.addDirty(b)
+				c.mutex.Unlock()
+			}
+
+			continue
+		}
+
+		if err := c.deleteDirty(b); err != nil {
+			c.logError("compact", b.keyspace, err)
+		}
+	}
+
+	c.refreshPending()
+}
+
+// refreshPending - keeps the oldest bucket still flagged of each keyspace resolution, the buckets
+// being compacted stay pending until the compaction finishes
+func (c *Compactor) refreshPending() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	pending := map[stateKey]int64{}
+
+	for b := range c.dirty {
+		key := stateKey{keyspace: b.keyspace, interval: c.resolutions[b.resolution].Interval}
+		if date, ok := pending[key]; !ok || b.date < date {
+			pending[key] = b.date
+		}
+	}
+
+	c.pending = pending
+}
+
+func (c *Compactor) compactBucket(b bucket) error {
+
+	start := time.Now()
+
+	r := c.resolutions[b.resolution]
+
+	var count int64
+	var min, max, sum float64
+
+	if err := c.cassandra.Query(
+		fmt.Sprintf(queryAggregate, b.keyspace),
+		b.tsid,
+		b.date,
+		b.date+r.Interval,
+	).Scan(&count, &min, &max, &sum); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		if err := c.cassandra.Query(fmt.Sprintf(queryDelete, b.keyspace, r.Table), b.tsid, b.date).Exec(); err != nil {
+			return err
+		}
+	} else {
+		if err := c.cassandra.Query(
+			fmt.Sprintf(queryInsert, b.keyspace, r.Table),
+			b.tsid,
+			b.date,
+			min,
+			max,
+			sum,
+			count,
+		).Exec(); err != nil {
+			return err
+		}
+	}
+
+	statsCompaction(c.timelineManager, b.keyspace, r.Table, time.Since(start))
+
+	return nil
+}
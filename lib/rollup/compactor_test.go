@@ -0,0 +1,126 @@
+package rollup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	msMinute int64 = 60 * 1000
+	msHour   int64 = 60 * msMinute
+)
+
+func testCompactor() *Compactor {
+
+	resolutions := []Resolution{
+		{Interval: 5 * msMinute, TTL: 30, Table: "ts_number_rollup_300s"},
+		{Interval: msHour, TTL: 365, Table: "ts_number_rollup_3600s"},
+	}
+
+	return newCompactor(resolutions, time.Minute, 2*time.Minute)
+}
+
+func TestBucketDate(t *testing.T) {
+
+	r := Resolution{Interval: msHour}
+
+	assert.Equal(t, int64(0), r.BucketDate(0))
+	assert.Equal(t, int64(0), r.BucketDate(msHour-1))
+	assert.Equal(t, msHour, r.BucketDate(msHour))
+	assert.Equal(t, -msHour, r.BucketDate(-1), "the buckets before the epoch are aligned too")
+
+	assert.True(t, r.Aligned(3*msHour))
+	assert.False(t, r.Aligned(3*msHour+msMinute))
+}
+
+func TestMarkPersistsOnlyNewBuckets(t *testing.T) {
+
+	c := testCompactor()
+	now := time.Unix(0, 10*msHour*int64(time.Millisecond))
+	timestamp := 9*msHour + 7*msMinute
+
+	added, unregistered := c.mark("ks", "tsid", timestamp, now)
+
+	assert.Len(t, added, 2)
+	assert.Equal(t, 9*msHour+5*msMinute, added[0].date)
+	assert.Equal(t, 9*msHour, added[1].date)
+
+	assert.Equal(t, map[stateKey]int64{
+		{keyspace: "ks", interval: 5 * msMinute}: 10*msHour + 5*msMinute,
+		{keyspace: "ks", interval: msHour}:       11 * msHour,
+	}, unregistered, "the first tracked bucket is the one after the current")
+
+	added, unregistered = c.mark("ks", "tsid", timestamp+msMinute, now)
+
+	assert.Empty(t, added)
+	assert.Empty(t, unregistered)
+	assert.Len(t, c.queued, 2, "the new buckets are queued to be stored")
+	assert.Len(t, c.unregistered, 2)
+}
+
+func TestCompactedRange(t *testing.T) {
+
+	c := testCompactor()
+	r := c.resolutions[1]
+	now := time.Unix(0, 20*msHour*int64(time.Millisecond))
+
+	from, until := c.Compacted("ks", r, now)
+	assert.Equal(t, from, until, "nothing is compacted in an untracked keyspace")
+
+	key := stateKey{keyspace: "ks", interval: r.Interval}
+	c.states[key] = 5 * msHour
+
+	from, until = c.Compacted("ks", r, now)
+	assert.Equal(t, 5*msHour, from)
+	assert.Equal(t, 19*msHour, until, "the buckets compacted in the last run are included")
+
+	c.mutex.Lock()
+	c.addDirty(bucket{keyspace: "ks", tsid: "tsid", resolution: 1, date: 12 * msHour})
+	c.mutex.Unlock()
+
+	_, until = c.Compacted("ks", r, now)
+	assert.Equal(t, 12*msHour, until, "a flagged bucket is not compacted yet")
+
+	c.mutex.Lock()
+	delete(c.dirty, bucket{keyspace: "ks", tsid: "tsid", resolution: 1, date: 12 * msHour})
+	c.mutex.Unlock()
+
+	_, until = c.Compacted("ks", r, now)
+	assert.Equal(t, 12*msHour, until, "the bucket stays pending while it is compacted")
+
+	c.refreshPending()
+
+	_, until = c.Compacted("ks", r, now)
+	assert.Equal(t, 19*msHour, until)
+
+	c.persisted[key] = 15 * msHour
+
+	_, until = c.Compacted("ks", r, now)
+	assert.Equal(t, 15*msHour, until, "a bucket flagged by another node is not compacted yet")
+
+	delete(c.persisted, key)
+	c.states[key] = 30 * msHour
+
+	from, until = c.Compacted("ks", r, now)
+	assert.Equal(t, from, until)
+}
+
+func TestReadyKeepsOpenBuckets(t *testing.T) {
+
+	c := testCompactor()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	c.mutex.Lock()
+	c.addDirty(bucket{keyspace: "ks", tsid: "closed", resolution: 0, date: c.resolutions[0].BucketDate(now - msHour)})
+	c.addDirty(bucket{keyspace: "ks", tsid: "open", resolution: 0, date: c.resolutions[0].BucketDate(now)})
+	c.mutex.Unlock()
+
+	buckets := c.ready(false)
+
+	assert.Len(t, buckets, 1)
+	assert.Equal(t, "closed", buckets[0].tsid)
+	assert.Len(t, c.dirty, 1)
+}
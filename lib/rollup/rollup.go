@@ -0,0 +1,111 @@
+package rollup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Keeps pre-aggregated points (min, max, sum and count) of every number
+// timeseries per resolution, so long range queries do not read raw points
+
+// Configuration - the rollup tables configuration
+type Configuration struct {
+
+	// Resolutions - the rollup tables, one per resolution
+	Resolutions []ResolutionConfiguration
+
+	// CompactionInterval - the time duration between two compaction runs
+	CompactionInterval string
+
+	// CompactionDelay - the time duration to wait after a bucket ends before compacting it, so delayed points are included
+	CompactionDelay string
+}
+
+// ResolutionConfiguration - a rollup table configuration
+type ResolutionConfiguration struct {
+
+	// Resolution - the bucket size of the table (a duration like 5m, 1h or 24h)
+	Resolution string
+
+	// TTL - the table TTL in days
+	TTL int
+}
+
+// Resolution - a parsed rollup table
+type Resolution struct {
+	Interval int64
+	TTL      int
+	Table    string
+}
+
+const fmtTableName string = "ts_number_rollup_%ds"
+
+// ParseResolutions - parses the configured resolutions, sorted from the finest to the coarsest
+func ParseResolutions(configuration *Configuration) ([]Resolution, error) {
+
+	if configuration == nil {
+		return nil, nil
+	}
+
+	resolutions := make([]Resolution, 0, len(configuration.Resolutions))
+	found := map[int64]bool{}
+
+	for _, conf := range configuration.Resolutions {
+
+		duration, err := time.ParseDuration(conf.Resolution)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rollup resolution: %s", conf.Resolution)
+		}
+
+		if duration < time.Second || duration%time.Second != 0 {
+			return nil, fmt.Errorf("rollup resolution needs to be a whole number of seconds: %s", conf.Resolution)
+		}
+
+		if conf.TTL <= 0 {
+			return nil, fmt.Errorf("rollup resolution %s needs a TTL bigger than zero", conf.Resolution)
+		}
+
+		interval := int64(duration / time.Millisecond)
+
+		if found[interval] {
+			return nil, fmt.Errorf("duplicated rollup resolution: %s", conf.Resolution)
+		}
+
+		found[interval] = true
+
+		resolutions = append(resolutions, Resolution{
+			Interval: interval,
+			TTL:      conf.TTL,
+			Table:    fmt.Sprintf(fmtTableName, interval/1000),
+		})
+	}
+
+	sort.Slice(resolutions, func(i, j int) bool { return resolutions[i].Interval < resolutions[j].Interval })
+
+	return resolutions, nil
+}
+
+// BucketDate - returns the date of the bucket containing the timestamp (in ms), the buckets
+// are aligned to the UTC epoch and not to the local time
+func (r Resolution) BucketDate(timestamp int64) int64 {
+
+	date := timestamp - timestamp%r.Interval
+	if date > timestamp {
+		date -= r.Interval
+	}
+
+	return date
+}
+
+// Aligned - tells if the date (in ms) is the beginning of a bucket
+func (r Resolution) Aligned(date int64) bool {
+
+	return r.BucketDate(date) == date
+}
+
+// Covers - tells if the table still has the buckets starting at the date (in ms)
+func (r Resolution) Covers(date int64, now time.Time) bool {
+
+	return now.UTC().AddDate(0, 0, -r.TTL).UnixNano()/int64(time.Millisecond) <= date
+}
@@ -0,0 +1,302 @@
+package rollup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uol/logh"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// The dirty buckets are stored in the admin keyspace until they are compacted, so a restart or a crash
+// does not lose them. Each keyspace and resolution also stores the date of the first bucket the compactor
+// tracked: the buckets before it may have points written before the rollups and are read from the raw table.
+// The buckets are queued by the ingestion and stored in batches every persist interval, the oldest bucket
+// stored by any node limits the compacted range, so a late point received by another node is read from
+// the raw table once this node reads the stored buckets again, at most one compaction interval later
+
+const (
+	formatCreateDirtyTable string = `CREATE TABLE IF NOT EXISTS %s.ts_rollup_dirty (keyspace text, resolution bigint, tsid text, date timestamp, PRIMARY KEY ((keyspace, resolution), tsid, date))`
+	formatCreateStateTable string = `CREATE TABLE IF NOT EXISTS %s.ts_rollup_state (keyspace text, resolution bigint, compacted_from timestamp, PRIMARY KEY (keyspace, resolution))`
+	formatInsertDirty      string = `INSERT INTO %s.ts_rollup_dirty (keyspace, resolution, tsid, date) VALUES (?, ?, ?, ?)`
+	formatDeleteDirty      string = `DELETE FROM %s.ts_rollup_dirty WHERE keyspace = ? AND resolution = ? AND tsid = ? AND date = ?`
+	formatListDirty        string = `SELECT keyspace, resolution, tsid, date FROM %s.ts_rollup_dirty`
+	formatInsertState      string = `INSERT INTO %s.ts_rollup_state (keyspace, resolution, compacted_from) VALUES (?, ?, ?) IF NOT EXISTS`
+	formatListStates       string = `SELECT keyspace, resolution, compacted_from FROM %s.ts_rollup_state`
+)
+
+const (
+	persistInterval  time.Duration = time.Second
+	persistBatchSize int           = 100
+)
+
+// stateKey - a keyspace and a resolution interval
+type stateKey struct {
+	keyspace string
+	interval int64
+}
+
+// createTables - creates the compactor tables in the admin keyspace
+func (c *Compactor) createTables() error {
+
+	for _, format := range []string{formatCreateDirtyTable, formatCreateStateTable} {
+		if err := c.cassandra.Query(fmt.Sprintf(format, c.ksAdmin)).Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolutionIndex - returns the index of the configured resolution with the interval
+func (c *Compactor) resolutionIndex(interval int64) (int, bool) {
+
+	for i, r := range c.resolutions {
+		if r.Interval == interval {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// loadDirty - reads the buckets not compacted by any node before it stopped, the buckets of
+// resolutions not configured anymore are ignored
+func (c *Compactor) loadDirty() error {
+
+	iter := c.cassandra.Query(fmt.Sprintf(formatListDirty, c.ksAdmin)).Iter()
+
+	var keyspace, tsid string
+	var interval, date int64
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for iter.Scan(&keyspace, &interval, &tsid, &date) {
+
+		i, ok := c.resolutionIndex(interval)
+		if !ok {
+			continue
+		}
+
+		c.addDirty(bucket{
+			keyspace:   keyspace,
+			tsid:       tsid,
+			resolution: i,
+			date:       date,
+		})
+	}
+
+	return iter.Close()
+}
+
+// refreshStates - reads the first compacted bucket of each keyspace, including the ones registered by other nodes
+func (c *Compactor) refreshStates() error {
+
+	iter := c.cassandra.Query(fmt.Sprintf(formatListStates, c.ksAdmin)).Iter()
+
+	var keyspace string
+	var interval, from int64
+
+	stored := map[stateKey]int64{}
+
+	for iter.Scan(&keyspace, &interval, &from) {
+		stored[stateKey{keyspace: keyspace, interval: interval}] = from
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	for key, from := range stored {
+		c.states[key] = from
+	}
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// refreshPersisted - reads the oldest bucket stored by any node in each keyspace resolution, the buckets
+// of resolutions not configured anymore are ignored
+func (c *Compactor) refreshPersisted() error {
+
+	iter := c.cassandra.Query(fmt.Sprintf(formatListDirty, c.ksAdmin)).Iter()
+
+	var keyspace, tsid string
+	var interval, date int64
+
+	persisted := map[stateKey]int64{}
+
+	for iter.Scan(&keyspace, &interval, &tsid, &date) {
+
+		if _, ok := c.resolutionIndex(interval); !ok {
+			continue
+		}
+
+		key := stateKey{keyspace: keyspace, interval: interval}
+		if oldest, ok := persisted[key]; !ok || date < oldest {
+			persisted[key] = date
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.persisted = persisted
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// register - stores the first bucket tracked in the keyspace, the bucket after the current one
+// since the current one may have points written before, unless another node stored it already
+func (c *Compactor) register(key stateKey, from int64) {
+
+	var keyspace string
+	var interval, stored int64
+
+	applied, err := c.cassandra.Query(
+		fmt.Sprintf(formatInsertState, c.ksAdmin),
+		key.keyspace,
+		key.interval,
+		from,
+	).ScanCAS(&keyspace, &interval, &stored)
+	if err != nil {
+		c.logError("register", key.keyspace, err)
+		return
+	}
+
+	if !applied {
+		c.mutex.Lock()
+		c.states[key] = stored
+		c.mutex.Unlock()
+	}
+}
+
+// persist - stores the queued buckets and registers the keyspaces tracked for the first time,
+// the buckets of a failed batch are queued again
+func (c *Compactor) persist() {
+
+	c.mutex.Lock()
+	queued, unregistered := c.queued, c.unregistered
+	c.queued, c.unregistered = nil, map[stateKey]int64{}
+	c.mutex.Unlock()
+
+	for key, from := range unregistered {
+		c.register(key, from)
+	}
+
+	// the batches only have buckets of the same partition
+	partitions := map[stateKey][]bucket{}
+	for _, b := range queued {
+		key := stateKey{keyspace: b.keyspace, interval: c.resolutions[b.resolution].Interval}
+		partitions[key] = append(partitions[key], b)
+	}
+
+	for key, buckets := range partitions {
+
+		for len(buckets) > 0 {
+
+			size := persistBatchSize
+			if size > len(buckets) {
+				size = len(buckets)
+			}
+
+			if err := c.persistDirty(key, buckets[:size]); err != nil {
+				c.logError("persist", key.keyspace, err)
+
+				c.mutex.Lock()
+				c.queued = append(c.queued, buckets[:size]...)
+				c.mutex.Unlock()
+			}
+
+			buckets = buckets[size:]
+		}
+	}
+}
+
+// persistDirty - stores the buckets of the keyspace resolution until they are compacted
+func (c *Compactor) persistDirty(key stateKey, buckets []bucket) error {
+
+	batch := c.cassandra.NewBatch(gocql.UnloggedBatch)
+
+	for _, b := range buckets {
+		batch.Query(
+			fmt.Sprintf(formatInsertDirty, c.ksAdmin),
+			b.keyspace,
+			key.interval,
+			b.tsid,
+			b.date,
+		)
+	}
+
+	return c.cassandra.ExecuteBatch(batch)
+}
+
+// deleteDirty - removes the compacted bucket, unless a new point flagged it again during the compaction
+func (c *Compactor) deleteDirty(b bucket) error {
+
+	c.mutex.Lock()
+	_, flagged := c.dirty[b]
+	c.mutex.Unlock()
+
+	if flagged {
+		return nil
+	}
+
+	return c.cassandra.Query(
+		fmt.Sprintf(formatDeleteDirty, c.ksAdmin),
+		b.keyspace,
+		c.resolutions[b.resolution].Interval,
+		b.tsid,
+		b.date,
+	).Exec()
+}
+
+// Compacted - returns the range of dates [from, until) whose buckets of the resolution are known to be
+// compacted in the keyspace, the range ends at the oldest bucket flagged by this node or stored by any
+// node, it is empty when the compactor never tracked the keyspace
+func (c *Compactor) Compacted(keyspace string, r Resolution, now time.Time) (int64, int64) {
+
+	key := stateKey{keyspace: keyspace, interval: r.Interval}
+
+	until := r.BucketDate(now.Add(-(c.interval + c.delay)).UnixNano() / int64(time.Millisecond))
+
+	c.mutex.Lock()
+	from, tracked := c.states[key]
+	pending, dirty := c.pending[key]
+	persisted, stored := c.persisted[key]
+	c.mutex.Unlock()
+
+	if !tracked {
+		return until, until
+	}
+
+	if dirty && pending < until {
+		until = pending
+	}
+
+	if stored && persisted < until {
+		until = persisted
+	}
+
+	if from > until {
+		from = until
+	}
+
+	return from, until
+}
+
+func (c *Compactor) logError(function, keyspace string, err error) {
+
+	if logh.ErrorEnabled {
+		c.logger.Error().Str(constants.StringsFunc, function).Str("keyspace", keyspace).Err(err).Send()
+	}
+
+	statsCompactionError(c.timelineManager, keyspace)
+}
@@ -0,0 +1,43 @@
+package rollup
+
+import (
+	"time"
+
+	tlmanager "github.com/uol/timelinemanager"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	metricCompaction      string = "rollup.compaction"
+	metricCompactionError string = "rollup.compaction.error"
+	metricCompactionTime  string = "rollup.compaction.duration"
+	tagTable              string = "table"
+)
+
+func statsCompaction(timelineManager *tlmanager.Instance, keyspace, table string, d time.Duration) {
+
+	timelineManager.FlattenMaxN(
+		constants.StringsEmpty,
+		float64(d.Nanoseconds())/float64(time.Millisecond),
+		metricCompactionTime,
+		constants.StringsKeyspace, keyspace,
+		tagTable, table,
+	)
+
+	timelineManager.FlattenCountIncN(
+		constants.StringsEmpty,
+		metricCompaction,
+		constants.StringsKeyspace, keyspace,
+		tagTable, table,
+	)
+}
+
+func statsCompactionError(timelineManager *tlmanager.Instance, keyspace string) {
+
+	timelineManager.FlattenCountIncN(
+		constants.StringsEmpty,
+		metricCompactionError,
+		constants.StringsKeyspace, keyspace,
+	)
+}
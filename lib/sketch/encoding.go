@@ -0,0 +1,164 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const encodingVersion byte = 1
+
+// ErrInvalidSketch - the bytes are not a sketch built by Bytes
+var ErrInvalidSketch = errors.New("invalid sketch encoding")
+
+func appendBuckets(buf []byte, buckets map[int32]uint64) []byte {
+
+	buf = appendUvarint(buf, uint64(len(buckets)))
+
+	for _, i := range sortedIndexes(buckets, false) {
+		buf = appendVarint(buf, int64(i))
+		buf = appendUvarint(buf, buckets[i])
+	}
+
+	return buf
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+
+	return append(buf, tmp[:n]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+
+	return append(buf, tmp[:n]...)
+}
+
+func appendFloat(buf []byte, v float64) []byte {
+
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], math.Float64bits(v))
+
+	return append(buf, tmp[:]...)
+}
+
+// Bytes - encodes the sketch, only the non empty buckets are written
+func (s *Sketch) Bytes() []byte {
+
+	buf := make([]byte, 0, 32+4*(len(s.positive)+len(s.negative)))
+
+	buf = append(buf, encodingVersion)
+	buf = appendUvarint(buf, s.count)
+	buf = appendUvarint(buf, s.zero)
+	buf = appendFloat(buf, s.sum)
+	buf = appendFloat(buf, s.min)
+	buf = appendFloat(buf, s.max)
+	buf = appendBuckets(buf, s.negative)
+	buf = appendBuckets(buf, s.positive)
+
+	return buf
+}
+
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = ErrInvalidSketch
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+
+	return v
+}
+
+func (r *reader) varint() int64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = ErrInvalidSketch
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+
+	return v
+}
+
+func (r *reader) float() float64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	if len(r.buf) < 8 {
+		r.err = ErrInvalidSketch
+		return 0
+	}
+
+	v := math.Float64frombits(binary.BigEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+
+	return v
+}
+
+func (r *reader) buckets() map[int32]uint64 {
+
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.err = ErrInvalidSketch
+		return nil
+	}
+
+	buckets := make(map[int32]uint64, n)
+
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		index := r.varint()
+		buckets[int32(index)] = r.uvarint()
+	}
+
+	return buckets
+}
+
+// FromBytes - decodes a sketch encoded by Bytes
+func FromBytes(data []byte) (*Sketch, error) {
+
+	if len(data) == 0 || data[0] != encodingVersion {
+		return nil, ErrInvalidSketch
+	}
+
+	r := &reader{buf: data[1:]}
+
+	s := &Sketch{}
+	s.count = r.uvarint()
+	s.zero = r.uvarint()
+	s.sum = r.float()
+	s.min = r.float()
+	s.max = r.float()
+	s.negative = r.buckets()
+	s.positive = r.buckets()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,197 @@
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// Implements a sparse and mergeable quantile sketch (Masson et al., DDSketch, 2019):
+// the values are counted in logarithmic buckets, so any quantile computed from it
+// is within the relative accuracy of the exact one, no matter how many sketches were merged
+
+const (
+	// RelativeAccuracy - the maximum relative error of the computed quantiles
+	RelativeAccuracy float64 = 0.01
+
+	// minIndexable - the absolute values below it are counted as zeroes
+	minIndexable float64 = 1e-9
+)
+
+var (
+	gamma    = (1 + RelativeAccuracy) / (1 - RelativeAccuracy)
+	logGamma = math.Log(gamma)
+)
+
+// Sketch - the buckets of a distribution, the negative values are indexed by their absolute value
+type Sketch struct {
+	positive map[int32]uint64
+	negative map[int32]uint64
+	zero     uint64
+	count    uint64
+	sum      float64
+	min      float64
+	max      float64
+}
+
+// New - creates an empty sketch
+func New() *Sketch {
+
+	return &Sketch{
+		positive: map[int32]uint64{},
+		negative: map[int32]uint64{},
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+func index(value float64) int32 {
+
+	return int32(math.Ceil(math.Log(value) / logGamma))
+}
+
+func bucketValue(index int32) float64 {
+
+	return 2 * math.Pow(gamma, float64(index)) / (gamma + 1)
+}
+
+// Add - counts the value n times
+func (s *Sketch) Add(value float64, n uint64) {
+
+	if n == 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	switch {
+	case value > minIndexable:
+		s.positive[index(value)] += n
+	case value < -minIndexable:
+		s.negative[index(-value)] += n
+	default:
+		s.zero += n
+	}
+
+	s.count += n
+	s.sum += value * float64(n)
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+}
+
+// Merge - adds the buckets of other sketch to this one
+func (s *Sketch) Merge(other *Sketch) {
+
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	for i, n := range other.positive {
+		s.positive[i] += n
+	}
+
+	for i, n := range other.negative {
+		s.negative[i] += n
+	}
+
+	s.zero += other.zero
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+}
+
+// Count - returns the number of values
+func (s *Sketch) Count() uint64 {
+
+	return s.count
+}
+
+// Sum - returns the sum of the values
+func (s *Sketch) Sum() float64 {
+
+	return s.sum
+}
+
+// Min - returns the smallest value, NaN when empty
+func (s *Sketch) Min() float64 {
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+
+	return s.min
+}
+
+// Max - returns the biggest value, NaN when empty
+func (s *Sketch) Max() float64 {
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+
+	return s.max
+}
+
+// Avg - returns the average of the values, NaN when empty
+func (s *Sketch) Avg() float64 {
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+
+	return s.sum / float64(s.count)
+}
+
+func sortedIndexes(buckets map[int32]uint64, desc bool) []int32 {
+
+	indexes := make([]int32, 0, len(buckets))
+	for i := range buckets {
+		indexes = append(indexes, i)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		if desc {
+			return indexes[i] > indexes[j]
+		}
+		return indexes[i] < indexes[j]
+	})
+
+	return indexes
+}
+
+// Quantile - returns the value at the quantile q (0 <= q <= 1), NaN when empty
+func (s *Sketch) Quantile(q float64) float64 {
+
+	if s.count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+
+	rank := q * float64(s.count-1)
+	result := s.max
+	var seen float64
+
+	for _, i := range sortedIndexes(s.negative, true) {
+		seen += float64(s.negative[i])
+		if seen > rank {
+			return s.clamp(-bucketValue(i))
+		}
+	}
+
+	seen += float64(s.zero)
+	if seen > rank {
+		return s.clamp(0)
+	}
+
+	for _, i := range sortedIndexes(s.positive, false) {
+		seen += float64(s.positive[i])
+		if seen > rank {
+			return s.clamp(bucketValue(i))
+		}
+	}
+
+	return result
+}
+
+// clamp - the bucket values never go beyond the exact extremes
+func (s *Sketch) clamp(value float64) float64 {
+
+	return math.Max(s.min, math.Min(s.max, value))
+}
@@ -0,0 +1,130 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func exactQuantile(sorted []float64, q float64) float64 {
+
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func checkQuantiles(t *testing.T, s *Sketch, values []float64) {
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	for _, q := range []float64{0, 0.1, 0.5, 0.9, 0.99, 1} {
+
+		expected := exactQuantile(sorted, q)
+		found := s.Quantile(q)
+
+		if math.Abs(found-expected) > RelativeAccuracy*math.Abs(expected)+1e-9 {
+			t.Fatalf("quantile %v: expected %v, found %v", q, expected, found)
+		}
+	}
+}
+
+func TestQuantilesWithinRelativeAccuracy(t *testing.T) {
+
+	r := rand.New(rand.NewSource(7))
+	s := New()
+	values := []float64{}
+
+	for i := 0; i < 10000; i++ {
+		v := math.Exp(r.NormFloat64() * 3)
+		if i%10 == 0 {
+			v = -v
+		}
+		if i%50 == 0 {
+			v = 0
+		}
+		s.Add(v, 1)
+		values = append(values, v)
+	}
+
+	checkQuantiles(t, s, values)
+}
+
+func TestMergeEqualsSingleSketch(t *testing.T) {
+
+	r := rand.New(rand.NewSource(11))
+	merged := New()
+	single := New()
+	values := []float64{}
+
+	for i := 0; i < 20; i++ {
+
+		part := New()
+
+		for j := 0; j < 500; j++ {
+			v := r.Float64() * float64(i+1) * 100
+			part.Add(v, 1)
+			single.Add(v, 1)
+			values = append(values, v)
+		}
+
+		merged.Merge(part)
+	}
+
+	if merged.Count() != single.Count() {
+		t.Fatalf("expected %d values, found %d", single.Count(), merged.Count())
+	}
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		if merged.Quantile(q) != single.Quantile(q) {
+			t.Fatalf("quantile %v: merged %v, single %v", q, merged.Quantile(q), single.Quantile(q))
+		}
+	}
+
+	checkQuantiles(t, merged, values)
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+
+	s := New()
+	s.Add(-3.5, 2)
+	s.Add(0, 1)
+	s.Add(12.25, 7)
+	s.Add(1e6, 1)
+
+	decoded, err := FromBytes(s.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Count() != s.Count() || decoded.Sum() != s.Sum() || decoded.Min() != s.Min() || decoded.Max() != s.Max() {
+		t.Fatalf("expected %+v, found %+v", s, decoded)
+	}
+
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		if decoded.Quantile(q) != s.Quantile(q) {
+			t.Fatalf("quantile %v: expected %v, found %v", q, s.Quantile(q), decoded.Quantile(q))
+		}
+	}
+
+	if _, err := FromBytes(s.Bytes()[:10]); err != ErrInvalidSketch {
+		t.Fatalf("expected an invalid sketch error, found %v", err)
+	}
+}
+
+func TestEmptySketch(t *testing.T) {
+
+	s := New()
+
+	if !math.IsNaN(s.Quantile(0.5)) || !math.IsNaN(s.Min()) || !math.IsNaN(s.Avg()) {
+		t.Fatal("expected NaN for an empty sketch")
+	}
+
+	decoded, err := FromBytes(s.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Count() != 0 {
+		t.Fatalf("expected no values, found %d", decoded.Count())
+	}
+}
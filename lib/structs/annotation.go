@@ -0,0 +1,130 @@
+package structs
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// AnnotationQueryPayload - the annotation query, each text of the matched text timeseries is an annotation
+type AnnotationQueryPayload struct {
+	Relative string       `json:"relative"`
+	Start    int64        `json:"start"`
+	End      int64        `json:"end"`
+	Metric   string       `json:"metric"`
+	Filters  []TSDBfilter `json:"filters"`
+}
+
+// Validate - validates the annotation query
+func (query AnnotationQueryPayload) Validate() gobol.Error {
+
+	checker := TSDBqueryPayload{}
+
+	if query.Relative != constants.StringsEmpty {
+		if err := checker.checkDuration(query.Relative); err != nil {
+			return err
+		}
+	}
+
+	if err := checker.checkField("metric", query.Metric); err != nil {
+		return err
+	}
+
+	return checker.checkFilter(query.Filters)
+}
+
+// GrafanaRange - the time range of a grafana request
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// GrafanaAnnotation - the annotation definition of the grafana dashboard,
+// the query is a metric followed by optional tag filters: metric{tagk=tagv,tagk=tagv1|tagv2,tagk=*}
+type GrafanaAnnotation struct {
+	Name       string `json:"name"`
+	Datasource string `json:"datasource"`
+	IconColor  string `json:"iconColor"`
+	Enable     bool   `json:"enable"`
+	Query      string `json:"query"`
+}
+
+// GrafanaAnnotationPayload - the grafana simple json annotation request
+type GrafanaAnnotationPayload struct {
+	Range      GrafanaRange      `json:"range"`
+	Annotation GrafanaAnnotation `json:"annotation"`
+}
+
+// Validate - validates the grafana annotation request
+func (query GrafanaAnnotationPayload) Validate() gobol.Error {
+
+	if query.Range.From.IsZero() {
+		return errValidation(errors.New("range from cannot be empty"))
+	}
+
+	if query.Range.To.Before(query.Range.From) {
+		return errValidation(errors.New("range to should be equal or after range from"))
+	}
+
+	_, _, err := query.Annotation.ParseQuery()
+
+	return err
+}
+
+// ParseQuery - parses the annotation query to a metric and its tag filters
+func (a GrafanaAnnotation) ParseQuery() (string, []TSDBfilter, gobol.Error) {
+
+	query := strings.TrimSpace(a.Query)
+	metric := query
+	filters := []TSDBfilter{}
+
+	if i := strings.Index(query, "{"); i >= 0 {
+
+		if !strings.HasSuffix(query, "}") {
+			return constants.StringsEmpty, nil, errValidation(errors.New("invalid annotation query: " + a.Query))
+		}
+
+		metric = strings.TrimSpace(query[:i])
+
+		tags := strings.TrimSpace(query[i+1 : len(query)-1])
+		if tags != constants.StringsEmpty {
+
+			for _, tag := range strings.Split(tags, constants.StringsComma) {
+
+				kv := strings.SplitN(tag, "=", 2)
+				if len(kv) != 2 {
+					return constants.StringsEmpty, nil, errValidation(errors.New("invalid annotation query tag: " + tag))
+				}
+
+				filter := TSDBfilter{
+					Ftype:   "literal_or",
+					Tagk:    strings.TrimSpace(kv[0]),
+					Filter:  strings.TrimSpace(kv[1]),
+					GroupBy: false,
+				}
+
+				if strings.Contains(filter.Filter, "*") {
+					filter.Ftype = "wildcard"
+				}
+
+				filters = append(filters, filter)
+			}
+		}
+	}
+
+	checker := TSDBqueryPayload{}
+
+	if err := checker.checkField("metric", metric); err != nil {
+		return constants.StringsEmpty, nil, err
+	}
+
+	if err := checker.checkFilter(filters); err != nil {
+		return constants.StringsEmpty, nil, err
+	}
+
+	return metric, filters, nil
+}
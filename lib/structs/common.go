@@ -4,9 +4,13 @@ import (
 	"github.com/uol/funks"
 	"github.com/uol/gobol/cassandra"
 	"github.com/uol/logh"
+	"github.com/uol/mycenae/lib/catalog"
+	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/keyset"
 	"github.com/uol/mycenae/lib/keyspace"
 	"github.com/uol/mycenae/lib/memcached"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/rollup"
 	tlmanager "github.com/uol/timelinemanager"
 )
 
@@ -37,6 +41,7 @@ type ValidationConfiguration struct {
 	KeysetNameRegexp string
 	DefaultTTL       int
 	MaxPropertySize  int
+	KeysetPrecision  map[string]constants.TimestampPrecision
 }
 
 // TelnetManagerConfiguration - the main and shared configuration for all telnet servers
@@ -65,6 +70,32 @@ type TelnetServerConfiguration struct {
 	MultipleConnsAllowedHosts      []string
 }
 
+// NumberBlocksConfiguration - the gorilla compressed blocks of the keyspaces with the block layout
+type NumberBlocksConfiguration struct {
+	// Window - the time window of each block, it must not change after the keyspaces have data
+	Window funks.Duration
+	// FlushInterval - the time duration between two writes of the open blocks
+	FlushInterval funks.Duration
+	// CloseDelay - the time duration a block stays open after its window ends, for delayed points
+	CloseDelay funks.Duration
+}
+
+// QueryCacheConfiguration - the memcached cache of the computed query results
+type QueryCacheConfiguration struct {
+	// Enabled - enables the query result cache
+	Enabled bool
+	// ChunkSize - the time window of each cached chunk, the downsample intervals of the cached queries must divide it
+	ChunkSize funks.Duration
+	// FreshTail - the most recent time window, it is never cached because its points are still arriving
+	FreshTail funks.Duration
+	// MaxChunks - queries spanning more chunks than this are not cached
+	MaxChunks int
+	// TTL - the time duration a chunk stays cached
+	TTL funks.Duration
+	// KeysetTTL - the chunk TTL of each keyset, replacing the default one, a zero TTL disables the keyset cache
+	KeysetTTL map[string]funks.Duration
+}
+
 type Settings struct {
 	MaxTimeseries                      int
 	LogQueryTSthreshold                int
@@ -87,6 +118,12 @@ type Settings struct {
 	BlacklistedKeysets                 []string
 	DefaultKeyspaceData                keyspace.Config
 	DefaultKeyspaces                   map[string]int
+	DefaultKeyspaceLayouts             map[string]constants.KeyspaceLayout
+	KeyspaceRefreshInterval            funks.Duration
+	NumberBlocks                       NumberBlocksConfiguration
+	QueryCache                         QueryCacheConfiguration
+	MetricCatalog                      catalog.Configuration
+	Keysets                            keyset.Configuration
 	EnableAutoKeyspaceCreation         bool
 	Cassandra                          cassandra.Settings
 	Memcached                          memcached.Configuration
@@ -94,4 +131,5 @@ type Settings struct {
 	Stats                              tlmanager.Configuration
 	MetadataSettings                   metadata.Settings
 	Validation                         ValidationConfiguration
+	Rollups                            rollup.Configuration
 }
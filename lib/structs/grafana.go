@@ -0,0 +1,123 @@
+package structs
+
+import (
+	"errors"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+const (
+	// GrafanaTargetTimeserie - the grafana target returning one serie of datapoints per timeseries group
+	GrafanaTargetTimeserie string = "timeserie"
+
+	// GrafanaTargetTable - the grafana target returning the datapoints as table rows
+	GrafanaTargetTable string = "table"
+)
+
+// GrafanaTarget - a grafana query target, the target is a mycenae expression
+type GrafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+	Hide   bool   `json:"hide"`
+}
+
+// GrafanaAdhocFilter - a grafana ad hoc filter, added to the filters of every target
+type GrafanaAdhocFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// GrafanaQueryPayload - the grafana json datasource query request
+type GrafanaQueryPayload struct {
+	Range         GrafanaRange         `json:"range"`
+	IntervalMs    int64                `json:"intervalMs"`
+	MaxDataPoints int64                `json:"maxDataPoints"`
+	Targets       []GrafanaTarget      `json:"targets"`
+	AdhocFilters  []GrafanaAdhocFilter `json:"adhocFilters"`
+}
+
+// Validate - validates the grafana query request, the expressions are validated when parsed
+func (query GrafanaQueryPayload) Validate() gobol.Error {
+
+	if query.Range.From.IsZero() {
+		return errValidation(errors.New("range from cannot be empty"))
+	}
+
+	if query.Range.To.Before(query.Range.From) {
+		return errValidation(errors.New("range to should be equal or after range from"))
+	}
+
+	for _, t := range query.Targets {
+		if t.Type != constants.StringsEmpty && t.Type != GrafanaTargetTimeserie && t.Type != GrafanaTargetTable {
+			return errValidation(errors.New("invalid target type: " + t.Type))
+		}
+	}
+
+	_, err := query.Filters()
+
+	return err
+}
+
+// Filters - converts the ad hoc filters to tag filters
+func (query GrafanaQueryPayload) Filters() ([]TSDBfilter, gobol.Error) {
+
+	filters := make([]TSDBfilter, len(query.AdhocFilters))
+
+	for i, f := range query.AdhocFilters {
+
+		filters[i] = TSDBfilter{
+			Tagk:    f.Key,
+			Filter:  f.Value,
+			GroupBy: false,
+		}
+
+		switch f.Operator {
+		case "=":
+			filters[i].Ftype = "literal_or"
+		case "!=":
+			filters[i].Ftype = "not_literal_or"
+		case "=~":
+			filters[i].Ftype = "regexp"
+		default:
+			return nil, errValidation(errors.New("unsupported ad hoc filter operator: " + f.Operator))
+		}
+	}
+
+	if err := (TSDBqueryPayload{}).checkFilter(filters); err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
+
+// GrafanaSearchPayload - the grafana json datasource metric search request
+type GrafanaSearchPayload struct {
+	Target string `json:"target"`
+}
+
+// Validate - nothing to validate, an empty target lists all metrics
+func (query GrafanaSearchPayload) Validate() gobol.Error {
+
+	return nil
+}
+
+// GrafanaTagPayload - the grafana json datasource tag keys and tag values request,
+// the metric restricts the tags to the ones of its timeseries
+type GrafanaTagPayload struct {
+	Metric string `json:"metric"`
+	Key    string `json:"key"`
+}
+
+// Validate - validates the grafana tag request
+func (query GrafanaTagPayload) Validate() gobol.Error {
+
+	if query.Metric == constants.StringsEmpty {
+		return nil
+	}
+
+	return (TSDBqueryPayload{}).checkField("metric", query.Metric)
+}
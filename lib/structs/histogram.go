@@ -0,0 +1,77 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// DefaultHistogramQuantiles - the quantiles returned when the query does not specify any
+var DefaultHistogramQuantiles = []float64{0.5, 0.9, 0.99}
+
+// HistogramQueryPayload - the histogram query, the sketches of each group are merged
+// by timestamp (or downsample interval) and the quantiles are computed from the merged sketch
+type HistogramQueryPayload struct {
+	Relative     string           `json:"relative"`
+	Start        int64            `json:"start"`
+	End          int64            `json:"end"`
+	MsResolution bool             `json:"msResolution"`
+	EstimateSize bool             `json:"estimateSize"`
+	Queries      []HistogramQuery `json:"queries"`
+}
+
+// HistogramQuery - a histogram metric query
+type HistogramQuery struct {
+	Metric     string       `json:"metric"`
+	Filters    []TSDBfilter `json:"filters"`
+	Downsample string       `json:"downsample"`
+	Quantiles  []float64    `json:"quantiles"`
+}
+
+// Validate - validates the histogram query and sets the default quantiles
+func (query HistogramQueryPayload) Validate() gobol.Error {
+
+	checker := TSDBqueryPayload{}
+
+	if query.Relative != constants.StringsEmpty {
+		if err := checker.checkDuration(query.Relative); err != nil {
+			return err
+		}
+	}
+
+	if len(query.Queries) == 0 {
+		return errValidation(errors.New("At least one query should be present"))
+	}
+
+	for i, q := range query.Queries {
+
+		if err := checker.checkField("metric", q.Metric); err != nil {
+			return err
+		}
+
+		if err := checker.checkFilter(q.Filters); err != nil {
+			return err
+		}
+
+		if q.Downsample != constants.StringsEmpty {
+			if err := checker.checkDuration(q.Downsample); err != nil {
+				return err
+			}
+		}
+
+		if len(q.Quantiles) == 0 {
+			query.Queries[i].Quantiles = DefaultHistogramQuantiles
+		}
+
+		for _, quantile := range q.Quantiles {
+			if quantile < 0 || quantile > 1 {
+				return errValidation(fmt.Errorf("invalid quantile %v, it must be between 0 and 1", quantile))
+			}
+		}
+	}
+
+	return nil
+}
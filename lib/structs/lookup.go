@@ -0,0 +1,51 @@
+package structs
+
+import (
+	"errors"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// TSDBlookupDefaultLimit - the number of series returned by the lookup when no limit is sent, the same of OpenTSDB
+const TSDBlookupDefaultLimit int = 25
+
+// TSDBlookupTag - a lookup tag pair, the key or the value can be the "*" wildcard
+type TSDBlookupTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// TSDBlookupPayload - the OpenTSDB search lookup request
+type TSDBlookupPayload struct {
+	Metric     string          `json:"metric"`
+	Tags       []TSDBlookupTag `json:"tags"`
+	UseMeta    bool            `json:"useMeta"`
+	Limit      int             `json:"limit"`
+	StartIndex int             `json:"startIndex"`
+}
+
+// Validate - validates the lookup request
+func (lookup TSDBlookupPayload) Validate() gobol.Error {
+
+	if lookup.Metric == constants.StringsEmpty && len(lookup.Tags) == 0 {
+		return errValidation(errors.New("missing metric and tags, please supply at least one value"))
+	}
+
+	if lookup.Limit < 0 {
+		return errValidation(errors.New("limit cannot be negative"))
+	}
+
+	if lookup.StartIndex < 0 {
+		return errValidation(errors.New("startIndex cannot be negative"))
+	}
+
+	for _, tag := range lookup.Tags {
+		if tag.Key == constants.StringsEmpty && tag.Value == constants.StringsEmpty {
+			return errValidation(errors.New("the tag key and value cannot be both empty"))
+		}
+	}
+
+	return nil
+}
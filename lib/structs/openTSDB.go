@@ -11,6 +11,7 @@ import (
 
 	"github.com/uol/mycenae/lib/config"
 	"github.com/uol/mycenae/lib/constants"
+	"github.com/uol/mycenae/lib/sketch"
 )
 
 var (
@@ -29,6 +30,94 @@ type TSDBquery struct {
 	Order       []string          `json:"order,omitempty"`
 	FilterValue string            `json:"filterValue,omitempty"`
 	Filters     []TSDBfilter      `json:"filters,omitempty"`
+	MaxGap      string            `json:"maxGap,omitempty"`
+	Increase    string            `json:"increase,omitempty"`
+	Delta       string            `json:"delta,omitempty"`
+	Irate       string            `json:"irate,omitempty"`
+	Deriv       string            `json:"deriv,omitempty"`
+
+	MovingAverage string           `json:"movingAverage,omitempty"`
+	RollingSum    string           `json:"rollingSum,omitempty"`
+	RollingMax    string           `json:"rollingMax,omitempty"`
+	RollingMin    string           `json:"rollingMin,omitempty"`
+	Ewma          float64          `json:"ewma,omitempty"`
+	HoltWinters   *TSDBholtWinters `json:"holtWinters,omitempty"`
+	TimeShift     string           `json:"timeShift,omitempty"`
+	Compare       bool             `json:"compare,omitempty"`
+}
+
+// windowOperations - the operations computed over a time window, in their default order
+var windowOperations = []string{"increase", "delta", "irate", "deriv"}
+
+// WindowFunctions - returns the window of each window operation configured in the query
+func (q TSDBquery) WindowFunctions() map[string]string {
+
+	windows := map[string]string{}
+
+	if q.Increase != constants.StringsEmpty {
+		windows["increase"] = q.Increase
+	}
+
+	if q.Delta != constants.StringsEmpty {
+		windows["delta"] = q.Delta
+	}
+
+	if q.Irate != constants.StringsEmpty {
+		windows["irate"] = q.Irate
+	}
+
+	if q.Deriv != constants.StringsEmpty {
+		windows["deriv"] = q.Deriv
+	}
+
+	return windows
+}
+
+// smoothingOperations - the operations that smooth the serie, in their default order
+var smoothingOperations = []string{"movingAverage", "rollingSum", "rollingMax", "rollingMin", "ewma", "holtWinters"}
+
+// RollingFunctions - returns the window of each rolling operation configured in the query
+func (q TSDBquery) RollingFunctions() map[string]string {
+
+	windows := map[string]string{}
+
+	if q.MovingAverage != constants.StringsEmpty {
+		windows["movingAverage"] = q.MovingAverage
+	}
+
+	if q.RollingSum != constants.StringsEmpty {
+		windows["rollingSum"] = q.RollingSum
+	}
+
+	if q.RollingMax != constants.StringsEmpty {
+		windows["rollingMax"] = q.RollingMax
+	}
+
+	if q.RollingMin != constants.StringsEmpty {
+		windows["rollingMin"] = q.RollingMin
+	}
+
+	return windows
+}
+
+func (q TSDBquery) hasSmoothing(oper string) bool {
+
+	switch oper {
+	case "ewma":
+		return q.Ewma != 0
+	case "holtWinters":
+		return q.HoltWinters != nil
+	}
+
+	_, ok := q.RollingFunctions()[oper]
+
+	return ok
+}
+
+// TSDBholtWinters - the smoothing and trend factors of the holtWinters function
+type TSDBholtWinters struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
 }
 
 type TSDBqueryPayload struct {
@@ -39,6 +128,7 @@ type TSDBqueryPayload struct {
 	ShowTSUIDs   bool        `json:"showTSUIDs"`
 	MsResolution bool        `json:"msResolution"`
 	EstimateSize bool        `json:"estimateSize"`
+	ActiveWithin string      `json:"activeWithin,omitempty"`
 }
 
 func (query TSDBqueryPayload) Validate() gobol.Error {
@@ -49,6 +139,12 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 		}
 	}
 
+	if query.ActiveWithin != constants.StringsEmpty {
+		if err := query.checkDuration(query.ActiveWithin); err != nil {
+			return err
+		}
+	}
+
 	if len(query.Queries) == 0 {
 		return errValidation(errors.New("At least one query should be present"))
 	}
@@ -65,7 +161,7 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 
 		if q.Downsample != constants.StringsEmpty {
 
-			ds := strings.Split(q.Downsample, "-")
+			ds := strings.SplitN(q.Downsample, "-", 3)
 
 			if len(ds) < 2 {
 				return errValidation(errors.New("invalid downsample format"))
@@ -87,12 +183,50 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 
 		}
 
+		if q.MaxGap != constants.StringsEmpty {
+
+			if q.Downsample == constants.StringsEmpty {
+				return errValidation(errors.New("maxGap configured but no downsample found"))
+			}
+
+			if err := query.checkDuration(q.MaxGap); err != nil {
+				return err
+			}
+		}
+
 		if q.Rate {
 			if err := query.checkRate(q.RateOptions); err != nil {
 				return err
 			}
 		}
 
+		for _, window := range q.WindowFunctions() {
+			if err := query.checkDuration(window); err != nil {
+				return err
+			}
+		}
+
+		for _, window := range q.RollingFunctions() {
+			if err := query.checkDuration(window); err != nil {
+				return err
+			}
+		}
+
+		if q.Ewma != 0 {
+			if err := query.checkSmoothingFactor("ewma alpha", q.Ewma); err != nil {
+				return err
+			}
+		}
+
+		if q.HoltWinters != nil {
+			if err := query.checkSmoothingFactor("holtWinters alpha", q.HoltWinters.Alpha); err != nil {
+				return err
+			}
+			if err := query.checkSmoothingFactor("holtWinters beta", q.HoltWinters.Beta); err != nil {
+				return err
+			}
+		}
+
 		if q.FilterValue != constants.StringsEmpty {
 			q.FilterValue = strings.Replace(q.FilterValue, constants.StringsWhitespace, constants.StringsEmpty, -1)
 			query.Queries[i].FilterValue = q.FilterValue
@@ -122,6 +256,12 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 				query.Queries[i].Order = append(query.Queries[i].Order, "filterValue")
 			}
 
+			for _, oper := range windowOperations {
+				if _, ok := q.WindowFunctions()[oper]; ok {
+					query.Queries[i].Order = append(query.Queries[i].Order, oper)
+				}
+			}
+
 			if q.Downsample != constants.StringsEmpty {
 				query.Queries[i].Order = append(query.Queries[i].Order, "downsample")
 			}
@@ -132,6 +272,12 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 				query.Queries[i].Order = append(query.Queries[i].Order, "rate")
 			}
 
+			for _, oper := range smoothingOperations {
+				if q.hasSmoothing(oper) {
+					query.Queries[i].Order = append(query.Queries[i].Order, oper)
+				}
+			}
+
 		} else {
 
 			orderCheck := make([]string, len(q.Order))
@@ -238,6 +384,26 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 				orderCheck = append(orderCheck[:k], orderCheck[k+1:]...)
 			}
 
+			for _, oper := range windowOperations {
+
+				_, configured := q.WindowFunctions()[oper]
+
+				var err gobol.Error
+				orderCheck, err = query.checkOrder(orderCheck, oper, configured)
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, oper := range smoothingOperations {
+
+				var err gobol.Error
+				orderCheck, err = query.checkOrder(orderCheck, oper, q.hasSmoothing(oper))
+				if err != nil {
+					return err
+				}
+			}
+
 			if len(orderCheck) != 0 {
 				return errValidation(fmt.Errorf("invalid operations in order array %v", orderCheck))
 			}
@@ -248,6 +414,50 @@ func (query TSDBqueryPayload) Validate() gobol.Error {
 			return err
 		}
 
+		if q.TimeShift != constants.StringsEmpty {
+			if err := query.checkDuration(q.TimeShift); err != nil {
+				return err
+			}
+		} else if q.Compare {
+			return errValidation(errors.New("compare configured but no timeShift found"))
+		}
+
+	}
+
+	return nil
+}
+
+// checkOrder - checks that a configured operation is in the order array only once and removes it from there
+func (query TSDBqueryPayload) checkOrder(orderCheck []string, oper string, configured bool) ([]string, gobol.Error) {
+
+	k := 0
+	occur := 0
+	for j, order := range orderCheck {
+		if order == oper {
+			k = j
+			occur++
+		}
+	}
+
+	if configured && occur == 0 {
+		return orderCheck, errValidation(fmt.Errorf("%s configured but no %s found in order array", oper, oper))
+	}
+
+	if occur > 1 {
+		return orderCheck, errValidation(fmt.Errorf("more than one %s found in order array", oper))
+	}
+
+	if occur == 1 {
+		orderCheck = append(orderCheck[:k], orderCheck[k+1:]...)
+	}
+
+	return orderCheck, nil
+}
+
+func (query TSDBqueryPayload) checkSmoothingFactor(name string, factor float64) gobol.Error {
+
+	if factor <= 0 || factor > 1 {
+		return errValidation(fmt.Errorf("%s needs to be bigger than 0 and at most 1", name))
 	}
 
 	return nil
@@ -300,6 +510,15 @@ func (query TSDBqueryPayload) checkDownsampler(DSr string) gobol.Error {
 
 func (query TSDBqueryPayload) checkFiller(DSf string) gobol.Error {
 
+	if strings.HasPrefix(DSf, "value(") {
+		if _, err := ParseFillValue(DSf); err != nil {
+			return errFiller(fmt.Sprintf("Invalid fill value %s", DSf))
+		}
+		DSf = "value"
+	} else if DSf == "value" {
+		return errFiller("Invalid fill value, the fixed value fill must be written as value(x)")
+	}
+
 	ok := false
 
 	for _, vDSf := range config.GetFillers() {
@@ -316,6 +535,16 @@ func (query TSDBqueryPayload) checkFiller(DSf string) gobol.Error {
 	return nil
 }
 
+// ParseFillValue - returns the number of a fixed value fill policy, written as value(x)
+func ParseFillValue(fill string) (float64, error) {
+
+	if !strings.HasPrefix(fill, "value(") || !strings.HasSuffix(fill, ")") {
+		return 0, fmt.Errorf("invalid fixed value fill %s", fill)
+	}
+
+	return strconv.ParseFloat(fill[6:len(fill)-1], 64)
+}
+
 func (query TSDBqueryPayload) checkFilter(filters []TSDBfilter) gobol.Error {
 
 	vFilters := config.GetFilters()
@@ -442,11 +671,13 @@ type TSDBTag struct {
 
 // TSDBpoint - an opentsdb point
 type TSDBpoint struct {
-	Metric    string
-	Timestamp int64
-	Value     *float64
-	Text      string
-	Tags      []TSDBTag
-	TTL       int
-	Keyset    string
+	Metric     string
+	Timestamp  int64
+	Value      *float64
+	Text       string
+	Histogram  *sketch.Sketch `json:"-"`
+	Tags       []TSDBTag
+	TTL        int
+	Keyset     string
+	TTLCoerced bool `json:"-"`
 }
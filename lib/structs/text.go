@@ -0,0 +1,101 @@
+package structs
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/uol/gobol"
+
+	"github.com/uol/mycenae/lib/constants"
+)
+
+// TextQueryPayload - the text series query
+type TextQueryPayload struct {
+	Relative     string      `json:"relative"`
+	Start        int64       `json:"start"`
+	End          int64       `json:"end"`
+	MsResolution bool        `json:"msResolution"`
+	EstimateSize bool        `json:"estimateSize"`
+	Queries      []TextQuery `json:"queries"`
+}
+
+// TextQuery - a text metric query, the texts can be filtered by a regexp (search) or a substring (contains),
+// the points of each group are returned in time order, limit points per page starting after the cursor,
+// when count is set only the number of texts per count interval is returned
+type TextQuery struct {
+	Metric   string       `json:"metric"`
+	Filters  []TSDBfilter `json:"filters"`
+	Search   string       `json:"search"`
+	Contains string       `json:"contains"`
+	Limit    int          `json:"limit"`
+	Cursor   string       `json:"cursor"`
+	Count    string       `json:"count"`
+}
+
+// Validate - validates the text query
+func (query TextQueryPayload) Validate() gobol.Error {
+
+	checker := TSDBqueryPayload{}
+
+	if query.Relative != constants.StringsEmpty {
+		if err := checker.checkDuration(query.Relative); err != nil {
+			return err
+		}
+	}
+
+	if len(query.Queries) == 0 {
+		return errValidation(errors.New("At least one query should be present"))
+	}
+
+	for _, q := range query.Queries {
+
+		if err := checker.checkField("metric", q.Metric); err != nil {
+			return err
+		}
+
+		if err := checker.checkFilter(q.Filters); err != nil {
+			return err
+		}
+
+		if q.Search != constants.StringsEmpty && q.Contains != constants.StringsEmpty {
+			return errValidation(errors.New("search and contains can not be used together"))
+		}
+
+		if q.Search != constants.StringsEmpty {
+			if _, err := regexp.Compile(q.Search); err != nil {
+				return errValidation(err)
+			}
+		}
+
+		if q.Limit < 0 {
+			return errValidation(errors.New("limit needs to be bigger than zero"))
+		}
+
+		if q.Count != constants.StringsEmpty {
+
+			if err := checker.checkDuration(q.Count); err != nil {
+				return err
+			}
+
+			if q.Cursor != constants.StringsEmpty {
+				return errValidation(errors.New("cursor can not be used in count mode"))
+			}
+		}
+	}
+
+	return nil
+}
+
+// SearchRegexp - returns the regexp matching the searched texts, nil when there is no search
+func (q TextQuery) SearchRegexp() *regexp.Regexp {
+
+	if q.Contains != constants.StringsEmpty {
+		return regexp.MustCompile(regexp.QuoteMeta(q.Contains))
+	}
+
+	if q.Search != constants.StringsEmpty {
+		return regexp.MustCompile(q.Search)
+	}
+
+	return nil
+}
@@ -19,14 +19,83 @@ type DSoptions struct {
 	Unit       string `json:"unit"`
 	Value      int    `json:"value"`
 	Fill       string
+	FillValue  float64
+	MaxGap     int64
 }
 
 type DataOperations struct {
 	Downsample  Downsample
 	Merge       string
+	Interpolate bool
 	Rate        RateOperation
 	Order       []string
 	FilterValue FilterValueOperation
+	Increase    WindowOperation
+	Delta       WindowOperation
+	Irate       WindowOperation
+	Deriv       WindowOperation
+
+	MovingAverage RollingOperation
+	RollingSum    RollingOperation
+	RollingMax    RollingOperation
+	RollingMin    RollingOperation
+	Ewma          SmoothingOperation
+	HoltWinters   SmoothingOperation
+}
+
+// WindowOperation - an increase, delta, irate or deriv computed for each window of the serie
+type WindowOperation struct {
+	Enabled bool
+	Unit    string
+	Value   int
+	Options TSDBrateOptions
+}
+
+// Window - returns the window operation with the given name
+func (opers DataOperations) Window(name string) WindowOperation {
+
+	switch name {
+	case "increase":
+		return opers.Increase
+	case "delta":
+		return opers.Delta
+	case "irate":
+		return opers.Irate
+	case "deriv":
+		return opers.Deriv
+	}
+
+	return WindowOperation{}
+}
+
+// RollingOperation - an average, sum, max or min of the points inside the window (in ms) ending on each point
+type RollingOperation struct {
+	Enabled bool
+	Window  int64
+}
+
+// Rolling - returns the rolling operation with the given name
+func (opers DataOperations) Rolling(name string) RollingOperation {
+
+	switch name {
+	case "movingAverage":
+		return opers.MovingAverage
+	case "rollingSum":
+		return opers.RollingSum
+	case "rollingMax":
+		return opers.RollingMax
+	case "rollingMin":
+		return opers.RollingMin
+	}
+
+	return RollingOperation{}
+}
+
+// SmoothingOperation - an exponential smoothing of the serie, beta is only used by holtWinters
+type SmoothingOperation struct {
+	Enabled bool
+	Alpha   float64
+	Beta    float64
 }
 
 type RateOperation struct {
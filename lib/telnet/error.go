@@ -29,6 +29,7 @@ const (
 	cMsgFInvalidTags        string = "tags validation failure: %s"
 	cMsgFPointCreationError string = "point creation error: %s"
 	cMsgFInvalidLineContent string = "error reading line content: %s"
+	cMsgFQuotaExceeded      string = "keyset quota exceeded: %s"
 	cMsgEmptyLine           string = "empty line received"
 )
 
@@ -215,13 +215,7 @@ func (nh *NetdataHandler) Handle(line, ip string) bool {
 		Tags:  []structs.TSDBTag{},
 	}
 
-	point.Timestamp, gerr = nh.validationService.ValidateTimestamp(pointJSON.Timestamp)
-	if gerr != nil {
-		logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, pointJSON.HostName, cMsgFInvalidTimestamp, point.Timestamp)
-		return false
-	}
-
-	ttlFound := false
+	ttlValue := constants.StringsEmpty
 	ksidFound := false
 	tagMatches := nh.tagsRegexp.FindAllStringSubmatch(pointJSON.DefaultTags, -1)
 	metricPropertyReplacement := propChartID
@@ -243,16 +237,11 @@ func (nh *NetdataHandler) Handle(line, ip string) bool {
 
 				switch tagMatches[i][1] {
 				case constants.StringsTTL:
-					ttl, ttlStr, gerr := nh.validationService.ParseTTL(tagMatches[i][2])
-					if gerr != nil {
-						logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, ip, cMsgFInvalidTTL, line)
-						continue
-					}
-					point.TTL = ttl
-					tagMatches[i][2] = ttlStr
-					ttlFound = true
+					// the ttl tag is added when the keyset policy is applied
+					ttlValue = tagMatches[i][2]
+					continue
 				case constants.StringsKSID:
-					gerr = nh.validationService.ValidateKeyset(tagMatches[i][2])
+					tagMatches[i][2], gerr = nh.validationService.ResolveKeyset(tagMatches[i][2])
 					if gerr != nil {
 						logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, ip, cMsgFInvalidKSID, line)
 						continue
@@ -288,10 +277,21 @@ func (nh *NetdataHandler) Handle(line, ip string) bool {
 		return false
 	}
 
-	if !ttlFound {
-		ttlTag, ttl := nh.validationService.GetDefaultTTLTag()
-		point.Tags = append(point.Tags, *ttlTag)
-		point.TTL = ttl
+	// the precision is the one of the resolved keyset, not of the alias
+	point.Timestamp, gerr = nh.validationService.ValidateTimestamp(pointJSON.Timestamp, point.Keyset)
+	if gerr != nil {
+		logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, pointJSON.HostName, cMsgFInvalidTimestamp, line)
+		return false
+	}
+
+	point.TTLCoerced, gerr = nh.validationService.ApplyTTLPolicy(&point, ttlValue)
+	if gerr != nil {
+		logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, pointJSON.HostName, cMsgFInvalidTTL, line)
+		return false
+	}
+
+	if point.TTLCoerced {
+		nh.validationService.StatsValidationError(cFuncHandle, point.Keyset, pointJSON.HostName, nh.GetSourceType(), validation.ErrTTLCoerced)
 	}
 
 	point.Tags = append(point.Tags, structs.TSDBTag{Name: propChartID, Value: pointJSON.ChartID})
@@ -360,13 +360,17 @@ func (nh *NetdataHandler) Handle(line, ip string) bool {
 		return false
 	}
 
-	packet, gerr := nh.collector.MakePacket(&point, true)
+	packet, gerr := nh.collector.MakePacket(&point, constants.PointTypeNumber)
 	if gerr != nil {
 		logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, pointJSON.HostName, cMsgFPointCreationError, line)
 		return false
 	}
 
-	nh.collector.HandlePacket(packet, nh.GetSourceType())
+	gerr = nh.collector.HandlePacket(packet, nh.GetSourceType())
+	if gerr != nil {
+		logAndStats(nh, gerr, cFuncHandle, pointJSON.Keyset, pointJSON.HostName, cMsgFQuotaExceeded, line)
+		return false
+	}
 
 	return true
 }
@@ -74,23 +74,18 @@ func (otsdbh *OpenTSDBHandler) Handle(line string, ip string) bool {
 	var gerr gobol.Error
 	point := structs.TSDBpoint{}
 	point.Tags = []structs.TSDBTag{}
-	ttlFound := false
+	ttlValue := constants.StringsEmpty
 	ksidFound := false
 
 	for i := 0; i < len(tagMatches); i++ {
 
 		switch tagMatches[i][1] {
 		case constants.StringsTTL:
-			ttl, ttlStr, gerr := otsdbh.validationService.ParseTTL(tagMatches[i][2])
-			if gerr != nil {
-				logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFInvalidTTL, line)
-				return false
-			}
-			point.TTL = ttl
-			tagMatches[i][2] = ttlStr
-			ttlFound = true
+			// the ttl tag is added when the keyset policy is applied
+			ttlValue = tagMatches[i][2]
+			continue
 		case constants.StringsKSID:
-			gerr = otsdbh.validationService.ValidateKeyset(tagMatches[i][2])
+			tagMatches[i][2], gerr = otsdbh.validationService.ResolveKeyset(tagMatches[i][2])
 			if gerr != nil {
 				logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFInvalidKSID, line)
 				return false
@@ -130,10 +125,14 @@ func (otsdbh *OpenTSDBHandler) Handle(line string, ip string) bool {
 		return false
 	}
 
-	if !ttlFound {
-		ttlTag, ttl := otsdbh.validationService.GetDefaultTTLTag()
-		point.Tags = append(point.Tags, *ttlTag)
-		point.TTL = ttl
+	point.TTLCoerced, gerr = otsdbh.validationService.ApplyTTLPolicy(&point, ttlValue)
+	if gerr != nil {
+		logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFInvalidTTL, line)
+		return false
+	}
+
+	if point.TTLCoerced {
+		otsdbh.validationService.StatsValidationError(cFuncHandle, point.Keyset, ip, otsdbh.GetSourceType(), validation.ErrTTLCoerced)
 	}
 
 	gerr = otsdbh.validationService.ValidateProperty(matches[1], validation.MetricType)
@@ -150,7 +149,7 @@ func (otsdbh *OpenTSDBHandler) Handle(line string, ip string) bool {
 		return false
 	}
 
-	point.Timestamp, gerr = otsdbh.validationService.ValidateTimestamp(point.Timestamp)
+	point.Timestamp, gerr = otsdbh.validationService.ValidateTimestamp(point.Timestamp, point.Keyset)
 	if gerr != nil {
 		logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFInvalidTimestamp, line)
 		return false
@@ -164,13 +163,17 @@ func (otsdbh *OpenTSDBHandler) Handle(line string, ip string) bool {
 
 	point.Value = &value
 
-	validatedPoint, gerr := otsdbh.collector.MakePacket(&point, true)
+	validatedPoint, gerr := otsdbh.collector.MakePacket(&point, constants.PointTypeNumber)
 	if err != nil {
 		logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFPointCreationError, line)
 		return false
 	}
 
-	otsdbh.collector.HandlePacket(validatedPoint, otsdbh.GetSourceType())
+	gerr = otsdbh.collector.HandlePacket(validatedPoint, otsdbh.GetSourceType())
+	if gerr != nil {
+		logAndStats(otsdbh, gerr, cFuncHandle, keyset, ip, cMsgFQuotaExceeded, line)
+		return false
+	}
 
 	return true
 }
@@ -10,6 +10,19 @@ import (
  */
 func MilliToSeconds(t int64) (int64, error) {
 
+	msTime, err := ToMilli(t)
+	if err != nil {
+		return t, err
+	}
+
+	return (msTime / 1000) * 1000, nil
+}
+
+/**
+* Converts the time to milliseconds keeping the sub-second part.
+ */
+func ToMilli(t int64) (int64, error) {
+
 	msTime := t
 
 	i := 0
@@ -25,9 +38,7 @@ func MilliToSeconds(t int64) (int64, error) {
 		return t, errors.New("the maximum resolution supported for timestamp is milliseconds")
 	}
 
-	if i > 10 {
-		return (t / 1000) * 1000, nil
-	} else if i < 10 {
+	if i < 10 {
 		return t * 1000, nil
 	}
 
@@ -45,3 +56,15 @@ func GetTimeNoMillis() int64 {
 
 	return int64(tv.Sec) * 1e3
 }
+
+/**
+* Returns the time in milliseconds.
+ */
+func GetTimeMillis() int64 {
+
+	var tv syscall.Timeval
+
+	syscall.Gettimeofday(&tv)
+
+	return int64(tv.Sec)*1e3 + int64(tv.Usec)/1e3
+}
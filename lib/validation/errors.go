@@ -32,6 +32,18 @@ func NewValidationError(function, message string, err error, errCode string) gob
 	)
 }
 
+// errQuotaExceeded - too many requests error of the keyset quotas
+func errQuotaExceeded(function, message, errCode string) gobol.Error {
+	return tserr.NewErrorWithCode(
+		fmt.Errorf(message),
+		message,
+		cPackage,
+		function,
+		http.StatusTooManyRequests,
+		errCode,
+	)
+}
+
 // errCommonValidation - bad request error without error
 func errCommonValidation(function, message, errCode string) gobol.Error {
 	return NewValidationError(function, message, fmt.Errorf(message), errCode)
@@ -56,9 +68,15 @@ var (
 	ErrInvalidTagValue     = errCommonValidation("ValidateProperty", `Wrong Format: Tag value has a invalid format.`, "C16")
 	ErrInvalidMetric       = errCommonValidation("ValidateProperty", `Wrong Format: Field "metric" (%s) is not well formed.`, "C17")
 	ErrInvalidPropertyType = errCommonValidation("ValidateProperty", `Property type is not mapped`, "C18")
-	ErrInvalidTTLValue     = errCommonValidation("ParseTTL", `Wrong Format: Tag "ttl" must be a positive number.`, "C19")
+	ErrInvalidTTLValue     = errCommonValidation("ApplyTTLPolicy", `Wrong Format: Tag "ttl" must be a positive number.`, "C19")
 	ErrInexistentKeyset    = errCommonValidation("ValidateKeyset", `Keyset not exists.`, "C20")
 	ErrMalformedJSON       = errCommonValidation("ParsePoint", `JSON is malformed.`, "C21")
 	ErrInvalidTimestamp    = errCommonValidation("ValidateTimestamp", `Wrong Format: timestamp has a invalid format.`, "C22")
 	ErrReadingJSONBytes    = errCommonValidation("ParsePointArray", "Error reading JSON bytes.", "C23")
+	ErrParsingHistogram    = errCommonValidation("ParsePoint", `Error parsing "histogram" from JSON, it must map values to positive counts.`, "C24")
+	ErrHistogramExpected   = errCommonValidation("ValidateType", `Wrong Format: Field "histogram" is required.`, "C25")
+	ErrTTLCoerced          = errCommonValidation("ApplyTTLPolicy", `Tag "ttl" is not allowed in the keyset, the default TTL was used.`, "C26")
+	ErrTTLRejected         = errCommonValidation("ApplyTTLPolicy", `Tag "ttl" is not allowed in the keyset.`, "C27")
+	ErrPointsQuotaExceeded = errQuotaExceeded("CheckPointQuota", `The keyset exceeded its points per second quota.`, "C28")
+	ErrSeriesQuotaExceeded = errQuotaExceeded("CheckSerieQuota", `The keyset exceeded its series quota.`, "C29")
 )
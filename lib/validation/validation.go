@@ -9,6 +9,7 @@ import (
 	"github.com/uol/logh"
 	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/persistence"
 	"github.com/uol/mycenae/lib/structs"
 	"github.com/uol/mycenae/lib/utils"
 	tlmanager "github.com/uol/timelinemanager"
@@ -31,27 +32,45 @@ const (
 	MetricType PropertyType = 3
 )
 
+// Keysets - the keyset aliases and policies
+type Keysets interface {
+	// Resolve - returns the target keyset of the alias or the keyset itself
+	Resolve(keyset string) string
+	// MirrorTarget - returns the keyset also receiving the writes of the keyset
+	MirrorTarget(keyset string) (string, bool)
+	// TTLPolicy - returns the default and allowed TTLs of the keyset and if the other TTLs are rejected
+	TTLPolicy(keyset string) (int, []int, bool)
+	// AllowPoint - counts a point of the keyset, false when it exceeded its points per second
+	AllowPoint(keyset string) bool
+	// AllowSerie - counts a new serie of the keyset, false when it exceeded its series
+	AllowSerie(keyset string) bool
+}
+
 // Service - the validation structure
 type Service struct {
 	configuration   *structs.ValidationConfiguration
 	propertyRegexp  *regexp.Regexp
-	keyspaceTTLMap  map[int]string
+	keyspaceTTLMap  *persistence.KeyspaceTTLMap
 	metadataStorage *metadata.Storage
 	logger          *logh.ContextualLogger
-	defaultTTLStr   string
 	defaultTTLTag   structs.TSDBTag
 	keysetRegexp    *regexp.Regexp
 	timelineManager *tlmanager.Instance
+	keysets         Keysets
 }
 
 // New - creates a new validation instance
-func New(configuration *structs.ValidationConfiguration, metadataStorage *metadata.Storage, keyspaceTTLMap map[int]string, timelineManager *tlmanager.Instance) (*Service, error) {
+func New(configuration *structs.ValidationConfiguration, metadataStorage *metadata.Storage, keyspaceTTLMap *persistence.KeyspaceTTLMap, timelineManager *tlmanager.Instance, keysets Keysets) (*Service, error) {
 
 	if configuration == nil {
 		return nil, fmt.Errorf("validation configuration is null")
 	}
 
-	defaultTTLStr := strconv.Itoa(configuration.DefaultTTL)
+	for keyset, precision := range configuration.KeysetPrecision {
+		if precision != constants.TimestampPrecisionSecond && precision != constants.TimestampPrecisionMillisecond {
+			return nil, fmt.Errorf("invalid timestamp precision '%s' for keyset '%s', it must be 's' or 'ms'", precision, keyset)
+		}
+	}
 
 	s := &Service{
 		configuration:   configuration,
@@ -60,9 +79,9 @@ func New(configuration *structs.ValidationConfiguration, metadataStorage *metada
 		keyspaceTTLMap:  keyspaceTTLMap,
 		metadataStorage: metadataStorage,
 		logger:          logh.CreateContextualLogger(constants.StringsPKG, "validation"),
-		defaultTTLStr:   defaultTTLStr,
-		defaultTTLTag:   structs.TSDBTag{Name: constants.StringsTTL, Value: defaultTTLStr},
+		defaultTTLTag:   structs.TSDBTag{Name: constants.StringsTTL, Value: strconv.Itoa(configuration.DefaultTTL)},
 		timelineManager: timelineManager,
+		keysets:         keysets,
 	}
 
 	s.storeValidationErrorCount()
@@ -71,13 +90,18 @@ func New(configuration *structs.ValidationConfiguration, metadataStorage *metada
 }
 
 // ValidateType - validates the point type
-func (v *Service) ValidateType(p *structs.TSDBpoint, number bool) gobol.Error {
+func (v *Service) ValidateType(p *structs.TSDBpoint, pointType constants.PointType) gobol.Error {
 
-	if number {
+	switch pointType {
+	case constants.PointTypeNumber:
 		if p.Value == nil {
 			return ErrNumberTypeExpected
 		}
-	} else {
+	case constants.PointTypeHistogram:
+		if p.Histogram == nil || p.Histogram.Count() == 0 {
+			return ErrHistogramExpected
+		}
+	default:
 		if p.Text == constants.StringsEmpty {
 			return ErrTextTypeExpected
 		}
@@ -142,41 +166,123 @@ func (v *Service) ValidateTags(p *structs.TSDBpoint) gobol.Error {
 	return nil
 }
 
-// ValidateKeyset - validates the keyset
-func (v *Service) ValidateKeyset(keyset string) gobol.Error {
+// ResolveKeyset - validates the keyset and returns it or the target keyset when it is an alias
+func (v *Service) ResolveKeyset(keyset string) (string, gobol.Error) {
 
 	if keyset == constants.StringsEmpty {
-		return ErrNoKeysetTag
+		return constants.StringsEmpty, ErrNoKeysetTag
 	}
 
 	if !v.keysetRegexp.MatchString(keyset) {
-		return ErrInvalidKeysetFormat
+		return constants.StringsEmpty, ErrInvalidKeysetFormat
 	}
 
+	keyset = v.keysets.Resolve(keyset)
+
 	keysetExists := v.metadataStorage.CheckKeyset(keyset)
 	if !keysetExists {
-		return ErrInexistentKeyset
+		return constants.StringsEmpty, ErrInexistentKeyset
+	}
+
+	return keyset, nil
+}
+
+// MirrorKeyset - returns the keyset also receiving the writes while a keyset is renamed
+func (v *Service) MirrorKeyset(keyset string) (string, bool) {
+
+	target, ok := v.keysets.MirrorTarget(keyset)
+	if !ok || !v.metadataStorage.CheckKeyset(target) {
+		return constants.StringsEmpty, false
+	}
+
+	return target, true
+}
+
+// CheckPointQuota - counts a point of the keyset against its points per second quota
+func (v *Service) CheckPointQuota(keyset string) gobol.Error {
+
+	if !v.keysets.AllowPoint(keyset) {
+		return ErrPointsQuotaExceeded
 	}
 
 	return nil
 }
 
-// ParseTTL - parses the TTL and returns its int value
-func (v *Service) ParseTTL(value string) (int, string, gobol.Error) {
-	if value == constants.StringsEmpty {
-		return v.configuration.DefaultTTL, v.defaultTTLStr, nil
+// CheckSerieQuota - counts a new serie of the keyset against its series quota
+func (v *Service) CheckSerieQuota(keyset string) gobol.Error {
+
+	if !v.keysets.AllowSerie(keyset) {
+		return ErrSeriesQuotaExceeded
 	}
 
-	ttl, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, constants.StringsEmpty, ErrInvalidTTLValue
+	return nil
+}
+
+// ApplyTTLPolicy - sets the point TTL and its tag following the keyset policy: the TTLs without keyspace or
+// not allowed in the keyset are replaced by the default TTL or rejected, the default TTL is used when none
+// is requested and the point is rejected when it has no keyspace. The keyset must be set in the point and
+// the returned flag tells if the TTL was replaced
+func (v *Service) ApplyTTLPolicy(p *structs.TSDBpoint, requested string) (bool, gobol.Error) {
+
+	defaultTTL, allowedTTLs, reject := v.keysets.TTLPolicy(p.Keyset)
+	if defaultTTL == 0 {
+		defaultTTL = v.configuration.DefaultTTL
 	}
 
-	if _, ok := v.keyspaceTTLMap[ttl]; !ok {
-		return v.configuration.DefaultTTL, v.defaultTTLStr, nil
+	ttl := defaultTTL
+	coerced := false
+
+	if requested != constants.StringsEmpty {
+
+		var err error
+		ttl, err = strconv.Atoi(requested)
+		if err != nil {
+			return false, ErrInvalidTTLValue
+		}
+
+		if !v.isTTLAllowed(ttl, allowedTTLs) {
+			if reject {
+				return false, ErrTTLRejected
+			}
+			ttl = defaultTTL
+			coerced = true
+		}
 	}
 
-	return ttl, value, nil
+	// the default TTL may have lost its keyspace after the policy was saved
+	if _, ok := v.keyspaceTTLMap.Get(ttl); !ok {
+		return false, ErrTTLRejected
+	}
+
+	p.TTL = ttl
+
+	if ttl == v.configuration.DefaultTTL {
+		p.Tags = append(p.Tags, v.defaultTTLTag)
+	} else {
+		p.Tags = append(p.Tags, structs.TSDBTag{Name: constants.StringsTTL, Value: strconv.Itoa(ttl)})
+	}
+
+	return coerced, nil
+}
+
+// isTTLAllowed - checks if the TTL has a keyspace and is one of the allowed TTLs, all are allowed when none is set
+func (v *Service) isTTLAllowed(ttl int, allowedTTLs []int) bool {
+
+	if _, ok := v.keyspaceTTLMap.Get(ttl); !ok {
+		return false
+	}
+
+	if len(allowedTTLs) == 0 {
+		return true
+	}
+
+	for _, allowed := range allowedTTLs {
+		if allowed == ttl {
+			return true
+		}
+	}
+
+	return false
 }
 
 const (
@@ -184,22 +290,34 @@ const (
 	cMsgParseTimestamp  string = "Error parsing timestamp."
 )
 
-// ValidateTimestamp - parses the timestamp
-func (v *Service) ValidateTimestamp(timestamp int64) (int64, gobol.Error) {
+// IsMillisecondKeyset - checks if the keyset keeps the milliseconds of its timestamps
+func (v *Service) IsMillisecondKeyset(keyset string) bool {
+
+	return v.configuration.KeysetPrecision[keyset] == constants.TimestampPrecisionMillisecond
+}
+
+// ValidateTimestamp - parses the timestamp, it is truncated to seconds unless the keyset has millisecond precision
+func (v *Service) ValidateTimestamp(timestamp int64, keyset string) (int64, gobol.Error) {
+
+	ms := v.IsMillisecondKeyset(keyset)
+
 	if timestamp == 0 {
+		if ms {
+			return utils.GetTimeMillis(), nil
+		}
 		return utils.GetTimeNoMillis(), nil
 	}
 
-	truncated, err := utils.MilliToSeconds(timestamp)
+	var err error
+	if ms {
+		timestamp, err = utils.ToMilli(timestamp)
+	} else {
+		timestamp, err = utils.MilliToSeconds(timestamp)
+	}
+
 	if err != nil {
 		return 0, ErrInvalidTimestamp
 	}
 
-	return truncated, nil
-}
-
-// GetDefaultTTLTag - returns the default TTL tag and its integer value
-func (v *Service) GetDefaultTTLTag() (*structs.TSDBTag, int) {
-
-	return &v.defaultTTLTag, v.configuration.DefaultTTL
+	return timestamp, nil
 }
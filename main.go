@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"syscall"
+	"time"
 
 	"github.com/uol/logh"
 
@@ -18,15 +19,19 @@ import (
 	"github.com/uol/gobol/cassandra"
 	"github.com/uol/gobol/loader"
 
+	"github.com/uol/mycenae/lib/catalog"
 	"github.com/uol/mycenae/lib/collector"
 	"github.com/uol/mycenae/lib/constants"
 	"github.com/uol/mycenae/lib/keyset"
 	"github.com/uol/mycenae/lib/keyspace"
 	"github.com/uol/mycenae/lib/memcached"
 	"github.com/uol/mycenae/lib/metadata"
+	"github.com/uol/mycenae/lib/migration"
 	"github.com/uol/mycenae/lib/persistence"
 	"github.com/uol/mycenae/lib/plot"
+	"github.com/uol/mycenae/lib/reindex"
 	"github.com/uol/mycenae/lib/rest"
+	"github.com/uol/mycenae/lib/rollup"
 	"github.com/uol/mycenae/lib/structs"
 	"github.com/uol/mycenae/lib/telnet"
 	"github.com/uol/mycenae/lib/telnetmgr"
@@ -81,9 +86,11 @@ func main() {
 	scyllaConn := createScyllaConnection(&settings.Cassandra)
 	memcachedConn := createMemcachedConnection(&settings.Memcached, timelineManager)
 	metadataStorage := createMetadataStorageService(&settings.MetadataSettings, timelineManager, memcachedConn)
-	scyllaStorageService, keyspaceTTLMap := createScyllaStorageService(settings, devMode, timelineManager, scyllaConn, metadataStorage)
-	validationService := createValidation(settings, metadataStorage, keyspaceTTLMap, timelineManager)
-	collectorService := createCollectorService(settings, timelineManager, metadataStorage, scyllaConn, validationService, keyspaceTTLMap)
+	rollupResolutions, rollupCompactor := createRollupCompactor(&settings.Rollups, settings.Cassandra.Keyspace, timelineManager, scyllaConn)
+	scyllaStorageService, keyspaceTTLMap, keyspaceLayouts := createScyllaStorageService(settings, devMode, timelineManager, scyllaConn, metadataStorage, rollupResolutions)
+	keysetManager := createKeysetManager(settings, metadataStorage, scyllaConn, keyspaceTTLMap)
+	validationService := createValidation(settings, metadataStorage, keyspaceTTLMap, timelineManager, keysetManager)
+	collectorService := createCollectorService(settings, timelineManager, metadataStorage, scyllaConn, validationService, keyspaceTTLMap, keyspaceLayouts, rollupCompactor)
 	telnetManager := createTelnetManager(settings, collectorService, timelineManager, validationService)
 
 	err = timelineManager.Start()
@@ -94,11 +101,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	keyspaceManager := createKeyspaceManager(settings, devMode, timelineManager, scyllaStorageService)
-	keysetManager := createKeysetManager(settings, metadataStorage)
-	plotService := createPlotService(settings, timelineManager, metadataStorage, scyllaConn, keyspaceTTLMap)
+	keyspaceManager := createKeyspaceManager(settings, devMode, timelineManager, scyllaStorageService, keyspaceTTLMap, keyspaceLayouts, keysetManager, collectorService.BlocksEnabled())
+	metricCatalog := createMetricCatalog(settings, timelineManager, scyllaConn, metadataStorage)
+	plotService := createPlotService(settings, timelineManager, metadataStorage, scyllaConn, keyspaceTTLMap, keyspaceLayouts, rollupCompactor, memcachedConn, metricCatalog)
 	udpServer := createUDPServer(&settings.UDPserver, collectorService, timelineManager)
-	restServer := createRESTserver(settings, timelineManager, plotService, collectorService, keyspaceManager, keysetManager, memcachedConn, telnetManager)
+	reindexJob := createReindexJob(settings, scyllaConn, metadataStorage, keyspaceLayouts, rollupResolutions)
+	metadataMigration := createMetadataMigration(settings, metadataStorage, collectorService, validationService)
+	restServer := createRESTserver(settings, timelineManager, plotService, collectorService, keyspaceManager, keysetManager, memcachedConn, telnetManager, metricCatalog, reindexJob, metadataMigration)
+
+	startMetadataReaper(metadataStorage, keyspaceTTLMap, rollupResolutions)
 
 	if logh.InfoEnabled {
 		logger.Info().Msg("mycenae started successfully")
@@ -143,6 +154,50 @@ func main() {
 		logger.Info().Msg("opentsdb telnet manager stopped")
 	}
 
+	if logh.InfoEnabled {
+		logger.Info().Msg("stopping keyset and keyspace refresh")
+	}
+
+	keysetManager.Stop()
+	keyspaceManager.Stop()
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("keyset and keyspace refresh stopped")
+	}
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("stopping collector service")
+	}
+
+	collectorService.Stop()
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("collector service stopped")
+	}
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("stopping metadata indexer")
+	}
+
+	metadataStorage.Shutdown()
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("metadata indexer stopped")
+	}
+
+	if rollupCompactor != nil {
+
+		if logh.InfoEnabled {
+			logger.Info().Msg("stopping rollup compactor")
+		}
+
+		rollupCompactor.Stop()
+
+		if logh.InfoEnabled {
+			logger.Info().Msg("rollup compactor stopped")
+		}
+	}
+
 	if logh.InfoEnabled {
 		logger.Info().Msg("stopping statistics service")
 	}
@@ -255,8 +310,43 @@ func createMetadataStorageService(conf *metadata.Settings, timelineManager *tlma
 	return metaStorage
 }
 
+// createRollupCompactor - creates the rollup compactor and starts it, it returns a nil compactor if there are no rollups configured
+func createRollupCompactor(conf *rollup.Configuration, ksAdmin string, timelineManager *tlmanager.Instance, scyllaConn *gocql.Session) ([]rollup.Resolution, *rollup.Compactor) {
+
+	resolutions, err := rollup.ParseResolutions(conf)
+	if err != nil {
+		if logh.FatalEnabled {
+			logger.Fatal().Err(err).Msg("error parsing rollup resolutions")
+		}
+		os.Exit(1)
+	}
+
+	compactor, err := rollup.NewCompactor(conf, resolutions, scyllaConn, ksAdmin, timelineManager)
+	if err != nil {
+		if logh.FatalEnabled {
+			logger.Fatal().Err(err).Msg("error creating rollup compactor")
+		}
+		os.Exit(1)
+	}
+
+	if compactor == nil {
+		if logh.InfoEnabled {
+			logger.Info().Msg("no rollups configured")
+		}
+		return nil, nil
+	}
+
+	compactor.Start()
+
+	if logh.InfoEnabled {
+		logger.Info().Msgf("rollup compactor was created with %d resolutions", len(resolutions))
+	}
+
+	return resolutions, compactor
+}
+
 // createScyllaStorageService - creates the scylla storage service
-func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineManager *tlmanager.Instance, scyllaConn *gocql.Session, metadataStorage *metadata.Storage) (*persistence.Storage, map[int]string) {
+func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineManager *tlmanager.Instance, scyllaConn *gocql.Session, metadataStorage *metadata.Storage, rollupResolutions []rollup.Resolution) (*persistence.Storage, *persistence.KeyspaceTTLMap, *persistence.KeyspaceLayouts) {
 
 	storage, err := persistence.NewStorage(
 		conf.Cassandra.Keyspace,
@@ -267,6 +357,7 @@ func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineMa
 		devMode,
 		conf.Validation.DefaultTTL,
 		conf.ClusteringOrder,
+		rollupResolutions,
 	)
 
 	if err != nil {
@@ -283,13 +374,15 @@ func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineMa
 	}
 
 	keyspaceTTLMap := map[int]string{}
+	keyspaceLayouts := map[string]constants.KeyspaceLayout{}
 	for k, ttl := range conf.DefaultKeyspaces {
 		if conf.EnableAutoKeyspaceCreation {
 			gerr := storage.CreateKeyspace(k,
 				conf.DefaultKeyspaceData.Datacenter,
 				conf.DefaultKeyspaceData.Contact,
 				conf.DefaultKeyspaceData.ReplicationFactor,
-				ttl)
+				ttl,
+				conf.DefaultKeyspaceLayouts[k])
 
 			if gerr != nil && gerr.StatusCode() != http.StatusConflict {
 				if logh.FatalEnabled {
@@ -299,6 +392,45 @@ func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineMa
 			}
 		}
 
+		if gerr := storage.CreateHistogramTable(k, ttl); gerr != nil {
+			if logh.ErrorEnabled {
+				logger.Error().Err(gerr).Msgf("error creating the histogram table of keyspace '%s'", k)
+			}
+		}
+
+		if gerr := storage.CreateSeriesTable(k, ttl); gerr != nil {
+			if logh.ErrorEnabled {
+				logger.Error().Err(gerr).Msgf("error creating the series table of keyspace '%s'", k)
+			}
+		}
+
+		if len(rollupResolutions) > 0 {
+			if gerr := storage.CreateRollupTables(k); gerr != nil {
+				if logh.ErrorEnabled {
+					logger.Error().Err(gerr).Msgf("error creating the rollup tables of keyspace '%s'", k)
+				}
+			}
+		}
+
+		ks, found, gerr := storage.GetKeyspace(k)
+		if gerr != nil {
+			if logh.FatalEnabled {
+				logger.Fatal().Err(gerr).Msgf("error reading the layout of keyspace '%s'", k)
+			}
+			os.Exit(1)
+		}
+
+		if found && ks.Layout == constants.KeyspaceLayoutBlock {
+			keyspaceLayouts[k] = constants.KeyspaceLayoutBlock
+		} else {
+			keyspaceLayouts[k] = constants.KeyspaceLayoutRow
+		}
+
+		// the TTL may have been changed after the keyspace creation
+		if found && ks.TTL > 0 && !devMode {
+			ttl = ks.TTL
+		}
+
 		keyspaceTTLMap[ttl] = k
 	}
 
@@ -306,11 +438,35 @@ func createScyllaStorageService(conf *structs.Settings, devMode bool, timelineMa
 		logger.Info().Msg("scylla storage service was created")
 	}
 
-	return storage, keyspaceTTLMap
+	return storage, persistence.NewKeyspaceTTLMap(keyspaceTTLMap), persistence.NewKeyspaceLayouts(keyspaceLayouts)
+}
+
+// startMetadataReaper - starts the stale series reaper, a serie has no points after the biggest keyspace or rollup ttl,
+// the ttls are read on every run since the keyspaces can be created or changed later
+func startMetadataReaper(metadataStorage *metadata.Storage, keyspaceTTLMap *persistence.KeyspaceTTLMap, rollupResolutions []rollup.Resolution) {
+
+	metadataStorage.StartReaper(func() time.Duration {
+
+		maxTTL := 0
+
+		for ttl := range keyspaceTTLMap.Map() {
+			if ttl > maxTTL {
+				maxTTL = ttl
+			}
+		}
+
+		for _, resolution := range rollupResolutions {
+			if resolution.TTL > maxTTL {
+				maxTTL = resolution.TTL
+			}
+		}
+
+		return time.Duration(maxTTL) * 24 * time.Hour
+	})
 }
 
 // createKeyspaceManager - creates the keyspace manager
-func createKeyspaceManager(conf *structs.Settings, devMode bool, timelineManager *tlmanager.Instance, scyllaStorageService *persistence.Storage) *keyspace.Keyspace {
+func createKeyspaceManager(conf *structs.Settings, devMode bool, timelineManager *tlmanager.Instance, scyllaStorageService *persistence.Storage, keyspaceTTLMap *persistence.KeyspaceTTLMap, keyspaceLayouts *persistence.KeyspaceLayouts, keysetManager *keyset.Manager, blocksEnabled bool) *keyspace.Keyspace {
 
 	keyspaceManager := keyspace.New(
 		timelineManager,
@@ -318,6 +474,13 @@ func createKeyspaceManager(conf *structs.Settings, devMode bool, timelineManager
 		devMode,
 		conf.Validation.DefaultTTL,
 		conf.MaxAllowedTTL,
+		conf.Cassandra.Keyspace,
+		keyspaceTTLMap,
+		keyspaceLayouts,
+		conf.DefaultKeyspaces,
+		conf.KeyspaceRefreshInterval.Duration,
+		keysetManager,
+		blocksEnabled,
 	)
 
 	if logh.InfoEnabled {
@@ -328,9 +491,15 @@ func createKeyspaceManager(conf *structs.Settings, devMode bool, timelineManager
 }
 
 // createKeysetManager - creates a new keyset manager
-func createKeysetManager(conf *structs.Settings, metadataStorage *metadata.Storage) *keyset.Manager {
+func createKeysetManager(conf *structs.Settings, metadataStorage *metadata.Storage, scyllaConn *gocql.Session, keyspaceTTLMap *persistence.KeyspaceTTLMap) *keyset.Manager {
 
-	keyset := keyset.New(metadataStorage, conf.Validation.KeysetNameRegexp)
+	keysetManager, err := keyset.New(conf.Keysets, metadataStorage, conf.Validation.KeysetNameRegexp, scyllaConn, conf.Cassandra.Keyspace, keyspaceTTLMap, conf.Validation.DefaultTTL)
+	if err != nil {
+		if logh.FatalEnabled {
+			logger.Fatal().Err(err).Msg("error creating the keyset manager")
+		}
+		os.Exit(1)
+	}
 
 	jsonStr, _ := json.Marshal(conf.DefaultKeysets)
 	if logh.InfoEnabled {
@@ -338,12 +507,19 @@ func createKeysetManager(conf *structs.Settings, metadataStorage *metadata.Stora
 	}
 
 	for _, v := range conf.DefaultKeysets {
+		if record, ok := keysetManager.Record(v); ok && record.DeletionDate != nil {
+			if logh.WarnEnabled {
+				logger.Warn().Msgf("default keyset '%s' was deleted, restore it to use it", v)
+			}
+			continue
+		}
+
 		exists := metadataStorage.CheckKeyset(v)
 		if !exists {
 			if logh.InfoEnabled {
 				logger.Info().Msgf("creating default keyset '%s'", v)
 			}
-			err := keyset.Create(v)
+			err := keysetManager.Create(&keyset.Record{Name: v})
 			if err != nil {
 				if logh.FatalEnabled {
 					logger.Fatal().Err(err).Msgf("error creating keyset '%s'", v)
@@ -357,11 +533,53 @@ func createKeysetManager(conf *structs.Settings, metadataStorage *metadata.Stora
 		logger.Info().Msg("keyset manager was created")
 	}
 
-	return keyset
+	return keysetManager
+}
+
+// createMetricCatalog - creates the metric catalog
+func createMetricCatalog(conf *structs.Settings, timelineManager *tlmanager.Instance, scyllaConn *gocql.Session, metadataStorage *metadata.Storage) *catalog.Catalog {
+
+	metricCatalog, err := catalog.New(conf.MetricCatalog, conf.Cassandra.Keyspace, scyllaConn, metadataStorage, timelineManager)
+	if err != nil {
+		if logh.FatalEnabled {
+			logger.Fatal().Err(err).Msg("error creating the metric catalog")
+		}
+		os.Exit(1)
+	}
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("metric catalog was created")
+	}
+
+	return metricCatalog
+}
+
+// createReindexJob - creates the metadata reindex job
+func createReindexJob(conf *structs.Settings, scyllaConn *gocql.Session, metadataStorage *metadata.Storage, keyspaceLayouts *persistence.KeyspaceLayouts, rollupResolutions []rollup.Resolution) *reindex.Job {
+
+	reindexJob := reindex.New(scyllaConn, metadataStorage, keyspaceLayouts, rollupResolutions, conf.NumberBlocks.Window.Duration)
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("metadata reindex job was created")
+	}
+
+	return reindexJob
+}
+
+// createMetadataMigration - creates the keyset metadata export and import
+func createMetadataMigration(conf *structs.Settings, metadataStorage *metadata.Storage, collectorService *collector.Collector, validationService *validation.Service) *migration.Migration {
+
+	metadataMigration := migration.New(metadataStorage, collectorService, validationService, conf.DefaultPaginationSize)
+
+	if logh.InfoEnabled {
+		logger.Info().Msg("metadata migration was created")
+	}
+
+	return metadataMigration
 }
 
 // createCollectorService - creates a new collector service
-func createCollectorService(conf *structs.Settings, timelineManager *tlmanager.Instance, metadataStorage *metadata.Storage, scyllaConn *gocql.Session, validationService *validation.Service, keyspaceTTLMap map[int]string) *collector.Collector {
+func createCollectorService(conf *structs.Settings, timelineManager *tlmanager.Instance, metadataStorage *metadata.Storage, scyllaConn *gocql.Session, validationService *validation.Service, keyspaceTTLMap *persistence.KeyspaceTTLMap, keyspaceLayouts *persistence.KeyspaceLayouts, rollupCompactor *rollup.Compactor) *collector.Collector {
 
 	collector, err := collector.New(
 		timelineManager,
@@ -369,7 +587,9 @@ func createCollectorService(conf *structs.Settings, timelineManager *tlmanager.I
 		metadataStorage,
 		conf,
 		keyspaceTTLMap,
+		keyspaceLayouts,
 		validationService,
+		rollupCompactor,
 	)
 
 	if err != nil {
@@ -387,7 +607,7 @@ func createCollectorService(conf *structs.Settings, timelineManager *tlmanager.I
 }
 
 // createPlotService - creates the plot service
-func createPlotService(conf *structs.Settings, timelineManager *tlmanager.Instance, metadataStorage *metadata.Storage, scyllaConn *gocql.Session, keyspaceTTLMap map[int]string) *plot.Plot {
+func createPlotService(conf *structs.Settings, timelineManager *tlmanager.Instance, metadataStorage *metadata.Storage, scyllaConn *gocql.Session, keyspaceTTLMap *persistence.KeyspaceTTLMap, keyspaceLayouts *persistence.KeyspaceLayouts, rollupCompactor *rollup.Compactor, memcachedConn *memcached.Memcached, metricCatalog *catalog.Catalog) *plot.Plot {
 
 	plotService, err := plot.New(
 		scyllaConn,
@@ -401,6 +621,13 @@ func createPlotService(conf *structs.Settings, timelineManager *tlmanager.Instan
 		conf.UnlimitedQueryBytesKeysetWhiteList,
 		timelineManager,
 		constants.ClusteringOrder(conf.ClusteringOrder),
+		rollupCompactor,
+		keyspaceLayouts,
+		conf.NumberBlocks.Window.Duration,
+		conf.Validation.KeysetPrecision,
+		conf.QueryCache,
+		memcachedConn,
+		metricCatalog,
 	)
 
 	if err != nil {
@@ -431,7 +658,7 @@ func createUDPServer(conf *structs.SettingsUDP, collectorService *collector.Coll
 }
 
 // createRESTserver - creates the REST server and starts it
-func createRESTserver(conf *structs.Settings, timelineManager *tlmanager.Instance, plotService *plot.Plot, collectorService *collector.Collector, keyspaceManager *keyspace.Keyspace, keysetManager *keyset.Manager, memcachedConn *memcached.Memcached, telnetManager *telnetmgr.Manager) *rest.REST {
+func createRESTserver(conf *structs.Settings, timelineManager *tlmanager.Instance, plotService *plot.Plot, collectorService *collector.Collector, keyspaceManager *keyspace.Keyspace, keysetManager *keyset.Manager, memcachedConn *memcached.Memcached, telnetManager *telnetmgr.Manager, metricCatalog *catalog.Catalog, reindexJob *reindex.Job, metadataMigration *migration.Migration) *rest.REST {
 
 	restServer := rest.New(
 		timelineManager,
@@ -442,6 +669,9 @@ func createRESTserver(conf *structs.Settings, timelineManager *tlmanager.Instanc
 		conf.HTTPserver,
 		keysetManager,
 		telnetManager,
+		metricCatalog,
+		reindexJob,
+		metadataMigration,
 	)
 
 	restServer.Start()
@@ -491,13 +721,14 @@ func createTelnetManager(conf *structs.Settings, collectorService *collector.Col
 }
 
 // createValidation - creates a new validation service
-func createValidation(conf *structs.Settings, metadataStorage *metadata.Storage, keyspaceTTLMap map[int]string, timelineManager *tlmanager.Instance) *validation.Service {
+func createValidation(conf *structs.Settings, metadataStorage *metadata.Storage, keyspaceTTLMap *persistence.KeyspaceTTLMap, timelineManager *tlmanager.Instance, keysetManager *keyset.Manager) *validation.Service {
 
 	service, err := validation.New(
 		&conf.Validation,
 		metadataStorage,
 		keyspaceTTLMap,
 		timelineManager,
+		keysetManager,
 	)
 
 	if err != nil {
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+type keysetAlias struct {
+	Alias          string  `json:"alias"`
+	Keyset         string  `json:"keyset"`
+	DualWriteUntil *string `json:"dualWriteUntil"`
+}
+
+func putAlias(t *testing.T, alias, payload string) int {
+
+	code, resp, err := mycenaeTools.HTTP.PUT("aliases/"+alias, []byte(payload))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	if code == http.StatusOK {
+		result := keysetAlias{}
+		assert.Nil(t, json.Unmarshal(resp, &result), string(resp))
+		assert.Equal(t, alias, result.Alias)
+	}
+
+	return code
+}
+
+func deleteAlias(t *testing.T, alias string) {
+
+	code, _, err := mycenaeTools.HTTP.DELETE("aliases/" + alias)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func sendAliasPoint(t *testing.T, keyset, metric string) {
+
+	point := fmt.Sprintf(`[{"value":1.0,"metric":"%s","tags":{"ksid":"%s","host":"alias-test"},"timestamp":%d}]`, metric, keyset, time.Now().Unix())
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/put", []byte(point))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+}
+
+func TestKeysetAlias(t *testing.T) {
+
+	target := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+	alias := createKeysetName()
+
+	assert.Equal(t, http.StatusOK, putAlias(t, alias, `{"keyset":"`+target+`"}`))
+	defer deleteAlias(t, alias)
+
+	code, resp, err := mycenaeTools.HTTP.GET("aliases/" + alias)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	result := keysetAlias{}
+	assert.Nil(t, json.Unmarshal(resp, &result), string(resp))
+	assert.Equal(t, target, result.Keyset)
+	assert.Nil(t, result.DualWriteUntil)
+
+	sendAliasPoint(t, alias, "alias.metric")
+	time.Sleep(tools.Sleep3)
+
+	for _, keyset := range []string{alias, target} {
+		code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", keyset), `{"metric":"alias.metric"}`)
+		assert.Equal(t, http.StatusOK, code, keyset)
+		assert.Equal(t, 1, response.TotalRecord, keyset)
+	}
+
+	code, resp, err = mycenaeTools.HTTP.PUT(fmt.Sprintf("keysets/%s/catalog/alias.metric", alias), []byte(`{"unit":"ms"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	code, _, err = mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/catalog/alias.metric", target))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, "the keyset routes of the alias are served by the target keyset")
+
+	code, _, err = mycenaeTools.HTTP.GET("keysets/" + alias)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code, "the keyset record routes are not rewritten")
+}
+
+func TestKeysetAliasDualWrite(t *testing.T) {
+
+	oldKeyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+	newKeyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	assert.Equal(t, http.StatusConflict, putAlias(t, oldKeyset, `{"keyset":"`+newKeyset+`"}`), "an existing keyset is only renamed with dual write")
+
+	dualWriteUntil := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	assert.Equal(t, http.StatusOK, putAlias(t, oldKeyset, `{"keyset":"`+newKeyset+`","dualWriteUntil":"`+dualWriteUntil+`"}`))
+	defer deleteAlias(t, oldKeyset)
+
+	sendAliasPoint(t, oldKeyset, "dual.write.metric")
+	time.Sleep(tools.Sleep3)
+
+	code, resp, err := mycenaeTools.HTTP.PUT(fmt.Sprintf("keysets/%s/catalog/dual.write.metric", oldKeyset), []byte(`{"unit":"ms"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	code, _, err = mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/catalog/dual.write.metric", newKeyset))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code, "the requests stay in the old keyset while in dual write")
+
+	for _, keyset := range []string{oldKeyset, newKeyset} {
+		code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", keyset), `{"metric":"dual.write.metric"}`)
+		assert.Equal(t, http.StatusOK, code, keyset)
+		assert.Equal(t, 1, response.TotalRecord, keyset)
+	}
+}
+
+func TestKeysetAliasInvalid(t *testing.T) {
+
+	alias := createKeysetName()
+
+	assert.Equal(t, http.StatusNotFound, putAlias(t, alias, `{"keyset":"not_a_keyset"}`))
+	assert.Equal(t, http.StatusBadRequest, putAlias(t, alias, `{}`))
+	assert.Equal(t, http.StatusBadRequest, putAlias(t, ksMycenae, `{"keyset":"`+ksMycenae+`"}`))
+
+	code, _, err := mycenaeTools.HTTP.DELETE("aliases/" + alias)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code)
+}
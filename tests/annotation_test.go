@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type openTSDBAnnotation struct {
+	Tsuid       string            `json:"tsuid"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	Custom      map[string]string `json:"custom"`
+	StartTime   int64             `json:"startTime"`
+	EndTime     int64             `json:"endTime"`
+}
+
+type grafanaAnnotation struct {
+	Annotation map[string]interface{} `json:"annotation"`
+	Time       int64                  `json:"time"`
+	Title      string                 `json:"title"`
+	Text       string                 `json:"text"`
+	Tags       []string               `json:"tags"`
+}
+
+func TestAnnotationOpenTSDB(t *testing.T) {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448453200000,
+		"metric": "text.query.events",
+		"filters": [{"type": "literal_or", "tagk": "host", "filter": "a", "groupBy": false}]
+	}`
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/api/annotation", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Equal(t, http.StatusOK, code, string(resp)) {
+		return
+	}
+
+	annotations := []openTSDBAnnotation{}
+	if err := json.Unmarshal(resp, &annotations); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, annotations, 6) {
+		assert.Equal(t, int64(1448452800), annotations[0].StartTime)
+		assert.Equal(t, "deploy 0 on a", annotations[0].Description)
+		assert.Equal(t, "deploy 0 on a", annotations[0].Notes)
+		assert.Equal(t, "a", annotations[0].Custom["host"])
+		assert.NotEmpty(t, annotations[0].Tsuid)
+		assert.Equal(t, int64(1448453100), annotations[5].StartTime)
+	}
+}
+
+func TestAnnotationGrafana(t *testing.T) {
+
+	payload := `{
+		"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:02:00.000Z"},
+		"annotation": {"name": "deploys", "enable": true, "query": "text.query.events{host=*}"}
+	}`
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/grafana/annotations", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Equal(t, http.StatusOK, code, string(resp)) {
+		return
+	}
+
+	annotations := []grafanaAnnotation{}
+	if err := json.Unmarshal(resp, &annotations); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, annotations, 6) {
+		assert.Equal(t, int64(1448452800000), annotations[0].Time)
+		assert.Equal(t, "deploy 0 on a", annotations[0].Title)
+		assert.Equal(t, "deploy 0 on a", annotations[0].Text)
+		assert.Contains(t, annotations[0].Tags, "host:a")
+		assert.Equal(t, "deploys", annotations[0].Annotation["name"])
+		assert.Equal(t, int64(1448452920000), annotations[5].Time)
+	}
+}
+
+func TestAnnotationGrafanaInvalidQuery(t *testing.T) {
+
+	payload := `{
+		"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:02:00.000Z"},
+		"annotation": {"name": "deploys", "query": "text.query.events{host}"}
+	}`
+
+	code, _, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/grafana/annotations", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
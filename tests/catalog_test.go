@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+type catalogEntry struct {
+	Metric      string            `json:"metric"`
+	Unit        string            `json:"unit"`
+	Description string            `json:"description"`
+	Owner       string            `json:"owner"`
+	Type        string            `json:"type"`
+	Attributes  map[string]string `json:"attributes"`
+}
+
+func TestCatalogCRUD(t *testing.T) {
+
+	path := fmt.Sprintf("keysets/%s/catalog/http.requests", ksMycenae)
+
+	payload := []byte(`{"unit":"requests","description":"the http requests","owner":"platform","type":"counter","attributes":{"team":"edge"}}`)
+
+	code, resp, err := mycenaeTools.HTTP.PUT(path, payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	code, resp, err = mycenaeTools.HTTP.GET(path)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	entry := catalogEntry{}
+	assert.Nil(t, json.Unmarshal(resp, &entry), string(resp))
+	assert.Equal(t, catalogEntry{
+		Metric:      "http.requests",
+		Unit:        "requests",
+		Description: "the http requests",
+		Owner:       "platform",
+		Type:        "counter",
+		Attributes:  map[string]string{"team": "edge"},
+	}, entry)
+
+	code, _, err = mycenaeTools.HTTP.PUT(path, []byte(`{"unit":"requests","type":"counter"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, err = mycenaeTools.HTTP.PUT(path, []byte(`{"type":"histogram"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	expression := url.QueryEscape(`merge(sum, query(http.requests, {host=*}, 5m))`)
+
+	code, resp, err = mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/expression/expand?exp=%s", ksMycenae, expression))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	expressions := []string{}
+	assert.Nil(t, json.Unmarshal(resp, &expressions), string(resp))
+	if assert.Len(t, expressions, 1) {
+		assert.True(t, strings.HasPrefix(expressions[0], "merge(sum,rate(true,null,0,"), "counters must default to rate: "+expressions[0])
+	}
+
+	code, _, err = mycenaeTools.HTTP.DELETE(path)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, err = mycenaeTools.HTTP.GET(path)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCatalogKeysetNotFound(t *testing.T) {
+
+	code, _, err := mycenaeTools.HTTP.GET("keysets/catalognotfound/catalog")
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCatalogUnitInQuery(t *testing.T) {
+
+	keyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	code, resp, err := mycenaeTools.HTTP.PUT(fmt.Sprintf("keysets/%s/catalog/catalog.latency", keyset), []byte(`{"unit":"ms","type":"gauge"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	points := `[
+		{"value": 1.0, "metric": "catalog.latency", "tags": {"ksid": "` + keyset + `", "host": "a"}, "timestamp": 1448452800},
+		{"value": 2.0, "metric": "catalog.other", "tags": {"ksid": "` + keyset + `", "host": "a"}, "timestamp": 1448452800}
+	]`
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(points))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+
+	time.Sleep(tools.Sleep3)
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448452900000,
+		"queries": [
+			{"metric": "catalog.latency", "aggregator": "sum"},
+			{"metric": "catalog.other", "aggregator": "sum"}
+		]
+	}`
+
+	code, resp, err = mycenaeTools.HTTP.POST("keysets/"+keyset+"/api/query", []byte(payload))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	responses := []struct {
+		Metric string `json:"metric"`
+		Unit   string `json:"unit"`
+	}{}
+	assert.Nil(t, json.Unmarshal(resp, &responses), string(resp))
+
+	units := map[string]string{}
+	for _, response := range responses {
+		units[response.Metric] = response.Unit
+	}
+
+	assert.Equal(t, map[string]string{"catalog.latency": "ms", "catalog.other": ""}, units, "only the cataloged metrics have a unit")
+}
@@ -209,9 +209,9 @@ func TestCheckExpressionInvalidQuery(t *testing.T) {
 			"Invalid unit",
 		},
 		"DownsampleExtraParamsRelative": {
-			`merge(sum, downsample(1m ,1m, min,none,query(os.cpu, {app=test}, 5m)))`,
-			"downsample needs 4 parameters: downsample operation, downsample period, fill option and a function",
-			"downsample expects 4 parameters but found 5: [1m 1m min none query(os.cpu,{app=test},5m)]",
+			`merge(sum, downsample(1m ,1m, min,none,5m,query(os.cpu, {app=test}, 5m)))`,
+			"downsample needs 4 or 5 parameters: downsample operation, downsample period, fill option, an optional max gap and a function",
+			"downsample expects 4 or 5 parameters but found 6: [1m 1m min none 5m query(os.cpu,{app=test},5m)]",
 		},
 		"MergeExtraParamsRelative": {
 			`merge(sum, sum, downsample(1m, min,none, query(os.cpu, {app=test}, 5m)))`,
@@ -580,9 +580,9 @@ func TestExpandInvalidQueryDifferentErrorMessage(t *testing.T) {
 			"query needs 3 parameters: metric, map or null and a time interval",
 		},
 		"DownsampleExtraParamsRelative": {
-			"merge(sum, downsample(1m ,1m, min,none,query(os.cpu, {app=test}, 5m)))",
-			"downsample expects 4 parameters but found 5: [1m 1m min none query(os.cpu,{app=test},5m)]",
-			"downsample needs 4 parameters: downsample operation, downsample period, fill option and a function",
+			"merge(sum, downsample(1m ,1m, min,none,5m,query(os.cpu, {app=test}, 5m)))",
+			"downsample expects 4 or 5 parameters but found 6: [1m 1m min none 5m query(os.cpu,{app=test},5m)]",
+			"downsample needs 4 or 5 parameters: downsample operation, downsample period, fill option, an optional max gap and a function",
 		},
 		"MergeExtraParamsRelative": {
 			"merge(sum, sum, downsample(1m, min,none, query(os.cpu, {app=test}, 5m)))",
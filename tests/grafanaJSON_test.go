@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+const grafanaJSONMetric = "grafana.json.cpu"
+
+type grafanaTimeserie struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaTable struct {
+	Type    string              `json:"type"`
+	Columns []map[string]string `json:"columns"`
+	Rows    [][]interface{}     `json:"rows"`
+}
+
+type grafanaTag struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func sendPointsGrafanaJSON(keyset string) {
+
+	points := []tools.Point{}
+
+	for i := 0; i < 3; i++ {
+		points = append(points,
+			tools.Point{Value: float32(i + 1), Metric: grafanaJSONMetric, Tags: map[string]string{"ksid": keyset, "host": "a"}, Timestamp: int64(1448452800 + i*60)},
+			tools.Point{Value: float32((i + 1) * 10), Metric: grafanaJSONMetric, Tags: map[string]string{"ksid": keyset, "host": "b"}, Timestamp: int64(1448452800 + i*60)},
+		)
+	}
+
+	jsonPoints, err := json.Marshal(points)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/put", jsonPoints)
+	if err != nil {
+		panic(err)
+	}
+	if code != http.StatusNoContent {
+		log.Fatal("Error sending points! - grafanaJSON_test.go, Code: ", code, " ", string(resp))
+	}
+}
+
+func postGrafanaJSON(t *testing.T, path, payload string, result interface{}) int {
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/grafana/"+path, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code == http.StatusOK && result != nil {
+		if err := json.Unmarshal(resp, result); err != nil {
+			t.Fatal(err, string(resp))
+		}
+	}
+
+	return code
+}
+
+func TestGrafanaJSONCheck(t *testing.T) {
+
+	code, _, err := mycenaeTools.HTTP.GET("keysets/" + ksMycenae + "/grafana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestGrafanaJSONSearch(t *testing.T) {
+
+	metrics := []string{}
+
+	code := postGrafanaJSON(t, "search", `{"target": "grafana.json"}`, &metrics)
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.Equal(t, []string{grafanaJSONMetric}, metrics)
+	}
+}
+
+func TestGrafanaJSONTagKeysAndValues(t *testing.T) {
+
+	keys := []grafanaTag{}
+
+	code := postGrafanaJSON(t, "tag-keys", `{"metric": "`+grafanaJSONMetric+`"}`, &keys)
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.Contains(t, keys, grafanaTag{Type: "string", Text: "host"})
+	}
+
+	values := []grafanaTag{}
+
+	code = postGrafanaJSON(t, "tag-values", `{"metric": "`+grafanaJSONMetric+`", "key": "host"}`, &values)
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.Equal(t, []grafanaTag{{Text: "a"}, {Text: "b"}}, values)
+	}
+
+	code = postGrafanaJSON(t, "tag-values", `{"metric": "`+grafanaJSONMetric+`"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGrafanaJSONQueryTimeserie(t *testing.T) {
+
+	payload := `{
+		"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:10:00.000Z"},
+		"targets": [{"refId": "A", "type": "timeserie", "target": "merge(sum,query(` + grafanaJSONMetric + `,{host=*},1h))"}]
+	}`
+
+	series := []grafanaTimeserie{}
+
+	code := postGrafanaJSON(t, "query", payload, &series)
+	if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, series, 2) {
+		return
+	}
+
+	assert.Equal(t, grafanaJSONMetric+"{host=a}", series[0].Target)
+	assert.Equal(t, [][2]float64{{1, 1448452800000}, {2, 1448452860000}, {3, 1448452920000}}, series[0].Datapoints)
+	assert.Equal(t, grafanaJSONMetric+"{host=b}", series[1].Target)
+	assert.Equal(t, [][2]float64{{10, 1448452800000}, {20, 1448452860000}, {30, 1448452920000}}, series[1].Datapoints)
+}
+
+func TestGrafanaJSONQueryTableWithAdhocFilter(t *testing.T) {
+
+	payload := `{
+		"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:10:00.000Z"},
+		"targets": [{"refId": "A", "type": "table", "target": "merge(sum,query(` + grafanaJSONMetric + `,{host=*},1h))"}],
+		"adhocFilters": [{"key": "host", "operator": "=", "value": "b"}]
+	}`
+
+	tables := []grafanaTable{}
+
+	code := postGrafanaJSON(t, "query", payload, &tables)
+	if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, tables, 1) {
+		return
+	}
+
+	assert.Equal(t, "table", tables[0].Type)
+	assert.Equal(t, []map[string]string{
+		{"text": "Time", "type": "time"},
+		{"text": "Metric", "type": "string"},
+		{"text": "host", "type": "string"},
+		{"text": "Value", "type": "number"},
+	}, tables[0].Columns)
+
+	if assert.Len(t, tables[0].Rows, 3) {
+		assert.Equal(t, []interface{}{1448452800000.0, grafanaJSONMetric, "b", 10.0}, tables[0].Rows[0])
+	}
+}
+
+func TestGrafanaJSONQueryInvalid(t *testing.T) {
+
+	cases := map[string]string{
+		"InvalidExpression": `{"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:10:00.000Z"}, "targets": [{"target": "merge(sum"}]}`,
+		"InvalidType":       `{"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:10:00.000Z"}, "targets": [{"target": "x", "type": "graph"}]}`,
+		"InvalidOperator":   `{"range": {"from": "2015-11-25T12:00:00.000Z", "to": "2015-11-25T12:10:00.000Z"}, "targets": [], "adhocFilters": [{"key": "host", "operator": "<", "value": "a"}]}`,
+		"MissingRange":      `{"targets": []}`,
+	}
+
+	for name, payload := range cases {
+		code := postGrafanaJSON(t, "query", payload, nil)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+	}
+}
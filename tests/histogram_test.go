@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type histogramStats struct {
+	Count     uint64             `json:"count"`
+	Sum       float64            `json:"sum"`
+	Min       float64            `json:"min"`
+	Max       float64            `json:"max"`
+	Avg       float64            `json:"avg"`
+	Quantiles map[string]float64 `json:"quantiles"`
+}
+
+type histogramResponse struct {
+	Metric         string                    `json:"metric"`
+	Tags           map[string]string         `json:"tags"`
+	AggregatedTags []string                  `json:"aggregateTags"`
+	Dps            map[string]histogramStats `json:"dps"`
+}
+
+func sendPointsHistogram(keyset string) {
+
+	points := `[
+	  {
+		"histogram": {"1": 10, "2": 10, "100": 1},
+		"metric": "histogram.latency",
+		"tags": {"ksid": "` + keyset + `", "host": "a"},
+		"timestamp": 1448452800
+	  },
+	  {
+		"histogram": {"3": 5},
+		"metric": "histogram.latency",
+		"tags": {"ksid": "` + keyset + `", "host": "b"},
+		"timestamp": 1448452800
+	  },
+	  {
+		"histogram": {"4": 4},
+		"metric": "histogram.latency",
+		"tags": {"ksid": "` + keyset + `", "host": "a"},
+		"timestamp": 1448452830
+	  }
+	]`
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/histogram/put", []byte(points))
+	if err != nil {
+		panic(err)
+	}
+	if code != http.StatusNoContent {
+		log.Fatal("Error sending points! - histogram_test.go, Code: ", code, " ", string(resp))
+	}
+}
+
+func TestHistogramQueryMergesSeriesAndBuckets(t *testing.T) {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448452900000,
+		"queries": [{
+			"metric": "histogram.latency",
+			"downsample": "1m",
+			"quantiles": [0.5, 0.99],
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": false}]
+		}]
+	}`
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/api/histogram/query", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Equal(t, http.StatusOK, code, string(resp)) {
+		return
+	}
+
+	responses := []histogramResponse{}
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, responses, 1) {
+
+		assert.Equal(t, []string{"host"}, responses[0].AggregatedTags)
+
+		stats, ok := responses[0].Dps["1448452800"]
+		if assert.True(t, ok) && assert.Len(t, responses[0].Dps, 1) {
+			assert.Equal(t, uint64(30), stats.Count)
+			assert.Equal(t, 161.0, stats.Sum)
+			assert.Equal(t, 1.0, stats.Min)
+			assert.Equal(t, 100.0, stats.Max)
+			assert.InDelta(t, 2.0, stats.Quantiles["p50"], 0.02)
+			assert.InDelta(t, 4.0, stats.Quantiles["p99"], 0.04)
+		}
+	}
+}
+
+func TestHistogramQueryInvalidQuantile(t *testing.T) {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448452900000,
+		"queries": [{"metric": "histogram.latency", "quantiles": [1.5]}]
+	}`
+
+	code, _, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/api/histogram/query", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestHistogramPutInvalidCount(t *testing.T) {
+
+	point := `{
+		"histogram": {"1": -3},
+		"metric": "histogram.latency",
+		"tags": {"ksid": "` + ksMycenae + `", "host": "a"},
+		"timestamp": 1448452800
+	}`
+
+	code, _, err := mycenaeTools.HTTP.POST("api/histogram/put", []byte(point))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
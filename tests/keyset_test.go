@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+type keysetRecord struct {
+	Name         string  `json:"name"`
+	Owner        string  `json:"owner"`
+	Contact      string  `json:"contact"`
+	Description  string  `json:"description"`
+	CreationDate *string `json:"creationDate"`
+	DefaultTTL   int     `json:"defaultTTL"`
+	AllowedTTLs  []int   `json:"allowedTTLs"`
+	TTLPolicy    string  `json:"ttlPolicy"`
+	Quotas       struct {
+		MaxSeries          int `json:"maxSeries"`
+		MaxPointsPerSecond int `json:"maxPointsPerSecond"`
+	} `json:"quotas"`
+	DeletionDate *string `json:"deletionDate"`
+	PurgeDate    *string `json:"purgeDate"`
+}
+
+func getKeysetRecord(t *testing.T, keyset string) (int, keysetRecord) {
+
+	code, resp, err := mycenaeTools.HTTP.GET("keysets/" + keyset)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	record := keysetRecord{}
+	if code == http.StatusOK {
+		assert.Nil(t, json.Unmarshal(resp, &record), string(resp))
+	}
+
+	return code, record
+}
+
+func TestKeysetRecordLifecycle(t *testing.T) {
+
+	keyset := createKeysetName()
+
+	payload := []byte(`{"owner":"platform","contact":"platform@example.com","description":"the platform series","defaultTTL":1,"allowedTTLs":[1],"quotas":{"maxSeries":1000}}`)
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset, payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	code, record := getKeysetRecord(t, keyset)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "platform", record.Owner)
+	assert.Equal(t, "platform@example.com", record.Contact)
+	assert.Equal(t, 1, record.DefaultTTL)
+	assert.Equal(t, []int{1}, record.AllowedTTLs)
+	assert.Equal(t, 1000, record.Quotas.MaxSeries)
+	assert.NotNil(t, record.CreationDate)
+	assert.Nil(t, record.DeletionDate)
+
+	code, resp, err = mycenaeTools.HTTP.PUT("keysets/"+keyset, []byte(`{"owner":"observability","allowedTTLs":[1]}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	code, record = getKeysetRecord(t, keyset)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "observability", record.Owner)
+	assert.Equal(t, 0, record.DefaultTTL)
+	assert.NotNil(t, record.CreationDate, "the creation date is kept")
+
+	code, resp, err = mycenaeTools.HTTP.DELETE("keysets/" + keyset)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	code, _, err = mycenaeTools.HTTP.HEAD("keysets/" + keyset)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code, "the deleted keyset is hidden")
+
+	code, record = getKeysetRecord(t, keyset)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, record.DeletionDate) && assert.NotNil(t, record.PurgeDate) {
+		deletionDate, err := time.Parse(time.RFC3339, *record.DeletionDate)
+		assert.Nil(t, err)
+		purgeDate, err := time.Parse(time.RFC3339, *record.PurgeDate)
+		assert.Nil(t, err)
+		assert.True(t, purgeDate.After(deletionDate), "the keyset is purged after the grace period")
+	}
+
+	code, _, err = mycenaeTools.HTTP.POST("keysets/"+keyset, nil)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusConflict, code, "a deleted keyset must be restored")
+
+	code, resp, err = mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/restore", keyset), nil)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	code, _, err = mycenaeTools.HTTP.HEAD("keysets/" + keyset)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp, err = mycenaeTools.HTTP.GET("keysets")
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	records := []keysetRecord{}
+	assert.Nil(t, json.Unmarshal(resp, &records), string(resp))
+
+	found := false
+	for _, r := range records {
+		if r.Name == keyset {
+			found = true
+			assert.Equal(t, "observability", r.Owner)
+			assert.Nil(t, r.DeletionDate)
+		}
+	}
+	assert.True(t, found, "the restored keyset is listed")
+}
+
+func TestKeysetRecordInvalidTTL(t *testing.T) {
+
+	// the TTLs need a keyspace and the configured default TTL 1 must be allowed when the keyset has no default
+	for _, payload := range []string{`{"defaultTTL":7,"allowedTTLs":[1]}`, `{"defaultTTL":90}`, `{"allowedTTLs":[1,90]}`, `{"allowedTTLs":[7]}`} {
+
+		code, resp, err := mycenaeTools.HTTP.POST("keysets/"+createKeysetName(), []byte(payload))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusBadRequest, code, payload+": "+string(resp))
+	}
+}
+
+func TestKeysetRecordInvalid(t *testing.T) {
+
+	for _, payload := range []string{`{"defaultTTL":-1}`, `{"quotas":{"maxSeries":-1}}`, `{"quotas":{"maxPointsPerSecond":-1}}`} {
+
+		code, resp, err := mycenaeTools.HTTP.POST("keysets/"+createKeysetName(), []byte(payload))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusBadRequest, code, payload+": "+string(resp))
+	}
+}
+
+func TestKeysetPointsQuota(t *testing.T) {
+
+	keyset := createKeysetName()
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset, []byte(`{"quotas":{"maxPointsPerSecond":3}}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	points := []string{}
+	for i := 0; i < 10; i++ {
+		points = append(points, fmt.Sprintf(`{"value":%d,"metric":"quota.points","tags":{"ksid":"%s","host":"a"},"timestamp":%d}`, i, keyset, 1448452800+i))
+	}
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte("["+strings.Join(points, ",")+"]"))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusTooManyRequests, code, string(resp))
+
+	time.Sleep(time.Second)
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(points[0]))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, "the points are counted per second: "+string(resp))
+}
+
+func TestKeysetSeriesQuota(t *testing.T) {
+
+	keyset := createKeysetName()
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset, []byte(`{"quotas":{"maxSeries":2}}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	points := []string{}
+	for _, host := range []string{"a", "b", "c"} {
+		points = append(points, fmt.Sprintf(`{"value":1.0,"metric":"quota.series","tags":{"ksid":"%s","host":"%s"},"timestamp":1448452800}`, keyset, host))
+	}
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte("["+strings.Join(points, ",")+"]"))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+
+	time.Sleep(tools.Sleep3)
+
+	code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", keyset), `{"metric":"quota.series"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, response.TotalRecord, "the series over the quota are not stored")
+}
+
+func TestKeysetTTLPolicy(t *testing.T) {
+
+	keyset := createKeysetName()
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset, []byte(`{"defaultTTL":1,"allowedTTLs":[1],"ttlPolicy":"reject"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	point := `{"value":1.0,"metric":"ttl.policy","tags":{"ksid":"` + keyset + `","host":"ttl-test","ttl":"%s"}}`
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(fmt.Sprintf(point, "2")))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusBadRequest, code, string(resp))
+	assert.Contains(t, string(resp), "not allowed in the keyset")
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(fmt.Sprintf(point, "1")))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+}
+
+func TestKeysetCoerceTTLPolicy(t *testing.T) {
+
+	keyset := createKeysetName()
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset, []byte(`{"defaultTTL":3,"allowedTTLs":[3,7]}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusCreated, code, string(resp))
+
+	point := `{"value":1.0,"metric":"ttl.coerce","tags":{"ksid":"` + keyset + `","host":"%s","ttl":"%s"},"timestamp":1448452800}`
+
+	for host, ttl := range map[string]string{"allowed": "7", "coerced": "1"} {
+		code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(fmt.Sprintf(point, host, ttl)))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusNoContent, code, string(resp))
+	}
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/put", []byte(fmt.Sprintf(point, "invalid", "x")))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusBadRequest, code, string(resp))
+
+	time.Sleep(tools.Sleep3)
+
+	code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", keyset), `{"metric":"ttl.coerce"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, response.TotalRecord)
+
+	ttls := map[string]string{}
+	for _, meta := range response.Payload {
+		ttls[meta.Tags["host"]] = meta.Tags["ttl"]
+	}
+	assert.Equal(t, map[string]string{"allowed": "7", "coerced": "3"}, ttls, "the TTLs not allowed are coerced to the keyset default")
+}
+
+func TestKeysetInvalidTTLPolicy(t *testing.T) {
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+createKeysetName(), []byte(`{"ttlPolicy":"ignore"}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusBadRequest, code, string(resp))
+}
@@ -595,3 +595,212 @@ func TestKeyspaceList(t *testing.T) {
 	assert.Equal(t, 200, code)
 	assert.NotContains(t, string(content), `"key":"mycenae"`)
 }
+
+// LIFECYCLE
+
+func TestKeyspaceUpdateTTL(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	data := getKeyspace()
+	testKeyspaceCreation(&data, t)
+
+	path := fmt.Sprintf("keyspaces/%s/ttl", data.Name)
+	code, resp, err := mycenaeTools.HTTP.PUT(path, []byte(`{"ttl":60}`))
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 200, code)
+	assert.Contains(t, string(resp), "next refresh")
+
+	tableProperties := mycenaeTools.Cassandra.Timeseries.TableProperties(data.Name, "ts_number_stamp")
+	assert.Exactly(t, 60*86400, tableProperties.Default_time_to_live)
+	assert.Equal(t, "2", tableProperties.Compaction["compaction_window_size"])
+}
+
+func TestKeyspaceUpdateTTLFail(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	data := getKeyspace()
+	testKeyspaceCreation(&data, t)
+
+	// the TTL of the three_days keyspace is in the policy of this keyset
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+createKeysetName(), []byte(`{"defaultTTL":3,"allowedTTLs":[1,3]}`))
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 201, code, string(resp))
+
+	cases := map[string]struct {
+		keyspace string
+		body     string
+		status   int
+		message  string
+	}{
+		"zero":     {data.Name, `{"ttl":0}`, 400, errKsTTL},
+		"maxTTL":   {data.Name, `{"ttl":91}`, 400, errKsTTLMax},
+		"notFound": {tools.GenerateRandomName(), `{"ttl":30}`, 404, ""},
+		"admin":    {"mycenae", `{"ttl":30}`, 404, ""},
+		"default":  {"one_day", `{"ttl":2}`, 409, "the keyspace stores the default TTL 1"},
+		"policy":   {"three_days", `{"ttl":4}`, 409, "is in the policy of the keysets"},
+		"stored":   {data.Name, `{"ttl":7}`, 409, "the TTL 7 is already stored in the keyspace one_week"},
+	}
+
+	for test, c := range cases {
+		path := fmt.Sprintf("keyspaces/%s/ttl", c.keyspace)
+		code, resp, err := mycenaeTools.HTTP.PUT(path, []byte(c.body))
+		if err != nil {
+			t.Error(err, t)
+			t.SkipNow()
+		}
+		assert.Equal(t, c.status, code, test)
+		if c.message != "" {
+			assert.Contains(t, string(resp), c.message, test)
+		}
+	}
+}
+
+func TestKeyspaceUpdateReplication(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	data := getKeyspace()
+	testKeyspaceCreation(&data, t)
+
+	path := fmt.Sprintf("keyspaces/%s/replication", data.Name)
+	body := []byte(fmt.Sprintf(`{"replication":{"%s":2}}`, datacenter))
+
+	code, _, err := mycenaeTools.HTTP.PUT(path, body)
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 200, code)
+
+	ksProperties := mycenaeTools.Cassandra.Timeseries.KeyspaceProperties(data.Name)
+	assert.Equal(t, "2", ksProperties.Replication[datacenter])
+	assert.True(t, mycenaeTools.Cassandra.Timeseries.ExistsInformation(data.Name, 2, data.Datacenter, data.Contact))
+}
+
+func TestKeyspaceUpdateReplicationFail(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	data := getKeyspace()
+	testKeyspaceCreation(&data, t)
+
+	cases := map[string]struct {
+		body    string
+		message string
+	}{
+		"empty":         {`{"replication":{}}`, "Replication cannot be empty"},
+		"factor":        {fmt.Sprintf(`{"replication":{"%s":4}}`, datacenter), errKsRF},
+		"ownDatacenter": {`{"replication":{"dc_error":1}}`, "the replication must include the keyspace datacenter"},
+		"datacenter":    {fmt.Sprintf(`{"replication":{"%s":1,"dc_error":1}}`, datacenter), "datacenter \"dc_error\" not exists"},
+	}
+
+	path := fmt.Sprintf("keyspaces/%s/replication", data.Name)
+
+	for test, c := range cases {
+		code, resp, err := mycenaeTools.HTTP.PUT(path, []byte(c.body))
+		if err != nil {
+			t.Error(err, t)
+			t.SkipNow()
+		}
+		assert.Equal(t, 400, code, test)
+		assert.Contains(t, string(resp), c.message, test)
+	}
+}
+
+func TestKeyspaceDelete(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	data := getKeyspace()
+	testKeyspaceCreation(&data, t)
+
+	path := fmt.Sprintf("keyspaces/%s", data.Name)
+
+	code, resp, err := mycenaeTools.HTTP.DELETE(path)
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 428, code)
+
+	var drop keyspace.DropResponse
+	err = json.Unmarshal(resp, &drop)
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.NotEmpty(t, drop.Token)
+
+	code, _, err = mycenaeTools.HTTP.DELETE(path + "?confirm=wrong")
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 412, code)
+	assert.True(t, mycenaeTools.Cassandra.Timeseries.Exists(data.Name))
+
+	code, _, err = mycenaeTools.HTTP.DELETE(path + "?confirm=" + drop.Token)
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 200, code)
+	assert.False(t, mycenaeTools.Cassandra.Timeseries.Exists(data.Name))
+	assert.Equal(t, 0, mycenaeTools.Cassandra.Timeseries.CountTsKeyspaceByKsid(data.Name))
+
+	code, _, err = mycenaeTools.HTTP.DELETE(path + "?confirm=" + drop.Token)
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 404, code, "the token is not valid after the drop")
+}
+
+func TestKeyspaceDeleteDefault(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	code, resp, err := mycenaeTools.HTTP.DELETE("keyspaces/one_day")
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 409, code)
+	assert.Contains(t, string(resp), "cannot be dropped")
+	assert.True(t, mycenaeTools.Cassandra.Timeseries.Exists("one_day"))
+}
+
+func TestKeyspaceDeleteAdmin(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	code, _, err := mycenaeTools.HTTP.DELETE("keyspaces/mycenae")
+	if err != nil {
+		t.Error(err, t)
+		t.SkipNow()
+	}
+	assert.Equal(t, 404, code)
+	assert.True(t, mycenaeTools.Cassandra.Timeseries.Exists("mycenae"))
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
 	"net/http"
 
@@ -747,3 +749,109 @@ func requestResponse(t *testing.T, url string, payload string) (int, tools.Respo
 
 	return code, response
 }
+
+func sendFilterCachePoint(t *testing.T, keyset, metric, host string) {
+
+	point := `{
+		"value": 1.0,
+		"metric": "` + metric + `",
+		"tags": {"ksid": "` + keyset + `", "host": "` + host + `"},
+		"timestamp": 1448452800
+	}`
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/put", []byte(point))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+}
+
+func queryFilterCacheSeries(t *testing.T, keyset, metric string) []tools.ResponseQuery {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448452900000,
+		"queries": [{
+			"metric": "` + metric + `",
+			"aggregator": "sum",
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": true}]
+		}]
+	}`
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+keyset+"/api/query", []byte(payload))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	series := []tools.ResponseQuery{}
+	assert.Nil(t, json.Unmarshal(resp, &series), string(resp))
+
+	return series
+}
+
+func TestFilterCacheNewSerieOfEscapedMetric(t *testing.T) {
+
+	keyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	// the metric is escaped in the solr query, the cache must be invalidated by the original one
+	metric := "os.cpu-user/total"
+
+	sendFilterCachePoint(t, keyset, metric, "a")
+	time.Sleep(tools.Sleep3)
+
+	assert.Len(t, queryFilterCacheSeries(t, keyset, metric), 1)
+
+	sendFilterCachePoint(t, keyset, metric, "b")
+	time.Sleep(tools.Sleep3)
+
+	assert.Len(t, queryFilterCacheSeries(t, keyset, metric), 2, "the new serie must invalidate the cached filter")
+}
+
+func TestIndexerDeduplicatesNewSerie(t *testing.T) {
+
+	keyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	points := []string{}
+	for i := 0; i < 10; i++ {
+		points = append(points, fmt.Sprintf(`{
+			"value": %d,
+			"metric": "indexer.dedup",
+			"tags": {"ksid": "%s", "host": "a"},
+			"timestamp": %d
+		}`, i, keyset, 1448452800+i))
+	}
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/put", []byte("["+strings.Join(points, ",")+"]"))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+
+	code, resp, err = mycenaeTools.HTTP.POST("api/text/put", []byte(`{
+		"text": "deduplicated",
+		"metric": "indexer.dedup",
+		"tags": {"ksid": "`+keyset+`", "host": "a"},
+		"timestamp": 1448452800
+	}`))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNoContent, code, string(resp))
+
+	time.Sleep(tools.Sleep3)
+
+	payload := `{"metric":"indexer.dedup"}`
+
+	code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", keyset), payload)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, response.TotalRecord, "the points of the same new serie must index one document")
+
+	code, response = requestResponse(t, fmt.Sprintf("keysets/%s/text/meta", keyset), payload)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, response.TotalRecord, "the text serie has its own document")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+type importReport struct {
+	Imported int    `json:"imported"`
+	Skipped  int    `json:"skipped"`
+	Error    string `json:"error,omitempty"`
+}
+
+func TestMetadataExportAndImport(t *testing.T) {
+
+	code, exported, err := mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/metadata/export", ksMycenaeMeta))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(exported))
+
+	lines := bytes.Split(bytes.TrimSpace(exported), []byte("\n"))
+	assert.Len(t, lines, 8, "4 number and 4 text series")
+
+	exportedIDs := map[string]bool{}
+	for _, line := range lines {
+		meta := tools.TsMeta{}
+		assert.Nil(t, json.Unmarshal(line, &meta), string(line))
+		exportedIDs[meta.TsID] = true
+	}
+
+	target := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	payload := append(exported, []byte("{invalid json}\n")...)
+
+	code, resp, err := mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/metadata/import?metricPrefix=os.:imported.os.&tagValue=host:a1-testMeta:a1-imported", target), payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	report := importReport{}
+	assert.Nil(t, json.Unmarshal(resp, &report), string(resp))
+	assert.Equal(t, importReport{Imported: 8, Skipped: 1}, report)
+
+	code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", target), `{"metric":"imported.os.cpu"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, response.TotalRecord)
+
+	hosts := map[string]bool{}
+	for _, meta := range response.Payload {
+		hosts[meta.Tags["host"]] = true
+		assert.False(t, exportedIDs[meta.TsID], "the tsid must be generated for the target keyset")
+	}
+	assert.Equal(t, map[string]bool{"a1-imported": true, "a2-testMeta": true}, hosts)
+}
+
+func TestMetadataImportInvalidRule(t *testing.T) {
+
+	for _, rule := range []string{"tagValue=host:a1", "metricPrefix=app", "metricPrefix=:app", "tagKey=host:", "tagValue=dc::dc2"} {
+
+		code, resp, err := mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/metadata/import?%s", ksMycenaeMeta, rule), []byte{})
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusBadRequest, code, rule+": "+string(resp))
+	}
+}
+
+func TestMetadataImportRewriteRules(t *testing.T) {
+
+	target := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	payload := `{"id":"r1","metric":"app.http.requests","tagKey":["host","dc","env"],"tagValue":["web01","dc1","dev"],"type":"meta"}
+{"id":"r2","metric":"application.cpu","tagKey":["dc"],"tagValue":["dc3"],"type":"meta"}
+`
+
+	// the longest prefix is replaced first and the wildcard replaces any value of the tag
+	rules := "metricPrefix=app:team.app&metricPrefix=app.http.:&tagKey=host:hostname&tagValue=dc:dc1:dc2&tagValue=host:*:a1"
+
+	code, resp, err := mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/metadata/import?%s", target, rules), []byte(payload))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code, string(resp))
+
+	code, response := requestResponse(t, fmt.Sprintf("keysets/%s/meta", target), `{"metric":"requests"}`)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Equal(t, 1, response.TotalRecord) {
+		tags := response.Payload[0].Tags
+		delete(tags, "ttl")
+		assert.Equal(t, map[string]string{"hostname": "a1", "dc": "dc2", "env": "dev"}, tags, "the tag values are matched by the original tag keys")
+	}
+
+	code, response = requestResponse(t, fmt.Sprintf("keysets/%s/meta", target), `{"metric":"team.application.cpu"}`)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Equal(t, 1, response.TotalRecord) {
+		assert.Equal(t, "dc3", response.Payload[0].Tags["dc"])
+	}
+}
+
+func TestMetadataImportLineTooLong(t *testing.T) {
+
+	target := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	payload := []byte(`{"id":"l1","metric":"line.metric","tagKey":["host"],"tagValue":["a"],"type":"meta"}` + "\n")
+	payload = append(payload, bytes.Repeat([]byte("a"), 2*1024*1024)...)
+
+	code, resp, err := mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/metadata/import", target), payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusBadRequest, code, string(resp))
+
+	report := importReport{}
+	assert.Nil(t, json.Unmarshal(resp, &report), string(resp))
+	assert.Equal(t, 0, report.Imported, "the client must know where to resume the import")
+	assert.Contains(t, report.Error, "error reading line 2")
+}
+
+func TestMetadataExportKeysetNotFound(t *testing.T) {
+
+	code, _, err := mycenaeTools.HTTP.GET("keysets/not_a_keyset/metadata/export")
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -1350,6 +1350,143 @@ func TestParseValidQueryMergeWithoutDownsample(t *testing.T) {
 
 }
 
+func TestParseValidQueryDownsampleFillMaxGap(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`merge(sum, downsample(1m, avg, previous, 10m, query(os.cpu, {app=nonexistent}, 1h)))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "1m-avg-previous", response[0].Queries[0].Downsample)
+	assert.Equal(t, "10m", response[0].Queries[0].MaxGap)
+	assert.Equal(t, 2, len(response[0].Queries[0].Order))
+	assert.Equal(t, "downsample", response[0].Queries[0].Order[0])
+	assert.Equal(t, "aggregation", response[0].Queries[0].Order[1])
+
+}
+
+func TestParseValidQueryDownsampleFillValue(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`merge(sum, downsample(1m, avg, value(-1.5), query(os.cpu, {app=nonexistent}, 1h)))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "1m-avg-value(-1.5)", response[0].Queries[0].Downsample)
+	assert.Equal(t, "", response[0].Queries[0].MaxGap)
+
+}
+
+func TestParseValidQueryWindowFunctions(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`merge(sum, downsample(1h, sum, none, increase(5m, query(os.cpu, {app=nonexistent}, 1d))))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "5m", response[0].Queries[0].Increase)
+	assert.Equal(t, "", response[0].Queries[0].Delta)
+	assert.Equal(t, 3, len(response[0].Queries[0].Order))
+	assert.Equal(t, "increase", response[0].Queries[0].Order[0])
+	assert.Equal(t, "downsample", response[0].Queries[0].Order[1])
+	assert.Equal(t, "aggregation", response[0].Queries[0].Order[2])
+
+	expression = url.QueryEscape(
+		`deriv(10m, merge(avg, delta(1m, irate(1m, query(os.cpu, {app=nonexistent}, 1d)))))`)
+
+	status, response = parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, "1m", response[0].Queries[0].Irate)
+	assert.Equal(t, "1m", response[0].Queries[0].Delta)
+	assert.Equal(t, "10m", response[0].Queries[0].Deriv)
+	assert.Equal(t, []string{"irate", "delta", "aggregation", "deriv"}, response[0].Queries[0].Order)
+
+}
+
+func TestParseValidQuerySmoothingFunctions(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`movingAverage(10m, merge(sum, rollingMax(5m, query(os.cpu, {app=nonexistent}, 1d))))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "10m", response[0].Queries[0].MovingAverage)
+	assert.Equal(t, "5m", response[0].Queries[0].RollingMax)
+	assert.Equal(t, "", response[0].Queries[0].RollingSum)
+	assert.Equal(t, []string{"rollingMax", "aggregation", "movingAverage"}, response[0].Queries[0].Order)
+
+	expression = url.QueryEscape(
+		`holtWinters(0.3, 0.1, ewma(0.5, merge(avg, query(os.cpu, {app=nonexistent}, 1d))))`)
+
+	status, response = parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 0.5, response[0].Queries[0].Ewma)
+	assert.Equal(t, 0.3, response[0].Queries[0].HoltWinters.Alpha)
+	assert.Equal(t, 0.1, response[0].Queries[0].HoltWinters.Beta)
+	assert.Equal(t, []string{"aggregation", "ewma", "holtWinters"}, response[0].Queries[0].Order)
+
+}
+
+func TestParseValidQueryTimeshift(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`timeshift(1w, merge(sum, downsample(1m, max, none, query(os.cpu, {app=nonexistent}, 1h))))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "1h", response[0].Relative)
+	assert.Equal(t, "sum", response[0].Queries[0].Aggregator)
+	assert.Equal(t, "1m-max-none", response[0].Queries[0].Downsample)
+	assert.Equal(t, "os.cpu", response[0].Queries[0].Metric)
+	assert.Equal(t, "1w", response[0].Queries[0].TimeShift)
+	assert.Equal(t, false, response[0].Queries[0].Compare)
+	assert.Equal(t, 2, len(response[0].Queries[0].Order))
+	assert.Equal(t, "downsample", response[0].Queries[0].Order[0])
+	assert.Equal(t, "aggregation", response[0].Queries[0].Order[1])
+	assert.Equal(t, 1, len(response[0].Queries[0].Filters))
+	assert.Equal(t, tagApp, response[0].Queries[0].Filters[0].Tagk)
+	assert.Equal(t, "nonexistent", response[0].Queries[0].Filters[0].Filter)
+
+}
+
+func TestParseValidQueryTimeshiftCompare(t *testing.T) {
+
+	expression := url.QueryEscape(
+		`merge(sum, timeshift(1d, compare, query(os.cpu, {app=nonexistent}, 1h)))`)
+
+	status, response := parseExp(t, fmt.Sprintf("exp=%s", expression))
+
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response[0].Queries))
+	assert.Equal(t, "1h", response[0].Relative)
+	assert.Equal(t, "sum", response[0].Queries[0].Aggregator)
+	assert.Equal(t, "1d", response[0].Queries[0].TimeShift)
+	assert.Equal(t, true, response[0].Queries[0].Compare)
+	assert.Equal(t, 1, len(response[0].Queries[0].Order))
+	assert.Equal(t, "aggregation", response[0].Queries[0].Order[0])
+
+}
+
 func TestParseValidQueryGroupbyExpandMatchNoTags(t *testing.T) {
 
 	expression := url.QueryEscape(
@@ -1901,9 +2038,9 @@ func TestParseInvalidQuery(t *testing.T) {
 			"Invalid unit",
 		},
 		"DownsampleExtraParamsRelative": {
-			`merge(sum, downsample(1m ,1m, min, none, query(os.cpu, {app=nonexistent}, 5m)))`,
-			"downsample expects 4 parameters but found 5: [1m 1m min none query(os.cpu,{app=nonexistent},5m)]",
-			"downsample needs 4 parameters: downsample operation, downsample period, fill option and a function",
+			`merge(sum, downsample(1m ,1m, min, none, 5m, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"downsample expects 4 or 5 parameters but found 6: [1m 1m min none 5m query(os.cpu,{app=nonexistent},5m)]",
+			"downsample needs 4 or 5 parameters: downsample operation, downsample period, fill option, an optional max gap and a function",
 		},
 		"MergeExtraParamsRelative": {
 			`merge(sum, sum, downsample(1m, min, none, query(os.cpu, {app=nonexistent}, 5m)))`,
@@ -2005,6 +2142,56 @@ func TestParseInvalidQuery(t *testing.T) {
 			"invalid filter value >",
 			"invalid filter value >",
 		},
+		"DownsampleInvalidFillValue": {
+			`merge(sum, downsample(1m, avg, value(x), query(os.cpu, {app=nonexistent}, 5m)))`,
+			"Invalid fill value value(x)",
+			"Invalid fill value value(x)",
+		},
+		"DownsampleInvalidMaxGap": {
+			`merge(sum, downsample(1m, avg, previous, 1x, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"Invalid unit",
+			"Invalid unit",
+		},
+		"IncreaseMissingWindow": {
+			`merge(sum, increase(query(os.cpu, {app=nonexistent}, 5m)))`,
+			"increase expects 2 parameters but found 1: [query(os.cpu,{app=nonexistent},5m)]",
+			"increase needs 2 parameters: a time window and a function",
+		},
+		"DeltaDouble": {
+			`merge(sum, delta(1m, delta(1m, query(os.cpu, {app=nonexistent}, 5m))))`,
+			"You can use only one delta function per expression",
+			"You can use only one delta function per expression",
+		},
+		"RollingSumDouble": {
+			`rollingSum(5m, merge(sum, rollingSum(1m, query(os.cpu, {app=nonexistent}, 5m))))`,
+			"You can use only one rollingSum function per expression",
+			"You can use only one rollingSum function per expression",
+		},
+		"EwmaOutOfRange": {
+			`ewma(1.5, merge(sum, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"ewma alpha, the 1st parameter is out of range: 1.5",
+			"ewma alpha, the 1st parameter, needs to be a number bigger than 0 and at most 1",
+		},
+		"HoltWintersMissingTrend": {
+			`holtWinters(0.5, merge(sum, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"holtWinters expects 3 parameters but found 2: [0.5 merge(sum,query(os.cpu,{app=nonexistent},5m))]",
+			"holtWinters needs 3 parameters: a smoothing factor, a trend factor and a function",
+		},
+		"TimeshiftInvalidDuration": {
+			`timeshift(1x, merge(sum, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"Invalid unit",
+			"Invalid unit",
+		},
+		"TimeshiftInvalidOption": {
+			`timeshift(1d, diff, merge(sum, query(os.cpu, {app=nonexistent}, 5m)))`,
+			"timeshift option, the 2nd parameter, needs to be 'compare' but found 'diff'",
+			"timeshift option, the 2nd parameter, needs to be 'compare' but found 'diff'",
+		},
+		"TimeshiftDouble": {
+			`timeshift(1d, timeshift(1w, merge(sum, query(os.cpu, {app=nonexistent}, 5m))))`,
+			"You can use only one timeshift function per expression",
+			"You can use only one timeshift function per expression",
+		},
 		"ParseEmptyQueryExpression": {
 			``,
 			"no expression found",
@@ -209,6 +209,9 @@ func tsInsert(keyset string) {
 		"ts15TsdbQuery":   {"ts15tsdb", "test", 1451649702000, 604800000, 1.0, 1.0, 53},
 		"ts17TsdbQuery":   {"ts17tsdb", "test", 1448452800000, 60000, 0.0, 1.0, 5},
 		"ts17_1TsdbQuery": {"ts17tsdb", "test2", 1448452800000, 60000, 0.0, 1.0, 5},
+		// series not aligned: 10, 20, 30 and 1, 2 thirty seconds later
+		"ts18TsdbQuery":  {"ts18tsdb", "test1", 1448452800000, 60000, 10.0, 10.0, 3},
+		"ts18TsdbQuery2": {"ts18tsdb", "test2", 1448452830000, 60000, 1.0, 1.0, 2},
 	}
 
 	for test, data := range cases {
@@ -2343,7 +2346,7 @@ func TestTsdbQueryMergeBiggerPointsSameDate(t *testing.T) {
 		"showTSUIDs": true,
 		"queries": [{
 			"metric": "ts05tsdb",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -2613,7 +2616,7 @@ func TestTsdbQueryMergeTimeDiff(t *testing.T) {
 		"showTSUIDs": true,
 		"queries": [{
 			"metric": "ts01_2tsdb",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -2639,6 +2642,45 @@ func TestTsdbQueryMergeTimeDiff(t *testing.T) {
 	}
 }
 
+func TestTsdbQueryMergeInterpolation(t *testing.T) {
+
+	cases := map[string]struct {
+		aggregator string
+		expected   []float32
+	}{
+		"SumInterpolated":  {"sum", []float32{10, 16, 21.5, 27, 30}},
+		"MaxInterpolated":  {"max", []float32{10, 15, 20, 25, 30}},
+		"AvgInterpolated":  {"avg", []float32{10, 8, 10.75, 13.5, 30}},
+		"ZimsumExactMatch": {"zimsum", []float32{10, 1, 20, 2, 30}},
+		"MimmaxExactMatch": {"mimmax", []float32{10, 1, 20, 2, 30}},
+		"MimminExactMatch": {"mimmin", []float32{10, 1, 20, 2, 30}},
+	}
+
+	for test, data := range cases {
+
+		payload := fmt.Sprintf(`{
+			"start": 1448452800000,
+			"end": 1448452920000,
+			"showTSUIDs": true,
+			"queries": [{
+				"metric": "ts18tsdb",
+				"aggregator": "%s"
+			}]
+		}`, data.aggregator)
+
+		keys, payloadPoints := postAPIQueryAndCheck(t, payload, "ts18tsdb", 1, 5, 0, 1, 2, "ts18TsdbQuery", "ts18TsdbQuery2")
+
+		assert.Equal(t, "host", payloadPoints[0].AggTags[0], test)
+
+		dateStart := 1448452800
+		for i, key := range keys {
+			assert.Exactly(t, data.expected[i], float32(payloadPoints[0].Dps[key].(float64)), test)
+			assert.Exactly(t, strconv.Itoa(dateStart), key, test)
+			dateStart += 30
+		}
+	}
+}
+
 func TestTsdbQueryMergeTimeDiffDownsampleExactBeginAndEnd(t *testing.T) {
 
 	payload := `{
@@ -2971,6 +3013,84 @@ func TestTsdbQueryNullValuesDownsampleZero(t *testing.T) {
 	}
 }
 
+func TestTsdbQueryNullValuesDownsampleFillPolicies(t *testing.T) {
+
+	// ts09tsdb 3m-sum buckets: 3, 12, 21, 30, 39, no data for 63 minutes, 228, 237...
+	bucket := func(k int) float64 {
+		return float64(9*k + 3)
+	}
+
+	cases := map[string]struct {
+		fill     string
+		maxGap   string
+		dps      int
+		expected func(k int) (float64, bool)
+	}{
+		"Previous": {"previous", "", 30, func(k int) (float64, bool) {
+			if k > 4 && k < 25 {
+				return bucket(4), true
+			}
+			return bucket(k), true
+		}},
+		"LastMaxGap": {"last", "15m", 15, func(k int) (float64, bool) {
+			if k > 9 && k < 25 {
+				return 0, false
+			}
+			if k > 4 && k < 25 {
+				return bucket(4), true
+			}
+			return bucket(k), true
+		}},
+		"Linear": {"linear", "", 30, func(k int) (float64, bool) {
+			return bucket(k), true
+		}},
+		"LinearMaxGap": {"linear", "30m", 10, func(k int) (float64, bool) {
+			return bucket(k), k < 5 || k >= 25
+		}},
+		"Value": {"value(-1)", "", 30, func(k int) (float64, bool) {
+			if k > 4 && k < 25 {
+				return -1, true
+			}
+			return bucket(k), true
+		}},
+	}
+
+	for test, data := range cases {
+
+		maxGap := ""
+		if data.maxGap != "" {
+			maxGap = fmt.Sprintf(`"maxGap": "%s",`, data.maxGap)
+		}
+
+		payload := fmt.Sprintf(`{
+			"start": 1448452800000,
+			"end": 1448458150000,
+			"showTSUIDs": true,
+			"queries": [{
+				"metric": "ts09tsdb",
+				"downsample": "3m-sum-%s",
+				%s
+				"aggregator": "sum"
+			}]
+		}`, data.fill, maxGap)
+
+		_, payloadPoints := postAPIQueryAndCheck(t, payload, "ts09tsdb", 1, data.dps, 1, 0, 1, "ts9TsdbQuery")
+
+		for k := 0; k < 30; k++ {
+
+			key := strconv.Itoa(1448452800 + k*180)
+			value, ok := data.expected(k)
+
+			if !ok {
+				assert.NotContains(t, payloadPoints[0].Dps, key, test)
+				continue
+			}
+
+			assert.Exactly(t, value, payloadPoints[0].Dps[key], test)
+		}
+	}
+}
+
 func TestTsdbQueryNullValueMerge(t *testing.T) {
 
 	payload := `{
@@ -2979,7 +3099,7 @@ func TestTsdbQueryNullValueMerge(t *testing.T) {
 		"showTSUIDs": true,
 		"queries": [{
 			"metric": "ts07_1tsdb",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -3331,7 +3451,7 @@ func TestTsdbQueryNullValueMergeAndDownsample(t *testing.T) {
 		"queries": [{
 			"metric": "ts07_1tsdb",
 			"downsample": "3m-sum",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -3369,7 +3489,7 @@ func TestTsdbQueryNullValueMergeAndDownsampleNone(t *testing.T) {
 		"queries": [{
 			"metric": "ts07_1tsdb",
 			"downsample": "3m-sum-none",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -3521,7 +3641,7 @@ func TestTsdbQueryTwoTSMerge(t *testing.T) {
 		"queries": [{
 			"metric": "ts07_1tsdb",
 			"downsample": "3m-sum",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		},{
 			"metric": "ts07_1tsdb",
 			"downsample": "3m-sum",
@@ -3908,7 +4028,7 @@ func TestTsdbQueryFilterValuesGreaterThan(t *testing.T) {
 		"queries": [{
 			"metric": "ts01_2tsdb",
 			"filterValue": "> 49",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -3943,7 +4063,7 @@ func TestTsdbQueryFilterValuesGreaterThanEqualTo(t *testing.T) {
 		"queries": [{
 			"metric": "ts01_2tsdb",
 			"filterValue": "> = 49",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -3978,7 +4098,7 @@ func TestTsdbQueryFilterValuesLessThan(t *testing.T) {
 		"queries": [{
 			"metric": "ts01_2tsdb",
 			"filterValue": "<50",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -4013,7 +4133,7 @@ func TestTsdbQueryFilterValuesLessThanEqualTo(t *testing.T) {
 		"queries": [{
 			"metric": "ts01_2tsdb",
 			"filterValue": "<=50",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -4048,7 +4168,7 @@ func TestTsdbQueryFilterValuesEqualTo(t *testing.T) {
 		"queries": [{
 			"metric": "ts01_2tsdb",
 			"filterValue": "==50",
-			"aggregator": "sum"
+			"aggregator": "zimsum"
 		}]
 	}`
 
@@ -4487,7 +4607,7 @@ func TestTsdbQueryOrderMergeDownsampleAndRate(t *testing.T) {
 			"rateOptions": {
 				"counter": false
 			},
-			"aggregator": "sum",
+			"aggregator": "zimsum",
 			"order":["aggregation","downsample","rate"]
 		}]
 	}`
@@ -4531,7 +4651,7 @@ func TestTsdbQueryOrderMergeRateAndDownsample(t *testing.T) {
 			"rateOptions": {
 				"counter": false
 			},
-			"aggregator": "sum",
+			"aggregator": "zimsum",
 			"order":["aggregation","rate","downsample"]
 		}]
 	}`
@@ -4596,7 +4716,7 @@ func TestTsdbQueryOrderRateMergeAndDownsample(t *testing.T) {
 			"metric": "ts07_2tsdb",
 			"rate": true,
 			"downsample": "3m-avg",
-			"aggregator": "sum",
+			"aggregator": "zimsum",
 			"order":["rate","aggregation","downsample"]
 		}]
 	}`
@@ -4780,7 +4900,7 @@ func TestTsdbQueryOrderFunctionNotUsed(t *testing.T) {
 			"rateOptions": {
 				"counter": false
 			},
-			"aggregator": "sum",
+			"aggregator": "zimsum",
 			"order":["aggregation","rate","filterValue","downsample"]
 		}]
 	}`
@@ -4871,6 +4991,127 @@ func TestTsdbQueryRateTrueRateOptionsTrueCounter(t *testing.T) {
 	}
 }
 
+func TestTsdbQueryWindowFunctions(t *testing.T) {
+
+	// ts12tsdb: 1, 10, 100, 1000, 10000 | reset | 1000, 1, 10, 100, 1000 | 10000, 3000
+	cases := map[string]struct {
+		function string
+		expected []float32
+	}{
+		"Increase": {"increase", []float32{9999, 2000, 12000}},
+		"Delta":    {"delta", []float32{9999, -9000, 2000}},
+		"Irate":    {"irate", []float32{150, 15, 50}},
+	}
+
+	for test, data := range cases {
+
+		payload := fmt.Sprintf(`{
+			"start": 1448452800000,
+			"end": 1448453460000,
+			"showTSUIDs": true,
+			"queries": [{
+				"metric": "ts12tsdb",
+				"%s": "5m",
+				"aggregator": "sum",
+				"tags": {
+					"host": "test-grafana1"
+				}
+			}]
+		}`, data.function)
+
+		keys, payloadPoints := postAPIQueryAndCheck(t, payload, "ts12tsdb", 1, 3, 1, 0, 1)
+
+		assert.Equal(t, ts12IDTsdbQuery, payloadPoints[0].Tsuuids[0], test)
+
+		dateStart := 1448452800
+		for i, key := range keys {
+			assert.Exactly(t, data.expected[i], float32(payloadPoints[0].Dps[key].(float64)), test)
+			assert.Exactly(t, strconv.Itoa(dateStart), key, test)
+			dateStart += 300
+		}
+	}
+}
+
+func TestTsdbQueryRollingFunctions(t *testing.T) {
+
+	// ts12tsdb: 1, 10, 100, 1000, 10000, 1000, 1, 10, 100, 1000, 10000, 3000
+	// the query starts on the 6th point, the read goes back so it has a full window
+	cases := map[string]struct {
+		function string
+		expected []float32
+	}{
+		"MovingAverage": {"movingAverage", []float32{5500, 500.5, 5.5, 55, 550, 5500, 6500}},
+		"RollingSum":    {"rollingSum", []float32{11000, 1001, 11, 110, 1100, 11000, 13000}},
+		"RollingMax":    {"rollingMax", []float32{10000, 1000, 10, 100, 1000, 10000, 10000}},
+		"RollingMin":    {"rollingMin", []float32{1000, 1, 1, 10, 100, 1000, 3000}},
+	}
+
+	for test, data := range cases {
+
+		payload := fmt.Sprintf(`{
+			"start": 1448453100000,
+			"end": 1448453460000,
+			"showTSUIDs": true,
+			"queries": [{
+				"metric": "ts12tsdb",
+				"%s": "2m",
+				"aggregator": "sum",
+				"tags": {
+					"host": "test-grafana1"
+				}
+			}]
+		}`, data.function)
+
+		keys, payloadPoints := postAPIQueryAndCheck(t, payload, "ts12tsdb", 1, 7, 1, 0, 1)
+
+		assert.Equal(t, ts12IDTsdbQuery, payloadPoints[0].Tsuuids[0], test)
+
+		dateStart := 1448453100
+		for i, key := range keys {
+			assert.Exactly(t, data.expected[i], float32(payloadPoints[0].Dps[key].(float64)), test)
+			assert.Exactly(t, strconv.Itoa(dateStart), key, test)
+			dateStart += 60
+		}
+	}
+}
+
+func TestTsdbQueryExponentialSmoothing(t *testing.T) {
+
+	cases := map[string]struct {
+		options  string
+		expected []float32
+	}{
+		"Ewma":        {`"ewma": 0.5`, []float32{1, 5.5, 52.75, 526.375}},
+		"HoltWinters": {`"holtWinters": {"alpha": 0.5, "beta": 0.5}`, []float32{1, 10, 59.5, 544.375}},
+	}
+
+	for test, data := range cases {
+
+		payload := fmt.Sprintf(`{
+			"start": 1448452800000,
+			"end": 1448452980000,
+			"showTSUIDs": true,
+			"queries": [{
+				"metric": "ts12tsdb",
+				%s,
+				"aggregator": "sum",
+				"tags": {
+					"host": "test-grafana1"
+				}
+			}]
+		}`, data.options)
+
+		keys, payloadPoints := postAPIQueryAndCheck(t, payload, "ts12tsdb", 1, 4, 1, 0, 1)
+
+		dateStart := 1448452800
+		for i, key := range keys {
+			assert.Exactly(t, data.expected[i], float32(payloadPoints[0].Dps[key].(float64)), test)
+			assert.Exactly(t, strconv.Itoa(dateStart), key, test)
+			dateStart += 60
+		}
+	}
+}
+
 func TestTsdbQueryRateTrueRateOptionsTrueCounterMax(t *testing.T) {
 
 	payload := `{
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reindexReport struct {
+	Status           string   `json:"status"`
+	ScannedTSIDs     int      `json:"scannedTSIDs"`
+	MissingDocuments int      `json:"missingDocuments"`
+	ScannedDocuments int      `json:"scannedDocuments"`
+	OrphanDocuments  int      `json:"orphanDocuments"`
+	Errors           []string `json:"errors"`
+}
+
+func TestReindexMetadata(t *testing.T) {
+
+	payload := []byte(`{"since":"1h","keysets":["` + ksMycenaeMeta + `"],"orphans":true}`)
+
+	code, resp, err := mycenaeTools.HTTP.POST("admin/metadata/reindex", payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusAccepted, code, string(resp))
+
+	report := reindexReport{}
+
+	// only one job runs at a time, the second one is refused unless the first is already done
+	code, resp, err = mycenaeTools.HTTP.POST("admin/metadata/reindex", payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	if code != http.StatusAccepted {
+		assert.Equal(t, http.StatusConflict, code, string(resp))
+	}
+
+	for i := 0; i < 60; i++ {
+
+		code, resp, err = mycenaeTools.HTTP.GET("admin/metadata/reindex")
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusOK, code)
+
+		assert.Nil(t, json.Unmarshal(resp, &report), string(resp))
+		if report.Status != "running" {
+			break
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	assert.Equal(t, "done", report.Status)
+	assert.Empty(t, report.Errors)
+	assert.True(t, report.ScannedDocuments > 0, string(resp))
+}
+
+func TestReindexMetadataInvalidRequest(t *testing.T) {
+
+	for _, payload := range []string{`{"since":"1d"}`, `{"since":"-1h"}`, `{"since":"0s"}`, `{"keyspaces":["not_a_keyspace"]}`, `{"keysets":["not_a_keyset"]}`} {
+
+		code, resp, err := mycenaeTools.HTTP.POST("admin/metadata/reindex", []byte(payload))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusBadRequest, code, payload+": "+string(resp))
+	}
+}
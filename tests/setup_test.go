@@ -66,7 +66,7 @@ func TestMain(m *testing.M) {
 		ksMycenaeTsdb = mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
 		ksTTLKeyspace = mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
 
-		wg.Add(8)
+		wg.Add(11)
 
 		go func() { sendPointsExpandExp(ksMycenae); wg.Done() }()
 		go func() { sendPointsMetadata(ksMycenaeMeta); wg.Done() }()
@@ -77,6 +77,9 @@ func TestMain(m *testing.M) {
 		go func() { sendPointsV2(ksMycenae); wg.Done() }()
 		go func() { sendPointsV2Text(ksMycenae); wg.Done() }()
 		go func() { sendPointsToTTLKeyspace(ksTTLKeyspace); wg.Done() }()
+		go func() { sendPointsHistogram(ksMycenae); wg.Done() }()
+		go func() { sendPointsTextQuery(ksMycenae); wg.Done() }()
+		go func() { sendPointsGrafanaJSON(ksMycenae); wg.Done() }()
 
 		wg.Wait()
 
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uol/mycenae/tests/tools"
+)
+
+type textQueryPoint struct {
+	Timestamp int64  `json:"timestamp"`
+	Text      string `json:"text"`
+	Tsuid     string `json:"tsuid"`
+}
+
+type textQueryResponse struct {
+	Metric         string            `json:"metric"`
+	Tags           map[string]string `json:"tags"`
+	AggregatedTags []string          `json:"aggregateTags"`
+	Texts          []textQueryPoint  `json:"texts"`
+	NextCursor     string            `json:"nextCursor"`
+	Counts         map[string]int    `json:"counts"`
+}
+
+func sendPointsTextQuery(keyset string) {
+
+	points := []tools.TextPoint{}
+
+	for i := 0; i < 6; i++ {
+		for _, host := range []string{"a", "b"} {
+			points = append(points, tools.TextPoint{
+				Text:      fmt.Sprintf("deploy %d on %s", i, host),
+				Metric:    "text.query.events",
+				Tags:      map[string]string{"ksid": keyset, "host": host},
+				Timestamp: int64(1448452800 + i*60),
+			})
+		}
+	}
+
+	jsonPoints, err := json.Marshal(points)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, resp, err := mycenaeTools.HTTP.POST("api/text/put", jsonPoints)
+	if err != nil {
+		panic(err)
+	}
+	if code != http.StatusNoContent {
+		log.Fatal("Error sending points! - textQuery_test.go, Code: ", code, " ", string(resp))
+	}
+}
+
+func postTextQuery(t *testing.T, payload string) (int, []textQueryResponse) {
+
+	code, resp, err := mycenaeTools.HTTP.POST("keysets/"+ksMycenae+"/api/text/query", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := []textQueryResponse{}
+
+	if code == http.StatusOK {
+		if err := json.Unmarshal(resp, &responses); err != nil {
+			t.Fatal(err, string(resp))
+		}
+	}
+
+	return code, responses
+}
+
+func TestTextQueryPagination(t *testing.T) {
+
+	query := `{
+		"start": 1448452800000,
+		"end": 1448453200000,
+		"queries": [{
+			"metric": "text.query.events",
+			"limit": 5,
+			"cursor": "%s",
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": false}]
+		}]
+	}`
+
+	texts := []textQueryPoint{}
+	cursor := ""
+	pages := 0
+
+	for {
+		code, responses := postTextQuery(t, fmt.Sprintf(query, cursor))
+		if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, responses, 1) {
+			return
+		}
+
+		assert.Equal(t, []string{"host"}, responses[0].AggregatedTags)
+
+		texts = append(texts, responses[0].Texts...)
+		pages++
+
+		cursor = responses[0].NextCursor
+		if cursor == "" {
+			break
+		}
+
+		assert.Len(t, responses[0].Texts, 5)
+	}
+
+	assert.Equal(t, 3, pages)
+
+	if assert.Len(t, texts, 12) {
+		for i := 1; i < len(texts); i++ {
+			assert.True(t, texts[i-1].Timestamp <= texts[i].Timestamp, "texts out of order")
+			assert.False(t, texts[i-1].Timestamp == texts[i].Timestamp && texts[i-1].Tsuid == texts[i].Tsuid, "repeated text")
+		}
+	}
+}
+
+func TestTextQueryPaginationPerGroup(t *testing.T) {
+
+	query := `{
+		"start": 1448452800000,
+		"end": 1448453200000,
+		"queries": [{
+			"metric": "text.query.events",
+			"limit": 4,
+			"cursor": "%s",
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": true}]
+		}]
+	}`
+
+	code, responses := postTextQuery(t, fmt.Sprintf(query, ""))
+	if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, responses, 2) {
+		return
+	}
+
+	for _, first := range responses {
+
+		host := first.Tags["host"]
+		texts := first.Texts
+		cursor := first.NextCursor
+
+		for cursor != "" {
+			code, next := postTextQuery(t, fmt.Sprintf(query, cursor))
+			if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, next, 1, "a cursor only continues its group") {
+				return
+			}
+
+			assert.Equal(t, host, next[0].Tags["host"])
+
+			texts = append(texts, next[0].Texts...)
+			cursor = next[0].NextCursor
+		}
+
+		assert.Len(t, texts, 6, host)
+	}
+}
+
+func TestTextQueryContains(t *testing.T) {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448453200000,
+		"queries": [{
+			"metric": "text.query.events",
+			"contains": "3 on",
+			"filters": [{"type": "literal_or", "tagk": "host", "filter": "a", "groupBy": false}]
+		}]
+	}`
+
+	code, responses := postTextQuery(t, payload)
+	if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, responses, 1) {
+		return
+	}
+
+	if assert.Len(t, responses[0].Texts, 1) {
+		assert.Equal(t, "deploy 3 on a", responses[0].Texts[0].Text)
+		assert.Equal(t, int64(1448452980), responses[0].Texts[0].Timestamp)
+	}
+}
+
+func TestTextQueryCount(t *testing.T) {
+
+	payload := `{
+		"start": 1448452800000,
+		"end": 1448453200000,
+		"queries": [{
+			"metric": "text.query.events",
+			"search": "deploy [0-3] on",
+			"count": "2m",
+			"filters": [{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": false}]
+		}]
+	}`
+
+	code, responses := postTextQuery(t, payload)
+	if !assert.Equal(t, http.StatusOK, code) || !assert.Len(t, responses, 1) {
+		return
+	}
+
+	assert.Empty(t, responses[0].Texts)
+	assert.Equal(t, map[string]int{"1448452800": 4, "1448452920": 4}, responses[0].Counts)
+}
+
+func TestTextQueryInvalid(t *testing.T) {
+
+	cases := map[string]string{
+		"SearchAndContains": `{"start": 1448452800000, "queries": [{"metric": "text.query.events", "search": "a", "contains": "a"}]}`,
+		"InvalidRegexp":     `{"start": 1448452800000, "queries": [{"metric": "text.query.events", "search": "("}]}`,
+		"CursorWithCount":   `{"start": 1448452800000, "queries": [{"metric": "text.query.events", "count": "1m", "cursor": "abc"}]}`,
+		"InvalidCursor":     `{"start": 1448452800000, "queries": [{"metric": "text.query.events", "cursor": "***"}]}`,
+	}
+
+	for name, payload := range cases {
+		code, _ := postTextQuery(t, payload)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+	}
+}
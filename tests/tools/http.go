@@ -121,6 +121,16 @@ func (hT *httpTool) DELETE(url string) (statusCode int, respData []byte, err err
 
 }
 
+func (hT *httpTool) HEAD(url string) (statusCode int, respData []byte, err error) {
+
+	var payload []byte
+
+	statusCode, respData, err = hT.request("HEAD", url, payload, nil)
+
+	return
+
+}
+
 func (hT *httpTool) request(method string, url string, payload []byte, headers map[string]string) (statusCode int, respData []byte, err error) {
 	fullPath := ""
 
@@ -168,6 +168,25 @@ type TSDBquery struct {
 	Order       []string          `json:"order"`
 	FilterValue string            `json:"filterValue"`
 	Filters     []TSDBfilter      `json:"filters"`
+	MaxGap      string            `json:"maxGap"`
+	Increase    string            `json:"increase"`
+	Delta       string            `json:"delta"`
+	Irate       string            `json:"irate"`
+	Deriv       string            `json:"deriv"`
+
+	MovingAverage string           `json:"movingAverage"`
+	RollingSum    string           `json:"rollingSum"`
+	RollingMax    string           `json:"rollingMax"`
+	RollingMin    string           `json:"rollingMin"`
+	Ewma          float64          `json:"ewma"`
+	HoltWinters   *TSDBholtWinters `json:"holtWinters"`
+	TimeShift     string           `json:"timeShift"`
+	Compare       bool             `json:"compare"`
+}
+
+type TSDBholtWinters struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
 }
 
 type TSDBrateOptions struct {
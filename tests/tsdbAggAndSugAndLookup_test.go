@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/uol/mycenae/tests/tools"
@@ -60,8 +61,8 @@ func TestTsdb(t *testing.T) {
 	}{
 		"Aggregator": {
 			fmt.Sprintf("keysets/%s/api/aggregators", ksMycenaeTsdb),
-			[]string{"avg", "count", "min", "max", "sum"},
-			5,
+			[]string{"avg", "count", "min", "max", "sum", "zimsum", "mimmin", "mimmax"},
+			8,
 		},
 		"SuggestMetrics": {
 			fmt.Sprintf("keysets/%s/api/suggest?type=metrics", ksMycenaeTsdb),
@@ -88,6 +89,11 @@ func TestTsdb(t *testing.T) {
 			[]string{"execution.time", "os.cpu", "os.cpuTest"},
 			3,
 		},
+		"SuggestMetricsActive": {
+			fmt.Sprintf("keysets/%s/api/suggest?type=metrics&activeWithin=1h", ksMycenaeTsdb),
+			[]string{"execution.time", "os.cpu", "os.cpuTest"},
+			3,
+		},
 	}
 
 	for test, data := range cases {
@@ -263,3 +269,125 @@ func TestTsdbLookupMetricFullNameOnlyOneResult(t *testing.T) {
 //    assert.Equal(t, 400, code)
 //    assert.Equal(t, "missing query parameter \"m\"", lookupList.Error, "the total records are different than expected")
 //}
+
+func TestTsdbSearchLookupPaging(t *testing.T) {
+
+	payload := []byte(`{"metric":"os.cpu","tags":[{"key":"host","value":"a1-testTsdbMeta"}],"limit":1,"startIndex":1}`)
+
+	code, response, err := mycenaeTools.HTTP.POST(fmt.Sprintf("keysets/%s/api/search/lookup", ksMycenaeTsdb), payload)
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	returnJson := struct {
+		Type         string               `json:"type"`
+		Metric       string               `json:"metric"`
+		Tags         []map[string]string  `json:"tags"`
+		Limit        int                  `json:"limit"`
+		Results      []tools.LookupResult `json:"results"`
+		StartIndex   int                  `json:"startIndex"`
+		TotalResults int                  `json:"totalResults"`
+	}{}
+
+	err = json.Unmarshal(response, &returnJson)
+	assert.Nil(t, err, "Error unmarshaling lookup result json: "+string(response))
+
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "LOOKUP", returnJson.Type)
+	assert.Equal(t, "os.cpu", returnJson.Metric)
+	assert.Equal(t, []map[string]string{{"key": "host", "value": "a1-testTsdbMeta"}}, returnJson.Tags)
+	assert.Equal(t, 1, returnJson.Limit)
+	assert.Equal(t, 1, returnJson.StartIndex)
+	assert.Equal(t, 2, returnJson.TotalResults)
+	assert.Len(t, returnJson.Results, 1)
+}
+
+func TestTsdbUIDMeta(t *testing.T) {
+
+	uid := fmt.Sprintf("%X", "os.cpuTest")
+
+	code, response, err := mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/api/uid/uidmeta?uid=%s&type=metric", ksMycenaeTsdb, uid))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	uidMeta := map[string]interface{}{}
+	err = json.Unmarshal(response, &uidMeta)
+	assert.Nil(t, err, "Error unmarshaling uid meta json: "+string(response))
+
+	assert.Equal(t, 200, code)
+	assert.Equal(t, uid, uidMeta["uid"])
+	assert.Equal(t, "METRIC", uidMeta["type"])
+	assert.Equal(t, "os.cpuTest", uidMeta["name"])
+
+	code, _, err = mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/api/uid/uidmeta?uid=%X&type=metric", ksMycenaeTsdb, "os.cpuNotFound"))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+
+	assert.Equal(t, 404, code)
+}
+
+func TestTsdbTSMetaSeen(t *testing.T) {
+
+	keyset := mycenaeTools.Mycenae.CreateKeyset(createKeysetName())
+
+	send := func() {
+		code, resp, err := mycenaeTools.HTTP.POST("api/put", []byte(`{
+			"value": 1.0,
+			"metric": "seen.metric",
+			"tags": {"ksid": "`+keyset+`", "host": "a"}
+		}`))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusNoContent, code, string(resp))
+	}
+
+	tsMetas := func() []map[string]interface{} {
+		code, response, err := mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/api/uid/tsmeta?m=%s", keyset, url.QueryEscape("seen.metric{host=a}")))
+		if err != nil {
+			t.Error(err)
+			t.SkipNow()
+		}
+		assert.Equal(t, http.StatusOK, code, string(response))
+
+		metas := []map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(response, &metas), string(response))
+
+		return metas
+	}
+
+	send()
+	time.Sleep(tools.Sleep3)
+
+	metas := tsMetas()
+	if !assert.Len(t, metas, 1) {
+		return
+	}
+
+	created := metas[0]["created"].(float64)
+	assert.True(t, created > 0, "the first seen timestamp is the creation")
+	assert.True(t, metas[0]["lastReceived"].(float64) >= created)
+
+	send()
+	send()
+	time.Sleep(tools.Sleep3)
+
+	metas = tsMetas()
+	if assert.Len(t, metas, 1, "the touched serie keeps its document") {
+		assert.Equal(t, created, metas[0]["created"], "the first seen timestamp is kept")
+	}
+
+	code, response, err := mycenaeTools.HTTP.GET(fmt.Sprintf("keysets/%s/api/suggest?type=metrics&activeWithin=1h", keyset))
+	if err != nil {
+		t.Error(err)
+		t.SkipNow()
+	}
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(response), "seen.metric")
+}